@@ -0,0 +1,55 @@
+// Command tickconv converts tick files between the raw format read by historical.NewTickReader and the block
+// compressed format read by historical.NewBlockTickReader.
+//
+//	tickconv -to block -digits 5 eurusd.bin eurusd.blk
+//	tickconv -to raw eurusd.blk eurusd.bin
+//
+// The symbol and the digits default to the header of the raw file, files written before the header was
+// introduced require -digits.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/datasource/historical"
+)
+
+func main() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	to := flag.String("to", "block", "target format: block or raw")
+	symbol := flag.String("symbol", "", "symbol recorded in the block file header")
+	digits := flag.Int("digits", -1, "price digits the block file stores prices at")
+	flag.Parse()
+
+	if flag.NArg() != 2 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: tickconv -to <block|raw> [flags] <src> <dst>")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	src, dst := flag.Arg(0), flag.Arg(1)
+
+	start := time.Now()
+	var err error
+	switch *to {
+	case "block":
+		err = historical.ConvertToBlocks(src, dst, *symbol, *digits)
+	case "raw":
+		err = historical.ConvertFromBlocks(src, dst)
+	default:
+		err = fmt.Errorf("unknown target format %q", *to)
+	}
+	if err != nil {
+		slog.Error("conversion failed", "error", err)
+		os.Exit(1)
+	}
+
+	srcInfo, _ := os.Stat(src)
+	dstInfo, _ := os.Stat(dst)
+	slog.Info("conversion finished", "src", src, "dst", dst, "src_bytes", srcInfo.Size(), "dst_bytes", dstInfo.Size(),
+		"duration", time.Since(start))
+}
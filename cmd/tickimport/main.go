@@ -0,0 +1,102 @@
+// Command tickimport converts Dukascopy, HistData and TrueFX csv tick exports into the binary tick files read by
+// historical.NewTickReader.
+//
+//	tickimport -format histdata -symbol EURUSD -digits 5 -out eurusd.bin HISTDATA_COM_ASCII_EURUSD_T202401.csv HISTDATA_COM_ASCII_EURUSD_T202402.csv
+//
+// The file starts with a header recording the symbol, digits, time range and checksum of the ticks. The flags
+// other than -format, -symbol, -digits and -out override the preset of the format, columns are zero based and -1
+// marks a missing column.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+	"unicode/utf8"
+
+	"github.com/peter-kozarec/equinox/pkg/datasource/historical"
+)
+
+func main() {
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	formatName := flag.String("format", "dukascopy", "preset csv format: dukascopy, histdata or truefx")
+	out := flag.String("out", "", "output tick file")
+	symbol := flag.String("symbol", "", "symbol recorded in the file header, e.g. EURUSD")
+	digits := flag.Int("digits", 0, "price digits recorded in the file header")
+	comma := flag.String("comma", ",", "field separator")
+	header := flag.Bool("header", false, "skip the first line")
+	timeColumn := flag.Int("time-col", 0, "time column")
+	bidColumn := flag.Int("bid-col", 0, "bid column")
+	askColumn := flag.Int("ask-col", 0, "ask column")
+	bidVolumeColumn := flag.Int("bid-volume-col", historical.NoColumn, "bid volume column")
+	askVolumeColumn := flag.Int("ask-volume-col", historical.NoColumn, "ask volume column")
+	layout := flag.String("layout", "", "time layout in Go syntax, or unix, unixms, unixus, unixns")
+	timeZone := flag.String("tz", "", "time zone of the timestamps, e.g. UTC or America/New_York")
+	priceScale := flag.Float64("price-scale", 0, "multiplier applied to prices")
+	volumeScale := flag.Float64("volume-scale", 0, "multiplier applied to volumes")
+	flag.Parse()
+
+	if *out == "" || flag.NArg() == 0 {
+		_, _ = fmt.Fprintln(os.Stderr, "usage: tickimport -format <format> -symbol <symbol> -out <file> [flags] <csv>...")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+
+	format, err := historical.CSVFormatByName(*formatName)
+	if err != nil {
+		slog.Error("invalid format", "error", err)
+		os.Exit(2)
+	}
+
+	var flagErr error
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "comma":
+			r, size := utf8.DecodeRuneInString(*comma)
+			if size == 0 || size != len(*comma) {
+				flagErr = fmt.Errorf("separator must be a single character")
+			}
+			format.Comma = r
+		case "header":
+			format.Header = *header
+		case "time-col":
+			format.TimeColumn = *timeColumn
+		case "bid-col":
+			format.BidColumn = *bidColumn
+		case "ask-col":
+			format.AskColumn = *askColumn
+		case "bid-volume-col":
+			format.BidVolumeColumn = *bidVolumeColumn
+		case "ask-volume-col":
+			format.AskVolumeColumn = *askVolumeColumn
+		case "layout":
+			format.TimeLayout = *layout
+			format.ParseTime = nil
+		case "tz":
+			location, err := time.LoadLocation(*timeZone)
+			if err != nil {
+				flagErr = fmt.Errorf("unknown time zone %q: %w", *timeZone, err)
+			}
+			format.Location = location
+		case "price-scale":
+			format.PriceScale = *priceScale
+		case "volume-scale":
+			format.VolumeScale = *volumeScale
+		}
+	})
+	if flagErr != nil {
+		slog.Error("invalid flag", "error", flagErr)
+		os.Exit(2)
+	}
+
+	start := time.Now()
+	count, err := historical.ImportCSV(*out, *symbol, *digits, format, flag.Args()...)
+	if err != nil {
+		slog.Error("import failed", "error", err)
+		os.Exit(1)
+	}
+	slog.Info("import finished", "file", *out, "ticks", count, "sources", flag.NArg(), "duration", time.Since(start))
+}
@@ -6,7 +6,6 @@ import (
 
 	"github.com/peter-kozarec/equinox/pkg/bus"
 	"github.com/peter-kozarec/equinox/pkg/common"
-	"github.com/peter-kozarec/equinox/pkg/utility"
 	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
 )
 
@@ -51,15 +50,16 @@ func (m *MeanReversion) OnBar(_ context.Context, bar common.Bar) {
 		if m.zScores.IsFull() {
 			if z.Gte(PositiveThreshold) || z.Lte(NegativeThreshold) {
 				_ = m.router.Post(bus.SignalEvent, common.Signal{
-					Source:      mrxComponentName,
-					Symbol:      bar.Symbol,
-					ExecutionID: utility.GetExecutionID(),
-					TraceID:     utility.CreateTraceID(),
-					TimeStamp:   bar.TimeStamp,
-					Entry:       m.tick.Bid.Add(m.tick.Ask).DivInt(2),
-					Target:      mean,
-					Strength:    100,
-					Comment:     fmt.Sprintf("z-score: %v", z),
+					Source:        mrxComponentName,
+					Symbol:        bar.Symbol,
+					ExecutionID:   m.router.IDs().ExecutionID(),
+					TraceID:       m.router.IDs().TraceID(),
+					ParentTraceID: bar.TraceID,
+					TimeStamp:     bar.TimeStamp,
+					Entry:         m.tick.Bid.Add(m.tick.Ask).DivInt(2),
+					Target:        mean,
+					Strength:      100,
+					Comment:       fmt.Sprintf("z-score: %v", z),
 				})
 			}
 		}
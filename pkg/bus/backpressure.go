@@ -0,0 +1,85 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrCapacityReached = errors.New("event capacity reached")
+)
+
+type BackpressurePolicy uint8
+
+const (
+	// BackpressureDropNewest rejects the posted event when the router is at capacity
+	BackpressureDropNewest BackpressurePolicy = iota
+	// BackpressureDropOldest evicts the oldest queued event of the same id to make room for the posted one,
+	// the posted event is rejected when there is none
+	BackpressureDropOldest
+	// BackpressureBlock waits until there is room or the context is done. Post uses the context passed to
+	// Exec or ExecLoop. Must not be used for events posted from within handlers, since the dispatcher
+	// would wait on itself, use BackpressureOverflow instead
+	BackpressureBlock
+	// BackpressureOverflow spills the posted event to an unbounded overflow above the capacity
+	BackpressureOverflow
+)
+
+func (p BackpressurePolicy) String() string {
+	switch p {
+	case BackpressureDropNewest:
+		return "drop-newest"
+	case BackpressureDropOldest:
+		return "drop-oldest"
+	case BackpressureBlock:
+		return "block"
+	case BackpressureOverflow:
+		return "overflow"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(p))
+	}
+}
+
+func (p BackpressurePolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// WithBackpressure sets the policy applied when the given events are posted to a router at capacity
+func WithBackpressure(policy BackpressurePolicy, ids ...EventId) Option {
+	return func(r *Router) {
+		for _, id := range ids {
+			r.policies[id] = policy
+		}
+	}
+}
+
+func (r *Router) handleBackpressure(ctx context.Context, ev event) error {
+	switch r.policies[ev.id] {
+	case BackpressureDropOldest:
+		if r.queue.replaceOldest(ev) {
+			r.postCount.Add(1)
+			r.drops[ev.id].Add(1)
+			return nil
+		}
+	case BackpressureBlock:
+		if err := r.queue.pushBlocking(ctx, ev); err != nil {
+			r.postFails.Add(1)
+			r.drops[ev.id].Add(1)
+			return fmt.Errorf("unable to post event %d: %w", ev.id, err)
+		}
+		r.postCount.Add(1)
+		return nil
+	case BackpressureOverflow:
+		if r.queue.pushOverflow(ev) {
+			r.spills[ev.id].Add(1)
+		}
+		r.postCount.Add(1)
+		return nil
+	default:
+	}
+
+	r.postFails.Add(1)
+	r.drops[ev.id].Add(1)
+	return ErrCapacityReached
+}
@@ -0,0 +1,175 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+func TestBusBackpressure_DropNewest(t *testing.T) {
+	r := NewRouter(1)
+
+	_ = r.Post(TickEvent, common.Tick{})
+	if err := r.Post(TickEvent, common.Tick{}); !errors.Is(err, ErrCapacityReached) {
+		t.Errorf("Expected ErrCapacityReached, got %v", err)
+	}
+
+	stats := r.GetStatistics()
+	if stats.Events[TickEvent].Drops != 1 {
+		t.Errorf("Expected drops=1, got %d", stats.Events[TickEvent].Drops)
+	}
+	if stats.Events[TickEvent].Policy != BackpressureDropNewest {
+		t.Errorf("Expected drop-newest policy, got %v", stats.Events[TickEvent].Policy)
+	}
+}
+
+func TestBusBackpressure_DropOldest(t *testing.T) {
+	r := NewRouter(3, WithBackpressure(BackpressureDropOldest, TickEvent))
+
+	_ = r.Post(TickEvent, common.Tick{Bid: fixed.FromInt(1, 0)})
+	_ = r.Post(OrderFilledEvent, common.OrderFilled{})
+	_ = r.Post(TickEvent, common.Tick{Bid: fixed.FromInt(2, 0)})
+
+	if err := r.Post(TickEvent, common.Tick{Bid: fixed.FromInt(3, 0)}); err != nil {
+		t.Errorf("Post failed: %v", err)
+	}
+
+	var bids []fixed.Point
+	var filled int
+	r.OnTick = func(_ context.Context, tick common.Tick) { bids = append(bids, tick.Bid) }
+	r.OnOrderFilled = func(_ context.Context, _ common.OrderFilled) { filled++ }
+	_ = r.DrainEvents(context.Background())
+
+	if filled != 1 {
+		t.Errorf("Expected filled=1, got %d", filled)
+	}
+	if len(bids) != 2 || !bids[0].Eq(fixed.FromInt(2, 0)) || !bids[1].Eq(fixed.FromInt(3, 0)) {
+		t.Errorf("Expected bids [2 3], got %v", bids)
+	}
+	if drops := r.GetStatistics().Events[TickEvent].Drops; drops != 1 {
+		t.Errorf("Expected drops=1, got %d", drops)
+	}
+}
+
+func TestBusBackpressure_DropOldestWithoutSameEvent(t *testing.T) {
+	r := NewRouter(1, WithBackpressure(BackpressureDropOldest, TickEvent))
+
+	_ = r.Post(OrderFilledEvent, common.OrderFilled{})
+	if err := r.Post(TickEvent, common.Tick{}); !errors.Is(err, ErrCapacityReached) {
+		t.Errorf("Expected ErrCapacityReached, got %v", err)
+	}
+
+	stats := r.GetStatistics()
+	if stats.Events[TickEvent].Drops != 1 {
+		t.Errorf("Expected tick drops=1, got %d", stats.Events[TickEvent].Drops)
+	}
+	if stats.Pending != 1 {
+		t.Errorf("Expected pending=1, got %d", stats.Pending)
+	}
+}
+
+func TestBusBackpressure_Overflow(t *testing.T) {
+	r := NewRouter(2, WithBackpressure(BackpressureOverflow, OrderFilledEvent))
+
+	_ = r.Post(TickEvent, common.Tick{})
+	_ = r.Post(TickEvent, common.Tick{})
+	for range 3 {
+		if err := r.Post(OrderFilledEvent, common.OrderFilled{}); err != nil {
+			t.Errorf("Post failed: %v", err)
+		}
+	}
+	if err := r.Post(TickEvent, common.Tick{}); !errors.Is(err, ErrCapacityReached) {
+		t.Errorf("Expected ErrCapacityReached, got %v", err)
+	}
+
+	var order []EventId
+	r.OnTick = func(_ context.Context, _ common.Tick) { order = append(order, TickEvent) }
+	r.OnOrderFilled = func(_ context.Context, _ common.OrderFilled) { order = append(order, OrderFilledEvent) }
+
+	stats := r.GetStatistics()
+	_ = r.DrainEvents(context.Background())
+
+	expected := []EventId{TickEvent, TickEvent, OrderFilledEvent, OrderFilledEvent, OrderFilledEvent}
+	if len(order) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, order)
+	}
+	for i := range expected {
+		if order[i] != expected[i] {
+			t.Errorf("At index %d: expected %d, got %d", i, expected[i], order[i])
+		}
+	}
+	if stats.Events[OrderFilledEvent].Spills != 3 {
+		t.Errorf("Expected spills=3, got %d", stats.Events[OrderFilledEvent].Spills)
+	}
+	if stats.Events[OrderFilledEvent].Policy != BackpressureOverflow {
+		t.Errorf("Expected overflow policy, got %v", stats.Events[OrderFilledEvent].Policy)
+	}
+}
+
+func TestBusBackpressure_Block(t *testing.T) {
+	r := NewRouter(1, WithBackpressure(BackpressureBlock, OrderFilledEvent))
+
+	_ = r.Post(OrderFilledEvent, common.OrderFilled{})
+
+	posted := make(chan error, 1)
+	go func() {
+		posted <- r.PostContext(context.Background(), OrderFilledEvent, common.OrderFilled{})
+	}()
+
+	select {
+	case err := <-posted:
+		t.Fatalf("Post returned before space was available: %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	if _, ok := r.queue.pop(); !ok {
+		t.Fatal("Expected queued event")
+	}
+
+	select {
+	case err := <-posted:
+		if err != nil {
+			t.Errorf("Post failed: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Post did not unblock")
+	}
+
+	if r.postCount.Load() != 2 {
+		t.Errorf("Expected postCount=2, got %d", r.postCount.Load())
+	}
+}
+
+func TestBusBackpressure_BlockContextDone(t *testing.T) {
+	r := NewRouter(1, WithBackpressure(BackpressureBlock, OrderFilledEvent))
+
+	_ = r.Post(OrderFilledEvent, common.OrderFilled{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := r.PostContext(ctx, OrderFilledEvent, common.OrderFilled{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if r.postFails.Load() != 1 {
+		t.Errorf("Expected postFails=1, got %d", r.postFails.Load())
+	}
+}
+
+func TestBusBackpressure_PolicyString(t *testing.T) {
+	tests := map[BackpressurePolicy]string{
+		BackpressureDropNewest: "drop-newest",
+		BackpressureDropOldest: "drop-oldest",
+		BackpressureBlock:      "block",
+		BackpressureOverflow:   "overflow",
+	}
+	for policy, expected := range tests {
+		if policy.String() != expected {
+			t.Errorf("Expected %s, got %s", expected, policy.String())
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	ErrReservedEvent   = errors.New("event id is reserved")
+	ErrEventRegistered = errors.New("event id is already registered")
+)
+
+type customEvent struct {
+	payloadType reflect.Type
+	dispatch    func(context.Context, any) error
+}
+
+// RegisterEvent adds a user defined event carrying payload T to the router. The id must be at least
+// FirstCustomEvent. Handler may be nil when the event is consumed only through Subscribe.
+// Events should be registered before the router is started.
+func RegisterEvent[T any](r *Router, id EventId, handler EventHandler[T]) error {
+	if id < FirstCustomEvent {
+		return fmt.Errorf("unable to register event %d: %w", id, ErrReservedEvent)
+	}
+
+	ev := &customEvent{
+		payloadType: reflect.TypeFor[T](),
+		dispatch: func(ctx context.Context, data any) error {
+			payload, ok := data.(T)
+			if !ok {
+				return fmt.Errorf("invalid type assertion for custom event %d", id)
+			}
+			if handler != nil {
+				handler(ctx, payload)
+			}
+			return nil
+		},
+	}
+
+	if !r.customEvents[id].CompareAndSwap(nil, ev) {
+		return fmt.Errorf("unable to register event %d: %w", id, ErrEventRegistered)
+	}
+	return nil
+}
+
+func (r *Router) dispatchCustom(ctx context.Context, ev event) error {
+	custom := r.customEvents[ev.id].Load()
+	if custom == nil {
+		return fmt.Errorf("unsupported event id: %v", ev.id)
+	}
+	return custom.dispatch(ctx, ev.data)
+}
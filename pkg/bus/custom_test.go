@@ -0,0 +1,98 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+const testRegimeEvent = FirstCustomEvent + 1
+
+type testRegimeChange struct {
+	Regime string
+}
+
+func TestBusCustom_RegisterEvent(t *testing.T) {
+	r := NewRouter(10)
+
+	var received testRegimeChange
+	if err := RegisterEvent(r, testRegimeEvent, func(_ context.Context, ev testRegimeChange) {
+		received = ev
+	}); err != nil {
+		t.Fatalf("RegisterEvent failed: %v", err)
+	}
+
+	if err := r.Post(testRegimeEvent, testRegimeChange{Regime: "trend"}); err != nil {
+		t.Errorf("Post failed: %v", err)
+	}
+	if err := r.DrainEvents(context.Background()); err != nil {
+		t.Errorf("DrainEvents failed: %v", err)
+	}
+
+	if received.Regime != "trend" {
+		t.Errorf("Expected regime=trend, got %q", received.Regime)
+	}
+}
+
+func TestBusCustom_RegisterEventErrors(t *testing.T) {
+	r := NewRouter(10)
+
+	if err := RegisterEvent[testRegimeChange](r, TickEvent, nil); !errors.Is(err, ErrReservedEvent) {
+		t.Errorf("Expected ErrReservedEvent, got %v", err)
+	}
+	if err := RegisterEvent[testRegimeChange](r, testRegimeEvent, nil); err != nil {
+		t.Errorf("RegisterEvent failed: %v", err)
+	}
+	if err := RegisterEvent[string](r, testRegimeEvent, nil); !errors.Is(err, ErrEventRegistered) {
+		t.Errorf("Expected ErrEventRegistered, got %v", err)
+	}
+}
+
+func TestBusCustom_Subscribe(t *testing.T) {
+	r := NewRouter(10)
+
+	if err := RegisterEvent[testRegimeChange](r, testRegimeEvent, nil); err != nil {
+		t.Fatalf("RegisterEvent failed: %v", err)
+	}
+
+	var count int
+	if _, err := Subscribe(r, func(_ context.Context, _ testRegimeChange) { count++ }); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if _, err := Subscribe(r, func(_ context.Context, _ string) {}, ForEvent(testRegimeEvent)); !errors.Is(err, ErrPayloadType) {
+		t.Errorf("Expected ErrPayloadType, got %v", err)
+	}
+
+	_ = r.Post(testRegimeEvent, testRegimeChange{})
+	_ = r.DrainEvents(context.Background())
+
+	if count != 1 {
+		t.Errorf("Expected count=1, got %d", count)
+	}
+}
+
+func TestBusCustom_InvalidTypeAssertion(t *testing.T) {
+	r := NewRouter(10)
+
+	if err := RegisterEvent(r, testRegimeEvent, func(_ context.Context, _ testRegimeChange) {
+		t.Error("Handler should not be called")
+	}); err != nil {
+		t.Fatalf("RegisterEvent failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errChan := r.Exec(ctx)
+
+	if err := r.Post(testRegimeEvent, "invalid data type"); err != nil {
+		t.Errorf("Post failed: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	<-errChan
+
+	if r.dispatchFails.Load() != 1 {
+		t.Errorf("Expected dispatchFails=1, got %d", r.dispatchFails.Load())
+	}
+}
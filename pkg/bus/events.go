@@ -18,4 +18,17 @@ const (
 	SignalEvent
 	SignalRejectionEvent
 	SignalAcceptanceEvent
+	TimerEvent
+	OrderAmendedEvent
+	DepthEvent
+	AccountEvent
+	MarginCallEvent
+	StopOutEvent
+)
+
+const (
+	// FirstCustomEvent is the lowest id available to user defined events, ids below it are reserved
+	FirstCustomEvent EventId = 128
+
+	maxEventCount = 1 << 8
 )
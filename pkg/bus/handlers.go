@@ -23,6 +23,12 @@ type OrderCancelledHandler EventHandler[common.OrderCancelled]
 type SignalEventHandler EventHandler[common.Signal]
 type SignalRejectionEventHandler EventHandler[common.SignalRejected]
 type SignalAcceptanceEventHandler EventHandler[common.SignalAccepted]
+type TimerEventHandler EventHandler[common.Timer]
+type OrderAmendedHandler EventHandler[common.OrderAmended]
+type DepthEventHandler EventHandler[common.OrderBook]
+type AccountEventHandler EventHandler[common.Account]
+type MarginCallEventHandler EventHandler[common.MarginCall]
+type StopOutEventHandler EventHandler[common.StopOut]
 
 func MergeHandlers[T any](handlers ...EventHandler[T]) EventHandler[T] {
 	return func(ctx context.Context, event T) {
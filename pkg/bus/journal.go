@@ -0,0 +1,171 @@
+package bus
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/utility"
+)
+
+var (
+	ErrJournalPayloadType = errors.New("journal payload type does not match event")
+)
+
+// JournalEntry is a single line of the journal
+type JournalEntry struct {
+	Sequence uint64          `json:"seq"`
+	Time     time.Time       `json:"ts"`
+	Event    EventId         `json:"event"`
+	Type     string          `json:"type"`
+	TraceID  utility.TraceID `json:"tid,omitempty"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// Journal is an append-only record of the events dispatched by a router, written as one json entry per line.
+// Sequence numbers start at 1 and follow the order in which the events were dispatched, events rejected or
+// evicted under backpressure are not recorded.
+type Journal struct {
+	mu  sync.Mutex
+	w   *bufio.Writer
+	seq uint64
+	err error
+}
+
+func NewJournal(w io.Writer) *Journal {
+	return &Journal{
+		w: bufio.NewWriter(w),
+	}
+}
+
+// WithJournal records every event to the journal right before it is dispatched
+func WithJournal(j *Journal) Option {
+	return func(r *Router) {
+		r.journal = j
+	}
+}
+
+// Record appends the event to the journal. After the first failure the journal stops recording and
+// returns the error from every subsequent call.
+func (j *Journal) Record(id EventId, data interface{}) error {
+	payload, err := json.Marshal(data)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.err != nil {
+		return j.err
+	}
+	if err != nil {
+		j.err = fmt.Errorf("unable to encode event %d payload: %w", id, err)
+		return j.err
+	}
+
+	j.seq++
+	entry := JournalEntry{
+		Sequence: j.seq,
+		Time:     time.Now(),
+		Event:    id,
+		Type:     fmt.Sprintf("%T", data),
+		TraceID:  traceIDOf(data),
+		Payload:  payload,
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		j.err = fmt.Errorf("unable to encode journal entry %d: %w", entry.Sequence, err)
+		return j.err
+	}
+	if _, err = j.w.Write(append(line, '\n')); err != nil {
+		j.err = fmt.Errorf("unable to write journal entry %d: %w", entry.Sequence, err)
+	}
+	return j.err
+}
+
+// Sequence returns the sequence number of the last recorded entry
+func (j *Journal) Sequence() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.seq
+}
+
+// Flush writes the buffered entries to the underlying writer
+func (j *Journal) Flush() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.err != nil {
+		return j.err
+	}
+	if err := j.w.Flush(); err != nil {
+		j.err = fmt.Errorf("unable to flush journal: %w", err)
+	}
+	return j.err
+}
+
+type JournalReader struct {
+	scanner *bufio.Scanner
+}
+
+func NewJournalReader(r io.Reader) *JournalReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	return &JournalReader{
+		scanner: scanner,
+	}
+}
+
+// Next returns the next journal entry or io.EOF when there are no more entries
+func (jr *JournalReader) Next() (JournalEntry, error) {
+	var entry JournalEntry
+
+	for jr.scanner.Scan() {
+		line := jr.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return entry, fmt.Errorf("unable to decode journal entry: %w", err)
+		}
+		return entry, nil
+	}
+	if err := jr.scanner.Err(); err != nil {
+		return entry, fmt.Errorf("unable to read journal: %w", err)
+	}
+	return entry, io.EOF
+}
+
+// DecodePayload decodes the entry payload into the type the router expects for the entry event,
+// user defined events must be registered on the router beforehand
+func (r *Router) DecodePayload(entry JournalEntry) (interface{}, error) {
+	typ, ok := r.payloadType(entry.Event)
+	if !ok {
+		return nil, fmt.Errorf("unable to decode event %d: %w", entry.Event, ErrUnknownEvent)
+	}
+	if typ.String() != entry.Type {
+		return nil, fmt.Errorf("%w: event %d, expected %s, got %s", ErrJournalPayloadType, entry.Event, typ, entry.Type)
+	}
+
+	payload := reflect.New(typ)
+	if err := json.Unmarshal(entry.Payload, payload.Interface()); err != nil {
+		return nil, fmt.Errorf("unable to decode event %d payload: %w", entry.Event, err)
+	}
+	return payload.Elem().Interface(), nil
+}
+
+func traceIDOf(data interface{}) utility.TraceID {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+	f := v.FieldByName("TraceID")
+	if !f.IsValid() || f.Kind() != reflect.Uint64 {
+		return 0
+	}
+	return f.Uint()
+}
@@ -0,0 +1,138 @@
+package bus
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+func TestBusJournal_Record(t *testing.T) {
+	var buf bytes.Buffer
+	journal := NewJournal(&buf)
+	r := NewRouter(1, WithJournal(journal))
+
+	tick := common.Tick{Bid: fixed.FromFloat64(1.08345), Ask: fixed.FromFloat64(1.08350), Symbol: "EURUSD", TraceID: 7}
+	_ = r.Post(TickEvent, tick)
+	_ = r.Post(TickEvent, tick)
+	_ = r.DrainEvents(context.Background())
+	_ = r.Post(OrderFilledEvent, common.OrderFilled{PositionId: 3})
+	if journal.Sequence() != 1 {
+		t.Errorf("Expected queued event not to be recorded before dispatch, got sequence=%d", journal.Sequence())
+	}
+	_ = r.DrainEvents(context.Background())
+
+	if err := journal.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if journal.Sequence() != 2 {
+		t.Errorf("Expected sequence=2, got %d", journal.Sequence())
+	}
+
+	reader := NewJournalReader(&buf)
+
+	entry, err := reader.Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if entry.Sequence != 1 || entry.Event != TickEvent || entry.Type != "common.Tick" || entry.TraceID != 7 {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if entry.Time.IsZero() {
+		t.Error("Expected entry time to be set")
+	}
+
+	data, err := NewRouter(1).DecodePayload(entry)
+	if err != nil {
+		t.Fatalf("DecodePayload failed: %v", err)
+	}
+	decoded, ok := data.(common.Tick)
+	if !ok {
+		t.Fatalf("Expected common.Tick, got %T", data)
+	}
+	if !decoded.Bid.Eq(tick.Bid) || !decoded.Ask.Eq(tick.Ask) || decoded.Symbol != tick.Symbol {
+		t.Errorf("Expected %+v, got %+v", tick, decoded)
+	}
+
+	entry, err = reader.Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+	if entry.Sequence != 2 || entry.Event != OrderFilledEvent {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+
+	if _, err = reader.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected io.EOF, got %v", err)
+	}
+}
+
+func TestBusJournal_DropOldest(t *testing.T) {
+	var buf bytes.Buffer
+	journal := NewJournal(&buf)
+	r := NewRouter(2, WithJournal(journal), WithBackpressure(BackpressureDropOldest, TickEvent))
+
+	for tid := uint64(1); tid <= 4; tid++ {
+		if err := r.Post(TickEvent, common.Tick{TraceID: tid}); err != nil {
+			t.Fatalf("Post failed: %v", err)
+		}
+	}
+	_ = r.DrainEvents(context.Background())
+	_ = journal.Flush()
+
+	reader := NewJournalReader(&buf)
+	for seq, tid := range []uint64{3, 4} {
+		entry, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Next failed: %v", err)
+		}
+		if entry.Sequence != uint64(seq+1) || entry.TraceID != tid {
+			t.Errorf("Expected sequence=%d trace id=%d, got %+v", seq+1, tid, entry)
+		}
+	}
+	if _, err := reader.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("Expected evicted events not to be recorded, got %v", err)
+	}
+}
+
+func TestBusJournal_CustomEvent(t *testing.T) {
+	var buf bytes.Buffer
+	journal := NewJournal(&buf)
+	r := NewRouter(10, WithJournal(journal))
+	if err := RegisterEvent[testRegimeChange](r, testRegimeEvent, nil); err != nil {
+		t.Fatalf("RegisterEvent failed: %v", err)
+	}
+
+	_ = r.Post(testRegimeEvent, testRegimeChange{Regime: "trend"})
+	_ = r.DrainEvents(context.Background())
+	_ = journal.Flush()
+
+	entry, err := NewJournalReader(&buf).Next()
+	if err != nil {
+		t.Fatalf("Next failed: %v", err)
+	}
+
+	if _, err = NewRouter(10).DecodePayload(entry); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("Expected ErrUnknownEvent, got %v", err)
+	}
+
+	replayRouter := NewRouter(10)
+	if err = RegisterEvent[string](replayRouter, testRegimeEvent, nil); err != nil {
+		t.Fatalf("RegisterEvent failed: %v", err)
+	}
+	if _, err = replayRouter.DecodePayload(entry); !errors.Is(err, ErrJournalPayloadType) {
+		t.Errorf("Expected ErrJournalPayloadType, got %v", err)
+	}
+
+	data, err := r.DecodePayload(entry)
+	if err != nil {
+		t.Fatalf("DecodePayload failed: %v", err)
+	}
+	if data.(testRegimeChange).Regime != "trend" {
+		t.Errorf("Expected regime=trend, got %+v", data)
+	}
+}
@@ -0,0 +1,122 @@
+package bus
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Each power of two range is split into histSubCount/2 linear buckets, which keeps the
+	// relative error of the recorded values below 1/16
+	histSubBits  = 5
+	histSubCount = 1 << histSubBits
+	histHalf     = histSubCount / 2
+
+	// Values above ~9.7 hours are recorded into the last bucket
+	histMaxShift  = 40
+	histMaxValue  = 1<<(histMaxShift+histSubBits) - 1
+	histBucketLen = (histMaxShift + 2) * histHalf
+)
+
+// LatencyHistogram is a log-linear histogram of durations in the spirit of HdrHistogram. It is safe
+// for one writer and concurrent readers.
+type LatencyHistogram struct {
+	count   atomic.Uint64
+	max     atomic.Int64
+	buckets [histBucketLen]atomic.Uint64
+}
+
+func (h *LatencyHistogram) Record(d time.Duration) {
+	v := max(int64(d), 0)
+	h.buckets[histBucketIndex(uint64(min(v, histMaxValue)))].Add(1)
+	h.count.Add(1)
+	if v > h.max.Load() {
+		h.max.Store(v)
+	}
+}
+
+func (h *LatencyHistogram) Count() uint64 {
+	return h.count.Load()
+}
+
+func (h *LatencyHistogram) Max() time.Duration {
+	return time.Duration(h.max.Load())
+}
+
+// Percentile returns the highest value equivalent to the bucket holding the given percentile (0-100),
+// capped at the recorded maximum
+func (h *LatencyHistogram) Percentile(p float64) time.Duration {
+	count := h.count.Load()
+	if count == 0 {
+		return 0
+	}
+
+	// the rank is rounded up as in HdrHistogram, so that small counts do not understate the high percentiles
+	target := uint64(math.Ceil(p * float64(count) / 100))
+	target = min(max(target, 1), count)
+
+	var seen uint64
+	for idx := range h.buckets {
+		seen += h.buckets[idx].Load()
+		if seen >= target {
+			return min(time.Duration(histBucketHighest(idx)), h.Max())
+		}
+	}
+	return h.Max()
+}
+
+func (h *LatencyHistogram) Summary() LatencySummary {
+	return LatencySummary{
+		Count: h.Count(),
+		P50:   h.Percentile(50),
+		P90:   h.Percentile(90),
+		P99:   h.Percentile(99),
+		Max:   h.Max(),
+	}
+}
+
+func histBucketIndex(v uint64) int {
+	if v < histSubCount {
+		return int(v)
+	}
+	shift := bits.Len64(v) - histSubBits
+	return shift*histHalf + int(v>>shift)
+}
+
+func histBucketHighest(idx int) uint64 {
+	if idx < histSubCount {
+		return uint64(idx)
+	}
+	shift := idx/histHalf - 1
+	mantissa := uint64(idx%histHalf + histHalf)
+	return (mantissa+1)<<shift - 1
+}
+
+type eventLatency struct {
+	queueWait LatencyHistogram
+	handler   LatencyHistogram
+}
+
+// WithLatencyHistograms records, per event, the time events wait in the queue between Post and dispatch
+// and the time spent in their handlers. The histograms are reported in Statistics.
+func WithLatencyHistograms() Option {
+	return func(r *Router) {
+		r.latencyEnabled = true
+		r.latencyEpoch = time.Now()
+	}
+}
+
+// since returns the monotonic time elapsed since the router was created, it is used to stamp events
+func (r *Router) since() int64 {
+	return int64(time.Since(r.latencyEpoch))
+}
+
+func (r *Router) latencyOf(id EventId) *eventLatency {
+	if l := r.latency[id].Load(); l != nil {
+		return l
+	}
+	r.latency[id].CompareAndSwap(nil, &eventLatency{})
+	return r.latency[id].Load()
+}
@@ -0,0 +1,145 @@
+package bus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+)
+
+func TestBusLatency_BucketIndex(t *testing.T) {
+	prev := -1
+	for _, v := range []uint64{0, 1, 31, 32, 33, 63, 64, 1000, 1 << 20, histMaxValue} {
+		idx := histBucketIndex(v)
+		if idx < prev {
+			t.Errorf("Bucket index for %d is decreasing: %d < %d", v, idx, prev)
+		}
+		if idx >= histBucketLen {
+			t.Fatalf("Bucket index for %d out of range: %d", v, idx)
+		}
+		if highest := histBucketHighest(idx); highest < v || float64(highest-v) > float64(v)/16+1 {
+			t.Errorf("Bucket %d highest value %d does not cover %d", idx, highest, v)
+		}
+		prev = idx
+	}
+}
+
+func TestBusLatency_Percentile(t *testing.T) {
+	var h LatencyHistogram
+
+	if h.Percentile(50) != 0 {
+		t.Errorf("Expected 0 for empty histogram, got %v", h.Percentile(50))
+	}
+
+	for i := 1; i <= 1000; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+
+	summary := h.Summary()
+	if summary.Count != 1000 {
+		t.Errorf("Expected count=1000, got %d", summary.Count)
+	}
+	if summary.Max != time.Millisecond {
+		t.Errorf("Expected max=1ms, got %v", summary.Max)
+	}
+
+	within := func(name string, got, want time.Duration) {
+		if got < want || got > want+want/16 {
+			t.Errorf("Expected %s within 1/16 of %v, got %v", name, want, got)
+		}
+	}
+	within("p50", summary.P50, 500*time.Microsecond)
+	within("p90", summary.P90, 900*time.Microsecond)
+	within("p99", summary.P99, 990*time.Microsecond)
+
+	h.Record(-time.Second)
+	if h.Percentile(0) != 0 {
+		t.Errorf("Expected negative durations to be recorded as 0, got %v", h.Percentile(0))
+	}
+}
+
+func TestBusLatency_PercentileRank(t *testing.T) {
+	var h LatencyHistogram
+	for i := 1; i <= 10; i++ {
+		h.Record(time.Duration(i))
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{p: 0, want: 1},
+		{p: 10, want: 1},
+		{p: 11, want: 2},
+		{p: 50, want: 5},
+		{p: 90, want: 9},
+		{p: 95, want: 10},
+		{p: 99, want: 10},
+		{p: 100, want: 10},
+	}
+	for _, tt := range tests {
+		if got := h.Percentile(tt.p); got != tt.want {
+			t.Errorf("Expected p%v=%v, got %v", tt.p, tt.want, got)
+		}
+	}
+}
+
+func TestBusLatency_Statistics(t *testing.T) {
+	r := NewRouter(10, WithLatencyHistograms())
+	r.OnTick = func(_ context.Context, _ common.Tick) { time.Sleep(time.Millisecond) }
+
+	_ = r.Post(TickEvent, common.Tick{})
+	_ = r.Post(TickEvent, common.Tick{})
+	_ = r.DrainEvents(context.Background())
+
+	stats := r.GetStatistics()
+	tick := stats.Events[TickEvent]
+	if tick.Handler.Count != 2 || tick.QueueWait.Count != 2 {
+		t.Fatalf("Expected 2 recorded ticks, got %+v", tick)
+	}
+	if tick.Handler.Max < time.Millisecond {
+		t.Errorf("Expected handler max >= 1ms, got %v", tick.Handler.Max)
+	}
+	if tick.QueueWait.Max < time.Millisecond {
+		t.Errorf("Expected second tick to wait >= 1ms, got %v", tick.QueueWait.Max)
+	}
+	if _, ok := stats.Events[BarEvent]; ok {
+		t.Error("Expected no statistics for bar event")
+	}
+
+	var buf bytes.Buffer
+	if err := stats.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON failed: %v", err)
+	}
+
+	var exported struct {
+		Events map[string]struct {
+			Policy  string `json:"policy"`
+			Handler struct {
+				Count uint64 `json:"count"`
+				MaxNs int64  `json:"max_ns"`
+			} `json:"handler"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &exported); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	exportedTick := exported.Events["0"]
+	if exportedTick.Policy != "drop-newest" || exportedTick.Handler.Count != 2 || exportedTick.Handler.MaxNs != int64(tick.Handler.Max) {
+		t.Errorf("Unexpected export: %s", buf.String())
+	}
+}
+
+func TestBusLatency_Disabled(t *testing.T) {
+	r := NewRouter(10)
+
+	_ = r.Post(TickEvent, common.Tick{})
+	_ = r.DrainEvents(context.Background())
+
+	if len(r.GetStatistics().Events) != 0 {
+		t.Errorf("Expected no event statistics, got %+v", r.GetStatistics().Events)
+	}
+}
@@ -0,0 +1,55 @@
+package bus
+
+import "fmt"
+
+const (
+	laneCount = 2
+
+	DefaultStarvationLimit = 64
+)
+
+type Lane uint8
+
+const (
+	// LaneControl holds control and execution events, it is drained before LaneMarket
+	LaneControl Lane = iota
+	// LaneMarket holds market data events
+	LaneMarket
+)
+
+func (l Lane) String() string {
+	switch l {
+	case LaneControl:
+		return "control"
+	case LaneMarket:
+		return "market"
+	default:
+		return fmt.Sprintf("unknown(%d)", uint8(l))
+	}
+}
+
+// WithPriorityLanes dispatches control and execution events ahead of queued market data. Tick and bar
+// events go to LaneMarket, everything else to LaneControl, use WithLane to move events between lanes.
+// At most starvationLimit control events are dispatched in a row while market data is waiting,
+// DefaultStarvationLimit is used when it is not positive. Without this option events are dispatched
+// in the order they were posted.
+func WithPriorityLanes(starvationLimit int) Option {
+	return func(r *Router) {
+		if starvationLimit <= 0 {
+			starvationLimit = DefaultStarvationLimit
+		}
+		r.queue.starvationLimit = starvationLimit
+	}
+}
+
+// WithLane assigns the given events to a lane, it has an effect only together with WithPriorityLanes
+func WithLane(lane Lane, ids ...EventId) Option {
+	return func(r *Router) {
+		if lane >= laneCount {
+			return
+		}
+		for _, id := range ids {
+			r.queue.laneOf[id] = lane
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package bus
+
+import (
+	"context"
+	"testing"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+)
+
+func recordOrder(r *Router) *[]EventId {
+	var order []EventId
+	r.OnTick = func(_ context.Context, _ common.Tick) { order = append(order, TickEvent) }
+	r.OnBar = func(_ context.Context, _ common.Bar) { order = append(order, BarEvent) }
+	r.OnOrderRejection = func(_ context.Context, _ common.OrderRejected) { order = append(order, OrderRejectionEvent) }
+	r.OnPositionClose = func(_ context.Context, _ common.Position) { order = append(order, PositionCloseEvent) }
+	return &order
+}
+
+func expectOrder(t *testing.T, expected, got []EventId) {
+	t.Helper()
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("At index %d: expected %d, got %d", i, expected[i], got[i])
+		}
+	}
+}
+
+func TestBusPriority_Disabled(t *testing.T) {
+	r := NewRouter(10)
+	order := recordOrder(r)
+
+	_ = r.Post(TickEvent, common.Tick{})
+	_ = r.Post(OrderRejectionEvent, common.OrderRejected{})
+	_ = r.Post(BarEvent, common.Bar{})
+	_ = r.DrainEvents(context.Background())
+
+	expectOrder(t, []EventId{TickEvent, OrderRejectionEvent, BarEvent}, *order)
+}
+
+func TestBusPriority_ControlFirst(t *testing.T) {
+	r := NewRouter(10, WithPriorityLanes(0))
+	order := recordOrder(r)
+
+	_ = r.Post(TickEvent, common.Tick{})
+	_ = r.Post(BarEvent, common.Bar{})
+	_ = r.Post(OrderRejectionEvent, common.OrderRejected{})
+	_ = r.Post(TickEvent, common.Tick{})
+	_ = r.Post(PositionCloseEvent, common.Position{})
+	_ = r.DrainEvents(context.Background())
+
+	expectOrder(t, []EventId{OrderRejectionEvent, PositionCloseEvent, TickEvent, BarEvent, TickEvent}, *order)
+}
+
+func TestBusPriority_StarvationLimit(t *testing.T) {
+	r := NewRouter(10, WithPriorityLanes(2))
+	order := recordOrder(r)
+
+	_ = r.Post(TickEvent, common.Tick{})
+	_ = r.Post(TickEvent, common.Tick{})
+	for range 5 {
+		_ = r.Post(OrderRejectionEvent, common.OrderRejected{})
+	}
+	_ = r.DrainEvents(context.Background())
+
+	expectOrder(t, []EventId{
+		OrderRejectionEvent, OrderRejectionEvent, TickEvent,
+		OrderRejectionEvent, OrderRejectionEvent, TickEvent,
+		OrderRejectionEvent,
+	}, *order)
+}
+
+func TestBusPriority_WithLane(t *testing.T) {
+	r := NewRouter(10, WithLane(LaneControl, BarEvent), WithPriorityLanes(0), WithLane(LaneMarket, PositionCloseEvent))
+	order := recordOrder(r)
+
+	_ = r.Post(TickEvent, common.Tick{})
+	_ = r.Post(PositionCloseEvent, common.Position{})
+	_ = r.Post(BarEvent, common.Bar{})
+	_ = r.DrainEvents(context.Background())
+
+	expectOrder(t, []EventId{BarEvent, TickEvent, PositionCloseEvent}, *order)
+}
+
+func TestBusPriority_DropOldest(t *testing.T) {
+	r := NewRouter(3, WithPriorityLanes(0), WithBackpressure(BackpressureDropOldest, TickEvent))
+	order := recordOrder(r)
+
+	_ = r.Post(TickEvent, common.Tick{})
+	_ = r.Post(OrderRejectionEvent, common.OrderRejected{})
+	_ = r.Post(BarEvent, common.Bar{})
+	if err := r.Post(TickEvent, common.Tick{}); err != nil {
+		t.Errorf("Post failed: %v", err)
+	}
+	_ = r.DrainEvents(context.Background())
+
+	expectOrder(t, []EventId{OrderRejectionEvent, BarEvent, TickEvent}, *order)
+}
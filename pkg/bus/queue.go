@@ -0,0 +1,219 @@
+package bus
+
+import (
+	"context"
+	"sync"
+)
+
+type ring struct {
+	buf  []event
+	head int
+	size int
+}
+
+func (r *ring) push(ev event) {
+	if r.size == len(r.buf) {
+		r.grow()
+	}
+	r.buf[(r.head+r.size)%len(r.buf)] = ev
+	r.size++
+}
+
+func (r *ring) pop() event {
+	ev := r.buf[r.head]
+	r.buf[r.head] = event{}
+	r.head = (r.head + 1) % len(r.buf)
+	r.size--
+	return ev
+}
+
+// remove drops the oldest event with the given id, shifting the events queued before it towards the tail
+func (r *ring) remove(id EventId) bool {
+	idx := -1
+	for i := 0; i < r.size; i++ {
+		if r.buf[(r.head+i)%len(r.buf)].id == id {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return false
+	}
+
+	for i := idx; i > 0; i-- {
+		r.buf[(r.head+i)%len(r.buf)] = r.buf[(r.head+i-1)%len(r.buf)]
+	}
+	r.pop()
+	return true
+}
+
+func (r *ring) grow() {
+	buf := make([]event, max(2*len(r.buf), 1))
+	for i := 0; i < r.size; i++ {
+		buf[i] = r.buf[(r.head+i)%len(r.buf)]
+	}
+	r.buf = buf
+	r.head = 0
+}
+
+type eventQueue struct {
+	mu       sync.Mutex
+	lanes    [laneCount]ring
+	size     int
+	capacity int
+	blocked  int
+
+	// laneOf and starvationLimit are only written by router options, before the queue is used
+	laneOf          [maxEventCount]Lane
+	starvationLimit int
+	starved         int
+
+	notify chan struct{}
+	space  chan struct{}
+}
+
+func newEventQueue(capacity int) *eventQueue {
+	q := &eventQueue{
+		capacity: capacity,
+		notify:   make(chan struct{}, 1),
+		space:    make(chan struct{}, 1),
+	}
+	q.lanes[LaneControl].buf = make([]event, capacity)
+	q.laneOf[TickEvent] = LaneMarket
+	q.laneOf[BarEvent] = LaneMarket
+	return q
+}
+
+// tryPush appends the event when the queue is below its capacity
+func (q *eventQueue) tryPush(ev event) bool {
+	q.mu.Lock()
+	if q.size >= q.capacity {
+		q.mu.Unlock()
+		return false
+	}
+	q.pushLocked(ev)
+	q.mu.Unlock()
+	q.signal()
+	return true
+}
+
+// pushOverflow appends the event regardless of the capacity and reports whether it was spilled above it
+func (q *eventQueue) pushOverflow(ev event) bool {
+	q.mu.Lock()
+	spilled := q.size >= q.capacity
+	q.pushLocked(ev)
+	q.mu.Unlock()
+	q.signal()
+	return spilled
+}
+
+// pushBlocking waits until there is space in the queue or the context is done
+func (q *eventQueue) pushBlocking(ctx context.Context, ev event) error {
+	for {
+		q.mu.Lock()
+		if q.size < q.capacity {
+			q.pushLocked(ev)
+			if q.blocked > 0 && q.size < q.capacity {
+				q.signalSpace()
+			}
+			q.mu.Unlock()
+			q.signal()
+			return nil
+		}
+		q.blocked++
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			q.mu.Lock()
+			q.blocked--
+			q.mu.Unlock()
+			return ctx.Err()
+		case <-q.space:
+			q.mu.Lock()
+			q.blocked--
+			q.mu.Unlock()
+		}
+	}
+}
+
+// replaceOldest removes the oldest queued event with the same id and appends the new one. It reports
+// false, without modifying the queue, when no such event is queued.
+func (q *eventQueue) replaceOldest(ev event) bool {
+	q.mu.Lock()
+	if !q.lanes[q.lane(ev.id)].remove(ev.id) {
+		q.mu.Unlock()
+		return false
+	}
+	q.size--
+
+	q.pushLocked(ev)
+	q.mu.Unlock()
+	q.signal()
+	return true
+}
+
+// pop removes the next event, control lane first. When priority lanes are enabled, a market event is
+// let through after starvationLimit consecutive control events so that market data keeps flowing.
+func (q *eventQueue) pop() (event, bool) {
+	q.mu.Lock()
+	if q.size == 0 {
+		q.mu.Unlock()
+		return event{}, false
+	}
+
+	control, market := &q.lanes[LaneControl], &q.lanes[LaneMarket]
+
+	var ev event
+	switch {
+	case market.size == 0:
+		ev = control.pop()
+		q.starved = 0
+	case control.size == 0 || q.starved >= q.starvationLimit:
+		ev = market.pop()
+		q.starved = 0
+	default:
+		ev = control.pop()
+		q.starved++
+	}
+
+	q.size--
+	if q.blocked > 0 {
+		q.signalSpace()
+	}
+	q.mu.Unlock()
+	return ev, true
+}
+
+func (q *eventQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.size
+}
+
+func (q *eventQueue) pushLocked(ev event) {
+	q.lanes[q.lane(ev.id)].push(ev)
+	q.size++
+}
+
+// lane returns LaneControl for every event unless priority lanes are enabled
+func (q *eventQueue) lane(id EventId) Lane {
+	if q.starvationLimit == 0 {
+		return LaneControl
+	}
+	return q.laneOf[id]
+}
+
+func (q *eventQueue) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (q *eventQueue) signalSpace() {
+	select {
+	case q.space <- struct{}{}:
+	default:
+	}
+}
@@ -5,19 +5,27 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility"
 )
 
 type event struct {
-	id   EventId
-	data interface{}
+	id     EventId
+	data   interface{}
+	posted int64
 }
 
+type Option func(*Router)
+
 type Router struct {
-	events chan event
+	queue   *eventQueue
+	runCtx  atomic.Pointer[context.Context]
+	journal *Journal
+	ids     *utility.IDGenerator
 
 	OnTick             TickEventHandler
 	OnBar              BarEventHandler
@@ -34,6 +42,25 @@ type Router struct {
 	OnSignal           SignalEventHandler
 	OnSignalAcceptance SignalAcceptanceEventHandler
 	OnSignalRejection  SignalRejectionEventHandler
+	OnTimer            TimerEventHandler
+	OnOrderAmended     OrderAmendedHandler
+	OnDepth            DepthEventHandler
+	OnAccount          AccountEventHandler
+	OnMarginCall       MarginCallEventHandler
+	OnStopOut          StopOutEventHandler
+
+	subscribersMu       sync.Mutex
+	subscriberIdCounter uint64
+	subscribers         [maxEventCount]atomic.Pointer[[]subscriber]
+	customEvents        [maxEventCount]atomic.Pointer[customEvent]
+
+	policies [maxEventCount]BackpressurePolicy
+	drops    [maxEventCount]atomic.Uint64
+	spills   [maxEventCount]atomic.Uint64
+
+	latencyEnabled bool
+	latencyEpoch   time.Time
+	latency        [maxEventCount]atomic.Pointer[eventLatency]
 
 	runTime       time.Duration
 	postCount     atomic.Uint64
@@ -42,30 +69,59 @@ type Router struct {
 	dispatchFails atomic.Uint64
 }
 
-func NewRouter(eventCapacity int) *Router {
-	return &Router{
-		events: make(chan event, eventCapacity),
+func NewRouter(eventCapacity int, options ...Option) *Router {
+	r := &Router{
+		queue: newEventQueue(eventCapacity),
+	}
+
+	for _, option := range options {
+		option(r)
+	}
+
+	return r
+}
+
+// WithIDGenerator makes the router issue run scoped ids, components posting events take their ids from
+// Router.IDs and handlers from utility.IDGeneratorFromContext
+func WithIDGenerator(g *utility.IDGenerator) Option {
+	return func(r *Router) {
+		r.ids = g
+	}
+}
+
+// IDs returns the id generator of the router, or the one carried by the context of the run when the router
+// has none. A nil generator issues the process wide ids.
+func (r *Router) IDs() *utility.IDGenerator {
+	if r.ids != nil {
+		return r.ids
 	}
+	return utility.IDGeneratorFromContext(r.context())
 }
 
 func (r *Router) Post(id EventId, data interface{}) error {
-	select {
-	case r.events <- event{id, data}:
+	return r.post(r.context(), event{id: id, data: data})
+}
+
+// PostContext behaves like Post, except that events with the BackpressureBlock policy wait on ctx
+func (r *Router) PostContext(ctx context.Context, id EventId, data interface{}) error {
+	return r.post(ctx, event{id: id, data: data})
+}
+
+func (r *Router) post(ctx context.Context, ev event) error {
+	if r.latencyEnabled {
+		ev.posted = r.since()
+	}
+	if r.queue.tryPush(ev) {
 		r.postCount.Add(1)
-		return nil
-	default:
-		r.postFails.Add(1)
-		return errors.New("event capacity reached")
+	} else if err := r.handleBackpressure(ctx, ev); err != nil {
+		return err
 	}
+	return nil
 }
 
 func (r *Router) Exec(ctx context.Context) <-chan error {
-
-	r.runTime = 0
-	r.dispatchCount.Store(0)
-	r.dispatchFails.Store(0)
-	r.postCount.Store(0)
-	r.postFails.Store(0)
+	ctx = r.withIDs(ctx)
+	r.reset(ctx)
 
 	start := time.Now()
 	errChan := make(chan error)
@@ -81,12 +137,22 @@ func (r *Router) Exec(ctx context.Context) <-chan error {
 			case <-ctx.Done():
 				errChan <- ctx.Err()
 				return
-			case ev := <-r.events:
-				r.dispatchCount.Add(1)
-				if err := r.dispatch(ctx, ev); err != nil {
-					r.dispatchFails.Add(1)
-					slog.Warn("dispatch failed", "error", err, "event", ev)
+			default:
+			}
+
+			ev, ok := r.queue.pop()
+			if !ok {
+				select {
+				case <-ctx.Done():
+				case <-r.queue.notify:
 				}
+				continue
+			}
+
+			r.dispatchCount.Add(1)
+			if err := r.dispatchEvent(ctx, ev); err != nil {
+				r.dispatchFails.Add(1)
+				slog.Warn("dispatch failed", "error", err, "event", ev)
 			}
 		}
 	}()
@@ -95,12 +161,8 @@ func (r *Router) Exec(ctx context.Context) <-chan error {
 }
 
 func (r *Router) ExecLoop(ctx context.Context, doOnceCb func() error) <-chan error {
-
-	r.runTime = 0
-	r.dispatchCount.Store(0)
-	r.dispatchFails.Store(0)
-	r.postCount.Store(0)
-	r.postFails.Store(0)
+	ctx = r.withIDs(ctx)
+	r.reset(ctx)
 
 	start := time.Now()
 	errChan := make(chan error)
@@ -116,17 +178,22 @@ func (r *Router) ExecLoop(ctx context.Context, doOnceCb func() error) <-chan err
 			case <-ctx.Done():
 				errChan <- ctx.Err()
 				return
-			case ev := <-r.events:
-				r.dispatchCount.Add(1)
-				if err := r.dispatch(ctx, ev); err != nil {
-					r.dispatchFails.Add(1)
-					slog.Warn("dispatch failed", "error", err, "event", ev)
-				}
 			default:
+			}
+
+			ev, ok := r.queue.pop()
+			if !ok {
 				if err := doOnceCb(); err != nil {
 					errChan <- err
 					return
 				}
+				continue
+			}
+
+			r.dispatchCount.Add(1)
+			if err := r.dispatchEvent(ctx, ev); err != nil {
+				r.dispatchFails.Add(1)
+				slog.Warn("dispatch failed", "error", err, "event", ev)
 			}
 		}
 	}()
@@ -135,15 +202,15 @@ func (r *Router) ExecLoop(ctx context.Context, doOnceCb func() error) <-chan err
 }
 
 func (r *Router) DrainEvents(ctx context.Context) error {
+	ctx = r.withIDs(ctx)
 	for {
-		select {
-		case ev := <-r.events:
-			if r := r.dispatch(ctx, ev); r != nil {
-				return fmt.Errorf("dispatch failed: %w", r)
-			}
-		default:
+		ev, ok := r.queue.pop()
+		if !ok {
 			return nil
 		}
+		if err := r.dispatchEvent(ctx, ev); err != nil {
+			return fmt.Errorf("dispatch failed: %w", err)
+		}
 	}
 }
 
@@ -155,14 +222,84 @@ func (r *Router) GetStatistics() Statistics {
 	stats.DispatchCount = r.dispatchCount.Load()
 	stats.PostFails = r.postFails.Load()
 	stats.DispatchFails = r.dispatchFails.Load()
+	stats.Pending = r.queue.len()
 
 	if stats.RunTime > 0 {
 		stats.Throughput = float64(stats.PostCount) / stats.RunTime.Seconds()
 	}
 
+	for id := range maxEventCount {
+		eventStats := EventStatistics{
+			Policy: r.policies[id],
+			Drops:  r.drops[id].Load(),
+			Spills: r.spills[id].Load(),
+		}
+		if l := r.latency[id].Load(); l != nil {
+			eventStats.QueueWait = l.queueWait.Summary()
+			eventStats.Handler = l.handler.Summary()
+		}
+		if eventStats == (EventStatistics{}) {
+			continue
+		}
+		if stats.Events == nil {
+			stats.Events = make(map[EventId]EventStatistics)
+		}
+		stats.Events[EventId(id)] = eventStats
+	}
+
 	return stats
 }
 
+func (r *Router) reset(ctx context.Context) {
+	r.runCtx.Store(&ctx)
+
+	r.runTime = 0
+	r.dispatchCount.Store(0)
+	r.dispatchFails.Store(0)
+	r.postCount.Store(0)
+	r.postFails.Store(0)
+	for id := range maxEventCount {
+		r.drops[id].Store(0)
+		r.spills[id].Store(0)
+		r.latency[id].Store(nil)
+	}
+}
+
+func (r *Router) context() context.Context {
+	if ctx := r.runCtx.Load(); ctx != nil {
+		return *ctx
+	}
+	return context.Background()
+}
+
+// withIDs makes the id generator of the router available to the handlers through their context
+func (r *Router) withIDs(ctx context.Context) context.Context {
+	if r.ids == nil {
+		return ctx
+	}
+	return utility.ContextWithIDGenerator(ctx, r.ids)
+}
+
+func (r *Router) dispatchEvent(ctx context.Context, ev event) error {
+	if r.journal != nil {
+		if err := r.journal.Record(ev.id, ev.data); err != nil {
+			slog.Warn("unable to record event", "error", err, "event", ev.id)
+		}
+	}
+	if !r.latencyEnabled {
+		return r.dispatch(ctx, ev)
+	}
+
+	start := r.since()
+	err := r.dispatch(ctx, ev)
+	end := r.since()
+
+	l := r.latencyOf(ev.id)
+	l.queueWait.Record(time.Duration(start - ev.posted))
+	l.handler.Record(time.Duration(end - start))
+	return err
+}
+
 func (r *Router) dispatch(ctx context.Context, ev event) error {
 	switch ev.id {
 	case TickEvent:
@@ -172,7 +309,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnTick != nil {
 			r.OnTick(ctx, tick)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("tick handler is nil")
 		}
 	case BarEvent:
@@ -182,7 +319,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnBar != nil {
 			r.OnBar(ctx, bar)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("bar handler is nil")
 		}
 	case EquityEvent:
@@ -192,7 +329,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnEquity != nil {
 			r.OnEquity(ctx, eq)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("equity handler is nil")
 		}
 	case BalanceEvent:
@@ -202,7 +339,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnBalance != nil {
 			r.OnBalance(ctx, bal)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("balance handler is nil")
 		}
 	case PositionOpenEvent:
@@ -212,7 +349,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnPositionOpen != nil {
 			r.OnPositionOpen(ctx, pos)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("position opened handler is nil")
 		}
 	case PositionCloseEvent:
@@ -222,7 +359,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnPositionClose != nil {
 			r.OnPositionClose(ctx, pos)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("position closed handler is nil")
 		}
 	case PositionUpdateEvent:
@@ -232,7 +369,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnPositionUpdate != nil {
 			r.OnPositionUpdate(ctx, pos)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("position pnl updated handler is nil")
 		}
 	case OrderEvent:
@@ -242,7 +379,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnOrder != nil {
 			r.OnOrder(ctx, order)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("order handler is nil")
 		}
 	case OrderAcceptanceEvent:
@@ -252,7 +389,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnOrderAcceptance != nil {
 			r.OnOrderAcceptance(ctx, orderAccepted)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("order accepted handler is nil")
 		}
 	case OrderRejectionEvent:
@@ -262,7 +399,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnOrderRejection != nil {
 			r.OnOrderRejection(ctx, orderRejected)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("order rejected handler is nil")
 		}
 	case OrderFilledEvent:
@@ -272,7 +409,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnOrderFilled != nil {
 			r.OnOrderFilled(ctx, orderFilled)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("order filled handler is nil")
 		}
 	case OrderCancelledEvent:
@@ -282,7 +419,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnOrderCancel != nil {
 			r.OnOrderCancel(ctx, orderCancelled)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("order cancelled handler is nil")
 		}
 	case SignalEvent:
@@ -292,7 +429,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnSignal != nil {
 			r.OnSignal(ctx, sig)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("signal handler is nil")
 		}
 	case SignalAcceptanceEvent:
@@ -302,7 +439,7 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnSignalAcceptance != nil {
 			r.OnSignalAcceptance(ctx, sigAccepted)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("signal accepted handler is nil")
 		}
 	case SignalRejectionEvent:
@@ -312,11 +449,75 @@ func (r *Router) dispatch(ctx context.Context, ev event) error {
 		}
 		if r.OnSignalRejection != nil {
 			r.OnSignalRejection(ctx, sigRejected)
-		} else {
+		} else if !r.hasSubscribers(ev.id) {
 			slog.Debug("signal rejected handler is nil")
 		}
+	case TimerEvent:
+		timer, ok := ev.data.(common.Timer)
+		if !ok {
+			return errors.New("invalid type assertion for timer event")
+		}
+		if r.OnTimer != nil {
+			r.OnTimer(ctx, timer)
+		} else if !r.hasSubscribers(ev.id) {
+			slog.Debug("timer handler is nil")
+		}
+	case OrderAmendedEvent:
+		amended, ok := ev.data.(common.OrderAmended)
+		if !ok {
+			return errors.New("invalid type assertion for order amended event")
+		}
+		if r.OnOrderAmended != nil {
+			r.OnOrderAmended(ctx, amended)
+		} else if !r.hasSubscribers(ev.id) {
+			slog.Debug("order amended handler is nil")
+		}
+	case DepthEvent:
+		book, ok := ev.data.(common.OrderBook)
+		if !ok {
+			return errors.New("invalid type assertion for depth event")
+		}
+		if r.OnDepth != nil {
+			r.OnDepth(ctx, book)
+		} else if !r.hasSubscribers(ev.id) {
+			slog.Debug("depth handler is nil")
+		}
+	case AccountEvent:
+		account, ok := ev.data.(common.Account)
+		if !ok {
+			return errors.New("invalid type assertion for account event")
+		}
+		if r.OnAccount != nil {
+			r.OnAccount(ctx, account)
+		} else if !r.hasSubscribers(ev.id) {
+			slog.Debug("account handler is nil")
+		}
+	case MarginCallEvent:
+		marginCall, ok := ev.data.(common.MarginCall)
+		if !ok {
+			return errors.New("invalid type assertion for margin call event")
+		}
+		if r.OnMarginCall != nil {
+			r.OnMarginCall(ctx, marginCall)
+		} else if !r.hasSubscribers(ev.id) {
+			slog.Debug("margin call handler is nil")
+		}
+	case StopOutEvent:
+		stopOut, ok := ev.data.(common.StopOut)
+		if !ok {
+			return errors.New("invalid type assertion for stop out event")
+		}
+		if r.OnStopOut != nil {
+			r.OnStopOut(ctx, stopOut)
+		} else if !r.hasSubscribers(ev.id) {
+			slog.Debug("stop out handler is nil")
+		}
 	default:
-		return fmt.Errorf("unsupported event id: %v", ev.id)
+		if err := r.dispatchCustom(ctx, ev); err != nil {
+			return err
+		}
 	}
+
+	r.publish(ctx, ev)
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility"
 )
 
 func TestBusRouter_Post(t *testing.T) {
@@ -113,133 +114,62 @@ func TestBusRouter_ExecLoop(t *testing.T) {
 }
 
 func TestBusRouter_AllEventTypes(t *testing.T) {
-	r := NewRouter(20)
-
-	handlers := map[EventId]bool{
-		TickEvent:             false,
-		BarEvent:              false,
-		EquityEvent:           false,
-		BalanceEvent:          false,
-		PositionOpenEvent:     false,
-		PositionCloseEvent:    false,
-		PositionUpdateEvent:   false,
-		OrderEvent:            false,
-		OrderAcceptanceEvent:  false,
-		OrderRejectionEvent:   false,
-		OrderFilledEvent:      false,
-		OrderCancelledEvent:   false,
-		SignalEvent:           false,
-		SignalAcceptanceEvent: false,
-		SignalRejectionEvent:  false,
-	}
-
-	r.OnTick = func(ctx context.Context, tick common.Tick) {
-		handlers[TickEvent] = true
-	}
-	r.OnBar = func(ctx context.Context, bar common.Bar) {
-		handlers[BarEvent] = true
-	}
-	r.OnEquity = func(ctx context.Context, eq common.Equity) {
-		handlers[EquityEvent] = true
-	}
-	r.OnBalance = func(ctx context.Context, bal common.Balance) {
-		handlers[BalanceEvent] = true
-	}
-	r.OnPositionOpen = func(ctx context.Context, pos common.Position) {
-		handlers[PositionOpenEvent] = true
-	}
-	r.OnPositionClose = func(ctx context.Context, pos common.Position) {
-		handlers[PositionCloseEvent] = true
-	}
-	r.OnPositionUpdate = func(ctx context.Context, pos common.Position) {
-		handlers[PositionUpdateEvent] = true
-	}
-	r.OnOrder = func(ctx context.Context, order common.Order) {
-		handlers[OrderEvent] = true
-	}
-	r.OnOrderAcceptance = func(ctx context.Context, oa common.OrderAccepted) {
-		handlers[OrderAcceptanceEvent] = true
-	}
-	r.OnOrderRejection = func(ctx context.Context, or common.OrderRejected) {
-		handlers[OrderRejectionEvent] = true
-	}
-	r.OnOrderFilled = func(ctx context.Context, filled common.OrderFilled) {
-		handlers[OrderFilledEvent] = true
-	}
-	r.OnOrderCancel = func(ctx context.Context, order common.OrderCancelled) {
-		handlers[OrderCancelledEvent] = true
-	}
-	r.OnSignal = func(ctx context.Context, sig common.Signal) {
-		handlers[SignalEvent] = true
-	}
-	r.OnSignalAcceptance = func(ctx context.Context, sa common.SignalAccepted) {
-		handlers[SignalAcceptanceEvent] = true
-	}
-	r.OnSignalRejection = func(ctx context.Context, sr common.SignalRejected) {
-		handlers[SignalRejectionEvent] = true
+	r := NewRouter(32)
+
+	events := []struct {
+		id   EventId
+		data interface{}
+		bind func(called func())
+	}{
+		{TickEvent, common.Tick{}, func(called func()) { r.OnTick = func(context.Context, common.Tick) { called() } }},
+		{BarEvent, common.Bar{}, func(called func()) { r.OnBar = func(context.Context, common.Bar) { called() } }},
+		{EquityEvent, common.Equity{}, func(called func()) { r.OnEquity = func(context.Context, common.Equity) { called() } }},
+		{BalanceEvent, common.Balance{}, func(called func()) { r.OnBalance = func(context.Context, common.Balance) { called() } }},
+		{PositionOpenEvent, common.Position{}, func(called func()) { r.OnPositionOpen = func(context.Context, common.Position) { called() } }},
+		{PositionCloseEvent, common.Position{}, func(called func()) { r.OnPositionClose = func(context.Context, common.Position) { called() } }},
+		{PositionUpdateEvent, common.Position{}, func(called func()) { r.OnPositionUpdate = func(context.Context, common.Position) { called() } }},
+		{OrderEvent, common.Order{}, func(called func()) { r.OnOrder = func(context.Context, common.Order) { called() } }},
+		{OrderAcceptanceEvent, common.OrderAccepted{}, func(called func()) { r.OnOrderAcceptance = func(context.Context, common.OrderAccepted) { called() } }},
+		{OrderRejectionEvent, common.OrderRejected{}, func(called func()) { r.OnOrderRejection = func(context.Context, common.OrderRejected) { called() } }},
+		{OrderFilledEvent, common.OrderFilled{}, func(called func()) { r.OnOrderFilled = func(context.Context, common.OrderFilled) { called() } }},
+		{OrderCancelledEvent, common.OrderCancelled{}, func(called func()) { r.OnOrderCancel = func(context.Context, common.OrderCancelled) { called() } }},
+		{SignalEvent, common.Signal{}, func(called func()) { r.OnSignal = func(context.Context, common.Signal) { called() } }},
+		{SignalAcceptanceEvent, common.SignalAccepted{}, func(called func()) { r.OnSignalAcceptance = func(context.Context, common.SignalAccepted) { called() } }},
+		{SignalRejectionEvent, common.SignalRejected{}, func(called func()) { r.OnSignalRejection = func(context.Context, common.SignalRejected) { called() } }},
+		{TimerEvent, common.Timer{}, func(called func()) { r.OnTimer = func(context.Context, common.Timer) { called() } }},
+		{OrderAmendedEvent, common.OrderAmended{}, func(called func()) { r.OnOrderAmended = func(context.Context, common.OrderAmended) { called() } }},
+		{DepthEvent, common.OrderBook{}, func(called func()) { r.OnDepth = func(context.Context, common.OrderBook) { called() } }},
+		{AccountEvent, common.Account{}, func(called func()) { r.OnAccount = func(context.Context, common.Account) { called() } }},
+		{MarginCallEvent, common.MarginCall{}, func(called func()) { r.OnMarginCall = func(context.Context, common.MarginCall) { called() } }},
+		{StopOutEvent, common.StopOut{}, func(called func()) { r.OnStopOut = func(context.Context, common.StopOut) { called() } }},
+	}
+
+	handled := make(map[EventId]bool, len(events))
+	for _, event := range events {
+		event.bind(func() { handled[event.id] = true })
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	errChan := r.Exec(ctx)
 
-	if err := r.Post(TickEvent, common.Tick{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(BarEvent, common.Bar{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(EquityEvent, common.Equity{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(BalanceEvent, common.Balance{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(PositionOpenEvent, common.Position{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(PositionCloseEvent, common.Position{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(PositionUpdateEvent, common.Position{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(OrderEvent, common.Order{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(OrderAcceptanceEvent, common.OrderAccepted{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(OrderRejectionEvent, common.OrderRejected{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(OrderFilledEvent, common.OrderFilled{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(OrderCancelledEvent, common.OrderCancelled{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(SignalEvent, common.Signal{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(SignalAcceptanceEvent, common.SignalAccepted{}); err != nil {
-		t.Errorf("Post failed: %v", err)
-	}
-	if err := r.Post(SignalRejectionEvent, common.SignalRejected{}); err != nil {
-		t.Errorf("Post failed: %v", err)
+	for _, event := range events {
+		if err := r.Post(event.id, event.data); err != nil {
+			t.Errorf("Post of event %d failed: %v", event.id, err)
+		}
 	}
 
 	time.Sleep(50 * time.Millisecond)
 	cancel()
 	<-errChan
 
-	for eventId, handled := range handlers {
-		if !handled {
-			t.Errorf("Event %d handler not called", eventId)
+	for _, event := range events {
+		if !handled[event.id] {
+			t.Errorf("Event %d handler not called", event.id)
 		}
 	}
 
-	if r.dispatchCount.Load() != 15 {
-		t.Errorf("Expected dispatchCount=11, got %d", r.dispatchCount.Load())
+	if r.dispatchCount.Load() != uint64(len(events)) {
+		t.Errorf("Expected dispatchCount=%d, got %d", len(events), r.dispatchCount.Load())
 	}
 }
 
@@ -352,6 +282,41 @@ func TestBusRouter_ContextCancellation(t *testing.T) {
 	}
 }
 
+func TestBusRouter_IDGenerator(t *testing.T) {
+	if NewRouter(1).IDs() != nil {
+		t.Error("Expected no generator by default")
+	}
+
+	ids := utility.NewIDGenerator(7)
+	r := NewRouter(10, WithIDGenerator(ids))
+	if r.IDs() != ids {
+		t.Error("Expected router generator")
+	}
+
+	var fromContext *utility.IDGenerator
+	r.OnTick = func(ctx context.Context, _ common.Tick) {
+		fromContext = utility.IDGeneratorFromContext(ctx)
+	}
+
+	_ = r.Post(TickEvent, common.Tick{})
+	if err := r.DrainEvents(context.Background()); err != nil {
+		t.Fatalf("DrainEvents failed: %v", err)
+	}
+	if fromContext != ids {
+		t.Error("Expected handler context to carry the router generator")
+	}
+
+	runIds := utility.NewIDGenerator(8)
+	r = NewRouter(10)
+	ctx, cancel := context.WithCancel(utility.ContextWithIDGenerator(context.Background(), runIds))
+	errChan := r.Exec(ctx)
+	if r.IDs() != runIds {
+		t.Error("Expected generator from the run context")
+	}
+	cancel()
+	<-errChan
+}
+
 func BenchmarkBusRouter_Post(b *testing.B) {
 	r := NewRouter(b.N)
 
@@ -1,18 +1,38 @@
 package bus
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"time"
 )
 
+type LatencySummary struct {
+	Count uint64        `json:"count"`
+	P50   time.Duration `json:"p50_ns"`
+	P90   time.Duration `json:"p90_ns"`
+	P99   time.Duration `json:"p99_ns"`
+	Max   time.Duration `json:"max_ns"`
+}
+
+type EventStatistics struct {
+	Policy    BackpressurePolicy `json:"policy"`
+	Drops     uint64             `json:"drops"`
+	Spills    uint64             `json:"spills"`
+	QueueWait LatencySummary     `json:"queue_wait"`
+	Handler   LatencySummary     `json:"handler"`
+}
+
 type Statistics struct {
-	RunTime       time.Duration
-	PostCount     uint64
-	PostFails     uint64
-	DispatchCount uint64
-	DispatchFails uint64
-	Throughput    float64
+	RunTime       time.Duration               `json:"run_time_ns"`
+	PostCount     uint64                      `json:"post_count"`
+	PostFails     uint64                      `json:"post_fails"`
+	DispatchCount uint64                      `json:"dispatch_count"`
+	DispatchFails uint64                      `json:"dispatch_fails"`
+	Pending       int                         `json:"pending"`
+	Throughput    float64                     `json:"throughput"`
+	Events        map[EventId]EventStatistics `json:"events,omitempty"`
 }
 
 func (s Statistics) Print() {
@@ -22,5 +42,37 @@ func (s Statistics) Print() {
 		"post_fails", s.PostFails,
 		"dispatch_count", s.DispatchCount,
 		"dispatch_fails", s.DispatchFails,
+		"pending", s.Pending,
 		"throughput", fmt.Sprintf("%.2f", s.Throughput))
+
+	for id := range maxEventCount {
+		eventStats, ok := s.Events[EventId(id)]
+		if !ok {
+			continue
+		}
+		attrs := []any{
+			"event", id,
+			"policy", eventStats.Policy.String(),
+			"drops", eventStats.Drops,
+			"spills", eventStats.Spills,
+		}
+		if eventStats.QueueWait.Count > 0 {
+			attrs = append(attrs,
+				"queue_wait", eventStats.QueueWait.String(),
+				"handler", eventStats.Handler.String())
+		}
+		slog.Info("router event statistics", attrs...)
+	}
+}
+
+// WriteJSON writes the statistics to w as a single json document
+func (s Statistics) WriteJSON(w io.Writer) error {
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		return fmt.Errorf("unable to encode router statistics: %w", err)
+	}
+	return nil
+}
+
+func (l LatencySummary) String() string {
+	return fmt.Sprintf("count=%d p50=%v p90=%v p99=%v max=%v", l.Count, l.P50, l.P90, l.P99, l.Max)
 }
@@ -0,0 +1,188 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+)
+
+var (
+	ErrAmbiguousEvent = errors.New("payload type is shared by multiple events, use ForEvent")
+	ErrUnknownEvent   = errors.New("no event is associated with payload type")
+	ErrPayloadType    = errors.New("payload type does not match event")
+	ErrHandlerIsNil   = errors.New("handler is nil")
+)
+
+var builtinEventTypes = map[EventId]reflect.Type{
+	TickEvent:             reflect.TypeFor[common.Tick](),
+	BarEvent:              reflect.TypeFor[common.Bar](),
+	EquityEvent:           reflect.TypeFor[common.Equity](),
+	BalanceEvent:          reflect.TypeFor[common.Balance](),
+	PositionOpenEvent:     reflect.TypeFor[common.Position](),
+	PositionCloseEvent:    reflect.TypeFor[common.Position](),
+	PositionUpdateEvent:   reflect.TypeFor[common.Position](),
+	OrderEvent:            reflect.TypeFor[common.Order](),
+	OrderRejectionEvent:   reflect.TypeFor[common.OrderRejected](),
+	OrderAcceptanceEvent:  reflect.TypeFor[common.OrderAccepted](),
+	OrderFilledEvent:      reflect.TypeFor[common.OrderFilled](),
+	OrderCancelledEvent:   reflect.TypeFor[common.OrderCancelled](),
+	SignalEvent:           reflect.TypeFor[common.Signal](),
+	SignalRejectionEvent:  reflect.TypeFor[common.SignalRejected](),
+	SignalAcceptanceEvent: reflect.TypeFor[common.SignalAccepted](),
+	TimerEvent:            reflect.TypeFor[common.Timer](),
+	OrderAmendedEvent:     reflect.TypeFor[common.OrderAmended](),
+	DepthEvent:            reflect.TypeFor[common.OrderBook](),
+	AccountEvent:          reflect.TypeFor[common.Account](),
+	MarginCallEvent:       reflect.TypeFor[common.MarginCall](),
+	StopOutEvent:          reflect.TypeFor[common.StopOut](),
+}
+
+type SubscribeOption func(*subscribeConfig)
+
+type subscribeConfig struct {
+	id    EventId
+	hasId bool
+}
+
+// ForEvent binds the subscription to a specific event id. It is required for payload types
+// shared by several events, e.g. common.Position.
+func ForEvent(id EventId) SubscribeOption {
+	return func(c *subscribeConfig) {
+		c.id = id
+		c.hasId = true
+	}
+}
+
+type subscriber struct {
+	id     uint64
+	handle func(context.Context, any)
+}
+
+// Subscribe attaches handler to the event carrying payload T and returns a function which detaches it
+// again. Subscribers are called after the corresponding On* field, in the order they subscribed.
+// It is safe to subscribe and unsubscribe while the router is running, including from within a handler.
+func Subscribe[T any](r *Router, handler EventHandler[T], options ...SubscribeOption) (func(), error) {
+	if handler == nil {
+		return nil, ErrHandlerIsNil
+	}
+
+	cfg := subscribeConfig{}
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	payloadType := reflect.TypeFor[T]()
+	if !cfg.hasId {
+		id, err := r.lookupEventId(payloadType)
+		if err != nil {
+			return nil, err
+		}
+		cfg.id = id
+	}
+
+	expectedType, ok := r.payloadType(cfg.id)
+	if !ok {
+		return nil, fmt.Errorf("unable to subscribe to event %d: %w", cfg.id, ErrUnknownEvent)
+	}
+	if expectedType != payloadType {
+		return nil, fmt.Errorf("unable to subscribe %v to event %d carrying %v: %w", payloadType, cfg.id, expectedType, ErrPayloadType)
+	}
+
+	sub := subscriber{
+		handle: func(ctx context.Context, data any) {
+			handler(ctx, data.(T))
+		},
+	}
+
+	r.subscribersMu.Lock()
+	defer r.subscribersMu.Unlock()
+
+	r.subscriberIdCounter++
+	sub.id = r.subscriberIdCounter
+
+	var subs []subscriber
+	if current := r.subscribers[cfg.id].Load(); current != nil {
+		subs = append(subs, *current...)
+	}
+	subs = append(subs, sub)
+	r.subscribers[cfg.id].Store(&subs)
+
+	eventId := cfg.id
+	unsubscribe := func() {
+		r.unsubscribe(eventId, sub.id)
+	}
+
+	return unsubscribe, nil
+}
+
+func (r *Router) unsubscribe(eventId EventId, subscriberId uint64) {
+	r.subscribersMu.Lock()
+	defer r.subscribersMu.Unlock()
+
+	current := r.subscribers[eventId].Load()
+	if current == nil {
+		return
+	}
+
+	subs := make([]subscriber, 0, len(*current))
+	for _, sub := range *current {
+		if sub.id != subscriberId {
+			subs = append(subs, sub)
+		}
+	}
+
+	if len(subs) == 0 {
+		r.subscribers[eventId].Store(nil)
+		return
+	}
+	r.subscribers[eventId].Store(&subs)
+}
+
+func (r *Router) publish(ctx context.Context, ev event) {
+	subs := r.subscribers[ev.id].Load()
+	if subs == nil {
+		return
+	}
+	for _, sub := range *subs {
+		sub.handle(ctx, ev.data)
+	}
+}
+
+func (r *Router) hasSubscribers(id EventId) bool {
+	return r.subscribers[id].Load() != nil
+}
+
+func (r *Router) payloadType(id EventId) (reflect.Type, bool) {
+	if t, ok := builtinEventTypes[id]; ok {
+		return t, true
+	}
+	if custom := r.customEvents[id].Load(); custom != nil {
+		return custom.payloadType, true
+	}
+	return nil, false
+}
+
+func (r *Router) lookupEventId(payloadType reflect.Type) (EventId, error) {
+	found := false
+	var id EventId
+
+	for eventId := range maxEventCount {
+		eventType, ok := r.payloadType(EventId(eventId))
+		if !ok || eventType != payloadType {
+			continue
+		}
+		if found {
+			return 0, fmt.Errorf("unable to subscribe %v: %w", payloadType, ErrAmbiguousEvent)
+		}
+		found = true
+		id = EventId(eventId)
+	}
+
+	if !found {
+		return 0, fmt.Errorf("unable to subscribe %v: %w", payloadType, ErrUnknownEvent)
+	}
+	return id, nil
+}
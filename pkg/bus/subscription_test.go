@@ -0,0 +1,177 @@
+package bus
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+)
+
+func TestBusSubscription_Subscribe(t *testing.T) {
+	r := NewRouter(10)
+
+	var first, second int
+	if _, err := Subscribe(r, func(_ context.Context, _ common.Tick) { first++ }); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if _, err := Subscribe(r, func(_ context.Context, _ common.Tick) { second++ }); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	if err := r.Post(TickEvent, common.Tick{}); err != nil {
+		t.Errorf("Post failed: %v", err)
+	}
+	if err := r.DrainEvents(context.Background()); err != nil {
+		t.Errorf("DrainEvents failed: %v", err)
+	}
+
+	if first != 1 || second != 1 {
+		t.Errorf("Expected both subscribers called once, got first=%d, second=%d", first, second)
+	}
+}
+
+func TestBusSubscription_Order(t *testing.T) {
+	r := NewRouter(10)
+
+	var calls []string
+	r.OnBar = func(_ context.Context, _ common.Bar) { calls = append(calls, "field") }
+
+	if _, err := Subscribe(r, func(_ context.Context, _ common.Bar) { calls = append(calls, "a") }); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if _, err := Subscribe(r, func(_ context.Context, _ common.Bar) { calls = append(calls, "b") }); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	_ = r.Post(BarEvent, common.Bar{})
+	_ = r.DrainEvents(context.Background())
+
+	expected := []string{"field", "a", "b"}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("At index %d: expected %s, got %s", i, expected[i], calls[i])
+		}
+	}
+}
+
+func TestBusSubscription_Unsubscribe(t *testing.T) {
+	r := NewRouter(10)
+
+	var count int
+	unsubscribe, err := Subscribe(r, func(_ context.Context, _ common.Signal) { count++ })
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	_ = r.Post(SignalEvent, common.Signal{})
+	_ = r.DrainEvents(context.Background())
+
+	unsubscribe()
+	unsubscribe()
+
+	_ = r.Post(SignalEvent, common.Signal{})
+	_ = r.DrainEvents(context.Background())
+
+	if count != 1 {
+		t.Errorf("Expected count=1, got %d", count)
+	}
+	if r.hasSubscribers(SignalEvent) {
+		t.Error("Expected no subscribers left")
+	}
+}
+
+func TestBusSubscription_UnsubscribeFromHandler(t *testing.T) {
+	r := NewRouter(10)
+
+	var count int
+	var unsubscribe func()
+	unsubscribe, err := Subscribe(r, func(_ context.Context, _ common.Tick) {
+		count++
+		unsubscribe()
+	})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	_ = r.Post(TickEvent, common.Tick{})
+	_ = r.Post(TickEvent, common.Tick{})
+	_ = r.DrainEvents(context.Background())
+
+	if count != 1 {
+		t.Errorf("Expected count=1, got %d", count)
+	}
+}
+
+func TestBusSubscription_ForEvent(t *testing.T) {
+	r := NewRouter(10)
+
+	var opened, closed int
+	if _, err := Subscribe(r, func(_ context.Context, _ common.Position) { opened++ }, ForEvent(PositionOpenEvent)); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	if _, err := Subscribe(r, func(_ context.Context, _ common.Position) { closed++ }, ForEvent(PositionCloseEvent)); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	_ = r.Post(PositionOpenEvent, common.Position{})
+	_ = r.Post(PositionCloseEvent, common.Position{})
+	_ = r.Post(PositionCloseEvent, common.Position{})
+	_ = r.DrainEvents(context.Background())
+
+	if opened != 1 || closed != 2 {
+		t.Errorf("Expected opened=1 and closed=2, got opened=%d, closed=%d", opened, closed)
+	}
+}
+
+func TestBusSubscription_Errors(t *testing.T) {
+	r := NewRouter(10)
+
+	if _, err := Subscribe(r, func(_ context.Context, _ common.Position) {}); !errors.Is(err, ErrAmbiguousEvent) {
+		t.Errorf("Expected ErrAmbiguousEvent, got %v", err)
+	}
+	if _, err := Subscribe(r, func(_ context.Context, _ string) {}); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("Expected ErrUnknownEvent, got %v", err)
+	}
+	if _, err := Subscribe(r, func(_ context.Context, _ common.Tick) {}, ForEvent(BarEvent)); !errors.Is(err, ErrPayloadType) {
+		t.Errorf("Expected ErrPayloadType, got %v", err)
+	}
+	if _, err := Subscribe[common.Tick](r, nil); !errors.Is(err, ErrHandlerIsNil) {
+		t.Errorf("Expected ErrHandlerIsNil, got %v", err)
+	}
+}
+
+func TestBusSubscription_InvalidTypeAssertion(t *testing.T) {
+	r := NewRouter(10)
+
+	if _, err := Subscribe(r, func(_ context.Context, _ common.Tick) {
+		t.Error("Handler should not be called")
+	}); err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+
+	_ = r.Post(TickEvent, "invalid data type")
+	if err := r.DrainEvents(context.Background()); err == nil {
+		t.Error("Expected dispatch error")
+	}
+}
+
+func BenchmarkBusSubscription_Dispatch(b *testing.B) {
+	r := NewRouter(b.N)
+
+	for range 4 {
+		if _, err := Subscribe(r, func(_ context.Context, _ common.Tick) {}); err != nil {
+			b.Fatalf("Subscribe failed: %v", err)
+		}
+	}
+
+	for i := 0; i < b.N; i++ {
+		_ = r.Post(TickEvent, common.Tick{})
+	}
+
+	b.ResetTimer()
+	_ = r.DrainEvents(context.Background())
+}
@@ -0,0 +1,35 @@
+package common
+
+import (
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/utility"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+// Account is a snapshot of the trading account, all amounts are in the account currency
+type Account struct {
+	Balance    fixed.Point `json:"balance"`
+	Equity     fixed.Point `json:"equity"`
+	UsedMargin fixed.Point `json:"used_margin"`
+	FreeMargin fixed.Point `json:"free_margin"`
+	// MarginLevel is the equity in percent of the used margin, zero without used margin
+	MarginLevel   fixed.Point `json:"margin_level"`
+	OpenPositions int         `json:"open_positions"`
+	// Exposure is the net amount held in each currency by the open positions, long in the base currency and
+	// short in the quote currency for a long forex position. Other instruments count in their quote currency.
+	Exposure map[string]fixed.Point `json:"exposure,omitempty"`
+
+	Source      string              `json:"src,omitempty"`
+	Account     string              `json:"account,omitempty"`
+	ExecutionId utility.ExecutionID `json:"eid,omitempty"`
+	TraceID     utility.TraceID     `json:"tid,omitempty"`
+	TimeStamp   time.Time           `json:"ts,omitempty"`
+}
+
+func MarginLevel(equity, usedMargin fixed.Point) fixed.Point {
+	if usedMargin.IsZero() {
+		return fixed.Zero
+	}
+	return equity.Div(usedMargin).MulInt(100)
+}
@@ -0,0 +1,222 @@
+package common
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+// encoder writes the binary form of the common types. Fields are written in declaration order without tags,
+// so appending a field to a type means appending it to its encoding too. The first error sticks.
+type encoder struct {
+	buf []byte
+	err error
+}
+
+func (e *encoder) uvarint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *encoder) varint(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+func (e *encoder) bool(v bool) {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *encoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *encoder) uuid(id uuid.UUID) {
+	e.buf = append(e.buf, id[:]...)
+}
+
+func (e *encoder) point(p fixed.Point) {
+	if e.err != nil {
+		return
+	}
+	e.buf, e.err = p.AppendBinary(e.buf)
+}
+
+// time writes the instant and the zone offset, the zero time is a single byte
+func (e *encoder) time(t time.Time) {
+	if t.IsZero() {
+		e.bool(false)
+		return
+	}
+	e.bool(true)
+	_, offset := t.Zone()
+	e.varint(t.Unix())
+	e.uvarint(uint64(t.Nanosecond()))
+	e.varint(int64(offset))
+}
+
+func (e *encoder) points(m map[string]fixed.Point) {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	e.uvarint(uint64(len(keys)))
+	for _, key := range keys {
+		e.string(key)
+		e.point(m[key])
+	}
+}
+
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+// finish returns the first decoding error, or an error when bytes are left over
+func (d *decoder) finish() error {
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.data) != 0 {
+		return fmt.Errorf("%d trailing bytes", len(d.data))
+	}
+	return nil
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		d.fail(io.ErrUnexpectedEOF)
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.data)
+	if n <= 0 {
+		d.fail(io.ErrUnexpectedEOF)
+		return 0
+	}
+	d.data = d.data[n:]
+	return v
+}
+
+func (d *decoder) bool() bool {
+	if d.err != nil {
+		return false
+	}
+	if len(d.data) == 0 {
+		d.fail(io.ErrUnexpectedEOF)
+		return false
+	}
+	v := d.data[0]
+	d.data = d.data[1:]
+	return v != 0
+}
+
+func (d *decoder) length() int {
+	n := d.uvarint()
+	if n > uint64(len(d.data)) {
+		d.fail(io.ErrUnexpectedEOF)
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) string() string {
+	n := d.length()
+	if d.err != nil {
+		return ""
+	}
+	s := string(d.data[:n])
+	d.data = d.data[n:]
+	return s
+}
+
+func (d *decoder) uuid() uuid.UUID {
+	var id uuid.UUID
+	if d.err != nil {
+		return id
+	}
+	if len(d.data) < len(id) {
+		d.fail(io.ErrUnexpectedEOF)
+		return id
+	}
+	copy(id[:], d.data)
+	d.data = d.data[len(id):]
+	return id
+}
+
+func (d *decoder) point() fixed.Point {
+	if d.err != nil {
+		return fixed.Point{}
+	}
+	p, n, err := fixed.ReadBinary(d.data)
+	if err != nil {
+		d.fail(err)
+		return fixed.Point{}
+	}
+	d.data = d.data[n:]
+	return p
+}
+
+func (d *decoder) time() time.Time {
+	if !d.bool() {
+		return time.Time{}
+	}
+	sec := d.varint()
+	nsec := d.uvarint()
+	offset := d.varint()
+	if d.err != nil {
+		return time.Time{}
+	}
+
+	t := time.Unix(sec, int64(nsec))
+	if offset == 0 {
+		return t.UTC()
+	}
+	return t.In(time.FixedZone("", int(offset)))
+}
+
+func (d *decoder) points() map[string]fixed.Point {
+	n := d.uvarint()
+	if d.err != nil || n == 0 {
+		return nil
+	}
+	if n > uint64(len(d.data)) {
+		d.fail(io.ErrUnexpectedEOF)
+		return nil
+	}
+
+	m := make(map[string]fixed.Point, n)
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		key := d.string()
+		m[key] = d.point()
+	}
+	return m
+}
@@ -0,0 +1,703 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/peter-kozarec/equinox/pkg/utility"
+)
+
+func marshal(encode func(*encoder)) ([]byte, error) {
+	e := &encoder{buf: make([]byte, 0, 128)}
+	encode(e)
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.buf, nil
+}
+
+func unmarshal(name string, data []byte, decode func(*decoder)) error {
+	d := &decoder{data: data}
+	decode(d)
+	if err := d.finish(); err != nil {
+		return fmt.Errorf("unable to decode %s: %w", name, err)
+	}
+	return nil
+}
+
+func (e *encoder) tick(t Tick) {
+	e.point(t.Ask)
+	e.point(t.Bid)
+	e.point(t.AskVolume)
+	e.point(t.BidVolume)
+	e.string(t.Source)
+	e.string(t.Symbol)
+	e.uuid(t.ExecutionId)
+	e.uvarint(t.TraceID)
+	e.time(t.TimeStamp)
+}
+
+func (d *decoder) tick(t *Tick) {
+	t.Ask = d.point()
+	t.Bid = d.point()
+	t.AskVolume = d.point()
+	t.BidVolume = d.point()
+	t.Source = d.string()
+	t.Symbol = d.string()
+	t.ExecutionId = d.uuid()
+	t.TraceID = d.uvarint()
+	t.TimeStamp = d.time()
+}
+
+func (e *encoder) bar(b Bar) {
+	e.time(b.OpenTime)
+	e.varint(int64(b.Period))
+	e.point(b.Open)
+	e.point(b.High)
+	e.point(b.Low)
+	e.point(b.Close)
+	e.point(b.Volume)
+	e.string(b.Source)
+	e.string(b.Symbol)
+	e.uuid(b.ExecutionId)
+	e.uvarint(b.TraceID)
+	e.time(b.TimeStamp)
+}
+
+func (d *decoder) bar(b *Bar) {
+	b.OpenTime = d.time()
+	b.Period = BarPeriod(d.varint())
+	b.Open = d.point()
+	b.High = d.point()
+	b.Low = d.point()
+	b.Close = d.point()
+	b.Volume = d.point()
+	b.Source = d.string()
+	b.Symbol = d.string()
+	b.ExecutionId = d.uuid()
+	b.TraceID = d.uvarint()
+	b.TimeStamp = d.time()
+}
+
+func (e *encoder) equity(q Equity) {
+	e.point(q.Value)
+	e.string(q.Source)
+	e.string(q.Account)
+	e.uuid(q.ExecutionId)
+	e.uvarint(q.TraceID)
+	e.time(q.TimeStamp)
+}
+
+func (d *decoder) equity(q *Equity) {
+	q.Value = d.point()
+	q.Source = d.string()
+	q.Account = d.string()
+	q.ExecutionId = d.uuid()
+	q.TraceID = d.uvarint()
+	q.TimeStamp = d.time()
+}
+
+func (e *encoder) balance(b Balance) {
+	e.point(b.Value)
+	e.string(b.Source)
+	e.string(b.Account)
+	e.uuid(b.ExecutionId)
+	e.uvarint(b.TraceID)
+	e.time(b.TimeStamp)
+}
+
+func (d *decoder) balance(b *Balance) {
+	b.Value = d.point()
+	b.Source = d.string()
+	b.Account = d.string()
+	b.ExecutionId = d.uuid()
+	b.TraceID = d.uvarint()
+	b.TimeStamp = d.time()
+}
+
+func (e *encoder) account(a Account) {
+	e.point(a.Balance)
+	e.point(a.Equity)
+	e.point(a.UsedMargin)
+	e.point(a.FreeMargin)
+	e.point(a.MarginLevel)
+	e.varint(int64(a.OpenPositions))
+	e.points(a.Exposure)
+	e.string(a.Source)
+	e.string(a.Account)
+	e.uuid(a.ExecutionId)
+	e.uvarint(a.TraceID)
+	e.time(a.TimeStamp)
+}
+
+func (d *decoder) account(a *Account) {
+	a.Balance = d.point()
+	a.Equity = d.point()
+	a.UsedMargin = d.point()
+	a.FreeMargin = d.point()
+	a.MarginLevel = d.point()
+	a.OpenPositions = int(d.varint())
+	a.Exposure = d.points()
+	a.Source = d.string()
+	a.Account = d.string()
+	a.ExecutionId = d.uuid()
+	a.TraceID = d.uvarint()
+	a.TimeStamp = d.time()
+}
+
+func (e *encoder) marginCall(m MarginCall) {
+	e.point(m.MarginLevel)
+	e.point(m.CallLevel)
+	e.point(m.Equity)
+	e.point(m.UsedMargin)
+	e.string(m.Source)
+	e.string(m.Account)
+	e.uuid(m.ExecutionId)
+	e.uvarint(m.TraceID)
+	e.time(m.TimeStamp)
+}
+
+func (d *decoder) marginCall(m *MarginCall) {
+	m.MarginLevel = d.point()
+	m.CallLevel = d.point()
+	m.Equity = d.point()
+	m.UsedMargin = d.point()
+	m.Source = d.string()
+	m.Account = d.string()
+	m.ExecutionId = d.uuid()
+	m.TraceID = d.uvarint()
+	m.TimeStamp = d.time()
+}
+
+func (e *encoder) stopOut(s StopOut) {
+	e.position(s.Position)
+	e.point(s.MarginLevel)
+	e.point(s.StopOutLevel)
+	e.string(s.Source)
+	e.string(s.Account)
+	e.uuid(s.ExecutionId)
+	e.uvarint(s.TraceID)
+	e.uvarint(s.ParentTraceID)
+	e.time(s.TimeStamp)
+}
+
+func (d *decoder) stopOut(s *StopOut) {
+	d.position(&s.Position)
+	s.MarginLevel = d.point()
+	s.StopOutLevel = d.point()
+	s.Source = d.string()
+	s.Account = d.string()
+	s.ExecutionId = d.uuid()
+	s.TraceID = d.uvarint()
+	s.ParentTraceID = d.uvarint()
+	s.TimeStamp = d.time()
+}
+
+func (e *encoder) order(o Order) {
+	e.varint(int64(o.Command))
+	e.varint(int64(o.Type))
+	e.varint(int64(o.Side))
+	e.point(o.Price)
+	e.point(o.StopPrice)
+	e.point(o.Size)
+	e.point(o.FilledSize)
+	e.varint(int64(o.TimeInForce))
+	e.time(o.ExpireTime)
+	e.point(o.StopLoss)
+	e.point(o.TakeProfit)
+	e.point(o.TrailingDistance)
+	e.varint(o.PositionId)
+	e.uvarint(o.OrderTraceID)
+	e.varint(o.GroupId)
+	e.varint(int64(o.GroupRelation))
+	e.string(o.Comment)
+	e.string(o.Source)
+	e.string(o.Symbol)
+	e.uuid(o.ExecutionId)
+	e.uvarint(o.TraceID)
+	e.uvarint(o.ParentTraceID)
+	e.time(o.TimeStamp)
+}
+
+func (d *decoder) order(o *Order) {
+	o.Command = OrderCommand(d.varint())
+	o.Type = OrderType(d.varint())
+	o.Side = OrderSide(d.varint())
+	o.Price = d.point()
+	o.StopPrice = d.point()
+	o.Size = d.point()
+	o.FilledSize = d.point()
+	o.TimeInForce = TimeInForce(d.varint())
+	o.ExpireTime = d.time()
+	o.StopLoss = d.point()
+	o.TakeProfit = d.point()
+	o.TrailingDistance = d.point()
+	o.PositionId = d.varint()
+	o.OrderTraceID = d.uvarint()
+	o.GroupId = d.varint()
+	o.GroupRelation = OrderGroupRelation(d.varint())
+	o.Comment = d.string()
+	o.Source = d.string()
+	o.Symbol = d.string()
+	o.ExecutionId = d.uuid()
+	o.TraceID = d.uvarint()
+	o.ParentTraceID = d.uvarint()
+	o.TimeStamp = d.time()
+}
+
+func (e *encoder) orderRejected(o OrderRejected) {
+	e.order(o.OriginalOrder)
+	e.string(o.Reason)
+	e.string(o.Source)
+	e.uuid(o.ExecutionId)
+	e.uvarint(o.TraceID)
+	e.uvarint(o.ParentTraceID)
+	e.time(o.TimeStamp)
+}
+
+func (d *decoder) orderRejected(o *OrderRejected) {
+	d.order(&o.OriginalOrder)
+	o.Reason = d.string()
+	o.Source = d.string()
+	o.ExecutionId = d.uuid()
+	o.TraceID = d.uvarint()
+	o.ParentTraceID = d.uvarint()
+	o.TimeStamp = d.time()
+}
+
+func (e *encoder) orderAccepted(o OrderAccepted) {
+	e.order(o.OriginalOrder)
+	e.string(o.Source)
+	e.uuid(o.ExecutionId)
+	e.uvarint(o.TraceID)
+	e.uvarint(o.ParentTraceID)
+	e.time(o.TimeStamp)
+}
+
+func (d *decoder) orderAccepted(o *OrderAccepted) {
+	d.order(&o.OriginalOrder)
+	o.Source = d.string()
+	o.ExecutionId = d.uuid()
+	o.TraceID = d.uvarint()
+	o.ParentTraceID = d.uvarint()
+	o.TimeStamp = d.time()
+}
+
+func (e *encoder) orderFilled(o OrderFilled) {
+	e.order(o.OriginalOrder)
+	e.varint(o.PositionId)
+	e.string(o.Source)
+	e.uuid(o.ExecutionId)
+	e.uvarint(o.TraceID)
+	e.uvarint(o.ParentTraceID)
+	e.time(o.TimeStamp)
+}
+
+func (d *decoder) orderFilled(o *OrderFilled) {
+	d.order(&o.OriginalOrder)
+	o.PositionId = d.varint()
+	o.Source = d.string()
+	o.ExecutionId = d.uuid()
+	o.TraceID = d.uvarint()
+	o.ParentTraceID = d.uvarint()
+	o.TimeStamp = d.time()
+}
+
+func (e *encoder) orderCancelled(o OrderCancelled) {
+	e.order(o.OriginalOrder)
+	e.point(o.CancelledSize)
+	e.string(o.Source)
+	e.uuid(o.ExecutionId)
+	e.uvarint(o.TraceID)
+	e.uvarint(o.ParentTraceID)
+	e.time(o.TimeStamp)
+}
+
+func (d *decoder) orderCancelled(o *OrderCancelled) {
+	d.order(&o.OriginalOrder)
+	o.CancelledSize = d.point()
+	o.Source = d.string()
+	o.ExecutionId = d.uuid()
+	o.TraceID = d.uvarint()
+	o.ParentTraceID = d.uvarint()
+	o.TimeStamp = d.time()
+}
+
+func (e *encoder) orderAmended(o OrderAmended) {
+	e.order(o.OriginalOrder)
+	e.order(o.AmendedOrder)
+	e.string(o.Source)
+	e.uuid(o.ExecutionId)
+	e.uvarint(o.TraceID)
+	e.uvarint(o.ParentTraceID)
+	e.time(o.TimeStamp)
+}
+
+func (d *decoder) orderAmended(o *OrderAmended) {
+	d.order(&o.OriginalOrder)
+	d.order(&o.AmendedOrder)
+	o.Source = d.string()
+	o.ExecutionId = d.uuid()
+	o.TraceID = d.uvarint()
+	o.ParentTraceID = d.uvarint()
+	o.TimeStamp = d.time()
+}
+
+func (e *encoder) position(p Position) {
+	e.varint(p.Id)
+	e.string(string(p.Status))
+	e.varint(int64(p.Side))
+	e.point(p.Size)
+	e.point(p.Margin)
+	e.point(p.GrossProfit)
+	e.point(p.NetProfit)
+	e.point(p.OpenPrice)
+	e.point(p.ClosePrice)
+	e.time(p.OpenTime)
+	e.time(p.CloseTime)
+	e.point(p.StopLoss)
+	e.point(p.TakeProfit)
+	e.point(p.TrailingDistance)
+	e.point(p.Commissions)
+	e.point(p.Swaps)
+	e.string(p.Currency)
+	e.point(p.OpenExchangeRate)
+	e.point(p.OpenConversionFeeRate)
+	e.point(p.OpenConversionFee)
+	e.point(p.CloseExchangeRate)
+	e.point(p.CloseConversionFeeRate)
+	e.point(p.CloseConversionFee)
+	e.point(p.Slippage)
+	e.string(p.Source)
+	e.string(p.Symbol)
+	e.uuid(p.ExecutionID)
+	e.uvarint(p.TraceID)
+	e.uvarint(p.ParentTraceID)
+	e.uvarint(uint64(len(p.OrderTraceIDs)))
+	for _, id := range p.OrderTraceIDs {
+		e.uvarint(id)
+	}
+	e.time(p.TimeStamp)
+}
+
+func (d *decoder) position(p *Position) {
+	p.Id = d.varint()
+	p.Status = PositionStatus(d.string())
+	p.Side = PositionSide(d.varint())
+	p.Size = d.point()
+	p.Margin = d.point()
+	p.GrossProfit = d.point()
+	p.NetProfit = d.point()
+	p.OpenPrice = d.point()
+	p.ClosePrice = d.point()
+	p.OpenTime = d.time()
+	p.CloseTime = d.time()
+	p.StopLoss = d.point()
+	p.TakeProfit = d.point()
+	p.TrailingDistance = d.point()
+	p.Commissions = d.point()
+	p.Swaps = d.point()
+	p.Currency = d.string()
+	p.OpenExchangeRate = d.point()
+	p.OpenConversionFeeRate = d.point()
+	p.OpenConversionFee = d.point()
+	p.CloseExchangeRate = d.point()
+	p.CloseConversionFeeRate = d.point()
+	p.CloseConversionFee = d.point()
+	p.Slippage = d.point()
+	p.Source = d.string()
+	p.Symbol = d.string()
+	p.ExecutionID = d.uuid()
+	p.TraceID = d.uvarint()
+	p.ParentTraceID = d.uvarint()
+	p.OrderTraceIDs = nil
+	if n := d.length(); n > 0 {
+		p.OrderTraceIDs = make([]utility.TraceID, n)
+		for i := range p.OrderTraceIDs {
+			p.OrderTraceIDs[i] = d.uvarint()
+		}
+	}
+	p.TimeStamp = d.time()
+}
+
+func (e *encoder) signal(s Signal) {
+	e.point(s.Entry)
+	e.point(s.Target)
+	e.uvarint(uint64(s.Strength))
+	e.string(s.Comment)
+	e.string(s.Source)
+	e.string(s.Symbol)
+	e.uuid(s.ExecutionID)
+	e.uvarint(s.TraceID)
+	e.uvarint(s.ParentTraceID)
+	e.time(s.TimeStamp)
+}
+
+func (d *decoder) signal(s *Signal) {
+	s.Entry = d.point()
+	s.Target = d.point()
+	s.Strength = uint8(d.uvarint())
+	s.Comment = d.string()
+	s.Source = d.string()
+	s.Symbol = d.string()
+	s.ExecutionID = d.uuid()
+	s.TraceID = d.uvarint()
+	s.ParentTraceID = d.uvarint()
+	s.TimeStamp = d.time()
+}
+
+func (e *encoder) signalRejected(s SignalRejected) {
+	e.string(s.Reason)
+	e.string(s.Comment)
+	e.signal(s.OriginalSignal)
+	e.string(s.Source)
+	e.uuid(s.ExecutionID)
+	e.uvarint(s.TraceID)
+	e.uvarint(s.ParentTraceID)
+	e.time(s.TimeStamp)
+}
+
+func (d *decoder) signalRejected(s *SignalRejected) {
+	s.Reason = d.string()
+	s.Comment = d.string()
+	d.signal(&s.OriginalSignal)
+	s.Source = d.string()
+	s.ExecutionID = d.uuid()
+	s.TraceID = d.uvarint()
+	s.ParentTraceID = d.uvarint()
+	s.TimeStamp = d.time()
+}
+
+func (e *encoder) signalAccepted(s SignalAccepted) {
+	e.string(s.Comment)
+	e.signal(s.OriginalSignal)
+	e.string(s.Source)
+	e.uuid(s.ExecutionID)
+	e.uvarint(s.TraceID)
+	e.uvarint(s.ParentTraceID)
+	e.time(s.TimeStamp)
+}
+
+func (d *decoder) signalAccepted(s *SignalAccepted) {
+	s.Comment = d.string()
+	d.signal(&s.OriginalSignal)
+	s.Source = d.string()
+	s.ExecutionID = d.uuid()
+	s.TraceID = d.uvarint()
+	s.ParentTraceID = d.uvarint()
+	s.TimeStamp = d.time()
+}
+
+func (e *encoder) timer(t Timer) {
+	e.uvarint(t.Id)
+	e.string(t.Name)
+	e.time(t.ScheduledTime)
+	e.bool(t.Recurring)
+	e.string(t.Source)
+	e.uuid(t.ExecutionId)
+	e.uvarint(t.TraceID)
+	e.time(t.TimeStamp)
+}
+
+func (d *decoder) timer(t *Timer) {
+	t.Id = d.uvarint()
+	t.Name = d.string()
+	t.ScheduledTime = d.time()
+	t.Recurring = d.bool()
+	t.Source = d.string()
+	t.ExecutionId = d.uuid()
+	t.TraceID = d.uvarint()
+	t.TimeStamp = d.time()
+}
+
+func (e *encoder) bookLevels(levels []BookLevel) {
+	e.uvarint(uint64(len(levels)))
+	for _, level := range levels {
+		e.point(level.Price)
+		e.point(level.Size)
+	}
+}
+
+func (d *decoder) bookLevels() []BookLevel {
+	n := d.length()
+	if n == 0 {
+		return nil
+	}
+	levels := make([]BookLevel, n)
+	for i := range levels {
+		levels[i].Price = d.point()
+		levels[i].Size = d.point()
+	}
+	return levels
+}
+
+func (e *encoder) orderBook(b OrderBook) {
+	e.bookLevels(b.Bids)
+	e.bookLevels(b.Asks)
+	e.string(b.Source)
+	e.string(b.Symbol)
+	e.uuid(b.ExecutionId)
+	e.uvarint(b.TraceID)
+	e.time(b.TimeStamp)
+}
+
+func (d *decoder) orderBook(b *OrderBook) {
+	b.Bids = d.bookLevels()
+	b.Asks = d.bookLevels()
+	b.Source = d.string()
+	b.Symbol = d.string()
+	b.ExecutionId = d.uuid()
+	b.TraceID = d.uvarint()
+	b.TimeStamp = d.time()
+}
+
+func (t Tick) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.tick(t) })
+}
+
+func (t *Tick) UnmarshalBinary(data []byte) error {
+	return unmarshal("tick", data, func(d *decoder) { d.tick(t) })
+}
+
+func (b Bar) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.bar(b) })
+}
+
+func (b *Bar) UnmarshalBinary(data []byte) error {
+	return unmarshal("bar", data, func(d *decoder) { d.bar(b) })
+}
+
+func (q Equity) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.equity(q) })
+}
+
+func (q *Equity) UnmarshalBinary(data []byte) error {
+	return unmarshal("equity", data, func(d *decoder) { d.equity(q) })
+}
+
+func (b Balance) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.balance(b) })
+}
+
+func (b *Balance) UnmarshalBinary(data []byte) error {
+	return unmarshal("balance", data, func(d *decoder) { d.balance(b) })
+}
+
+func (a Account) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.account(a) })
+}
+
+func (a *Account) UnmarshalBinary(data []byte) error {
+	return unmarshal("account", data, func(d *decoder) { d.account(a) })
+}
+
+func (m MarginCall) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.marginCall(m) })
+}
+
+func (m *MarginCall) UnmarshalBinary(data []byte) error {
+	return unmarshal("margin call", data, func(d *decoder) { d.marginCall(m) })
+}
+
+func (s StopOut) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.stopOut(s) })
+}
+
+func (s *StopOut) UnmarshalBinary(data []byte) error {
+	return unmarshal("stop out", data, func(d *decoder) { d.stopOut(s) })
+}
+
+func (o Order) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.order(o) })
+}
+
+func (o *Order) UnmarshalBinary(data []byte) error {
+	return unmarshal("order", data, func(d *decoder) { d.order(o) })
+}
+
+func (o OrderRejected) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.orderRejected(o) })
+}
+
+func (o *OrderRejected) UnmarshalBinary(data []byte) error {
+	return unmarshal("order rejected", data, func(d *decoder) { d.orderRejected(o) })
+}
+
+func (o OrderAccepted) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.orderAccepted(o) })
+}
+
+func (o *OrderAccepted) UnmarshalBinary(data []byte) error {
+	return unmarshal("order accepted", data, func(d *decoder) { d.orderAccepted(o) })
+}
+
+func (o OrderFilled) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.orderFilled(o) })
+}
+
+func (o *OrderFilled) UnmarshalBinary(data []byte) error {
+	return unmarshal("order filled", data, func(d *decoder) { d.orderFilled(o) })
+}
+
+func (o OrderCancelled) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.orderCancelled(o) })
+}
+
+func (o *OrderCancelled) UnmarshalBinary(data []byte) error {
+	return unmarshal("order cancelled", data, func(d *decoder) { d.orderCancelled(o) })
+}
+
+func (o OrderAmended) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.orderAmended(o) })
+}
+
+func (o *OrderAmended) UnmarshalBinary(data []byte) error {
+	return unmarshal("order amended", data, func(d *decoder) { d.orderAmended(o) })
+}
+
+func (p Position) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.position(p) })
+}
+
+func (p *Position) UnmarshalBinary(data []byte) error {
+	return unmarshal("position", data, func(d *decoder) { d.position(p) })
+}
+
+func (s Signal) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.signal(s) })
+}
+
+func (s *Signal) UnmarshalBinary(data []byte) error {
+	return unmarshal("signal", data, func(d *decoder) { d.signal(s) })
+}
+
+func (s SignalRejected) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.signalRejected(s) })
+}
+
+func (s *SignalRejected) UnmarshalBinary(data []byte) error {
+	return unmarshal("signal rejected", data, func(d *decoder) { d.signalRejected(s) })
+}
+
+func (s SignalAccepted) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.signalAccepted(s) })
+}
+
+func (s *SignalAccepted) UnmarshalBinary(data []byte) error {
+	return unmarshal("signal accepted", data, func(d *decoder) { d.signalAccepted(s) })
+}
+
+func (t Timer) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.timer(t) })
+}
+
+func (t *Timer) UnmarshalBinary(data []byte) error {
+	return unmarshal("timer", data, func(d *decoder) { d.timer(t) })
+}
+
+func (b OrderBook) MarshalBinary() ([]byte, error) {
+	return marshal(func(e *encoder) { e.orderBook(b) })
+}
+
+func (b *OrderBook) UnmarshalBinary(data []byte) error {
+	return unmarshal("order book", data, func(d *decoder) { d.orderBook(b) })
+}
@@ -0,0 +1,271 @@
+package common
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+var (
+	codecTime = time.Date(2025, 3, 14, 9, 26, 53, 589793238, time.UTC)
+	codecEid  = uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+)
+
+func codecOrder() Order {
+	return Order{
+		Command:          OrderCommandAmend,
+		Type:             OrderTypeStopLimit,
+		Side:             OrderSideSell,
+		Price:            fixed.FromFloat64(1.08525),
+		StopPrice:        fixed.FromFloat64(1.0855),
+		Size:             fixed.FromFloat64(0.35),
+		FilledSize:       fixed.FromFloat64(0.1),
+		TimeInForce:      TimeInForceGoodTillDate,
+		ExpireTime:       codecTime.Add(time.Hour),
+		StopLoss:         fixed.FromFloat64(1.09),
+		TakeProfit:       fixed.FromFloat64(1.07),
+		TrailingDistance: fixed.FromFloat64(0.0015),
+		PositionId:       42,
+		OrderTraceID:     7,
+		GroupId:          -3,
+		GroupRelation:    OrderGroupRelationOneCancelsOther,
+		Comment:          "amend ✓",
+		Source:           "strategy",
+		Symbol:           "EURUSD",
+		ExecutionId:      codecEid,
+		TraceID:          1 << 63,
+		ParentTraceID:    5,
+		TimeStamp:        codecTime,
+	}
+}
+
+func codecPosition() Position {
+	return Position{
+		Id:                     42,
+		Status:                 PositionStatusClosed,
+		Side:                   PositionSideShort,
+		Size:                   fixed.FromFloat64(0.35),
+		Margin:                 fixed.FromFloat64(379.84),
+		GrossProfit:            fixed.FromFloat64(-12.25),
+		NetProfit:              fixed.FromFloat64(-14.1),
+		OpenPrice:              fixed.FromFloat64(1.08525),
+		ClosePrice:             fixed.FromFloat64(1.0856),
+		OpenTime:               codecTime,
+		CloseTime:              codecTime.Add(time.Minute),
+		StopLoss:               fixed.FromFloat64(1.09),
+		TakeProfit:             fixed.FromFloat64(1.07),
+		TrailingDistance:       fixed.FromFloat64(0.0015),
+		Commissions:            fixed.FromFloat64(-1.75),
+		Swaps:                  fixed.FromFloat64(-0.1),
+		Currency:               "EUR",
+		OpenExchangeRate:       fixed.FromFloat64(0.9214),
+		OpenConversionFeeRate:  fixed.FromFloat64(0.001),
+		OpenConversionFee:      fixed.FromFloat64(0.01),
+		CloseExchangeRate:      fixed.FromFloat64(0.9212),
+		CloseConversionFeeRate: fixed.FromFloat64(0.001),
+		CloseConversionFee:     fixed.FromFloat64(0.01),
+		Slippage:               fixed.FromFloat64(0.00002),
+		Source:                 "simulator",
+		Symbol:                 "EURUSD",
+		ExecutionID:            codecEid,
+		TraceID:                11,
+		ParentTraceID:          10,
+		OrderTraceIDs:          []uint64{7, 9},
+		TimeStamp:              codecTime,
+	}
+}
+
+func codecSignal() Signal {
+	return Signal{
+		Entry:         fixed.FromFloat64(1.0852),
+		Target:        fixed.FromFloat64(1.09),
+		Strength:      200,
+		Comment:       "breakout",
+		Source:        "strategy",
+		Symbol:        "EURUSD",
+		ExecutionID:   codecEid,
+		TraceID:       3,
+		ParentTraceID: 2,
+		TimeStamp:     codecTime,
+	}
+}
+
+func codecValues() []any {
+	return []any{
+		Tick{
+			Ask:         fixed.FromFloat64(1.08527),
+			Bid:         fixed.FromFloat64(1.08525),
+			AskVolume:   fixed.FromFloat64(1.5),
+			BidVolume:   fixed.FromFloat64(2.25),
+			Source:      "historical",
+			Symbol:      "EURUSD",
+			ExecutionId: codecEid,
+			TraceID:     1,
+			TimeStamp:   codecTime,
+		},
+		Tick{},
+		Bar{
+			OpenTime:    codecTime.Truncate(time.Minute),
+			Period:      BarPeriodM5,
+			Open:        fixed.FromFloat64(1.085),
+			High:        fixed.FromFloat64(1.0861),
+			Low:         fixed.FromFloat64(1.0849),
+			Close:       fixed.FromFloat64(1.0855),
+			Volume:      fixed.FromInt(1250, 0),
+			Source:      "bar builder",
+			Symbol:      "EURUSD",
+			ExecutionId: codecEid,
+			TraceID:     2,
+			TimeStamp:   codecTime,
+		},
+		Equity{Value: fixed.FromFloat64(10012.5), Source: "simulator", Account: "demo", ExecutionId: codecEid, TraceID: 4, TimeStamp: codecTime},
+		Balance{Value: fixed.FromFloat64(-25.75), Source: "simulator", Account: "demo", ExecutionId: codecEid, TraceID: 5, TimeStamp: codecTime},
+		Account{
+			Balance:       fixed.FromInt(10000, 0),
+			Equity:        fixed.FromFloat64(10012.5),
+			UsedMargin:    fixed.FromFloat64(379.84),
+			FreeMargin:    fixed.FromFloat64(9632.66),
+			MarginLevel:   fixed.FromFloat64(2635.94),
+			OpenPositions: 2,
+			Exposure: map[string]fixed.Point{
+				"EUR": fixed.FromInt(35000, 0),
+				"USD": fixed.FromFloat64(-37983.75),
+			},
+			Source:      "simulator",
+			Account:     "demo",
+			ExecutionId: codecEid,
+			TraceID:     6,
+			TimeStamp:   codecTime,
+		},
+		MarginCall{
+			MarginLevel: fixed.FromInt(95, 0),
+			CallLevel:   fixed.FromInt(100, 0),
+			Equity:      fixed.FromFloat64(361.2),
+			UsedMargin:  fixed.FromFloat64(379.84),
+			Source:      "simulator",
+			Account:     "demo",
+			ExecutionId: codecEid,
+			TraceID:     7,
+			TimeStamp:   codecTime,
+		},
+		StopOut{
+			Position:      codecPosition(),
+			MarginLevel:   fixed.FromInt(49, 0),
+			StopOutLevel:  fixed.FromInt(50, 0),
+			Source:        "simulator",
+			Account:       "demo",
+			ExecutionId:   codecEid,
+			TraceID:       8,
+			ParentTraceID: 7,
+			TimeStamp:     codecTime,
+		},
+		codecOrder(),
+		OrderRejected{OriginalOrder: codecOrder(), Reason: "insufficient margin", Source: "simulator", ExecutionId: codecEid, TraceID: 9, ParentTraceID: 8, TimeStamp: codecTime},
+		OrderAccepted{OriginalOrder: codecOrder(), Source: "simulator", ExecutionId: codecEid, TraceID: 9, ParentTraceID: 8, TimeStamp: codecTime},
+		OrderFilled{OriginalOrder: codecOrder(), PositionId: 42, Source: "simulator", ExecutionId: codecEid, TraceID: 9, ParentTraceID: 8, TimeStamp: codecTime},
+		OrderCancelled{OriginalOrder: codecOrder(), CancelledSize: fixed.FromFloat64(0.25), Source: "simulator", ExecutionId: codecEid, TraceID: 9, ParentTraceID: 8, TimeStamp: codecTime},
+		OrderAmended{OriginalOrder: codecOrder(), AmendedOrder: Order{Price: fixed.FromFloat64(1.086), Symbol: "EURUSD", TimeStamp: codecTime}, Source: "simulator", ExecutionId: codecEid, TraceID: 9, ParentTraceID: 8, TimeStamp: codecTime},
+		codecPosition(),
+		codecSignal(),
+		SignalRejected{Reason: "risk", Comment: "too large", OriginalSignal: codecSignal(), Source: "risk", ExecutionID: codecEid, TraceID: 12, ParentTraceID: 3, TimeStamp: codecTime},
+		SignalAccepted{Comment: "ok", OriginalSignal: codecSignal(), Source: "risk", ExecutionID: codecEid, TraceID: 12, ParentTraceID: 3, TimeStamp: codecTime},
+		Timer{Id: 3, Name: "session close", ScheduledTime: codecTime.Add(time.Hour), Recurring: true, Source: "clock", ExecutionId: codecEid, TraceID: 13, TimeStamp: codecTime},
+		OrderBook{
+			Bids:        []BookLevel{{Price: fixed.FromFloat64(1.08525), Size: fixed.FromInt(3, 0)}, {Price: fixed.FromFloat64(1.0852), Size: fixed.FromFloat64(7.5)}},
+			Asks:        []BookLevel{{Price: fixed.FromFloat64(1.08527), Size: fixed.FromInt(2, 0)}},
+			Source:      "ctrader",
+			Symbol:      "EURUSD",
+			ExecutionId: codecEid,
+			TraceID:     14,
+			TimeStamp:   codecTime,
+		},
+	}
+}
+
+func TestCodec_BinaryRoundTrip(t *testing.T) {
+	for _, value := range codecValues() {
+		t.Run(reflect.TypeOf(value).Name(), func(t *testing.T) {
+			data, err := value.(encoding.BinaryMarshaler).MarshalBinary()
+			require.NoError(t, err)
+
+			decoded := reflect.New(reflect.TypeOf(value))
+			require.NoError(t, decoded.Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data))
+			assert.Equal(t, value, decoded.Elem().Interface())
+
+			err = decoded.Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data[:len(data)-1])
+			assert.Error(t, err)
+			err = decoded.Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(append(data, 0))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCodec_JSONRoundTrip(t *testing.T) {
+	for _, value := range codecValues() {
+		t.Run(reflect.TypeOf(value).Name(), func(t *testing.T) {
+			data, err := json.Marshal(value)
+			require.NoError(t, err)
+
+			decoded := reflect.New(reflect.TypeOf(value))
+			require.NoError(t, json.Unmarshal(data, decoded.Interface()))
+			assert.Equal(t, value, decoded.Elem().Interface())
+		})
+	}
+}
+
+func TestCodec_Messages(t *testing.T) {
+	values := codecValues()
+
+	var buf bytes.Buffer
+	for _, value := range values {
+		require.NoError(t, WriteMessage(&buf, value))
+	}
+
+	for _, expected := range values {
+		value, err := ReadMessage(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, expected, value)
+	}
+
+	_, err := ReadMessage(&buf)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestCodec_MessageErrors(t *testing.T) {
+	err := WriteMessage(io.Discard, "tick")
+	assert.ErrorIs(t, err, ErrUnknownMessageType)
+
+	_, err = ReadMessage(bytes.NewReader([]byte{0, 0, 0, 1, 0xff}))
+	assert.ErrorIs(t, err, ErrUnknownMessageType)
+
+	_, err = ReadMessage(bytes.NewReader([]byte{0xff, 0, 0, 0}))
+	assert.ErrorIs(t, err, ErrMessageTooLarge)
+
+	data, err := AppendMessage(nil, Tick{Symbol: "EURUSD"})
+	require.NoError(t, err)
+	_, err = ReadMessage(bytes.NewReader(data[:len(data)-1]))
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestCodec_TimeZone(t *testing.T) {
+	ts := time.Date(2025, 3, 14, 9, 26, 53, 1, time.FixedZone("CET", 3600))
+
+	data, err := Tick{TimeStamp: ts}.MarshalBinary()
+	require.NoError(t, err)
+
+	var tick Tick
+	require.NoError(t, tick.UnmarshalBinary(data))
+	assert.True(t, ts.Equal(tick.TimeStamp))
+	_, offset := tick.TimeStamp.Zone()
+	assert.Equal(t, 3600, offset)
+}
@@ -0,0 +1,38 @@
+package common
+
+import (
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/utility"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+// MarginCall warns that the margin level fell to the margin call level, positions are liquidated once it
+// falls further to the stop out level
+type MarginCall struct {
+	MarginLevel fixed.Point `json:"margin_level"`
+	CallLevel   fixed.Point `json:"call_level"`
+	Equity      fixed.Point `json:"equity"`
+	UsedMargin  fixed.Point `json:"used_margin"`
+
+	Source      string              `json:"src,omitempty"`
+	Account     string              `json:"account,omitempty"`
+	ExecutionId utility.ExecutionID `json:"eid,omitempty"`
+	TraceID     utility.TraceID     `json:"tid,omitempty"`
+	TimeStamp   time.Time           `json:"ts,omitempty"`
+}
+
+// StopOut reports a position force closed because the margin level fell to the stop out level. StopOutLevel is
+// zero when the venue liquidates on a maintenance margin rule instead.
+type StopOut struct {
+	Position     Position    `json:"position"`
+	MarginLevel  fixed.Point `json:"margin_level"`
+	StopOutLevel fixed.Point `json:"stop_out_level"`
+
+	Source        string              `json:"src,omitempty"`
+	Account       string              `json:"account,omitempty"`
+	ExecutionId   utility.ExecutionID `json:"eid,omitempty"`
+	TraceID       utility.TraceID     `json:"tid,omitempty"`
+	ParentTraceID utility.TraceID     `json:"ptid,omitempty"`
+	TimeStamp     time.Time           `json:"ts,omitempty"`
+}
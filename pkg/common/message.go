@@ -0,0 +1,198 @@
+package common
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MessageType tags the payload of a framed message, the values are part of the wire format and must not change
+type MessageType uint8
+
+const (
+	MessageTypeTick MessageType = iota + 1
+	MessageTypeBar
+	MessageTypeEquity
+	MessageTypeBalance
+	MessageTypeAccount
+	MessageTypeMarginCall
+	MessageTypeStopOut
+	MessageTypeOrder
+	MessageTypeOrderRejected
+	MessageTypeOrderAccepted
+	MessageTypeOrderFilled
+	MessageTypeOrderCancelled
+	MessageTypeOrderAmended
+	MessageTypePosition
+	MessageTypeSignal
+	MessageTypeSignalRejected
+	MessageTypeSignalAccepted
+	MessageTypeTimer
+	MessageTypeOrderBook
+)
+
+// MaxMessageSize bounds the payload ReadMessage accepts
+const MaxMessageSize = 16 << 20
+
+var (
+	ErrUnknownMessageType = errors.New("unknown message type")
+	ErrMessageTooLarge    = errors.New("message too large")
+)
+
+// AppendMessage appends v framed as a message, a 4 byte big endian length of the rest of the frame, the
+// message type and the binary encoding of v
+func AppendMessage(b []byte, v any) ([]byte, error) {
+	var msgType MessageType
+	var encode func(*encoder)
+
+	switch v := v.(type) {
+	case Tick:
+		msgType, encode = MessageTypeTick, func(e *encoder) { e.tick(v) }
+	case Bar:
+		msgType, encode = MessageTypeBar, func(e *encoder) { e.bar(v) }
+	case Equity:
+		msgType, encode = MessageTypeEquity, func(e *encoder) { e.equity(v) }
+	case Balance:
+		msgType, encode = MessageTypeBalance, func(e *encoder) { e.balance(v) }
+	case Account:
+		msgType, encode = MessageTypeAccount, func(e *encoder) { e.account(v) }
+	case MarginCall:
+		msgType, encode = MessageTypeMarginCall, func(e *encoder) { e.marginCall(v) }
+	case StopOut:
+		msgType, encode = MessageTypeStopOut, func(e *encoder) { e.stopOut(v) }
+	case Order:
+		msgType, encode = MessageTypeOrder, func(e *encoder) { e.order(v) }
+	case OrderRejected:
+		msgType, encode = MessageTypeOrderRejected, func(e *encoder) { e.orderRejected(v) }
+	case OrderAccepted:
+		msgType, encode = MessageTypeOrderAccepted, func(e *encoder) { e.orderAccepted(v) }
+	case OrderFilled:
+		msgType, encode = MessageTypeOrderFilled, func(e *encoder) { e.orderFilled(v) }
+	case OrderCancelled:
+		msgType, encode = MessageTypeOrderCancelled, func(e *encoder) { e.orderCancelled(v) }
+	case OrderAmended:
+		msgType, encode = MessageTypeOrderAmended, func(e *encoder) { e.orderAmended(v) }
+	case Position:
+		msgType, encode = MessageTypePosition, func(e *encoder) { e.position(v) }
+	case Signal:
+		msgType, encode = MessageTypeSignal, func(e *encoder) { e.signal(v) }
+	case SignalRejected:
+		msgType, encode = MessageTypeSignalRejected, func(e *encoder) { e.signalRejected(v) }
+	case SignalAccepted:
+		msgType, encode = MessageTypeSignalAccepted, func(e *encoder) { e.signalAccepted(v) }
+	case Timer:
+		msgType, encode = MessageTypeTimer, func(e *encoder) { e.timer(v) }
+	case OrderBook:
+		msgType, encode = MessageTypeOrderBook, func(e *encoder) { e.orderBook(v) }
+	default:
+		return b, fmt.Errorf("%w: %T", ErrUnknownMessageType, v)
+	}
+
+	start := len(b)
+	e := &encoder{buf: append(b, 0, 0, 0, 0, byte(msgType))}
+	encode(e)
+	if e.err != nil {
+		return b, e.err
+	}
+
+	size := len(e.buf) - start - 4
+	if size > MaxMessageSize {
+		return b, ErrMessageTooLarge
+	}
+	binary.BigEndian.PutUint32(e.buf[start:], uint32(size))
+	return e.buf, nil
+}
+
+// WriteMessage writes v framed as a message to w, see AppendMessage
+func WriteMessage(w io.Writer, v any) error {
+	b, err := AppendMessage(nil, v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// ReadMessage reads one framed message from r and returns the decoded value, e.g. a Tick. It returns io.EOF
+// when r ends before a message starts.
+func ReadMessage(r io.Reader) (any, error) {
+	var header [4]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+
+	size := binary.BigEndian.Uint32(header[:])
+	if size == 0 {
+		return nil, fmt.Errorf("empty message")
+	}
+	if size > MaxMessageSize {
+		return nil, ErrMessageTooLarge
+	}
+
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return DecodeMessage(MessageType(frame[0]), frame[1:])
+}
+
+// DecodeMessage decodes the payload of a message of the given type
+func DecodeMessage(msgType MessageType, data []byte) (any, error) {
+	switch msgType {
+	case MessageTypeTick:
+		return decodeMessage[Tick](data)
+	case MessageTypeBar:
+		return decodeMessage[Bar](data)
+	case MessageTypeEquity:
+		return decodeMessage[Equity](data)
+	case MessageTypeBalance:
+		return decodeMessage[Balance](data)
+	case MessageTypeAccount:
+		return decodeMessage[Account](data)
+	case MessageTypeMarginCall:
+		return decodeMessage[MarginCall](data)
+	case MessageTypeStopOut:
+		return decodeMessage[StopOut](data)
+	case MessageTypeOrder:
+		return decodeMessage[Order](data)
+	case MessageTypeOrderRejected:
+		return decodeMessage[OrderRejected](data)
+	case MessageTypeOrderAccepted:
+		return decodeMessage[OrderAccepted](data)
+	case MessageTypeOrderFilled:
+		return decodeMessage[OrderFilled](data)
+	case MessageTypeOrderCancelled:
+		return decodeMessage[OrderCancelled](data)
+	case MessageTypeOrderAmended:
+		return decodeMessage[OrderAmended](data)
+	case MessageTypePosition:
+		return decodeMessage[Position](data)
+	case MessageTypeSignal:
+		return decodeMessage[Signal](data)
+	case MessageTypeSignalRejected:
+		return decodeMessage[SignalRejected](data)
+	case MessageTypeSignalAccepted:
+		return decodeMessage[SignalAccepted](data)
+	case MessageTypeTimer:
+		return decodeMessage[Timer](data)
+	case MessageTypeOrderBook:
+		return decodeMessage[OrderBook](data)
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnknownMessageType, msgType)
+	}
+}
+
+func decodeMessage[T any, PT interface {
+	*T
+	UnmarshalBinary([]byte) error
+}](data []byte) (any, error) {
+	var v T
+	if err := PT(&v).UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
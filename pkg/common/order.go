@@ -10,16 +10,27 @@ type OrderCommand int
 type OrderType int
 type OrderSide int
 type TimeInForce int
+type OrderGroupId = int64
+type OrderGroupRelation int
 
 const (
 	OrderCommandPositionOpen OrderCommand = iota
 	OrderCommandPositionClose
 	OrderCommandPositionModify
+	// OrderCommandCancel cancels the pending order with trace id OrderTraceID
+	OrderCommandCancel
+	// OrderCommandAmend replaces the non-zero price, stop price, size and expire time of the pending order
+	// with trace id OrderTraceID
+	OrderCommandAmend
 )
 
 const (
 	OrderTypeMarket OrderType = iota
 	OrderTypeLimit
+	// OrderTypeStop becomes a market order once the market reaches StopPrice
+	OrderTypeStop
+	// OrderTypeStopLimit becomes a limit order at Price once the market reaches StopPrice
+	OrderTypeStopLimit
 )
 
 const (
@@ -34,62 +45,93 @@ const (
 	TimeInForceGoodTillDate
 )
 
+const (
+	OrderGroupRelationNone OrderGroupRelation = iota
+	// OrderGroupRelationOneCancelsOther cancels the other one-cancels-other orders of the group once the order fills
+	OrderGroupRelationOneCancelsOther
+	// OrderGroupRelationOneTriggersOther marks the parent of the group, the other orders of the group stay inactive
+	// until it fills and are cancelled when it is cancelled or rejected. Close and modify orders of the group without
+	// a position id are assigned the position opened by the parent
+	OrderGroupRelationOneTriggersOther
+)
+
 type Order struct {
-	Command     OrderCommand `json:"command"`
-	Type        OrderType    `json:"type"`
-	Side        OrderSide    `json:"side"`
-	Price       fixed.Point  `json:"price"`
-	Size        fixed.Point  `json:"size"`
-	FilledSize  fixed.Point  `json:"filled_size"`
-	TimeInForce TimeInForce  `json:"time_in_force"`
-	ExpireTime  time.Time    `json:"expire_time"`
-	StopLoss    fixed.Point  `json:"stop_loss,omitempty"`
-	TakeProfit  fixed.Point  `json:"take_profit,omitempty"`
-	PositionId  PositionId   `json:"position_id,omitempty"`
-	Comment     string       `json:"comment,omitempty"`
-
-	Source      string              `json:"src,omitempty"`
-	Symbol      string              `json:"symbol,omitempty"`
-	ExecutionId utility.ExecutionID `json:"eid,omitempty"`
-	TraceID     utility.TraceID     `json:"tid,omitempty"`
-	TimeStamp   time.Time           `json:"ts"`
+	Command          OrderCommand       `json:"command"`
+	Type             OrderType          `json:"type"`
+	Side             OrderSide          `json:"side"`
+	Price            fixed.Point        `json:"price"`
+	StopPrice        fixed.Point        `json:"stop_price,omitempty"`
+	Size             fixed.Point        `json:"size"`
+	FilledSize       fixed.Point        `json:"filled_size"`
+	TimeInForce      TimeInForce        `json:"time_in_force"`
+	ExpireTime       time.Time          `json:"expire_time"`
+	StopLoss         fixed.Point        `json:"stop_loss,omitempty"`
+	TakeProfit       fixed.Point        `json:"take_profit,omitempty"`
+	TrailingDistance fixed.Point        `json:"trailing_distance,omitempty"`
+	PositionId       PositionId         `json:"position_id,omitempty"`
+	OrderTraceID     utility.TraceID    `json:"order_tid,omitempty"`
+	GroupId          OrderGroupId       `json:"group_id,omitempty"`
+	GroupRelation    OrderGroupRelation `json:"group_relation,omitempty"`
+	Comment          string             `json:"comment,omitempty"`
+
+	Source        string              `json:"src,omitempty"`
+	Symbol        string              `json:"symbol,omitempty"`
+	ExecutionId   utility.ExecutionID `json:"eid,omitempty"`
+	TraceID       utility.TraceID     `json:"tid,omitempty"`
+	ParentTraceID utility.TraceID     `json:"ptid,omitempty"`
+	TimeStamp     time.Time           `json:"ts"`
 }
 
 type OrderRejected struct {
 	OriginalOrder Order  `json:"original_order"`
 	Reason        string `json:"reason,omitempty"`
 
-	Source      string              `json:"src,omitempty"`
-	ExecutionId utility.ExecutionID `json:"eid,omitempty"`
-	TraceID     utility.TraceID     `json:"tid,omitempty"`
-	TimeStamp   time.Time           `json:"ts"`
+	Source        string              `json:"src,omitempty"`
+	ExecutionId   utility.ExecutionID `json:"eid,omitempty"`
+	TraceID       utility.TraceID     `json:"tid,omitempty"`
+	ParentTraceID utility.TraceID     `json:"ptid,omitempty"`
+	TimeStamp     time.Time           `json:"ts"`
 }
 
 type OrderAccepted struct {
 	OriginalOrder Order `json:"original_order"`
 
-	Source      string              `json:"src,omitempty"`
-	ExecutionId utility.ExecutionID `json:"eid,omitempty"`
-	TraceID     utility.TraceID     `json:"tid,omitempty"`
-	TimeStamp   time.Time           `json:"ts"`
+	Source        string              `json:"src,omitempty"`
+	ExecutionId   utility.ExecutionID `json:"eid,omitempty"`
+	TraceID       utility.TraceID     `json:"tid,omitempty"`
+	ParentTraceID utility.TraceID     `json:"ptid,omitempty"`
+	TimeStamp     time.Time           `json:"ts"`
 }
 
 type OrderFilled struct {
 	OriginalOrder Order      `json:"original_order"`
 	PositionId    PositionId `json:"position_id"`
 
-	Source      string              `json:"src,omitempty"`
-	ExecutionId utility.ExecutionID `json:"eid,omitempty"`
-	TraceID     utility.TraceID     `json:"tid,omitempty"`
-	TimeStamp   time.Time           `json:"ts"`
+	Source        string              `json:"src,omitempty"`
+	ExecutionId   utility.ExecutionID `json:"eid,omitempty"`
+	TraceID       utility.TraceID     `json:"tid,omitempty"`
+	ParentTraceID utility.TraceID     `json:"ptid,omitempty"`
+	TimeStamp     time.Time           `json:"ts"`
 }
 
 type OrderCancelled struct {
 	OriginalOrder Order       `json:"original_order"`
 	CancelledSize fixed.Point `json:"cancelled_size"`
 
-	Source      string              `json:"src,omitempty"`
-	ExecutionId utility.ExecutionID `json:"eid,omitempty"`
-	TraceID     utility.TraceID     `json:"tid,omitempty"`
-	TimeStamp   time.Time           `json:"ts"`
+	Source        string              `json:"src,omitempty"`
+	ExecutionId   utility.ExecutionID `json:"eid,omitempty"`
+	TraceID       utility.TraceID     `json:"tid,omitempty"`
+	ParentTraceID utility.TraceID     `json:"ptid,omitempty"`
+	TimeStamp     time.Time           `json:"ts"`
+}
+
+type OrderAmended struct {
+	OriginalOrder Order `json:"original_order"`
+	AmendedOrder  Order `json:"amended_order"`
+
+	Source        string              `json:"src,omitempty"`
+	ExecutionId   utility.ExecutionID `json:"eid,omitempty"`
+	TraceID       utility.TraceID     `json:"tid,omitempty"`
+	ParentTraceID utility.TraceID     `json:"ptid,omitempty"`
+	TimeStamp     time.Time           `json:"ts"`
 }
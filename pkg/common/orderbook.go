@@ -0,0 +1,75 @@
+package common
+
+import (
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/utility"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+type BookLevel struct {
+	Price fixed.Point `json:"price"`
+	Size  fixed.Point `json:"size"`
+}
+
+// OrderBook is a level-2 snapshot of a symbol, Bids are ordered from the highest price down and Asks from the
+// lowest price up
+type OrderBook struct {
+	Bids []BookLevel `json:"bids"`
+	Asks []BookLevel `json:"asks"`
+
+	Source      string              `json:"src,omitempty"`
+	Symbol      string              `json:"symbol,omitempty"`
+	ExecutionId utility.ExecutionID `json:"eid,omitempty"`
+	TraceID     utility.TraceID     `json:"tid,omitempty"`
+	TimeStamp   time.Time           `json:"ts"`
+}
+
+func (b OrderBook) BestBid() (BookLevel, bool) {
+	if len(b.Bids) == 0 {
+		return BookLevel{}, false
+	}
+	return b.Bids[0], true
+}
+
+func (b OrderBook) BestAsk() (BookLevel, bool) {
+	if len(b.Asks) == 0 {
+		return BookLevel{}, false
+	}
+	return b.Asks[0], true
+}
+
+// Fill walks the levels an order of the given side takes, buys take the asks and sells the bids, and returns
+// the size available up to size and its volume weighted average price. A non-zero limit stops at the first
+// level priced worse than it.
+func (b OrderBook) Fill(side OrderSide, size, limit fixed.Point) (fixed.Point, fixed.Point) {
+	levels := b.Asks
+	if side == OrderSideSell {
+		levels = b.Bids
+	}
+
+	filled := fixed.Zero
+	notional := fixed.Zero
+	for _, level := range levels {
+		if filled.Gte(size) {
+			break
+		}
+		if !limit.IsZero() {
+			if side == OrderSideBuy && level.Price.Gt(limit) || side == OrderSideSell && level.Price.Lt(limit) {
+				break
+			}
+		}
+
+		take := size.Sub(filled)
+		if level.Size.Lt(take) {
+			take = level.Size
+		}
+		filled = filled.Add(take)
+		notional = notional.Add(take.Mul(level.Price))
+	}
+
+	if filled.IsZero() {
+		return fixed.Zero, fixed.Zero
+	}
+	return filled, notional.Div(filled)
+}
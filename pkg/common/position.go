@@ -35,6 +35,7 @@ type Position struct {
 	CloseTime              time.Time      `json:"close_time"`
 	StopLoss               fixed.Point    `json:"stop_loss"`
 	TakeProfit             fixed.Point    `json:"take_profit"`
+	TrailingDistance       fixed.Point    `json:"trailing_distance,omitempty"`
 	Commissions            fixed.Point    `json:"commission"`
 	Swaps                  fixed.Point    `json:"swaps"`
 	Currency               string         `json:"currency"`
@@ -50,6 +51,7 @@ type Position struct {
 	Symbol        string              `json:"symbol,omitempty"`
 	ExecutionID   utility.ExecutionID `json:"eid,omitempty"`
 	TraceID       utility.TraceID     `json:"tid,omitempty"`
+	ParentTraceID utility.TraceID     `json:"ptid,omitempty"`
 	OrderTraceIDs []utility.TraceID   `json:"order_tid,omitempty"`
 	TimeStamp     time.Time           `json:"ts"`
 }
@@ -13,11 +13,12 @@ type Signal struct {
 	Strength uint8       `json:"strength,omitempty"`
 	Comment  string      `json:"comment,omitempty"`
 
-	Source      string              `json:"src,omitempty"`
-	Symbol      string              `json:"symbol,omitempty"`
-	ExecutionID utility.ExecutionID `json:"eid,omitempty"`
-	TraceID     utility.TraceID     `json:"tid,omitempty"`
-	TimeStamp   time.Time           `json:"ts"`
+	Source        string              `json:"src,omitempty"`
+	Symbol        string              `json:"symbol,omitempty"`
+	ExecutionID   utility.ExecutionID `json:"eid,omitempty"`
+	TraceID       utility.TraceID     `json:"tid,omitempty"`
+	ParentTraceID utility.TraceID     `json:"ptid,omitempty"`
+	TimeStamp     time.Time           `json:"ts"`
 }
 
 type SignalRejected struct {
@@ -25,18 +26,20 @@ type SignalRejected struct {
 	Comment        string `json:"comment,omitempty"`
 	OriginalSignal Signal `json:"original_signal"`
 
-	Source      string              `json:"src,omitempty"`
-	ExecutionID utility.ExecutionID `json:"eid,omitempty"`
-	TraceID     utility.TraceID     `json:"tid,omitempty"`
-	TimeStamp   time.Time           `json:"ts"`
+	Source        string              `json:"src,omitempty"`
+	ExecutionID   utility.ExecutionID `json:"eid,omitempty"`
+	TraceID       utility.TraceID     `json:"tid,omitempty"`
+	ParentTraceID utility.TraceID     `json:"ptid,omitempty"`
+	TimeStamp     time.Time           `json:"ts"`
 }
 
 type SignalAccepted struct {
 	Comment        string `json:"comment,omitempty"`
 	OriginalSignal Signal `json:"original_signal"`
 
-	Source      string              `json:"src,omitempty"`
-	ExecutionID utility.ExecutionID `json:"eid,omitempty"`
-	TraceID     utility.TraceID     `json:"tid,omitempty"`
-	TimeStamp   time.Time           `json:"ts"`
+	Source        string              `json:"src,omitempty"`
+	ExecutionID   utility.ExecutionID `json:"eid,omitempty"`
+	TraceID       utility.TraceID     `json:"tid,omitempty"`
+	ParentTraceID utility.TraceID     `json:"ptid,omitempty"`
+	TimeStamp     time.Time           `json:"ts"`
 }
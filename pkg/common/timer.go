@@ -0,0 +1,21 @@
+package common
+
+import (
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/utility"
+)
+
+type TimerId = uint64
+
+type Timer struct {
+	Id            TimerId   `json:"id"`
+	Name          string    `json:"name,omitempty"`
+	ScheduledTime time.Time `json:"scheduled_time"`
+	Recurring     bool      `json:"recurring,omitempty"`
+
+	Source      string              `json:"src,omitempty"`
+	ExecutionId utility.ExecutionID `json:"eid,omitempty"`
+	TraceID     utility.TraceID     `json:"tid,omitempty"`
+	TimeStamp   time.Time           `json:"ts"`
+}
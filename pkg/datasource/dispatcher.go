@@ -3,12 +3,29 @@ package datasource
 import (
 	"github.com/peter-kozarec/equinox/pkg/bus"
 	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility"
 )
 
 type TickDataSource interface {
 	GetNext() (common.Tick, error)
 }
 
+type DepthDataSource interface {
+	GetNext() (common.OrderBook, error)
+}
+
+type BarDataSource interface {
+	GetNext() (common.Bar, error)
+}
+
+// IDStamper is implemented by the sources which stamp their ticks, bars or order books with an execution id and
+// trace ids. By default they take them from the process wide utility.GetExecutionID and utility.CreateTraceID,
+// a backtest running with bus.WithIDGenerator passes its generator to the sources as well, so that the events
+// read from them carry the run scoped ids and a run with the same seed reproduces them.
+type IDStamper interface {
+	SetIDGenerator(ids *utility.IDGenerator)
+}
+
 func CreateTickDispatcher(r *bus.Router, ds TickDataSource) func() error {
 	return func() error {
 		var tick common.Tick
@@ -23,3 +40,35 @@ func CreateTickDispatcher(r *bus.Router, ds TickDataSource) func() error {
 		return nil
 	}
 }
+
+func CreateDepthDispatcher(r *bus.Router, ds DepthDataSource) func() error {
+	return func() error {
+		var book common.OrderBook
+		var err error
+
+		if book, err = ds.GetNext(); err != nil {
+			return err
+		}
+		if err = r.Post(bus.DepthEvent, book); err != nil {
+			return err
+		}
+		return nil
+	}
+}
+
+// CreateBarDispatcher posts the bars of ds directly, for backtests without tick data. See
+// sandbox.WithSyntheticTicks for executing orders on them.
+func CreateBarDispatcher(r *bus.Router, ds BarDataSource) func() error {
+	return func() error {
+		var bar common.Bar
+		var err error
+
+		if bar, err = ds.GetNext(); err != nil {
+			return err
+		}
+		if err = r.Post(bus.BarEvent, bar); err != nil {
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,59 @@
+package datasource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/datasource/historical"
+)
+
+type testBarSource struct {
+	bars []common.Bar
+}
+
+func (s *testBarSource) GetNext() (common.Bar, error) {
+	if len(s.bars) == 0 {
+		return common.Bar{}, historical.ErrEof
+	}
+	bar := s.bars[0]
+	s.bars = s.bars[1:]
+	return bar, nil
+}
+
+func testBar(minute int) common.Bar {
+	openTime := time.Date(2024, 1, 2, 10, minute, 0, 0, time.UTC)
+	return common.Bar{Symbol: "EURUSD", OpenTime: openTime, Period: common.BarPeriodM1, TimeStamp: openTime.Add(time.Minute)}
+}
+
+func TestCreateBarDispatcher(t *testing.T) {
+	router := bus.NewRouter(10)
+
+	var dispatched []common.Bar
+	router.OnBar = func(_ context.Context, bar common.Bar) { dispatched = append(dispatched, bar) }
+
+	dispatch := CreateBarDispatcher(router, &testBarSource{bars: []common.Bar{testBar(0), testBar(1)}})
+
+	require.NoError(t, dispatch())
+	require.NoError(t, dispatch())
+	assert.ErrorIs(t, dispatch(), historical.ErrEof, "the end of the source is passed on")
+
+	require.NoError(t, router.DrainEvents(context.Background()))
+	assert.Equal(t, []common.Bar{testBar(0), testBar(1)}, dispatched)
+}
+
+func TestCreateBarDispatcher_PostFailure(t *testing.T) {
+	router := bus.NewRouter(1)
+	require.NoError(t, router.Post(bus.TickEvent, common.Tick{Symbol: "EURUSD"}))
+
+	source := &testBarSource{bars: []common.Bar{testBar(0)}}
+	err := CreateBarDispatcher(router, source)()
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, historical.ErrEof)
+	assert.Empty(t, source.bars, "the bar was read")
+}
@@ -0,0 +1,73 @@
+package historical
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility"
+)
+
+const barReaderComponentName = "datasource.historical.bar"
+
+// BarReader reads the bars of a bar file opening between from and to
+type BarReader struct {
+	source *Source[BinaryBar]
+
+	symbol string
+	from   int64
+	to     int64
+	idx    int64
+
+	ids *utility.IDGenerator
+}
+
+func NewBarReader(source *Source[BinaryBar], symbol string, from, to time.Time) *BarReader {
+	return &BarReader{
+		source: source,
+		symbol: symbol,
+		from:   from.UnixNano(),
+		to:     to.UnixNano(),
+		idx:    invalidIndex,
+	}
+}
+
+// SetIDGenerator stamps the bars with the ids of the run, see datasource.IDStamper
+func (b *BarReader) SetIDGenerator(ids *utility.IDGenerator) {
+	b.ids = ids
+}
+
+func (b *BarReader) GetNext() (common.Bar, error) {
+
+	var bar common.Bar
+	var binBar BinaryBar
+
+	if b.idx == invalidIndex {
+		if err := checkSymbol(b.source, b.symbol); err != nil {
+			return bar, err
+		}
+		idx, err := searchIndex(b.source, b.from)
+		if err != nil {
+			return bar, err
+		}
+		b.idx = idx
+	}
+
+	if err := b.source.Read(b.idx, &binBar); err != nil {
+		return bar, fmt.Errorf("error reading entry at index %d: %w", b.idx, err)
+	}
+	b.idx++
+
+	if binBar.OpenTime > b.to {
+		return bar, ErrEof
+	}
+
+	binBar.ToModelBar(&bar)
+
+	bar.Source = barReaderComponentName
+	bar.Symbol = b.symbol
+	bar.ExecutionId = b.ids.ExecutionID()
+	bar.TraceID = b.ids.TraceID()
+
+	return bar, nil
+}
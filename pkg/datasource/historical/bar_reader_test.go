@@ -0,0 +1,91 @@
+package historical
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+func testBars(n int) []BinaryBar {
+	start := time.Date(2024, time.January, 2, 10, 0, 0, 0, time.UTC)
+
+	bars := make([]BinaryBar, 0, n)
+	for i := 0; i < n; i++ {
+		bars = append(bars, BinaryBar{
+			OpenTime: start.Add(time.Duration(i) * time.Hour).UnixNano(),
+			Period:   int64(time.Hour),
+			Open:     1.1000,
+			High:     1.1010,
+			Low:      1.0990,
+			Close:    1.1005,
+			Volume:   float64(i + 1),
+		})
+	}
+	return bars
+}
+
+func TestBarReader_GetNext(t *testing.T) {
+	bars := testBars(5)
+	source := writeTestSource(t, "EURUSD", bars)
+
+	tests := []struct {
+		name string
+		from time.Time
+		to   time.Time
+		want []int
+	}{
+		{name: "whole file", from: time.Unix(0, bars[0].OpenTime), to: time.Unix(0, bars[4].OpenTime), want: []int{0, 1, 2, 3, 4}},
+		{name: "bounds are open times", from: time.Unix(0, bars[1].OpenTime), to: time.Unix(0, bars[3].OpenTime), want: []int{1, 2, 3}},
+		{name: "from within a bar", from: time.Unix(0, bars[1].OpenTime+1), to: time.Unix(0, bars[2].OpenTime), want: []int{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewBarReader(source, "EURUSD", tt.from, tt.to)
+			reader.SetIDGenerator(utility.NewIDGenerator(1))
+
+			var read []int
+			for {
+				bar, err := reader.GetNext()
+				if errors.Is(err, ErrEof) {
+					break
+				}
+				require.NoError(t, err)
+
+				volume, _ := bar.Volume.Float64()
+				idx := int(volume) - 1
+				read = append(read, idx)
+
+				assert.Equal(t, "EURUSD", bar.Symbol)
+				assert.Equal(t, barReaderComponentName, bar.Source)
+				assert.NotZero(t, bar.TraceID)
+				assert.Equal(t, common.BarPeriodH1, bar.Period)
+				assert.Equal(t, bars[idx].OpenTime, bar.OpenTime.UnixNano())
+				assert.True(t, bar.OpenTime.Add(time.Hour).Equal(bar.TimeStamp), "bars are stamped at their close")
+				assert.True(t, fixed.FromFloat64(1.1000).Eq(bar.Open), bar.Open.String())
+				assert.True(t, fixed.FromFloat64(1.1010).Eq(bar.High), bar.High.String())
+				assert.True(t, fixed.FromFloat64(1.0990).Eq(bar.Low), bar.Low.String())
+				assert.True(t, fixed.FromFloat64(1.1005).Eq(bar.Close), bar.Close.String())
+			}
+			assert.Equal(t, tt.want, read)
+		})
+	}
+
+	t.Run("symbol mismatch", func(t *testing.T) {
+		_, err := NewBarReader(source, "GBPUSD", time.Unix(0, 0), time.Now()).GetNext()
+		assert.ErrorIs(t, err, ErrMismatch)
+	})
+
+	t.Run("tick file", func(t *testing.T) {
+		name := source.dataSourceName + ".ticks"
+		require.NoError(t, WriteFile(name, "EURUSD", 5, testTicks(2)))
+		assert.ErrorIs(t, NewSource[BinaryBar](name).Open(), ErrMismatch)
+	})
+}
@@ -0,0 +1,543 @@
+package historical
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"unsafe"
+
+	"golang.org/x/exp/mmap"
+)
+
+// DefaultBlockSize is the number of ticks per block written by WriteBlockFile and ConvertToBlocks
+const DefaultBlockSize = 4096
+
+const (
+	blockIndexEntrySize = 16
+	maxBlockDigits      = 12
+)
+
+// Block compressed tick files hold the ticks in blocks of BlockSize ticks, each decodable on its own, followed by
+// a sparse index of the first timestamp and the file offset of every block. Within a block the timestamps are
+// deltas and the prices are integers at the symbol digits stored as the bid delta and the spread. Time deltas
+// and volumes are stored as a decimal mantissa and exponent, volumes without a short decimal form as their
+// float bits. Prices with more digits than the header records are rejected when writing.
+
+// decimalRaw is the exponent marking a value stored verbatim
+const decimalRaw = 15
+
+var pow10 = [...]float64{1, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9}
+
+type blockIndexEntry struct {
+	timeStamp int64
+	offset    int64
+}
+
+// blockEncoder writes the blocks and the index of a block compressed file behind a header placeholder
+type blockEncoder struct {
+	w         io.Writer
+	crc       hash.Hash32
+	scale     float64
+	blockSize int
+
+	offset  int64
+	block   []byte
+	index   []blockIndexEntry
+	inBlock int
+
+	count   int64
+	first   int64
+	last    int64
+	prevBid int64
+}
+
+func newBlockEncoder(w io.Writer, digits, blockSize int) (*blockEncoder, error) {
+	if digits < 0 || digits > maxBlockDigits {
+		return nil, fmt.Errorf("digits must be between 0 and %d, got %d", maxBlockDigits, digits)
+	}
+	if blockSize <= 0 {
+		return nil, fmt.Errorf("block size must be positive, got %d", blockSize)
+	}
+	crc := crc32.New(crcTable)
+	return &blockEncoder{
+		w:         io.MultiWriter(w, crc),
+		crc:       crc,
+		scale:     math.Pow10(digits),
+		blockSize: blockSize,
+		offset:    HeaderSize,
+	}, nil
+}
+
+func (e *blockEncoder) add(tick BinaryTick) error {
+	if e.count > 0 && tick.TimeStamp < e.last {
+		return fmt.Errorf("tick %d at %d is older than the previous tick at %d", e.count, tick.TimeStamp, e.last)
+	}
+	bid, err := e.toInt(tick.Bid)
+	if err != nil {
+		return fmt.Errorf("tick %d: invalid bid: %w", e.count, err)
+	}
+	ask, err := e.toInt(tick.Ask)
+	if err != nil {
+		return fmt.Errorf("tick %d: invalid ask: %w", e.count, err)
+	}
+
+	if e.inBlock == 0 {
+		e.index = append(e.index, blockIndexEntry{timeStamp: tick.TimeStamp, offset: e.offset})
+		e.block = binary.AppendVarint(e.block[:0], tick.TimeStamp)
+		e.prevBid = 0
+	} else {
+		e.block = appendDelta(e.block, uint64(tick.TimeStamp-e.last))
+	}
+	e.block = binary.AppendVarint(e.block, bid-e.prevBid)
+	e.block = binary.AppendVarint(e.block, ask-bid)
+	e.block = appendVolume(e.block, tick.BidVolume)
+	e.block = appendVolume(e.block, tick.AskVolume)
+	e.prevBid = bid
+
+	if e.count == 0 {
+		e.first = tick.TimeStamp
+	}
+	e.last = tick.TimeStamp
+	e.count++
+	e.inBlock++
+
+	if e.inBlock == e.blockSize {
+		return e.flush()
+	}
+	return nil
+}
+
+func (e *blockEncoder) flush() error {
+	if e.inBlock == 0 {
+		return nil
+	}
+	if _, err := e.w.Write(e.block); err != nil {
+		return fmt.Errorf("unable to write block: %w", err)
+	}
+	e.offset += int64(len(e.block))
+	e.inBlock = 0
+	return nil
+}
+
+// finish writes the index and returns the header describing the file
+func (e *blockEncoder) finish(symbol string, digits int) (Header, error) {
+	if err := e.flush(); err != nil {
+		return Header{}, err
+	}
+
+	index := make([]byte, 0, len(e.index)*blockIndexEntrySize)
+	for _, entry := range e.index {
+		index = binary.LittleEndian.AppendUint64(index, uint64(entry.timeStamp))
+		index = binary.LittleEndian.AppendUint64(index, uint64(entry.offset))
+	}
+	if _, err := e.w.Write(index); err != nil {
+		return Header{}, fmt.Errorf("unable to write block index: %w", err)
+	}
+
+	return Header{
+		Version:        HeaderVersion,
+		Kind:           RecordKindTickBlock,
+		RecordSize:     uint32(unsafe.Sizeof(BinaryTick{})),
+		Symbol:         symbol,
+		Digits:         digits,
+		Count:          e.count,
+		FirstTimeStamp: e.first,
+		LastTimeStamp:  e.last,
+		CRC:            e.crc.Sum32(),
+		BlockSize:      uint32(e.blockSize),
+		IndexOffset:    e.offset,
+	}, nil
+}
+
+func (e *blockEncoder) toInt(price float64) (int64, error) {
+	scaled := price * e.scale
+	rounded := math.Round(scaled)
+	if math.IsNaN(scaled) || math.Abs(rounded) > 1<<53 {
+		return 0, fmt.Errorf("price %v out of range", price)
+	}
+	if math.Abs(scaled-rounded) > 1e-4 {
+		return 0, fmt.Errorf("price %v has more digits than the symbol", price)
+	}
+	return int64(rounded), nil
+}
+
+// appendDelta appends a non-negative delta as its mantissa shifted left by four bits and its exponent, deltas of
+// whole milliseconds take three bytes instead of five
+func appendDelta(b []byte, delta uint64) []byte {
+	mantissa, exp := delta, uint64(0)
+	for mantissa != 0 && mantissa%10 == 0 && exp < decimalRaw-1 {
+		mantissa /= 10
+		exp++
+	}
+	if mantissa >= 1<<59 {
+		return binary.AppendUvarint(binary.AppendUvarint(b, decimalRaw), delta)
+	}
+	return binary.AppendUvarint(b, mantissa<<4|exp)
+}
+
+// appendVolume appends a volume as its zigzag encoded mantissa shifted left by four bits and its number of
+// decimals, volumes without an exact short decimal form follow a raw marker as float bits
+func appendVolume(b []byte, volume float64) []byte {
+	// negative zero keeps its sign only in the raw form
+	for decimals, scale := range pow10 {
+		if volume == 0 && math.Signbit(volume) {
+			break
+		}
+		scaled := volume * scale
+		if math.IsNaN(scaled) || math.Abs(scaled) >= 1<<52 {
+			break
+		}
+		mantissa := int64(scaled)
+		if float64(mantissa) == scaled && float64(mantissa)/scale == volume {
+			zigzag := uint64(mantissa<<1) ^ uint64(mantissa>>63)
+			return binary.AppendUvarint(b, zigzag<<4|uint64(decimals))
+		}
+	}
+	b = binary.AppendUvarint(b, decimalRaw)
+	return binary.LittleEndian.AppendUint64(b, math.Float64bits(volume))
+}
+
+func readDelta(data []byte) (uint64, []byte, bool) {
+	v, data, ok := readUvarint(data)
+	if !ok {
+		return 0, data, false
+	}
+	exp := v & 0xf
+	if exp == decimalRaw {
+		return readUvarint(data)
+	}
+	mantissa := v >> 4
+	for ; exp > 0; exp-- {
+		mantissa *= 10
+	}
+	return mantissa, data, true
+}
+
+func readVolume(data []byte) (float64, []byte, bool) {
+	v, data, ok := readUvarint(data)
+	if !ok {
+		return 0, data, false
+	}
+	decimals := v & 0xf
+	if decimals == decimalRaw {
+		if len(data) < 8 {
+			return 0, data, false
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), data[8:], true
+	}
+	if decimals >= uint64(len(pow10)) {
+		return 0, data, false
+	}
+	zigzag := v >> 4
+	mantissa := int64(zigzag>>1) ^ -int64(zigzag&1)
+	return float64(mantissa) / pow10[decimals], data, true
+}
+
+// writeBlocks writes a block compressed file, next returns the ticks in order until it returns false
+func writeBlocks(name, symbol string, digits, blockSize int, next func() (BinaryTick, bool, error)) error {
+	return writeFile(name, func(f *os.File) error {
+		if _, err := f.Write(make([]byte, HeaderSize)); err != nil {
+			return fmt.Errorf("unable to write header: %w", err)
+		}
+
+		encoder, err := newBlockEncoder(f, digits, blockSize)
+		if err != nil {
+			return err
+		}
+		for {
+			tick, ok, err := next()
+			if err != nil {
+				return err
+			}
+			if !ok {
+				break
+			}
+			if err := encoder.add(tick); err != nil {
+				return err
+			}
+		}
+
+		header, err := encoder.finish(symbol, digits)
+		if err != nil {
+			return err
+		}
+		b, err := header.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if _, err := f.WriteAt(b, 0); err != nil {
+			return fmt.Errorf("unable to write header: %w", err)
+		}
+		return nil
+	})
+}
+
+// WriteBlockFile writes ticks ordered by timestamp to a block compressed file, prices are stored at digits
+func WriteBlockFile(name, symbol string, digits int, ticks []BinaryTick) error {
+	idx := 0
+	return writeBlocks(name, symbol, digits, DefaultBlockSize, func() (BinaryTick, bool, error) {
+		if idx == len(ticks) {
+			return BinaryTick{}, false, nil
+		}
+		idx++
+		return ticks[idx-1], true, nil
+	})
+}
+
+// BlockSource reads a block compressed tick file, see WriteBlockFile. It keeps the last decoded block, so
+// sequential reads decode every block once.
+type BlockSource struct {
+	dataSourceName string
+	options        sourceOptions
+	reader         *mmap.ReaderAt
+
+	header Header
+	index  []blockIndexEntry
+	scale  float64
+
+	buffer     []byte
+	block      []BinaryTick
+	blockIndex int
+}
+
+func NewBlockSource(dataSourceName string, options ...SourceOption) *BlockSource {
+	s := &BlockSource{
+		dataSourceName: dataSourceName,
+		blockIndex:     invalidIndex,
+	}
+	for _, option := range options {
+		option(&s.options)
+	}
+	return s
+}
+
+func (s *BlockSource) Open() error {
+	var err error
+	s.reader, err = mmap.Open(s.dataSourceName)
+	if err != nil {
+		return fmt.Errorf("unable to open data source %q: %w", s.dataSourceName, err)
+	}
+
+	if err := s.readHeader(); err != nil {
+		_ = s.reader.Close()
+		return fmt.Errorf("unable to open data source %q: %w", s.dataSourceName, err)
+	}
+	return nil
+}
+
+func (s *BlockSource) Close() {
+	_ = s.reader.Close()
+}
+
+func (s *BlockSource) Header() Header {
+	return s.header
+}
+
+func (s *BlockSource) EntryCount() (int64, error) {
+	if s.reader == nil {
+		return 0, fmt.Errorf("data source %q is not open", s.dataSourceName)
+	}
+	return s.header.Count, nil
+}
+
+// Read decodes the tick at index
+func (s *BlockSource) Read(index int64, tick *BinaryTick) error {
+	if index < 0 || index >= s.header.Count {
+		return ErrEof
+	}
+	block := int(index / int64(s.header.BlockSize))
+	if err := s.decodeBlock(block); err != nil {
+		return err
+	}
+	*tick = s.block[index%int64(s.header.BlockSize)]
+	return nil
+}
+
+// Search returns the index of the first tick with a timestamp >= ts, the entry count if there is none. It
+// looks up the block in the index and decodes only that block.
+func (s *BlockSource) Search(ts int64) (int64, error) {
+	if s.header.Count == 0 {
+		return 0, fmt.Errorf("entry count is zero")
+	}
+
+	// the last block starting before ts holds the tick, unless all its ticks are older
+	block := sort.Search(len(s.index), func(i int) bool {
+		return s.index[i].timeStamp >= ts
+	}) - 1
+	if block < 0 {
+		return 0, nil
+	}
+
+	if err := s.decodeBlock(block); err != nil {
+		return 0, err
+	}
+	idx := sort.Search(len(s.block), func(i int) bool {
+		return s.block[i].TimeStamp >= ts
+	})
+	return int64(block)*int64(s.header.BlockSize) + int64(idx), nil
+}
+
+func (s *BlockSource) readHeader() error {
+	size := int64(s.reader.Len())
+	if size < HeaderSize {
+		return fmt.Errorf("%w: truncated header", ErrCorrupt)
+	}
+
+	b := make([]byte, HeaderSize)
+	if _, err := s.reader.ReadAt(b, 0); err != nil {
+		return fmt.Errorf("unable to read header: %w", err)
+	}
+	var header Header
+	if err := header.UnmarshalBinary(b); err != nil {
+		return err
+	}
+	if header.Kind != RecordKindTickBlock {
+		return fmt.Errorf("%w: file is not block compressed, open it with NewSource", ErrMismatch)
+	}
+	if header.BlockSize == 0 || header.Digits < 0 || header.Digits > maxBlockDigits || header.Count < 0 {
+		return fmt.Errorf("%w: invalid block header", ErrCorrupt)
+	}
+
+	blocks := (header.Count + int64(header.BlockSize) - 1) / int64(header.BlockSize)
+	if header.IndexOffset < HeaderSize || header.IndexOffset+blocks*blockIndexEntrySize != size {
+		return fmt.Errorf("%w: header announces %d blocks, file holds %d bytes", ErrCorrupt, blocks, size)
+	}
+
+	if !s.options.skipChecksum {
+		if err := verifyChecksum(s.reader, header.CRC); err != nil {
+			return err
+		}
+	}
+
+	index := make([]byte, blocks*blockIndexEntrySize)
+	if _, err := s.reader.ReadAt(index, header.IndexOffset); err != nil && len(index) > 0 {
+		return fmt.Errorf("unable to read block index: %w", err)
+	}
+	s.index = make([]blockIndexEntry, blocks)
+	for i := range s.index {
+		entry := index[i*blockIndexEntrySize:]
+		s.index[i].timeStamp = int64(binary.LittleEndian.Uint64(entry))
+		s.index[i].offset = int64(binary.LittleEndian.Uint64(entry[8:]))
+		if i > 0 && s.index[i].offset < s.index[i-1].offset || s.index[i].offset < HeaderSize || s.index[i].offset > header.IndexOffset {
+			return fmt.Errorf("%w: invalid offset of block %d", ErrCorrupt, i)
+		}
+	}
+
+	s.header = header
+	s.scale = math.Pow10(header.Digits)
+	s.block = make([]BinaryTick, 0, header.BlockSize)
+	s.blockIndex = invalidIndex
+	return nil
+}
+
+func (s *BlockSource) decodeBlock(block int) error {
+	if block == s.blockIndex {
+		return nil
+	}
+
+	start := s.index[block].offset
+	end := s.header.IndexOffset
+	if block+1 < len(s.index) {
+		end = s.index[block+1].offset
+	}
+	count := int(s.header.BlockSize)
+	if block == len(s.index)-1 {
+		count = int(s.header.Count - int64(block)*int64(s.header.BlockSize))
+	}
+
+	if cap(s.buffer) < int(end-start) {
+		s.buffer = make([]byte, end-start)
+	}
+	data := s.buffer[:end-start]
+	if _, err := s.reader.ReadAt(data, start); err != nil && err != io.EOF {
+		return fmt.Errorf("unable to read block %d: %w", block, err)
+	}
+
+	s.blockIndex = invalidIndex
+	s.block = s.block[:0]
+
+	var ts, bid int64
+	for i := 0; i < count; i++ {
+		var delta, spread int64
+		var bidVol, askVol float64
+		var ok bool
+
+		if i == 0 {
+			ts, data, ok = readVarint(data)
+		} else {
+			var d uint64
+			d, data, ok = readDelta(data)
+			ts += int64(d)
+		}
+		if ok {
+			delta, data, ok = readVarint(data)
+		}
+		if ok {
+			spread, data, ok = readVarint(data)
+		}
+		if ok {
+			bidVol, data, ok = readVolume(data)
+		}
+		if ok {
+			askVol, data, ok = readVolume(data)
+		}
+		if !ok {
+			return fmt.Errorf("%w: block %d ends after %d of %d ticks", ErrCorrupt, block, i, count)
+		}
+
+		bid += delta
+		s.block = append(s.block, BinaryTick{
+			TimeStamp: ts,
+			Bid:       float64(bid) / s.scale,
+			Ask:       float64(bid+spread) / s.scale,
+			BidVolume: bidVol,
+			AskVolume: askVol,
+		})
+	}
+	if len(data) != 0 {
+		return fmt.Errorf("%w: %d trailing bytes in block %d", ErrCorrupt, len(data), block)
+	}
+
+	s.blockIndex = block
+	return nil
+}
+
+func readVarint(data []byte) (int64, []byte, bool) {
+	v, n := binary.Varint(data)
+	if n <= 0 {
+		return 0, data, false
+	}
+	return v, data[n:], true
+}
+
+func readUvarint(data []byte) (uint64, []byte, bool) {
+	v, n := binary.Uvarint(data)
+	if n <= 0 {
+		return 0, data, false
+	}
+	return v, data[n:], true
+}
+
+// verifyChecksum compares the CRC-32C checksum of everything following the header with crc
+func verifyChecksum(reader *mmap.ReaderAt, crc uint32) error {
+	buffer := make([]byte, 1<<20)
+	size := int64(reader.Len())
+
+	var sum uint32
+	for offset := int64(HeaderSize); offset < size; offset += int64(len(buffer)) {
+		n := min(int64(len(buffer)), size-offset)
+		if _, err := reader.ReadAt(buffer[:n], offset); err != nil && err != io.EOF {
+			return fmt.Errorf("unable to read: %w", err)
+		}
+		sum = crc32.Update(sum, crcTable, buffer[:n])
+	}
+	if sum != crc {
+		return fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package historical
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility"
+)
+
+const blockReaderComponentName = "datasource.historical.block"
+
+// BlockTickReader reads the ticks of a block compressed file, see NewBlockSource
+type BlockTickReader struct {
+	source *BlockSource
+
+	symbol string
+	from   int64
+	to     int64
+	idx    int64
+
+	ids *utility.IDGenerator
+}
+
+func NewBlockTickReader(source *BlockSource, symbol string, from, to time.Time) *BlockTickReader {
+	return &BlockTickReader{
+		source: source,
+		symbol: symbol,
+		from:   from.UnixNano(),
+		to:     to.UnixNano(),
+		idx:    invalidIndex,
+	}
+}
+
+// SetIDGenerator stamps the ticks with the ids of the run, see datasource.IDStamper
+func (r *BlockTickReader) SetIDGenerator(ids *utility.IDGenerator) {
+	r.ids = ids
+}
+
+func (r *BlockTickReader) GetNext() (common.Tick, error) {
+
+	var tick common.Tick
+	var binTick BinaryTick
+
+	if r.idx == invalidIndex {
+		if err := r.lookupStartIndex(); err != nil {
+			return tick, err
+		}
+	}
+
+	if err := r.source.Read(r.idx, &binTick); err != nil {
+		return tick, fmt.Errorf("error reading entry at index %d: %w", r.idx, err)
+	}
+	r.idx++
+
+	if binTick.TimeStamp > r.to {
+		return tick, ErrEof
+	}
+
+	binTick.ToModelTick(&tick)
+
+	tick.Source = blockReaderComponentName
+	tick.Symbol = r.symbol
+	tick.ExecutionId = r.ids.ExecutionID()
+	tick.TraceID = r.ids.TraceID()
+
+	return tick, nil
+}
+
+func (r *BlockTickReader) lookupStartIndex() error {
+	if err := matchSymbol(r.source.dataSourceName, r.source.header.Symbol, r.symbol); err != nil {
+		return err
+	}
+
+	idx, err := r.source.Search(r.from)
+	if err != nil {
+		return err
+	}
+	if idx >= r.source.header.Count {
+		return fmt.Errorf("no entry found with timestamp >= from: %w", ErrEof)
+	}
+
+	r.idx = idx
+	return nil
+}
@@ -0,0 +1,394 @@
+package historical
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockTestVolumes mixes short decimals with volumes that need the raw form
+var blockTestVolumes = []float64{0, 1, 0.5, 1.25, 1234.5678, -3, 1.0 / 3, 1e-12, 1e20, math.MaxFloat64, math.Copysign(0, -1)}
+
+// testBlockTicks returns ticks at 5 digits with whole millisecond, odd nanosecond and zero time deltas
+func testBlockTicks(n int) []BinaryTick {
+	deltas := []int64{int64(time.Millisecond), 0, 7, int64(250 * time.Millisecond), int64(time.Second) + 1}
+
+	ticks := make([]BinaryTick, 0, n)
+	ts := time.Date(2024, time.January, 2, 10, 0, 0, 0, time.UTC).UnixNano()
+	bid := int64(110010)
+	for i := 0; i < n; i++ {
+		ts += deltas[i%len(deltas)]
+		bid += int64(i%7) - 3
+		ticks = append(ticks, BinaryTick{
+			TimeStamp: ts,
+			Bid:       float64(bid) / 1e5,
+			Ask:       float64(bid+int64(i%3)) / 1e5,
+			BidVolume: blockTestVolumes[i%len(blockTestVolumes)],
+			AskVolume: blockTestVolumes[(i+3)%len(blockTestVolumes)],
+		})
+	}
+	return ticks
+}
+
+func writeTestBlocks(t testing.TB, ticks []BinaryTick, blockSize int, options ...SourceOption) (*BlockSource, string) {
+	t.Helper()
+
+	name := filepath.Join(t.TempDir(), "ticks.blk")
+	idx := 0
+	require.NoError(t, writeBlocks(name, "EURUSD", 5, blockSize, func() (BinaryTick, bool, error) {
+		if idx == len(ticks) {
+			return BinaryTick{}, false, nil
+		}
+		idx++
+		return ticks[idx-1], true, nil
+	}))
+
+	source := NewBlockSource(name, options...)
+	require.NoError(t, source.Open())
+	t.Cleanup(source.Close)
+	return source, name
+}
+
+func assertSameTick(t *testing.T, expected, actual BinaryTick, idx int) {
+	t.Helper()
+	require.Equal(t, expected, actual, "tick %d", idx)
+	require.Equal(t, math.Signbit(expected.BidVolume), math.Signbit(actual.BidVolume), "tick %d bid volume sign", idx)
+	require.Equal(t, math.Signbit(expected.AskVolume), math.Signbit(actual.AskVolume), "tick %d ask volume sign", idx)
+}
+
+func TestBlocks_RoundTrip(t *testing.T) {
+	ticks := testBlockTicks(2*DefaultBlockSize + 100)
+
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "ticks.bin")
+	blocks := filepath.Join(dir, "ticks.blk")
+	back := filepath.Join(dir, "back.bin")
+
+	require.NoError(t, WriteFile(raw, "EURUSD", 5, ticks))
+	require.NoError(t, ConvertToBlocks(raw, blocks, "", -1))
+	require.NoError(t, ConvertFromBlocks(blocks, back))
+
+	blockSource := NewBlockSource(blocks)
+	require.NoError(t, blockSource.Open())
+	defer blockSource.Close()
+
+	header := blockSource.Header()
+	assert.Equal(t, RecordKindTickBlock, header.Kind)
+	assert.Equal(t, "EURUSD", header.Symbol)
+	assert.Equal(t, 5, header.Digits)
+	assert.Equal(t, int64(len(ticks)), header.Count)
+
+	rawInfo, err := os.Stat(raw)
+	require.NoError(t, err)
+	blockInfo, err := os.Stat(blocks)
+	require.NoError(t, err)
+	assert.Less(t, blockInfo.Size(), rawInfo.Size()/2)
+
+	source := NewSource[BinaryTick](back)
+	require.NoError(t, source.Open())
+	defer source.Close()
+
+	rawHeader, ok := source.Header()
+	require.True(t, ok)
+	original := NewHeader("EURUSD", 5, ticks)
+	assert.Equal(t, original, rawHeader, "the converted file is identical to the original")
+
+	for idx, tick := range ticks {
+		var fromBlocks, fromRaw BinaryTick
+		require.NoError(t, blockSource.Read(int64(idx), &fromBlocks))
+		require.NoError(t, source.Read(int64(idx), &fromRaw))
+		assertSameTick(t, tick, fromBlocks, idx)
+		assertSameTick(t, tick, fromRaw, idx)
+	}
+
+	var tick BinaryTick
+	assert.ErrorIs(t, blockSource.Read(int64(len(ticks)), &tick), ErrEof)
+	assert.ErrorIs(t, blockSource.Read(-1, &tick), ErrEof)
+}
+
+func TestBlocks_Volumes(t *testing.T) {
+	for _, volume := range append(blockTestVolumes, 0.1, 123456789.123, -0.001, math.SmallestNonzeroFloat64, math.Inf(1)) {
+		b := appendVolume(nil, volume)
+		decoded, rest, ok := readVolume(b)
+		require.True(t, ok, "%v", volume)
+		assert.Empty(t, rest)
+		assert.Equal(t, math.Float64bits(volume), math.Float64bits(decoded), "%v", volume)
+	}
+
+	nan, _, ok := readVolume(appendVolume(nil, math.NaN()))
+	require.True(t, ok)
+	assert.True(t, math.IsNaN(nan))
+
+	_, _, ok = readVolume(appendVolume(nil, 1e-12)[:3])
+	assert.False(t, ok, "truncated raw volume")
+}
+
+func TestBlockSource_Search(t *testing.T) {
+	// 60 is repeated across the boundary of the second and the third block
+	timestamps := []int64{10, 20, 30, 40, 50, 60, 60, 60, 60, 70, 80, 90, 100}
+	ticks := make([]BinaryTick, 0, len(timestamps))
+	for _, ts := range timestamps {
+		ticks = append(ticks, BinaryTick{TimeStamp: ts, Bid: 1.1, Ask: 1.1})
+	}
+	source, _ := writeTestBlocks(t, ticks, 4)
+
+	tests := []struct {
+		name string
+		ts   int64
+		want int64
+	}{
+		{name: "before the first tick", ts: 5, want: 0},
+		{name: "first tick", ts: 10, want: 0},
+		{name: "within a block", ts: 25, want: 2},
+		{name: "between blocks", ts: 45, want: 4},
+		{name: "first timestamp of a block", ts: 50, want: 4},
+		{name: "duplicates across a block boundary", ts: 60, want: 5},
+		{name: "after the duplicates", ts: 65, want: 9},
+		{name: "last tick", ts: 100, want: 12},
+		{name: "after the last tick", ts: 101, want: 13},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idx, err := source.Search(tt.ts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, idx)
+		})
+	}
+
+	t.Run("matches the raw search", func(t *testing.T) {
+		raw := writeTestSource(t, "EURUSD", ticks)
+		for ts := int64(0); ts <= 110; ts++ {
+			want, err := searchIndex(raw, ts)
+			require.NoError(t, err)
+			got, err := source.Search(ts)
+			require.NoError(t, err)
+			require.Equal(t, want, got, "ts %d", ts)
+		}
+	})
+
+	t.Run("reader starts at the first duplicate", func(t *testing.T) {
+		reader := NewBlockTickReader(source, "EURUSD", time.Unix(0, 60), time.Unix(0, 70))
+		var read []int64
+		for {
+			tick, err := reader.GetNext()
+			if errors.Is(err, ErrEof) {
+				break
+			}
+			require.NoError(t, err)
+			read = append(read, tick.TimeStamp.UnixNano())
+		}
+		assert.Equal(t, []int64{60, 60, 60, 60, 70}, read)
+	})
+}
+
+func TestBlockSource_Corrupt(t *testing.T) {
+	ticks := testBlockTicks(10)
+
+	header := func(t *testing.T, b []byte) Header {
+		var h Header
+		require.NoError(t, h.UnmarshalBinary(b))
+		return h
+	}
+
+	tests := []struct {
+		name     string
+		corrupt  func(t *testing.T, b []byte) []byte
+		checksum bool
+		read     bool
+		wantMsg  string
+	}{
+		{
+			name:    "truncated file",
+			corrupt: func(_ *testing.T, b []byte) []byte { return b[:len(b)-1] },
+			wantMsg: "header announces",
+		},
+		{
+			name:    "truncated header",
+			corrupt: func(_ *testing.T, b []byte) []byte { return b[:HeaderSize-1] },
+			wantMsg: "truncated header",
+		},
+		{
+			name: "checksum mismatch",
+			corrupt: func(_ *testing.T, b []byte) []byte {
+				b[HeaderSize] ^= 0xff
+				return b
+			},
+			checksum: true,
+			wantMsg:  "checksum mismatch",
+		},
+		{
+			name: "bad index offset",
+			corrupt: func(t *testing.T, b []byte) []byte {
+				h := header(t, b)
+				binary.LittleEndian.PutUint64(b[h.IndexOffset+blockIndexEntrySize+8:], uint64(h.IndexOffset+1))
+				return b
+			},
+			wantMsg: "invalid offset of block 1",
+		},
+		{
+			name: "index offset before the header",
+			corrupt: func(t *testing.T, b []byte) []byte {
+				h := header(t, b)
+				binary.LittleEndian.PutUint64(b[h.IndexOffset+8:], 0)
+				return b
+			},
+			wantMsg: "invalid offset of block 0",
+		},
+		{
+			name: "undecodable block",
+			corrupt: func(t *testing.T, b []byte) []byte {
+				h := header(t, b)
+				end := binary.LittleEndian.Uint64(b[h.IndexOffset+blockIndexEntrySize+8:])
+				for i := HeaderSize; i < int(end); i++ {
+					b[i] = 0xff
+				}
+				return b
+			},
+			read:    true,
+			wantMsg: "block 0 ends after 0 of 4 ticks",
+		},
+		{
+			name: "trailing bytes in a block",
+			corrupt: func(t *testing.T, b []byte) []byte {
+				h := header(t, b)
+				next := b[h.IndexOffset+blockIndexEntrySize+8:]
+				binary.LittleEndian.PutUint64(next, binary.LittleEndian.Uint64(next)+1)
+				return b
+			},
+			read:    true,
+			wantMsg: "trailing bytes in block 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, name := writeTestBlocks(t, ticks, 4)
+			b, err := os.ReadFile(name)
+			require.NoError(t, err)
+
+			corrupt := filepath.Join(t.TempDir(), "corrupt.blk")
+			require.NoError(t, os.WriteFile(corrupt, tt.corrupt(t, b), 0o644))
+
+			// the checksum would catch every corruption, skip it to reach the structural checks
+			var options []SourceOption
+			if !tt.checksum {
+				options = append(options, WithoutChecksum())
+			}
+			source := NewBlockSource(corrupt, options...)
+			err = source.Open()
+			if tt.read {
+				require.NoError(t, err)
+				defer source.Close()
+				var tick BinaryTick
+				err = source.Read(0, &tick)
+			}
+
+			require.Error(t, err)
+			assert.ErrorIs(t, err, ErrCorrupt)
+			assert.Contains(t, err.Error(), tt.wantMsg)
+		})
+	}
+
+	t.Run("raw file", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "ticks.bin")
+		require.NoError(t, WriteFile(name, "EURUSD", 5, ticks))
+		assert.ErrorIs(t, NewBlockSource(name).Open(), ErrMismatch)
+	})
+}
+
+func TestBlockEncoder_toInt(t *testing.T) {
+	tests := []struct {
+		name    string
+		digits  int
+		price   float64
+		want    int64
+		wantErr bool
+	}{
+		{name: "symbol digits", digits: 5, price: 1.10012, want: 110012},
+		{name: "fewer digits", digits: 5, price: 1.1, want: 110000},
+		{name: "negative", digits: 2, price: -1.25, want: -125},
+		{name: "no digits", digits: 0, price: 150, want: 150},
+		{name: "one digit too many", digits: 5, price: 1.100125, wantErr: true},
+		{name: "fraction without digits", digits: 0, price: 150.5, wantErr: true},
+		{name: "not a number", digits: 5, price: math.NaN(), wantErr: true},
+		{name: "out of range", digits: 5, price: 1e300, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder, err := newBlockEncoder(io.Discard, tt.digits, 4)
+			require.NoError(t, err)
+
+			value, err := encoder.toInt(tt.price)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, value)
+		})
+	}
+
+	t.Run("writing rejects the tick", func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), "ticks.blk")
+		err := WriteBlockFile(name, "EURUSD", 2, []BinaryTick{{TimeStamp: 1, Bid: 1.1, Ask: 1.123}})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid ask")
+		assert.NoFileExists(t, name)
+	})
+}
+
+func BenchmarkTickReader(b *testing.B) {
+	ticks := testBlockTicks(100_000)
+	// the readers convert to fixed point, which cannot hold the extreme volumes
+	for idx := range ticks {
+		ticks[idx].BidVolume = float64(idx%10+1) * 0.5
+		ticks[idx].AskVolume = float64(idx%4+1) * 0.25
+	}
+	from, to := time.Unix(0, ticks[0].TimeStamp), time.Unix(0, ticks[len(ticks)-1].TimeStamp)
+
+	readAll := func(b *testing.B, next func() error) {
+		for {
+			err := next()
+			if errors.Is(err, ErrEof) {
+				return
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	b.Run("raw", func(b *testing.B) {
+		source := writeTestSource(b, "EURUSD", ticks)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			reader := NewTickReader(source, "EURUSD", from, to)
+			readAll(b, func() error {
+				_, err := reader.GetNext()
+				return err
+			})
+		}
+	})
+
+	b.Run("blocks", func(b *testing.B) {
+		source, _ := writeTestBlocks(b, ticks, DefaultBlockSize)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			reader := NewBlockTickReader(source, "EURUSD", from, to)
+			readAll(b, func() error {
+				_, err := reader.GetNext()
+				return err
+			})
+		}
+	})
+}
@@ -7,6 +7,12 @@ import (
 	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
 )
 
+const BinaryDepthLevels = 10
+
+type timestamped interface {
+	unixNano() int64
+}
+
 type BinaryTick struct {
 	TimeStamp int64
 	Bid       float64
@@ -22,3 +28,67 @@ func (binaryTick BinaryTick) ToModelTick(tick *common.Tick) {
 	tick.AskVolume = fixed.FromFloat64(binaryTick.AskVolume)
 	tick.BidVolume = fixed.FromFloat64(binaryTick.BidVolume)
 }
+
+func (binaryTick BinaryTick) unixNano() int64 {
+	return binaryTick.TimeStamp
+}
+
+// BinaryDepth is an order book snapshot of up to BinaryDepthLevels levels per side, best level first.
+// Unused levels have a zero size.
+type BinaryDepth struct {
+	TimeStamp int64
+	BidPrices [BinaryDepthLevels]float64
+	BidSizes  [BinaryDepthLevels]float64
+	AskPrices [BinaryDepthLevels]float64
+	AskSizes  [BinaryDepthLevels]float64
+}
+
+func (binaryDepth BinaryDepth) ToModelOrderBook(book *common.OrderBook) {
+	book.TimeStamp = time.Unix(0, binaryDepth.TimeStamp)
+	book.Bids = book.Bids[:0]
+	book.Asks = book.Asks[:0]
+	for level := 0; level < BinaryDepthLevels; level++ {
+		if binaryDepth.BidSizes[level] > 0 {
+			book.Bids = append(book.Bids, common.BookLevel{
+				Price: fixed.FromFloat64(binaryDepth.BidPrices[level]),
+				Size:  fixed.FromFloat64(binaryDepth.BidSizes[level]),
+			})
+		}
+		if binaryDepth.AskSizes[level] > 0 {
+			book.Asks = append(book.Asks, common.BookLevel{
+				Price: fixed.FromFloat64(binaryDepth.AskPrices[level]),
+				Size:  fixed.FromFloat64(binaryDepth.AskSizes[level]),
+			})
+		}
+	}
+}
+
+func (binaryDepth BinaryDepth) unixNano() int64 {
+	return binaryDepth.TimeStamp
+}
+
+// BinaryBar is a bar of Period nanoseconds opening at OpenTime
+type BinaryBar struct {
+	OpenTime int64
+	Period   int64
+	Open     float64
+	High     float64
+	Low      float64
+	Close    float64
+	Volume   float64
+}
+
+func (binaryBar BinaryBar) ToModelBar(bar *common.Bar) {
+	bar.OpenTime = time.Unix(0, binaryBar.OpenTime)
+	bar.Period = common.BarPeriod(binaryBar.Period)
+	bar.Open = fixed.FromFloat64(binaryBar.Open)
+	bar.High = fixed.FromFloat64(binaryBar.High)
+	bar.Low = fixed.FromFloat64(binaryBar.Low)
+	bar.Close = fixed.FromFloat64(binaryBar.Close)
+	bar.Volume = fixed.FromFloat64(binaryBar.Volume)
+	bar.TimeStamp = bar.OpenTime.Add(bar.Period)
+}
+
+func (binaryBar BinaryBar) unixNano() int64 {
+	return binaryBar.OpenTime
+}
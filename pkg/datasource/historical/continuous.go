@@ -0,0 +1,514 @@
+package historical
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+const continuousReaderComponentName = "datasource.historical.continuous"
+
+type RollRule int
+type Adjustment int
+
+const (
+	// RollCalendar rolls a fixed offset before the expiry of the front contract
+	RollCalendar RollRule = iota
+	// RollVolume rolls the day after the next contract traded more volume than the front contract
+	RollVolume
+	// RollActivity rolls the day after the next contract printed more ticks than the front contract, a stand-in
+	// for open interest which tick data does not carry
+	RollActivity
+)
+
+const (
+	AdjustNone Adjustment = iota
+	// AdjustBackward shifts the prices of earlier contracts by the gap at each roll, the last contract is unadjusted
+	AdjustBackward
+	// AdjustRatio scales the prices of earlier contracts by the ratio at each roll, the last contract is unadjusted
+	AdjustRatio
+)
+
+// Contract is one futures contract of a continuous series, its sources must be open while the series is used.
+// Source serves the ticks and Bars the bars of the contract, one of them is enough. The rolls are derived from
+// the ticks, or from the bars for contracts without ticks. Bars count their volume for both RollVolume and
+// RollActivity, since the volume of bar exports is usually a tick count.
+type Contract struct {
+	Symbol string
+	Expiry time.Time
+	Source *Source[BinaryTick]
+	Bars   *Source[BinaryBar]
+}
+
+// Roll is the switch from one contract to the next, ticks from Time on are served from To. Gap and Ratio
+// compare the mid prices of both contracts just before the roll.
+type Roll struct {
+	Time  time.Time
+	From  string
+	To    string
+	Gap   fixed.Point
+	Ratio fixed.Point
+}
+
+type ContinuousOption func(*ContinuousSeries)
+
+func WithRollRule(rule RollRule) ContinuousOption {
+	return func(c *ContinuousSeries) {
+		c.rule = rule
+	}
+}
+
+// WithRollOffset sets how long before the expiry the calendar rule rolls, the other rules fall back to it when
+// the next contract never takes over
+func WithRollOffset(offset time.Duration) ContinuousOption {
+	return func(c *ContinuousSeries) {
+		c.offset = offset
+	}
+}
+
+func WithAdjustment(adjustment Adjustment) ContinuousOption {
+	return func(c *ContinuousSeries) {
+		c.adjustment = adjustment
+	}
+}
+
+// ContinuousSeries stitches the contracts of one root into a single series
+type ContinuousSeries struct {
+	contracts  []Contract
+	rule       RollRule
+	offset     time.Duration
+	adjustment Adjustment
+
+	rolls   []Roll
+	offsets []fixed.Point
+	factors []fixed.Point
+}
+
+func NewContinuousSeries(contracts []Contract, options ...ContinuousOption) (*ContinuousSeries, error) {
+	if len(contracts) == 0 {
+		return nil, fmt.Errorf("continuous series requires at least one contract")
+	}
+
+	for _, contract := range contracts {
+		if contract.Source == nil && contract.Bars == nil {
+			return nil, fmt.Errorf("contract %s has neither ticks nor bars", contract.Symbol)
+		}
+	}
+
+	c := &ContinuousSeries{
+		contracts: append([]Contract(nil), contracts...),
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	sort.SliceStable(c.contracts, func(i, j int) bool {
+		return c.contracts[i].Expiry.Before(c.contracts[j].Expiry)
+	})
+
+	if err := c.buildRolls(); err != nil {
+		return nil, err
+	}
+	c.buildAdjustments()
+	return c, nil
+}
+
+// Rolls returns the roll schedule of the series
+func (c *ContinuousSeries) Rolls() []Roll {
+	return c.rolls
+}
+
+// Symbol returns the contract the series is served from at t
+func (c *ContinuousSeries) Symbol(t time.Time) string {
+	return c.contracts[c.segment(t.UnixNano())].Symbol
+}
+
+// AdjustPrice applies the adjustment of the contract active at t to price
+func (c *ContinuousSeries) AdjustPrice(t time.Time, price fixed.Point) fixed.Point {
+	return c.adjust(c.segment(t.UnixNano()), price)
+}
+
+// AdjustBar applies the adjustment of the contract active at the bar's open time to its prices
+func (c *ContinuousSeries) AdjustBar(bar *common.Bar) {
+	segment := c.segment(bar.OpenTime.UnixNano())
+	bar.Open = c.adjust(segment, bar.Open)
+	bar.High = c.adjust(segment, bar.High)
+	bar.Low = c.adjust(segment, bar.Low)
+	bar.Close = c.adjust(segment, bar.Close)
+}
+
+func (c *ContinuousSeries) NewTickReader(symbol string, from, to time.Time) *ContinuousTickReader {
+	return &ContinuousTickReader{
+		series: c,
+		symbol: symbol,
+		from:   from.UnixNano(),
+		to:     to.UnixNano(),
+		idx:    invalidIndex,
+	}
+}
+
+func (c *ContinuousSeries) NewBarReader(symbol string, from, to time.Time) *ContinuousBarReader {
+	return &ContinuousBarReader{
+		series: c,
+		symbol: symbol,
+		from:   from.UnixNano(),
+		to:     to.UnixNano(),
+		idx:    invalidIndex,
+	}
+}
+
+func (c *ContinuousSeries) adjust(segment int, price fixed.Point) fixed.Point {
+	switch c.adjustment {
+	case AdjustBackward:
+		return price.Add(c.offsets[segment])
+	case AdjustRatio:
+		return price.Mul(c.factors[segment])
+	default:
+		return price
+	}
+}
+
+// segment returns the index of the contract the series is served from at ts
+func (c *ContinuousSeries) segment(ts int64) int {
+	return sort.Search(len(c.rolls), func(i int) bool {
+		return c.rolls[i].Time.UnixNano() > ts
+	})
+}
+
+// segmentRange returns the time range [start, end) the contract at segment is served in
+func (c *ContinuousSeries) segmentRange(segment int) (int64, int64) {
+	start, end := int64(math.MinInt64), int64(math.MaxInt64)
+	if segment > 0 {
+		start = c.rolls[segment-1].Time.UnixNano()
+	}
+	if segment < len(c.rolls) {
+		end = c.rolls[segment].Time.UnixNano()
+	}
+	return start, end
+}
+
+func (c *ContinuousSeries) buildRolls() error {
+	c.rolls = make([]Roll, 0, len(c.contracts)-1)
+
+	previous := time.Time{}
+	for idx := 0; idx+1 < len(c.contracts); idx++ {
+		front, next := c.contracts[idx], c.contracts[idx+1]
+
+		rollTime := front.Expiry.Add(-c.offset)
+		if c.rule == RollVolume || c.rule == RollActivity {
+			t, ok, err := c.takeoverTime(front, next, previous)
+			if err != nil {
+				return fmt.Errorf("unable to determine roll from %s to %s: %w", front.Symbol, next.Symbol, err)
+			}
+			if ok {
+				rollTime = t
+			}
+		}
+		if rollTime.Before(previous) {
+			rollTime = previous
+		}
+
+		frontPrice, err := priceBefore(front, rollTime.UnixNano(), false)
+		if err != nil {
+			return fmt.Errorf("unable to price %s at roll: %w", front.Symbol, err)
+		}
+		nextPrice, err := priceBefore(next, rollTime.UnixNano(), true)
+		if err != nil {
+			return fmt.Errorf("unable to price %s at roll: %w", next.Symbol, err)
+		}
+
+		roll := Roll{
+			Time:  rollTime,
+			From:  front.Symbol,
+			To:    next.Symbol,
+			Gap:   fixed.Zero,
+			Ratio: fixed.One,
+		}
+		if frontPrice != 0 && nextPrice != 0 {
+			roll.Gap = fixed.FromFloat64(nextPrice - frontPrice)
+			roll.Ratio = fixed.FromFloat64(nextPrice / frontPrice)
+		}
+		c.rolls = append(c.rolls, roll)
+		previous = rollTime
+	}
+	return nil
+}
+
+// buildAdjustments accumulates the roll gaps and ratios backwards, so that each contract lines up with the last one
+func (c *ContinuousSeries) buildAdjustments() {
+	c.offsets = make([]fixed.Point, len(c.contracts))
+	c.factors = make([]fixed.Point, len(c.contracts))
+
+	offset, factor := fixed.Zero, fixed.One
+	for segment := len(c.contracts) - 1; segment >= 0; segment-- {
+		if segment < len(c.rolls) {
+			offset = offset.Add(c.rolls[segment].Gap)
+			factor = factor.Mul(c.rolls[segment].Ratio)
+		}
+		c.offsets[segment] = offset
+		c.factors[segment] = factor
+	}
+}
+
+// takeoverTime returns the start of the day after the first day since the previous roll on which the next
+// contract was more active than the front contract
+func (c *ContinuousSeries) takeoverTime(front, next Contract, previous time.Time) (time.Time, bool, error) {
+	from := previous.UnixNano()
+	if previous.IsZero() {
+		from = math.MinInt64
+	}
+	to := front.Expiry.UnixNano()
+
+	frontDays, err := c.dailyActivity(front, from, to)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	nextDays, err := c.dailyActivity(next, from, to)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	days := make([]int64, 0, len(nextDays))
+	for day := range nextDays {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i] < days[j] })
+
+	for _, day := range days {
+		if nextDays[day] > frontDays[day] {
+			t := time.Unix(0, (day+1)*int64(24*time.Hour)).UTC()
+			if t.Before(front.Expiry) {
+				return t, true, nil
+			}
+			break
+		}
+	}
+	return time.Time{}, false, nil
+}
+
+// dailyActivity sums the volume or counts the ticks of the contract for each UTC day in [from, to)
+func (c *ContinuousSeries) dailyActivity(contract Contract, from, to int64) (map[int64]float64, error) {
+	if contract.Source == nil {
+		return dailySum(contract.Bars, from, to, func(bar BinaryBar) float64 { return bar.Volume })
+	}
+	return dailySum(contract.Source, from, to, func(tick BinaryTick) float64 {
+		if c.rule == RollVolume {
+			return tick.BidVolume + tick.AskVolume
+		}
+		return 1
+	})
+}
+
+// dailySum sums value over the entries of each UTC day in [from, to)
+func dailySum[T timestamped](source *Source[T], from, to int64, value func(T) float64) (map[int64]float64, error) {
+	days := make(map[int64]float64)
+
+	idx, err := searchIndex(source, from)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry T
+	for ; ; idx++ {
+		if err := source.Read(idx, &entry); err != nil {
+			if errors.Is(err, ErrEof) {
+				break
+			}
+			return nil, fmt.Errorf("error reading entry at index %d: %w", idx, err)
+		}
+		if entry.unixNano() >= to {
+			break
+		}
+		days[entry.unixNano()/int64(24*time.Hour)] += value(entry)
+	}
+	return days, nil
+}
+
+// priceBefore returns the mid price of the last tick, or the close of the last bar, of the contract before ts.
+// It optionally falls back to the first one after.
+func priceBefore(contract Contract, ts int64, orAfter bool) (float64, error) {
+	if contract.Source == nil {
+		bar, ok, err := entryBefore(contract.Bars, ts, orAfter)
+		if !ok || err != nil {
+			return 0, err
+		}
+		return bar.Close, nil
+	}
+	tick, ok, err := entryBefore(contract.Source, ts, orAfter)
+	if !ok || err != nil {
+		return 0, err
+	}
+	return (tick.Bid + tick.Ask) / 2, nil
+}
+
+func entryBefore[T timestamped](source *Source[T], ts int64, orAfter bool) (T, bool, error) {
+	var entry T
+
+	idx, err := searchIndex(source, ts)
+	if err != nil {
+		return entry, false, err
+	}
+
+	switch {
+	case idx > 0:
+		idx--
+	case !orAfter:
+		return entry, false, nil
+	}
+
+	if err := source.Read(idx, &entry); err != nil {
+		if errors.Is(err, ErrEof) {
+			return entry, false, nil
+		}
+		return entry, false, fmt.Errorf("error reading entry at index %d: %w", idx, err)
+	}
+	return entry, true, nil
+}
+
+// ContinuousTickReader reads the ticks of a continuous series, switching contracts at the rolls
+type ContinuousTickReader struct {
+	series *ContinuousSeries
+
+	symbol  string
+	from    int64
+	to      int64
+	segment int
+	idx     int64
+
+	ids *utility.IDGenerator
+}
+
+// SetIDGenerator stamps the ticks with the ids of the run, see datasource.IDStamper
+func (r *ContinuousTickReader) SetIDGenerator(ids *utility.IDGenerator) {
+	r.ids = ids
+}
+
+func (r *ContinuousTickReader) GetNext() (common.Tick, error) {
+
+	var tick common.Tick
+	var binTick BinaryTick
+
+	for r.segment < len(r.series.contracts) {
+		source := r.series.contracts[r.segment].Source
+		start, end := r.series.segmentRange(r.segment)
+		if source == nil {
+			return tick, fmt.Errorf("contract %s has no ticks", r.series.contracts[r.segment].Symbol)
+		}
+
+		if r.idx == invalidIndex {
+			if err := checkSymbol(source, r.series.contracts[r.segment].Symbol); err != nil {
+				return tick, err
+			}
+			idx, err := searchIndex(source, max(start, r.from))
+			if err != nil {
+				return tick, fmt.Errorf("error looking up %s: %w", r.series.contracts[r.segment].Symbol, err)
+			}
+			r.idx = idx
+		}
+
+		err := source.Read(r.idx, &binTick)
+		if errors.Is(err, ErrEof) || (err == nil && binTick.TimeStamp >= end) {
+			r.segment++
+			r.idx = invalidIndex
+			continue
+		}
+		if err != nil {
+			return tick, fmt.Errorf("error reading entry at index %d: %w", r.idx, err)
+		}
+		r.idx++
+
+		if binTick.TimeStamp > r.to {
+			return tick, ErrEof
+		}
+
+		binTick.ToModelTick(&tick)
+		tick.Bid = r.series.adjust(r.segment, tick.Bid)
+		tick.Ask = r.series.adjust(r.segment, tick.Ask)
+
+		tick.Source = continuousReaderComponentName
+		tick.Symbol = r.symbol
+		tick.ExecutionId = r.ids.ExecutionID()
+		tick.TraceID = r.ids.TraceID()
+
+		return tick, nil
+	}
+
+	return tick, ErrEof
+}
+
+// ContinuousBarReader reads the bars of a continuous series, switching contracts at the rolls. A bar belongs to
+// the contract the series is served from at its open time.
+type ContinuousBarReader struct {
+	series *ContinuousSeries
+
+	symbol  string
+	from    int64
+	to      int64
+	segment int
+	idx     int64
+
+	ids *utility.IDGenerator
+}
+
+// SetIDGenerator stamps the bars with the ids of the run, see datasource.IDStamper
+func (r *ContinuousBarReader) SetIDGenerator(ids *utility.IDGenerator) {
+	r.ids = ids
+}
+
+func (r *ContinuousBarReader) GetNext() (common.Bar, error) {
+
+	var bar common.Bar
+	var binBar BinaryBar
+
+	for r.segment < len(r.series.contracts) {
+		source := r.series.contracts[r.segment].Bars
+		start, end := r.series.segmentRange(r.segment)
+		if source == nil {
+			return bar, fmt.Errorf("contract %s has no bars", r.series.contracts[r.segment].Symbol)
+		}
+
+		if r.idx == invalidIndex {
+			if err := checkSymbol(source, r.series.contracts[r.segment].Symbol); err != nil {
+				return bar, err
+			}
+			idx, err := searchIndex(source, max(start, r.from))
+			if err != nil {
+				return bar, fmt.Errorf("error looking up %s: %w", r.series.contracts[r.segment].Symbol, err)
+			}
+			r.idx = idx
+		}
+
+		err := source.Read(r.idx, &binBar)
+		if errors.Is(err, ErrEof) || (err == nil && binBar.OpenTime >= end) {
+			r.segment++
+			r.idx = invalidIndex
+			continue
+		}
+		if err != nil {
+			return bar, fmt.Errorf("error reading entry at index %d: %w", r.idx, err)
+		}
+		r.idx++
+
+		if binBar.OpenTime > r.to {
+			return bar, ErrEof
+		}
+
+		binBar.ToModelBar(&bar)
+		r.series.AdjustBar(&bar)
+
+		bar.Source = continuousReaderComponentName
+		bar.Symbol = r.symbol
+		bar.ExecutionId = r.ids.ExecutionID()
+		bar.TraceID = r.ids.TraceID()
+
+		return bar, nil
+	}
+
+	return bar, ErrEof
+}
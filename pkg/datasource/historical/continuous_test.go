@@ -0,0 +1,304 @@
+package historical
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+func day(d int) time.Time {
+	return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+}
+
+// contractTicks quotes mid from Jan 1 up to lastDay, activity returns the tick count and the volume per side of
+// each day
+func contractTicks(mid float64, lastDay int, activity func(d int) (int, float64)) []BinaryTick {
+	var ticks []BinaryTick
+	for d := 1; d <= lastDay; d++ {
+		count, volume := activity(d)
+		for i := 0; i < count; i++ {
+			ticks = append(ticks, BinaryTick{
+				TimeStamp: day(d).Add(12*time.Hour + time.Duration(i)*time.Minute).UnixNano(),
+				Bid:       mid - 0.5,
+				Ask:       mid + 0.5,
+				BidVolume: volume,
+				AskVolume: volume,
+			})
+		}
+	}
+	return ticks
+}
+
+// dailyBars aggregates ticks into daily bars of their mid prices with the volume of both sides
+func dailyBars(ticks []BinaryTick) []BinaryBar {
+	var bars []BinaryBar
+	for _, tick := range ticks {
+		openTime := tick.TimeStamp - tick.TimeStamp%int64(24*time.Hour)
+		mid := (tick.Bid + tick.Ask) / 2
+		if len(bars) == 0 || bars[len(bars)-1].OpenTime != openTime {
+			bars = append(bars, BinaryBar{OpenTime: openTime, Period: int64(24 * time.Hour), Open: mid, High: mid, Low: mid})
+		}
+		bar := &bars[len(bars)-1]
+		bar.High = max(bar.High, mid)
+		bar.Low = min(bar.Low, mid)
+		bar.Close = mid
+		bar.Volume += tick.BidVolume + tick.AskVolume
+	}
+	return bars
+}
+
+// The next contract prints more ticks two days before it trades more volume, so each rule rolls on a different
+// day. A quotes 100, B 110 and C 121, a gap of 10 and 11 and a ratio of 1.1 at each roll.
+func testContractTicks() map[string][]BinaryTick {
+	return map[string][]BinaryTick{
+		"A": contractTicks(100, 9, func(int) (int, float64) { return 2, 5 }),
+		"B": contractTicks(110, 19, func(d int) (int, float64) {
+			switch {
+			case d <= 3:
+				return 1, 1
+			case d <= 5:
+				return 3, 1
+			default:
+				return 3, 10
+			}
+		}),
+		"C": contractTicks(121, 29, func(d int) (int, float64) {
+			switch {
+			case d <= 13:
+				return 1, 1
+			case d <= 15:
+				return 4, 1
+			default:
+				return 4, 20
+			}
+		}),
+	}
+}
+
+func testContracts(t *testing.T, ticks, bars bool) []Contract {
+	expiries := map[string]time.Time{"A": day(10), "B": day(20), "C": day(30)}
+
+	var contracts []Contract
+	// out of expiry order on purpose, the series sorts them
+	for _, symbol := range []string{"C", "A", "B"} {
+		contractTicks := testContractTicks()[symbol]
+		contract := Contract{Symbol: symbol, Expiry: expiries[symbol]}
+		if ticks {
+			contract.Source = writeTestSource(t, symbol, contractTicks)
+		}
+		if bars {
+			contract.Bars = writeTestSource(t, symbol, dailyBars(contractTicks))
+		}
+		contracts = append(contracts, contract)
+	}
+	return contracts
+}
+
+func assertPrice(t *testing.T, expected float64, price fixed.Point) {
+	t.Helper()
+	value, _ := price.Float64()
+	assert.InDelta(t, expected, value, 1e-6)
+}
+
+func TestContinuousSeries_Rolls(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []ContinuousOption
+		ticks   bool
+		bars    bool
+		want    []time.Time
+	}{
+		{
+			name:    "calendar",
+			options: []ContinuousOption{WithRollRule(RollCalendar), WithRollOffset(48 * time.Hour)},
+			ticks:   true,
+			want:    []time.Time{day(8), day(18)},
+		},
+		{
+			name:    "volume",
+			options: []ContinuousOption{WithRollRule(RollVolume)},
+			ticks:   true,
+			want:    []time.Time{day(7), day(17)},
+		},
+		{
+			name:    "activity",
+			options: []ContinuousOption{WithRollRule(RollActivity)},
+			ticks:   true,
+			want:    []time.Time{day(5), day(15)},
+		},
+		{
+			name:    "activity prefers ticks over bars",
+			options: []ContinuousOption{WithRollRule(RollActivity)},
+			ticks:   true,
+			bars:    true,
+			want:    []time.Time{day(5), day(15)},
+		},
+		{
+			name:    "volume from bars",
+			options: []ContinuousOption{WithRollRule(RollVolume)},
+			bars:    true,
+			want:    []time.Time{day(7), day(17)},
+		},
+		{
+			name:    "activity from bars counts their volume",
+			options: []ContinuousOption{WithRollRule(RollActivity)},
+			bars:    true,
+			want:    []time.Time{day(7), day(17)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series, err := NewContinuousSeries(testContracts(t, tt.ticks, tt.bars), tt.options...)
+			require.NoError(t, err)
+
+			rolls := series.Rolls()
+			require.Len(t, rolls, len(tt.want))
+			for idx, roll := range rolls {
+				assert.Equal(t, tt.want[idx], roll.Time, "roll %d", idx)
+			}
+
+			assert.Equal(t, "A", rolls[0].From)
+			assert.Equal(t, "B", rolls[0].To)
+			assert.Equal(t, "B", rolls[1].From)
+			assert.Equal(t, "C", rolls[1].To)
+			assertPrice(t, 10, rolls[0].Gap)
+			assertPrice(t, 11, rolls[1].Gap)
+			assertPrice(t, 1.1, rolls[0].Ratio)
+			assertPrice(t, 1.1, rolls[1].Ratio)
+
+			assert.Equal(t, "A", series.Symbol(tt.want[0].Add(-time.Nanosecond)))
+			assert.Equal(t, "B", series.Symbol(tt.want[0]))
+			assert.Equal(t, "C", series.Symbol(tt.want[1]))
+		})
+	}
+}
+
+func TestContinuousSeries_RequiresData(t *testing.T) {
+	_, err := NewContinuousSeries(nil)
+	assert.Error(t, err)
+
+	_, err = NewContinuousSeries([]Contract{{Symbol: "A", Expiry: day(10)}})
+	assert.Error(t, err)
+}
+
+func TestContinuousTickReader_GetNext(t *testing.T) {
+	tests := []struct {
+		name       string
+		adjustment Adjustment
+		mids       map[string]float64
+	}{
+		{name: "unadjusted", adjustment: AdjustNone, mids: map[string]float64{"A": 100, "B": 110, "C": 121}},
+		{name: "backward", adjustment: AdjustBackward, mids: map[string]float64{"A": 121, "B": 121, "C": 121}},
+		{name: "ratio", adjustment: AdjustRatio, mids: map[string]float64{"A": 121, "B": 121, "C": 121}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series, err := NewContinuousSeries(testContracts(t, true, false),
+				WithRollRule(RollVolume), WithAdjustment(tt.adjustment))
+			require.NoError(t, err)
+
+			reader := series.NewTickReader("ES", day(1), day(31))
+
+			counts := make(map[string]int)
+			var last time.Time
+			for {
+				tick, err := reader.GetNext()
+				if errors.Is(err, ErrEof) {
+					break
+				}
+				require.NoError(t, err)
+
+				require.True(t, tick.TimeStamp.After(last))
+				last = tick.TimeStamp
+
+				contract := series.Symbol(tick.TimeStamp)
+				counts[contract]++
+				assert.Equal(t, "ES", tick.Symbol)
+				assertPrice(t, tt.mids[contract], tick.Bid.Add(tick.Ask).DivInt(2))
+			}
+
+			// A until the end of Jan 6, B until the end of Jan 16, C afterward
+			assert.Equal(t, map[string]int{"A": 12, "B": 30, "C": 52}, counts)
+		})
+	}
+}
+
+func TestContinuousTickReader_Range(t *testing.T) {
+	series, err := NewContinuousSeries(testContracts(t, true, false), WithRollRule(RollVolume))
+	require.NoError(t, err)
+
+	reader := series.NewTickReader("ES", day(16), day(17).Add(12*time.Hour))
+
+	var symbols []string
+	for {
+		tick, err := reader.GetNext()
+		if errors.Is(err, ErrEof) {
+			break
+		}
+		require.NoError(t, err)
+		symbols = append(symbols, series.Symbol(tick.TimeStamp))
+	}
+	assert.Equal(t, []string{"B", "B", "B", "C"}, symbols)
+}
+
+func TestContinuousBarReader_GetNext(t *testing.T) {
+	tests := []struct {
+		name       string
+		adjustment Adjustment
+		closes     map[string]float64
+	}{
+		{name: "unadjusted", adjustment: AdjustNone, closes: map[string]float64{"A": 100, "B": 110, "C": 121}},
+		{name: "backward", adjustment: AdjustBackward, closes: map[string]float64{"A": 121, "B": 121, "C": 121}},
+		{name: "ratio", adjustment: AdjustRatio, closes: map[string]float64{"A": 121, "B": 121, "C": 121}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			series, err := NewContinuousSeries(testContracts(t, true, true),
+				WithRollRule(RollActivity), WithAdjustment(tt.adjustment))
+			require.NoError(t, err)
+
+			reader := series.NewBarReader("ES", day(1), day(31))
+
+			counts := make(map[string]int)
+			var last time.Time
+			for {
+				bar, err := reader.GetNext()
+				if errors.Is(err, ErrEof) {
+					break
+				}
+				require.NoError(t, err)
+
+				require.True(t, bar.OpenTime.After(last))
+				last = bar.OpenTime
+
+				contract := series.Symbol(bar.OpenTime)
+				counts[contract]++
+				assert.Equal(t, "ES", bar.Symbol)
+				assertPrice(t, tt.closes[contract], bar.Open)
+				assertPrice(t, tt.closes[contract], bar.High)
+				assertPrice(t, tt.closes[contract], bar.Low)
+				assertPrice(t, tt.closes[contract], bar.Close)
+			}
+
+			// the ticks roll on Jan 5 and Jan 15
+			assert.Equal(t, map[string]int{"A": 4, "B": 10, "C": 15}, counts)
+		})
+	}
+}
+
+func TestContinuousBarReader_WithoutBars(t *testing.T) {
+	series, err := NewContinuousSeries(testContracts(t, true, false))
+	require.NoError(t, err)
+
+	_, err = series.NewBarReader("ES", day(1), day(31)).GetNext()
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrEof)
+}
@@ -0,0 +1,94 @@
+package historical
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"unsafe"
+)
+
+// ConvertToBlocks converts a raw tick file into a block compressed one. The symbol and the digits are taken
+// from the header of src unless given, an empty symbol and negative digits are not given. Files without a header
+// require the digits.
+func ConvertToBlocks(src, dst, symbol string, digits int) error {
+	source := NewSource[BinaryTick](src)
+	if err := source.Open(); err != nil {
+		return err
+	}
+	defer source.Close()
+
+	if header, ok := source.Header(); ok {
+		if symbol == "" {
+			symbol = header.Symbol
+		}
+		if digits < 0 {
+			digits = header.Digits
+		}
+	}
+	if digits < 0 {
+		return fmt.Errorf("%q has no header, the digits are required", src)
+	}
+
+	idx := int64(0)
+	return writeBlocks(dst, symbol, digits, DefaultBlockSize, func() (BinaryTick, bool, error) {
+		var tick BinaryTick
+		if err := source.Read(idx, &tick); err != nil {
+			if errors.Is(err, ErrEof) {
+				return tick, false, nil
+			}
+			return tick, false, fmt.Errorf("error reading entry at index %d: %w", idx, err)
+		}
+		idx++
+		return tick, true, nil
+	})
+}
+
+// ConvertFromBlocks converts a block compressed tick file into a raw one with a header
+func ConvertFromBlocks(src, dst string) error {
+	source := NewBlockSource(src)
+	if err := source.Open(); err != nil {
+		return err
+	}
+	defer source.Close()
+
+	header := source.Header()
+	header.Kind = RecordKindTick
+	header.BlockSize = 0
+	header.IndexOffset = 0
+
+	return writeFile(dst, func(f *os.File) error {
+		if _, err := f.Write(make([]byte, HeaderSize)); err != nil {
+			return fmt.Errorf("unable to write header: %w", err)
+		}
+
+		crc := crc32.New(crcTable)
+		w := io.MultiWriter(f, crc)
+
+		var tick BinaryTick
+		buffer := make([]byte, 0, int(source.header.BlockSize)*int(unsafe.Sizeof(tick)))
+		for idx := int64(0); idx < header.Count; idx++ {
+			if err := source.Read(idx, &tick); err != nil {
+				return fmt.Errorf("error reading entry at index %d: %w", idx, err)
+			}
+			buffer = append(buffer, unsafe.Slice((*byte)(unsafe.Pointer(&tick)), unsafe.Sizeof(tick))...) // #nosec G103
+			if len(buffer) == cap(buffer) || idx+1 == header.Count {
+				if _, err := w.Write(buffer); err != nil {
+					return fmt.Errorf("unable to write entries: %w", err)
+				}
+				buffer = buffer[:0]
+			}
+		}
+
+		header.CRC = crc.Sum32()
+		b, err := header.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if _, err := f.WriteAt(b, 0); err != nil {
+			return fmt.Errorf("unable to write header: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,302 @@
+package historical
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NoColumn marks a column the csv format does not have
+const NoColumn = -1
+
+const (
+	TimeLayoutUnix      = "unix"
+	TimeLayoutUnixMilli = "unixms"
+	TimeLayoutUnixMicro = "unixus"
+	TimeLayoutUnixNano  = "unixns"
+)
+
+// CSVFormat describes the columns of a tick csv export, column indexes are zero based. Prices and volumes are
+// multiplied by their scale when it is set, e.g. to convert points or lots.
+type CSVFormat struct {
+	Comma  rune
+	Header bool
+
+	TimeColumn      int
+	BidColumn       int
+	AskColumn       int
+	BidVolumeColumn int
+	AskVolumeColumn int
+
+	// TimeLayout is a time.Parse layout or one of the TimeLayoutUnix* epoch layouts. ParseTime replaces it
+	// for layouts time.Parse cannot express.
+	TimeLayout string
+	ParseTime  func(value string, location *time.Location) (time.Time, error)
+	// Location is the time zone of the timestamps without a zone, UTC when nil
+	Location *time.Location
+
+	PriceScale  float64
+	VolumeScale float64
+}
+
+var (
+	// DukascopyCSV reads the JForex historical data export, "Gmt time,Ask,Bid,AskVolume,BidVolume"
+	DukascopyCSV = CSVFormat{
+		Comma:           ',',
+		Header:          true,
+		TimeColumn:      0,
+		AskColumn:       1,
+		BidColumn:       2,
+		AskVolumeColumn: 3,
+		BidVolumeColumn: 4,
+		TimeLayout:      "02.01.2006 15:04:05.000",
+	}
+
+	// HistDataCSV reads the HistData.com tick export, "20200101 170000123,bid,ask,volume" in EST without
+	// daylight saving. Its volume column is always zero and is skipped.
+	HistDataCSV = CSVFormat{
+		Comma:           ',',
+		TimeColumn:      0,
+		BidColumn:       1,
+		AskColumn:       2,
+		BidVolumeColumn: NoColumn,
+		AskVolumeColumn: NoColumn,
+		ParseTime:       parseHistDataTime,
+		Location:        time.FixedZone("EST", -5*60*60),
+	}
+
+	// TrueFXCSV reads the TrueFX tick export, "EUR/USD,20200101 22:00:00.123,bid,ask" in UTC
+	TrueFXCSV = CSVFormat{
+		Comma:           ',',
+		TimeColumn:      1,
+		BidColumn:       2,
+		AskColumn:       3,
+		BidVolumeColumn: NoColumn,
+		AskVolumeColumn: NoColumn,
+		TimeLayout:      "20060102 15:04:05.000",
+	}
+)
+
+// CSVFormatByName returns the preset format of a data vendor
+func CSVFormatByName(name string) (CSVFormat, error) {
+	switch strings.ToLower(name) {
+	case "dukascopy":
+		return DukascopyCSV, nil
+	case "histdata":
+		return HistDataCSV, nil
+	case "truefx":
+		return TrueFXCSV, nil
+	default:
+		return CSVFormat{}, fmt.Errorf("unknown csv format %q", name)
+	}
+}
+
+// ReadCSVTicks parses the ticks of a csv export in file order
+func ReadCSVTicks(r io.Reader, format CSVFormat) ([]BinaryTick, error) {
+	reader := newCSVReader(r, format.Comma)
+
+	var ticks []BinaryTick
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return ticks, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read csv: %w", err)
+		}
+		if (line == 1 && format.Header) || isBlank(record) {
+			continue
+		}
+
+		tick, err := format.parseRecord(record)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		ticks = append(ticks, tick)
+	}
+}
+
+// ReadCSVTickFile parses the ticks of a csv export file in file order
+func ReadCSVTickFile(name string, format CSVFormat) ([]BinaryTick, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %q: %w", name, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	ticks, err := ReadCSVTicks(f, format)
+	if err != nil {
+		return nil, fmt.Errorf("unable to import %q: %w", name, err)
+	}
+	return ticks, nil
+}
+
+// SortTicks orders ticks by timestamp and keeps only the last tick of each timestamp, so that a later export
+// overrides an earlier one. It returns the shortened slice.
+func SortTicks(ticks []BinaryTick) []BinaryTick {
+	return sortEntries(ticks)
+}
+
+// ImportCSV converts csv exports of symbol into a tick file NewTickReader can read, the ticks of all exports are
+// sorted and deduplicated by timestamp. It returns the number of ticks written.
+func ImportCSV(dst, symbol string, digits int, format CSVFormat, sources ...string) (int, error) {
+	var ticks []BinaryTick
+	for _, source := range sources {
+		fileTicks, err := ReadCSVTickFile(source, format)
+		if err != nil {
+			return 0, err
+		}
+		ticks = append(ticks, fileTicks...)
+	}
+
+	ticks = SortTicks(ticks)
+	if err := WriteFile(dst, symbol, digits, ticks); err != nil {
+		return 0, err
+	}
+	return len(ticks), nil
+}
+
+func (f CSVFormat) parseRecord(record []string) (BinaryTick, error) {
+	var tick BinaryTick
+
+	value, err := column(record, f.TimeColumn, "time")
+	if err != nil {
+		return tick, err
+	}
+	ts, err := parseTime(value, f.TimeLayout, f.ParseTime, f.Location)
+	if err != nil {
+		return tick, fmt.Errorf("invalid time %q: %w", value, err)
+	}
+	tick.TimeStamp = ts.UnixNano()
+
+	if tick.Bid, err = parseFloat(record, f.BidColumn, "bid", f.PriceScale); err != nil {
+		return tick, err
+	}
+	if tick.Ask, err = parseFloat(record, f.AskColumn, "ask", f.PriceScale); err != nil {
+		return tick, err
+	}
+	if tick.BidVolume, err = parseFloat(record, f.BidVolumeColumn, "bid volume", f.VolumeScale); err != nil {
+		return tick, err
+	}
+	if tick.AskVolume, err = parseFloat(record, f.AskVolumeColumn, "ask volume", f.VolumeScale); err != nil {
+		return tick, err
+	}
+	return tick, nil
+}
+
+func newCSVReader(r io.Reader, comma rune) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	if reader.Comma == 0 {
+		reader.Comma = ','
+	}
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	reader.TrimLeadingSpace = true
+	return reader
+}
+
+func isBlank(record []string) bool {
+	return len(record) == 1 && strings.TrimSpace(record[0]) == ""
+}
+
+// parseTime parses value with parse when set, with layout otherwise
+func parseTime(value, layout string, parse func(string, *time.Location) (time.Time, error), location *time.Location) (time.Time, error) {
+	if location == nil {
+		location = time.UTC
+	}
+	if parse != nil {
+		return parse(value, location)
+	}
+
+	var unit time.Duration
+	switch layout {
+	case TimeLayoutUnix:
+		unit = time.Second
+	case TimeLayoutUnixMilli:
+		unit = time.Millisecond
+	case TimeLayoutUnixMicro:
+		unit = time.Microsecond
+	case TimeLayoutUnixNano:
+		unit = time.Nanosecond
+	default:
+		return time.ParseInLocation(layout, value, location)
+	}
+
+	epoch, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, epoch*int64(unit)), nil
+}
+
+func parseFloat(record []string, idx int, name string, scale float64) (float64, error) {
+	if idx == NoColumn {
+		return 0, nil
+	}
+	value, err := column(record, idx, name)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	if scale != 0 {
+		v *= scale
+	}
+	return v, nil
+}
+
+func column(record []string, idx int, name string) (string, error) {
+	if idx < 0 || idx >= len(record) {
+		return "", fmt.Errorf("missing %s column %d", name, idx)
+	}
+	return strings.TrimSpace(record[idx]), nil
+}
+
+// parseHistDataTime parses "20060102 150405" followed by milliseconds without a separator, which time.Parse
+// cannot express
+func parseHistDataTime(value string, location *time.Location) (time.Time, error) {
+	const layout = "20060102 150405"
+	if len(value) != len(layout)+3 {
+		return time.Time{}, fmt.Errorf("expected %q followed by milliseconds", layout)
+	}
+	ts, err := time.ParseInLocation(layout, value[:len(layout)], location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ms, err := strconv.Atoi(value[len(layout):])
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ts.Add(time.Duration(ms) * time.Millisecond), nil
+}
+
+func sortEntries[T timestamped](entries []T) []T {
+	slices.SortStableFunc(entries, func(a, b T) int {
+		switch {
+		case a.unixNano() < b.unixNano():
+			return -1
+		case a.unixNano() > b.unixNano():
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	out := entries[:0]
+	for idx, entry := range entries {
+		if idx+1 < len(entries) && entries[idx+1].unixNano() == entry.unixNano() {
+			continue
+		}
+		out = append(out, entry)
+	}
+	return out
+}
@@ -0,0 +1,300 @@
+package historical
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility"
+)
+
+const csvBarReaderComponentName = "datasource.historical.csv"
+
+// CSVBarFormat describes the columns of a bar csv export, column indexes are zero based. The timestamp of a bar
+// is its open time, exports with separate date and time columns are parsed as "date time".
+type CSVBarFormat struct {
+	Comma  rune
+	Header bool
+
+	DateColumn   int
+	TimeColumn   int
+	OpenColumn   int
+	HighColumn   int
+	LowColumn    int
+	CloseColumn  int
+	VolumeColumn int
+
+	// TimeLayout is a time.Parse layout or one of the TimeLayoutUnix* epoch layouts. ParseTime replaces it
+	// for layouts time.Parse cannot express.
+	TimeLayout string
+	ParseTime  func(value string, location *time.Location) (time.Time, error)
+	// Location is the time zone of the timestamps without a zone, UTC when nil
+	Location *time.Location
+
+	// Period is the timeframe of the bars, exports do not record it
+	Period common.BarPeriod
+
+	PriceScale  float64
+	VolumeScale float64
+}
+
+var (
+	// DukascopyBarCSV reads the JForex historical candle export, "Gmt time,Open,High,Low,Close,Volume"
+	DukascopyBarCSV = CSVBarFormat{
+		Comma:        ',',
+		Header:       true,
+		DateColumn:   NoColumn,
+		TimeColumn:   0,
+		OpenColumn:   1,
+		HighColumn:   2,
+		LowColumn:    3,
+		CloseColumn:  4,
+		VolumeColumn: 5,
+		TimeLayout:   "02.01.2006 15:04:05.000",
+		Period:       common.BarPeriodM1,
+	}
+
+	// HistDataBarCSV reads the HistData.com M1 export, "20200101 170000;open;high;low;close;volume" in EST
+	// without daylight saving. Its volume column is always zero and is skipped.
+	HistDataBarCSV = CSVBarFormat{
+		Comma:        ';',
+		DateColumn:   NoColumn,
+		TimeColumn:   0,
+		OpenColumn:   1,
+		HighColumn:   2,
+		LowColumn:    3,
+		CloseColumn:  4,
+		VolumeColumn: NoColumn,
+		TimeLayout:   "20060102 150405",
+		Location:     time.FixedZone("EST", -5*60*60),
+		Period:       common.BarPeriodM1,
+	}
+
+	// MetaTraderBarCSV reads the MetaTrader history center export, "2020.01.01,17:00,open,high,low,close,volume"
+	// in the time zone of the trade server, set Location and Period to match the export
+	MetaTraderBarCSV = CSVBarFormat{
+		Comma:        ',',
+		DateColumn:   0,
+		TimeColumn:   1,
+		OpenColumn:   2,
+		HighColumn:   3,
+		LowColumn:    4,
+		CloseColumn:  5,
+		VolumeColumn: 6,
+		TimeLayout:   "2006.01.02 15:04",
+		Period:       common.BarPeriodM1,
+	}
+)
+
+// CSVBarFormatByName returns the preset bar format of a data vendor
+func CSVBarFormatByName(name string) (CSVBarFormat, error) {
+	switch strings.ToLower(name) {
+	case "dukascopy":
+		return DukascopyBarCSV, nil
+	case "histdata":
+		return HistDataBarCSV, nil
+	case "metatrader":
+		return MetaTraderBarCSV, nil
+	default:
+		return CSVBarFormat{}, fmt.Errorf("unknown csv bar format %q", name)
+	}
+}
+
+// ReadCSVBars parses the bars of a csv export in file order
+func ReadCSVBars(r io.Reader, format CSVBarFormat) ([]BinaryBar, error) {
+	scanner := newCSVBarScanner(r, format)
+
+	var bars []BinaryBar
+	for {
+		bar, err := scanner.next()
+		if errors.Is(err, io.EOF) {
+			return bars, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		bars = append(bars, bar)
+	}
+}
+
+// ReadCSVBarFile parses the bars of a csv export file in file order
+func ReadCSVBarFile(name string, format CSVBarFormat) ([]BinaryBar, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %q: %w", name, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	bars, err := ReadCSVBars(f, format)
+	if err != nil {
+		return nil, fmt.Errorf("unable to import %q: %w", name, err)
+	}
+	return bars, nil
+}
+
+// ImportCSVBars converts csv exports of symbol into a bar file NewBarReader can read, the bars of all exports are
+// sorted and deduplicated by open time. It returns the number of bars written.
+func ImportCSVBars(dst, symbol string, digits int, format CSVBarFormat, sources ...string) (int, error) {
+	var bars []BinaryBar
+	for _, source := range sources {
+		fileBars, err := ReadCSVBarFile(source, format)
+		if err != nil {
+			return 0, err
+		}
+		bars = append(bars, fileBars...)
+	}
+
+	bars = sortEntries(bars)
+	if err := WriteFile(dst, symbol, digits, bars); err != nil {
+		return 0, err
+	}
+	return len(bars), nil
+}
+
+// CSVBarReader streams the bars of a csv export opening between from and to. The export must be ordered by
+// open time.
+type CSVBarReader struct {
+	scanner *csvBarScanner
+
+	symbol string
+	from   int64
+	to     int64
+	last   int64
+	done   bool
+
+	ids *utility.IDGenerator
+}
+
+func NewCSVBarReader(r io.Reader, format CSVBarFormat, symbol string, from, to time.Time) *CSVBarReader {
+	return &CSVBarReader{
+		scanner: newCSVBarScanner(r, format),
+		symbol:  symbol,
+		from:    from.UnixNano(),
+		to:      to.UnixNano(),
+	}
+}
+
+// SetIDGenerator stamps the bars with the ids of the run, see datasource.IDStamper
+func (c *CSVBarReader) SetIDGenerator(ids *utility.IDGenerator) {
+	c.ids = ids
+}
+
+func (c *CSVBarReader) GetNext() (common.Bar, error) {
+	var bar common.Bar
+
+	for !c.done {
+		binBar, err := c.scanner.next()
+		if errors.Is(err, io.EOF) {
+			c.done = true
+			break
+		}
+		if err != nil {
+			return bar, err
+		}
+
+		if c.scanner.bars > 1 && binBar.OpenTime <= c.last {
+			return bar, fmt.Errorf("line %d: bar opening at %s is not after the previous bar",
+				c.scanner.line, time.Unix(0, binBar.OpenTime).UTC())
+		}
+		c.last = binBar.OpenTime
+
+		if binBar.OpenTime < c.from {
+			continue
+		}
+		if binBar.OpenTime > c.to {
+			c.done = true
+			break
+		}
+
+		binBar.ToModelBar(&bar)
+
+		bar.Source = csvBarReaderComponentName
+		bar.Symbol = c.symbol
+		bar.ExecutionId = c.ids.ExecutionID()
+		bar.TraceID = c.ids.TraceID()
+
+		return bar, nil
+	}
+	return bar, ErrEof
+}
+
+type csvBarScanner struct {
+	reader *csv.Reader
+	format CSVBarFormat
+	line   int
+	bars   int
+}
+
+func newCSVBarScanner(r io.Reader, format CSVBarFormat) *csvBarScanner {
+	return &csvBarScanner{
+		reader: newCSVReader(r, format.Comma),
+		format: format,
+	}
+}
+
+// next returns the next bar of the export, io.EOF at its end
+func (s *csvBarScanner) next() (BinaryBar, error) {
+	for {
+		record, err := s.reader.Read()
+		if errors.Is(err, io.EOF) {
+			return BinaryBar{}, io.EOF
+		}
+		if err != nil {
+			return BinaryBar{}, fmt.Errorf("unable to read csv: %w", err)
+		}
+		s.line++
+		if (s.line == 1 && s.format.Header) || isBlank(record) {
+			continue
+		}
+
+		bar, err := s.format.parseRecord(record)
+		if err != nil {
+			return bar, fmt.Errorf("line %d: %w", s.line, err)
+		}
+		s.bars++
+		return bar, nil
+	}
+}
+
+func (f CSVBarFormat) parseRecord(record []string) (BinaryBar, error) {
+	bar := BinaryBar{Period: int64(f.Period)}
+
+	value, err := column(record, f.TimeColumn, "time")
+	if err != nil {
+		return bar, err
+	}
+	if f.DateColumn != NoColumn {
+		date, err := column(record, f.DateColumn, "date")
+		if err != nil {
+			return bar, err
+		}
+		value = date + " " + value
+	}
+	ts, err := parseTime(value, f.TimeLayout, f.ParseTime, f.Location)
+	if err != nil {
+		return bar, fmt.Errorf("invalid time %q: %w", value, err)
+	}
+	bar.OpenTime = ts.UnixNano()
+
+	if bar.Open, err = parseFloat(record, f.OpenColumn, "open", f.PriceScale); err != nil {
+		return bar, err
+	}
+	if bar.High, err = parseFloat(record, f.HighColumn, "high", f.PriceScale); err != nil {
+		return bar, err
+	}
+	if bar.Low, err = parseFloat(record, f.LowColumn, "low", f.PriceScale); err != nil {
+		return bar, err
+	}
+	if bar.Close, err = parseFloat(record, f.CloseColumn, "close", f.PriceScale); err != nil {
+		return bar, err
+	}
+	if bar.Volume, err = parseFloat(record, f.VolumeColumn, "volume", f.VolumeScale); err != nil {
+		return bar, err
+	}
+	return bar, nil
+}
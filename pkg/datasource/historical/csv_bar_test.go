@@ -0,0 +1,194 @@
+package historical
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+)
+
+func TestReadCSVBars(t *testing.T) {
+	metaTrader := MetaTraderBarCSV
+	metaTrader.Location = time.FixedZone("EET", 2*60*60)
+	metaTrader.Period = common.BarPeriodH1
+
+	tests := []struct {
+		name   string
+		format CSVBarFormat
+		csv    string
+		want   []BinaryBar
+	}{
+		{
+			name:   "dukascopy",
+			format: DukascopyBarCSV,
+			csv: "Gmt time,Open,High,Low,Close,Volume\n" +
+				"02.01.2024 10:00:00.000,1.10010,1.10050,1.09950,1.10020,123.45\n" +
+				"02.01.2024 10:01:00.000,1.10020,1.10030,1.10000,1.10010,0\n",
+			want: []BinaryBar{
+				{OpenTime: ms("2024-01-02 10:00:00.000"), Period: int64(time.Minute), Open: 1.10010, High: 1.10050, Low: 1.09950, Close: 1.10020, Volume: 123.45},
+				{OpenTime: ms("2024-01-02 10:01:00.000"), Period: int64(time.Minute), Open: 1.10020, High: 1.10030, Low: 1.10000, Close: 1.10010},
+			},
+		},
+		{
+			name:   "histdata is converted from est and skips the volume",
+			format: HistDataBarCSV,
+			csv: "20240102 170000;1.10010;1.10050;1.09950;1.10020;0\n" +
+				"\n" +
+				"20240102 170100;1.10020;1.10030;1.10000;1.10010;7\n",
+			want: []BinaryBar{
+				{OpenTime: ms("2024-01-02 22:00:00.000"), Period: int64(time.Minute), Open: 1.10010, High: 1.10050, Low: 1.09950, Close: 1.10020},
+				{OpenTime: ms("2024-01-02 22:01:00.000"), Period: int64(time.Minute), Open: 1.10020, High: 1.10030, Low: 1.10000, Close: 1.10010},
+			},
+		},
+		{
+			name:   "metatrader joins the date and time columns",
+			format: metaTrader,
+			csv:    "2024.01.02,12:00,1.10010,1.10050,1.09950,1.10020,42\n",
+			want: []BinaryBar{
+				{OpenTime: ms("2024-01-02 10:00:00.000"), Period: int64(time.Hour), Open: 1.10010, High: 1.10050, Low: 1.09950, Close: 1.10020, Volume: 42},
+			},
+		},
+		{
+			name: "points and epochs",
+			format: CSVBarFormat{
+				DateColumn:   NoColumn,
+				TimeColumn:   0,
+				OpenColumn:   1,
+				HighColumn:   2,
+				LowColumn:    3,
+				CloseColumn:  4,
+				VolumeColumn: 5,
+				TimeLayout:   TimeLayoutUnix,
+				Period:       common.BarPeriodM5,
+				PriceScale:   0.5,
+				VolumeScale:  100,
+			},
+			csv: "1704189600,220020,220100,219900,220040,3\n",
+			want: []BinaryBar{
+				{OpenTime: ms("2024-01-02 10:00:00.000"), Period: int64(5 * time.Minute), Open: 110010, High: 110050, Low: 109950, Close: 110020, Volume: 300},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bars, err := ReadCSVBars(strings.NewReader(tt.csv), tt.format)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, bars)
+		})
+	}
+}
+
+func TestReadCSVBars_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want string
+	}{
+		{name: "invalid time", csv: "2024-01-02 10:00,1.1,1.1,1.1,1.1,1\n", want: "line 2: invalid time"},
+		{name: "invalid price", csv: "02.01.2024 10:00:00.000,1.1,1.1,x,1.1,1\n", want: "line 2: invalid low"},
+		{name: "missing column", csv: "02.01.2024 10:00:00.000,1.1,1.1,1.1,1.1\n", want: "line 2: missing volume column 5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadCSVBars(strings.NewReader("Gmt time,Open,High,Low,Close,Volume\n"+tt.csv), DukascopyBarCSV)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestCSVBarReader_GetNext(t *testing.T) {
+	const csv = "Gmt time,Open,High,Low,Close,Volume\n" +
+		"02.01.2024 10:00:00.000,1.1,1.1,1.1,1.1,1\n" +
+		"02.01.2024 10:01:00.000,1.2,1.2,1.2,1.2,1\n" +
+		"02.01.2024 10:02:00.000,1.3,1.3,1.3,1.3,1\n" +
+		"02.01.2024 10:03:00.000,1.4,1.4,1.4,1.4,1\n"
+
+	from := time.Unix(0, ms("2024-01-02 10:01:00.000"))
+	to := time.Unix(0, ms("2024-01-02 10:02:00.000"))
+	reader := NewCSVBarReader(strings.NewReader(csv), DukascopyBarCSV, "EURUSD", from, to)
+
+	var opens []time.Time
+	for {
+		bar, err := reader.GetNext()
+		if errors.Is(err, ErrEof) {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, "EURUSD", bar.Symbol)
+		assert.Equal(t, common.BarPeriodM1, bar.Period)
+		assert.True(t, bar.OpenTime.Add(time.Minute).Equal(bar.TimeStamp), "bars are stamped at their close")
+		opens = append(opens, bar.OpenTime.UTC())
+	}
+	assert.Equal(t, []time.Time{from.UTC(), to.UTC()}, opens)
+
+	t.Run("bars out of order", func(t *testing.T) {
+		reader := NewCSVBarReader(strings.NewReader("Gmt time,Open,High,Low,Close,Volume\n"+
+			"02.01.2024 10:01:00.000,1.1,1.1,1.1,1.1,1\n"+
+			"02.01.2024 10:01:00.000,1.1,1.1,1.1,1.1,1\n"), DukascopyBarCSV, "EURUSD", from, to)
+
+		_, err := reader.GetNext()
+		require.NoError(t, err)
+		_, err = reader.GetNext()
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "line 3")
+	})
+}
+
+func TestImportCSVBars_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	first := filepath.Join(dir, "first.csv")
+	require.NoError(t, os.WriteFile(first, []byte("Gmt time,Open,High,Low,Close,Volume\n"+
+		"02.01.2024 10:02:00.000,1.3,1.3,1.3,1.3,1\n"+
+		"02.01.2024 10:00:00.000,1.1,1.1,1.1,1.1,1\n"+
+		"02.01.2024 10:01:00.000,1.2,1.2,1.2,1.2,1\n"), 0o644))
+	second := filepath.Join(dir, "second.csv")
+	require.NoError(t, os.WriteFile(second, []byte("Gmt time,Open,High,Low,Close,Volume\n"+
+		"02.01.2024 10:01:00.000,1.25,1.25,1.25,1.25,2\n"), 0o644))
+
+	dst := filepath.Join(dir, "EURUSD.bars")
+	count, err := ImportCSVBars(dst, "EURUSD", 5, DukascopyBarCSV, first, second)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	source := NewSource[BinaryBar](dst)
+	require.NoError(t, source.Open())
+	defer source.Close()
+
+	header, ok := source.Header()
+	require.True(t, ok)
+	assert.Equal(t, RecordKindBar, header.Kind)
+
+	reader := NewBarReader(source, "EURUSD", time.Unix(0, ms("2024-01-02 10:00:00.000")), time.Unix(0, ms("2024-01-02 11:00:00.000")))
+
+	var closes []float64
+	for {
+		bar, err := reader.GetNext()
+		if errors.Is(err, ErrEof) {
+			break
+		}
+		require.NoError(t, err)
+		value, _ := bar.Close.Float64()
+		closes = append(closes, value)
+	}
+	assert.Equal(t, []float64{1.1, 1.25, 1.3}, closes, "sorted with the later export winning")
+}
+
+func TestCSVBarFormatByName(t *testing.T) {
+	for _, name := range []string{"dukascopy", "HistData", "metatrader"} {
+		_, err := CSVBarFormatByName(name)
+		assert.NoError(t, err, name)
+	}
+	_, err := CSVBarFormatByName("unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,188 @@
+package historical
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ms(value string) int64 {
+	ts, err := time.Parse("2006-01-02 15:04:05.000", value)
+	if err != nil {
+		panic(err)
+	}
+	return ts.UnixNano()
+}
+
+func TestReadCSVTicks(t *testing.T) {
+	tests := []struct {
+		name   string
+		format CSVFormat
+		csv    string
+		want   []BinaryTick
+	}{
+		{
+			name:   "dukascopy",
+			format: DukascopyCSV,
+			csv: "Gmt time,Ask,Bid,AskVolume,BidVolume\n" +
+				"02.01.2024 10:00:00.123,1.10012,1.10010,1.5,2.25\n" +
+				"02.01.2024 10:00:00.456,1.10013,1.10011,0.75,1\n",
+			want: []BinaryTick{
+				{TimeStamp: ms("2024-01-02 10:00:00.123"), Bid: 1.10010, Ask: 1.10012, BidVolume: 2.25, AskVolume: 1.5},
+				{TimeStamp: ms("2024-01-02 10:00:00.456"), Bid: 1.10011, Ask: 1.10013, BidVolume: 1, AskVolume: 0.75},
+			},
+		},
+		{
+			name:   "histdata is converted from est",
+			format: HistDataCSV,
+			csv: "20240102 170000123,1.10010,1.10012,0\n" +
+				"\n" +
+				"20240102 235959999,1.10011,1.10013,0\n",
+			want: []BinaryTick{
+				{TimeStamp: ms("2024-01-02 22:00:00.123"), Bid: 1.10010, Ask: 1.10012},
+				{TimeStamp: ms("2024-01-03 04:59:59.999"), Bid: 1.10011, Ask: 1.10013},
+			},
+		},
+		{
+			name:   "truefx",
+			format: TrueFXCSV,
+			csv: "EUR/USD,20240102 22:00:00.123,1.10010,1.10012\n" +
+				"EUR/USD,20240102 22:00:01.000,1.10011,1.10013\n",
+			want: []BinaryTick{
+				{TimeStamp: ms("2024-01-02 22:00:00.123"), Bid: 1.10010, Ask: 1.10012},
+				{TimeStamp: ms("2024-01-02 22:00:01.000"), Bid: 1.10011, Ask: 1.10013},
+			},
+		},
+		{
+			name: "points and lots are scaled",
+			format: CSVFormat{
+				Comma:           ';',
+				TimeColumn:      0,
+				BidColumn:       1,
+				AskColumn:       2,
+				BidVolumeColumn: 3,
+				AskVolumeColumn: 4,
+				TimeLayout:      TimeLayoutUnixMilli,
+				PriceScale:      0.5,
+				VolumeScale:     100,
+			},
+			csv: "1704189600123;220020;220024;2;3\n",
+			want: []BinaryTick{
+				{TimeStamp: ms("2024-01-02 10:00:00.123"), Bid: 110010, Ask: 110012, BidVolume: 200, AskVolume: 300},
+			},
+		},
+		{
+			name: "zone of the location",
+			format: CSVFormat{
+				TimeColumn:      0,
+				BidColumn:       1,
+				AskColumn:       2,
+				BidVolumeColumn: NoColumn,
+				AskVolumeColumn: NoColumn,
+				TimeLayout:      "2006-01-02 15:04:05.000",
+				Location:        time.FixedZone("CET", 60*60),
+			},
+			csv: "2024-01-02 11:00:00.123,1.10010,1.10012\n",
+			want: []BinaryTick{
+				{TimeStamp: ms("2024-01-02 10:00:00.123"), Bid: 1.10010, Ask: 1.10012},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticks, err := ReadCSVTicks(strings.NewReader(tt.csv), tt.format)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, ticks)
+		})
+	}
+}
+
+func TestReadCSVTicks_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+		want string
+	}{
+		{name: "invalid time", csv: "02.01.2024 10:00,1.1,1.1,1,1\n", want: "line 2: invalid time"},
+		{name: "invalid price", csv: "02.01.2024 10:00:00.000,1.1,x,1,1\n", want: "line 2: invalid bid"},
+		{name: "missing column", csv: "02.01.2024 10:00:00.000,1.1,1.1,1\n", want: "line 2: missing bid volume column 4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadCSVTicks(strings.NewReader("Gmt time,Ask,Bid,AskVolume,BidVolume\n"+tt.csv), DukascopyCSV)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestSortTicks(t *testing.T) {
+	ticks := SortTicks([]BinaryTick{
+		{TimeStamp: 3, Bid: 3},
+		{TimeStamp: 1, Bid: 1},
+		{TimeStamp: 2, Bid: 2},
+		{TimeStamp: 1, Bid: 1.5},
+		{TimeStamp: 3, Bid: 3.5},
+	})
+
+	assert.Equal(t, []BinaryTick{
+		{TimeStamp: 1, Bid: 1.5},
+		{TimeStamp: 2, Bid: 2},
+		{TimeStamp: 3, Bid: 3.5},
+	}, ticks, "the later duplicate wins")
+}
+
+func TestImportCSV_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	first := filepath.Join(dir, "first.csv")
+	require.NoError(t, os.WriteFile(first, []byte(
+		"Gmt time,Ask,Bid,AskVolume,BidVolume\n"+
+			"02.01.2024 10:00:02.000,1.10032,1.10030,1,1\n"+
+			"02.01.2024 10:00:00.000,1.10012,1.10010,1,1\n"+
+			"02.01.2024 10:00:01.000,1.10022,1.10020,1,1\n"), 0o644))
+	second := filepath.Join(dir, "second.csv")
+	require.NoError(t, os.WriteFile(second, []byte(
+		"Gmt time,Ask,Bid,AskVolume,BidVolume\n"+
+			"02.01.2024 10:00:01.000,1.10025,1.10023,2,2\n"+
+			"02.01.2024 10:00:03.000,1.10042,1.10040,1,1\n"), 0o644))
+
+	dst := filepath.Join(dir, "EURUSD.bin")
+	count, err := ImportCSV(dst, "EURUSD", 5, DukascopyCSV, first, second)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	source := NewSource[BinaryTick](dst)
+	require.NoError(t, source.Open())
+	defer source.Close()
+
+	header, ok := source.Header()
+	require.True(t, ok)
+	assert.Equal(t, "EURUSD", header.Symbol)
+
+	reader := NewTickReader(source, "EURUSD", time.Unix(0, ms("2024-01-02 10:00:00.000")), time.Unix(0, ms("2024-01-02 11:00:00.000")))
+
+	var bids []float64
+	var last time.Time
+	for {
+		tick, err := reader.GetNext()
+		if errors.Is(err, ErrEof) {
+			break
+		}
+		require.NoError(t, err)
+		require.True(t, tick.TimeStamp.After(last), "ticks are sorted and unique")
+		last = tick.TimeStamp
+
+		bid, _ := tick.Bid.Float64()
+		bids = append(bids, bid)
+	}
+	assert.Equal(t, []float64{1.10010, 1.10023, 1.10030, 1.10040}, bids, "the later export overrides the earlier one")
+}
@@ -0,0 +1,72 @@
+package historical
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility"
+)
+
+const depthReaderComponentName = "datasource.historical.depth"
+
+type DepthReader struct {
+	source *Source[BinaryDepth]
+
+	symbol string
+	from   int64
+	to     int64
+	idx    int64
+
+	ids *utility.IDGenerator
+}
+
+func NewDepthReader(source *Source[BinaryDepth], symbol string, from, to time.Time) *DepthReader {
+	return &DepthReader{
+		source: source,
+		symbol: symbol,
+		from:   from.UnixNano(),
+		to:     to.UnixNano(),
+		idx:    invalidIndex,
+	}
+}
+
+// SetIDGenerator stamps the order books with the ids of the run, see datasource.IDStamper
+func (d *DepthReader) SetIDGenerator(ids *utility.IDGenerator) {
+	d.ids = ids
+}
+
+func (d *DepthReader) GetNext() (common.OrderBook, error) {
+
+	var book common.OrderBook
+	var binDepth BinaryDepth
+
+	if d.idx == invalidIndex {
+		if err := checkSymbol(d.source, d.symbol); err != nil {
+			return book, err
+		}
+		idx, err := searchIndex(d.source, d.from)
+		if err != nil {
+			return book, err
+		}
+		d.idx = idx
+	}
+
+	if err := d.source.Read(d.idx, &binDepth); err != nil {
+		return book, fmt.Errorf("error reading entry at index %d: %w", d.idx, err)
+	}
+	d.idx++
+
+	if binDepth.TimeStamp > d.to {
+		return book, ErrEof
+	}
+
+	binDepth.ToModelOrderBook(&book)
+
+	book.Source = depthReaderComponentName
+	book.Symbol = d.symbol
+	book.ExecutionId = d.ids.ExecutionID()
+	book.TraceID = d.ids.TraceID()
+
+	return book, nil
+}
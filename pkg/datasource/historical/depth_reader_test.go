@@ -0,0 +1,87 @@
+package historical
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+func testDepths() []BinaryDepth {
+	start := time.Date(2024, time.January, 2, 9, 0, 0, 0, time.UTC)
+
+	var depths []BinaryDepth
+	for i := 0; i < 5; i++ {
+		depth := BinaryDepth{TimeStamp: start.Add(time.Duration(i) * time.Second).UnixNano()}
+		// i bid levels and two ask levels with an empty one in between
+		for level := 0; level < i; level++ {
+			depth.BidPrices[level] = 1.1000 - float64(level)*0.0001
+			depth.BidSizes[level] = float64(level + 1)
+		}
+		depth.AskPrices[0], depth.AskSizes[0] = 1.1002, 1
+		depth.AskPrices[1], depth.AskSizes[1] = 1.1003, 0
+		depth.AskPrices[2], depth.AskSizes[2] = 1.1004, 2.5
+		depths = append(depths, depth)
+	}
+	return depths
+}
+
+func TestDepthReader_RoundTrip(t *testing.T) {
+	depths := testDepths()
+	source := writeTestSource(t, "EURUSD", depths)
+
+	tests := []struct {
+		name  string
+		from  int
+		to    int
+		books []int
+	}{
+		{name: "whole file", from: 0, to: 4, books: []int{0, 1, 2, 3, 4}},
+		{name: "bounds are inclusive", from: 1, to: 3, books: []int{1, 2, 3}},
+		{name: "single snapshot", from: 2, to: 2, books: []int{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := time.Unix(0, depths[tt.from].TimeStamp)
+			to := time.Unix(0, depths[tt.to].TimeStamp)
+			reader := NewDepthReader(source, "EURUSD", from, to)
+
+			var books []int
+			for {
+				book, err := reader.GetNext()
+				if errors.Is(err, ErrEof) {
+					break
+				}
+				require.NoError(t, err)
+
+				idx := len(books) + tt.from
+				books = append(books, idx)
+
+				assert.Equal(t, "EURUSD", book.Symbol)
+				assert.Equal(t, depths[idx].TimeStamp, book.TimeStamp.UnixNano())
+
+				require.Len(t, book.Bids, idx, "empty bid levels are skipped")
+				for level, bid := range book.Bids {
+					assert.True(t, fixed.FromFloat64(depths[idx].BidPrices[level]).Eq(bid.Price), bid.Price.String())
+					assert.True(t, fixed.FromFloat64(depths[idx].BidSizes[level]).Eq(bid.Size), bid.Size.String())
+				}
+
+				require.Len(t, book.Asks, 2, "empty ask levels are skipped")
+				assert.True(t, fixed.FromFloat64(1.1002).Eq(book.Asks[0].Price), book.Asks[0].Price.String())
+				assert.True(t, fixed.FromFloat64(1.1004).Eq(book.Asks[1].Price), book.Asks[1].Price.String())
+				assert.True(t, fixed.FromFloat64(2.5).Eq(book.Asks[1].Size), book.Asks[1].Size.String())
+			}
+			assert.Equal(t, tt.books, books)
+		})
+	}
+
+	t.Run("symbol mismatch", func(t *testing.T) {
+		_, err := NewDepthReader(source, "GBPUSD", time.Unix(0, 0), time.Now()).GetNext()
+		assert.ErrorIs(t, err, ErrMismatch)
+	})
+}
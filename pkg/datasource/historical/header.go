@@ -0,0 +1,211 @@
+package historical
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"unsafe"
+)
+
+// HeaderVersion is the newest header version Source understands
+const HeaderVersion = 1
+
+// HeaderSize is the size of the header, the records start right after it
+const HeaderSize = 128
+
+const (
+	headerSymbolSize = 64
+
+	byteOrderLittle = 1
+	byteOrderBig    = 2
+)
+
+var headerMagic = [8]byte{'E', 'Q', 'N', 'X', 'H', 'I', 'S', 'T'}
+
+var (
+	ErrCorrupt  = errors.New("corrupt historical file")
+	ErrMismatch = errors.New("historical file does not match")
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type RecordKind uint8
+
+const (
+	RecordKindUnknown RecordKind = iota
+	RecordKindTick
+	RecordKindDepth
+	// RecordKindTickBlock marks a block compressed tick file, see NewBlockSource
+	RecordKindTickBlock
+	RecordKindBar
+)
+
+// Header describes the records of a historical file. Files written before the header was introduced have none
+// and are read as raw records.
+type Header struct {
+	Version    uint16
+	Kind       RecordKind
+	RecordSize uint32
+	Symbol     string
+	Digits     int
+	Count      int64
+	// FirstTimeStamp and LastTimeStamp are the unix nano timestamps of the first and the last record
+	FirstTimeStamp int64
+	LastTimeStamp  int64
+	// CRC is the CRC-32C checksum of everything following the header
+	CRC uint32
+
+	// BlockSize and IndexOffset locate the blocks of block compressed files, they are zero otherwise
+	BlockSize   uint32
+	IndexOffset int64
+}
+
+// NewHeader describes entries ordered by timestamp
+func NewHeader[T timestamped](symbol string, digits int, entries []T) Header {
+	header := Header{
+		Version:    HeaderVersion,
+		Kind:       recordKind[T](),
+		RecordSize: uint32(unsafe.Sizeof(*new(T))),
+		Symbol:     symbol,
+		Digits:     digits,
+		Count:      int64(len(entries)),
+		CRC:        crc32.Checksum(entriesBytes(entries), crcTable),
+	}
+	if len(entries) > 0 {
+		header.FirstTimeStamp = entries[0].unixNano()
+		header.LastTimeStamp = entries[len(entries)-1].unixNano()
+	}
+	return header
+}
+
+func (h Header) MarshalBinary() ([]byte, error) {
+	if len(h.Symbol) > headerSymbolSize {
+		return nil, fmt.Errorf("symbol %q longer than %d bytes", h.Symbol, headerSymbolSize)
+	}
+
+	b := make([]byte, HeaderSize)
+	le := binary.LittleEndian
+	copy(b[0:8], headerMagic[:])
+	le.PutUint16(b[8:], h.Version)
+	b[10] = nativeByteOrder()
+	b[11] = byte(h.Kind)
+	le.PutUint32(b[12:], h.RecordSize)
+	le.PutUint32(b[16:], uint32(int32(h.Digits)))
+	le.PutUint32(b[20:], h.BlockSize)
+	le.PutUint64(b[24:], uint64(h.Count))
+	le.PutUint64(b[32:], uint64(h.FirstTimeStamp))
+	le.PutUint64(b[40:], uint64(h.LastTimeStamp))
+	le.PutUint32(b[48:], h.CRC)
+	copy(b[56:56+headerSymbolSize], h.Symbol)
+	le.PutUint64(b[120:], uint64(h.IndexOffset))
+	le.PutUint32(b[52:], crc32.Checksum(b, crcTable))
+	return b, nil
+}
+
+func (h *Header) UnmarshalBinary(b []byte) error {
+	if len(b) < HeaderSize || !hasHeader(b) {
+		return fmt.Errorf("%w: missing header", ErrCorrupt)
+	}
+	b = b[:HeaderSize]
+	le := binary.LittleEndian
+
+	version := le.Uint16(b[8:])
+	if version == 0 || version > HeaderVersion {
+		return fmt.Errorf("unsupported header version %d, newest supported is %d", version, HeaderVersion)
+	}
+
+	checksum := le.Uint32(b[52:])
+	unsummed := bytes.Clone(b)
+	le.PutUint32(unsummed[52:], 0)
+	if crc32.Checksum(unsummed, crcTable) != checksum {
+		return fmt.Errorf("%w: header checksum mismatch", ErrCorrupt)
+	}
+
+	if order := b[10]; order != nativeByteOrder() {
+		return fmt.Errorf("%w: records were written with byte order %d, this machine uses %d", ErrMismatch, order, nativeByteOrder())
+	}
+
+	*h = Header{
+		Version:        version,
+		Kind:           RecordKind(b[11]),
+		RecordSize:     le.Uint32(b[12:]),
+		Digits:         int(int32(le.Uint32(b[16:]))),
+		Count:          int64(le.Uint64(b[24:])),
+		FirstTimeStamp: int64(le.Uint64(b[32:])),
+		LastTimeStamp:  int64(le.Uint64(b[40:])),
+		CRC:            le.Uint32(b[48:]),
+		Symbol:         string(bytes.TrimRight(b[56:56+headerSymbolSize], "\x00")),
+		BlockSize:      le.Uint32(b[20:]),
+		IndexOffset:    int64(le.Uint64(b[120:])),
+	}
+	return nil
+}
+
+// checkHeader validates that the header describes records of type T filling size bytes
+func checkHeader[T any](h Header, size int64) error {
+	var entry T
+	if h.Kind == RecordKindTickBlock {
+		return fmt.Errorf("%w: file is block compressed, open it with NewBlockSource", ErrMismatch)
+	}
+	if kind := recordKind[T](); kind != RecordKindUnknown && h.Kind != kind {
+		return fmt.Errorf("%w: file holds record kind %d, expected %d for %T", ErrMismatch, h.Kind, kind, entry)
+	}
+	if h.RecordSize != uint32(unsafe.Sizeof(entry)) {
+		return fmt.Errorf("%w: record size is %d, expected %d for %T", ErrMismatch, h.RecordSize, unsafe.Sizeof(entry), entry)
+	}
+	if h.Count < 0 || h.Count*int64(h.RecordSize) != size {
+		return fmt.Errorf("%w: header announces %d records, file holds %d bytes of records", ErrCorrupt, h.Count, size)
+	}
+	return nil
+}
+
+// checkSymbol fails when the file of source was written for another symbol
+func checkSymbol[T any](source *Source[T], symbol string) error {
+	header, ok := source.Header()
+	if !ok {
+		return nil
+	}
+	return matchSymbol(source.dataSourceName, header.Symbol, symbol)
+}
+
+func matchSymbol(dataSourceName, fileSymbol, symbol string) error {
+	if fileSymbol == "" || symbol == "" || strings.EqualFold(fileSymbol, symbol) {
+		return nil
+	}
+	return fmt.Errorf("%w: %q holds %s, not %s", ErrMismatch, dataSourceName, fileSymbol, symbol)
+}
+
+func hasHeader(b []byte) bool {
+	return len(b) >= len(headerMagic) && bytes.Equal(b[:len(headerMagic)], headerMagic[:])
+}
+
+func recordKind[T any]() RecordKind {
+	switch any(*new(T)).(type) {
+	case BinaryTick:
+		return RecordKindTick
+	case BinaryDepth:
+		return RecordKindDepth
+	case BinaryBar:
+		return RecordKindBar
+	default:
+		return RecordKindUnknown
+	}
+}
+
+func nativeByteOrder() byte {
+	if binary.NativeEndian.Uint16([]byte{1, 0}) == 1 {
+		return byteOrderLittle
+	}
+	return byteOrderBig
+}
+
+func entriesBytes[T any](entries []T) []byte {
+	if len(entries) == 0 {
+		return nil
+	}
+	size := int(unsafe.Sizeof(entries[0]))
+	return unsafe.Slice((*byte)(unsafe.Pointer(&entries[0])), size*len(entries)) // #nosec G103
+}
@@ -0,0 +1,255 @@
+package historical
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testTicks(n int) []BinaryTick {
+	start := time.Date(2024, time.January, 2, 10, 0, 0, 0, time.UTC)
+
+	ticks := make([]BinaryTick, 0, n)
+	for i := 0; i < n; i++ {
+		ticks = append(ticks, BinaryTick{
+			TimeStamp: start.Add(time.Duration(i) * time.Second).UnixNano(),
+			Bid:       1.10010 + float64(i)*0.00001,
+			Ask:       1.10012 + float64(i)*0.00001,
+			BidVolume: 1,
+			AskVolume: 2,
+		})
+	}
+	return ticks
+}
+
+func writeTestSource[T timestamped](t testing.TB, symbol string, entries []T) *Source[T] {
+	t.Helper()
+
+	name := filepath.Join(t.TempDir(), symbol+".bin")
+	require.NoError(t, WriteFile(name, symbol, 5, entries))
+
+	source := NewSource[T](name)
+	require.NoError(t, source.Open())
+	t.Cleanup(source.Close)
+	return source
+}
+
+// writeHeaderFile writes ticks behind header, file allows corrupting the bytes before they are written
+func writeHeaderFile(t *testing.T, header Header, ticks []BinaryTick, file func(b []byte)) string {
+	t.Helper()
+
+	b, err := header.MarshalBinary()
+	require.NoError(t, err)
+	b = append(b, entriesBytes(ticks)...)
+	if file != nil {
+		file(b)
+	}
+
+	name := filepath.Join(t.TempDir(), "ticks.bin")
+	require.NoError(t, os.WriteFile(name, b, 0o644))
+	return name
+}
+
+func TestHeader_RoundTrip(t *testing.T) {
+	ticks := testTicks(3)
+	header := NewHeader("EURUSD", 5, ticks)
+
+	source := NewSource[BinaryTick](writeHeaderFile(t, header, ticks, nil))
+	require.NoError(t, source.Open())
+	defer source.Close()
+
+	read, ok := source.Header()
+	require.True(t, ok)
+	assert.Equal(t, header, read)
+	assert.Equal(t, uint16(HeaderVersion), read.Version)
+	assert.Equal(t, RecordKindTick, read.Kind)
+	assert.Equal(t, "EURUSD", read.Symbol)
+	assert.Equal(t, 5, read.Digits)
+	assert.Equal(t, int64(3), read.Count)
+	assert.Equal(t, ticks[0].TimeStamp, read.FirstTimeStamp)
+	assert.Equal(t, ticks[2].TimeStamp, read.LastTimeStamp)
+
+	count, err := source.EntryCount()
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+	for idx, tick := range ticks {
+		var entry BinaryTick
+		require.NoError(t, source.Read(int64(idx), &entry))
+		assert.Equal(t, tick, entry)
+	}
+}
+
+func TestHeader_MarshalBinary_LongSymbol(t *testing.T) {
+	_, err := NewHeader(strings.Repeat("X", headerSymbolSize+1), 5, testTicks(1)).MarshalBinary()
+	assert.Error(t, err)
+}
+
+func TestSource_Open_Header(t *testing.T) {
+	ticks := testTicks(3)
+
+	tests := []struct {
+		name    string
+		header  func(h *Header)
+		file    func(b []byte)
+		options []SourceOption
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name:    "header checksum mismatch",
+			file:    func(b []byte) { b[56] = 'X' },
+			wantErr: ErrCorrupt,
+			wantMsg: "header checksum mismatch",
+		},
+		{
+			name:    "payload checksum mismatch",
+			file:    func(b []byte) { b[HeaderSize+10] ^= 0xff },
+			wantErr: ErrCorrupt,
+			wantMsg: "checksum mismatch",
+		},
+		{
+			name:    "payload checksum is skipped on request",
+			file:    func(b []byte) { b[HeaderSize+10] ^= 0xff },
+			options: []SourceOption{WithoutChecksum()},
+		},
+		{
+			name:    "unsupported version",
+			header:  func(h *Header) { h.Version = HeaderVersion + 1 },
+			wantMsg: "unsupported header version",
+		},
+		{
+			name:    "missing version",
+			header:  func(h *Header) { h.Version = 0 },
+			wantMsg: "unsupported header version",
+		},
+		{
+			name:    "wrong record kind",
+			header:  func(h *Header) { h.Kind = RecordKindBar },
+			wantErr: ErrMismatch,
+			wantMsg: "record kind",
+		},
+		{
+			name:    "block compressed file",
+			header:  func(h *Header) { h.Kind = RecordKindTickBlock },
+			wantErr: ErrMismatch,
+			wantMsg: "NewBlockSource",
+		},
+		{
+			name:    "wrong record size",
+			header:  func(h *Header) { h.RecordSize++ },
+			wantErr: ErrMismatch,
+			wantMsg: "record size",
+		},
+		{
+			name:    "count above the file size",
+			header:  func(h *Header) { h.Count++ },
+			wantErr: ErrCorrupt,
+			wantMsg: "announces 4 records",
+		},
+		{
+			name:    "count below the file size",
+			header:  func(h *Header) { h.Count-- },
+			wantErr: ErrCorrupt,
+			wantMsg: "announces 2 records",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := NewHeader("EURUSD", 5, ticks)
+			if tt.header != nil {
+				tt.header(&header)
+			}
+
+			source := NewSource[BinaryTick](writeHeaderFile(t, header, ticks, tt.file), tt.options...)
+			err := source.Open()
+			if tt.wantErr == nil && tt.wantMsg == "" {
+				require.NoError(t, err)
+				source.Close()
+				return
+			}
+
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.Contains(t, err.Error(), tt.wantMsg)
+		})
+	}
+}
+
+func TestSource_Open_TruncatedHeader(t *testing.T) {
+	b, err := NewHeader("EURUSD", 5, testTicks(1)).MarshalBinary()
+	require.NoError(t, err)
+
+	name := filepath.Join(t.TempDir(), "ticks.bin")
+	require.NoError(t, os.WriteFile(name, b[:HeaderSize/2], 0o644))
+
+	assert.ErrorIs(t, NewSource[BinaryTick](name).Open(), ErrCorrupt)
+}
+
+func TestSource_Open_Legacy(t *testing.T) {
+	ticks := testTicks(3)
+
+	name := filepath.Join(t.TempDir(), "ticks.bin")
+	f, err := os.Create(name)
+	require.NoError(t, err)
+	require.NoError(t, WriteEntries(f, ticks))
+	require.NoError(t, f.Close())
+
+	source := NewSource[BinaryTick](name)
+	require.NoError(t, source.Open())
+	defer source.Close()
+
+	_, ok := source.Header()
+	assert.False(t, ok)
+	assert.NoError(t, checkSymbol(source, "GBPUSD"), "files without a header match any symbol")
+
+	reader := NewTickReader(source, "EURUSD", time.Unix(0, ticks[0].TimeStamp), time.Unix(0, ticks[2].TimeStamp))
+	for _, tick := range ticks {
+		read, err := reader.GetNext()
+		require.NoError(t, err)
+		assert.Equal(t, tick.TimeStamp, read.TimeStamp.UnixNano())
+	}
+	_, err = reader.GetNext()
+	assert.ErrorIs(t, err, ErrEof)
+
+	t.Run("partial record", func(t *testing.T) {
+		partial := filepath.Join(t.TempDir(), "partial.bin")
+		require.NoError(t, os.WriteFile(partial, entriesBytes(ticks)[:10], 0o644))
+		assert.ErrorIs(t, NewSource[BinaryTick](partial).Open(), ErrCorrupt)
+	})
+}
+
+func TestCheckSymbol(t *testing.T) {
+	source := writeTestSource(t, "EURUSD", testTicks(2))
+
+	tests := []struct {
+		symbol  string
+		wantErr bool
+	}{
+		{symbol: "EURUSD"},
+		{symbol: "eurusd"},
+		{symbol: ""},
+		{symbol: "GBPUSD", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			err := checkSymbol(source, tt.symbol)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrMismatch)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	_, err := NewTickReader(source, "GBPUSD", time.Unix(0, 0), time.Now()).GetNext()
+	assert.ErrorIs(t, err, ErrMismatch, "readers check the symbol")
+}
@@ -20,6 +20,8 @@ type TickReader struct {
 	from   int64
 	to     int64
 	idx    int64
+
+	ids *utility.IDGenerator
 }
 
 func NewTickReader(source *Source[BinaryTick], symbol string, from, to time.Time) *TickReader {
@@ -32,6 +34,11 @@ func NewTickReader(source *Source[BinaryTick], symbol string, from, to time.Time
 	}
 }
 
+// SetIDGenerator stamps the ticks with the ids of the run, see datasource.IDStamper
+func (t *TickReader) SetIDGenerator(ids *utility.IDGenerator) {
+	t.ids = ids
+}
+
 func (t *TickReader) GetNext() (common.Tick, error) {
 
 	var tick common.Tick
@@ -60,23 +67,46 @@ func (t *TickReader) GetNext() (common.Tick, error) {
 
 	tick.Source = tickReaderComponentName
 	tick.Symbol = t.symbol
-	tick.ExecutionId = utility.GetExecutionID()
-	tick.TraceID = utility.CreateTraceID()
+	tick.ExecutionId = t.ids.ExecutionID()
+	tick.TraceID = t.ids.TraceID()
 
 	return tick, nil
 }
 
 func (t *TickReader) lookupStartIndex() error {
+	if err := checkSymbol(t.source, t.symbol); err != nil {
+		return err
+	}
+
+	idx, err := searchIndex(t.source, t.from)
+	if err != nil {
+		return err
+	}
+
 	entryCount, err := t.source.EntryCount()
 	if err != nil {
 		return fmt.Errorf("error getting entry count: %w", err)
 	}
+	if idx >= entryCount {
+		return fmt.Errorf("no entry found with timestamp >= from: %w", ErrEof)
+	}
+
+	t.idx = idx
+	return nil
+}
+
+// searchIndex returns the index of the first entry with a timestamp >= ts, the entry count if there is none
+func searchIndex[T timestamped](source *Source[T], ts int64) (int64, error) {
+	entryCount, err := source.EntryCount()
+	if err != nil {
+		return 0, fmt.Errorf("error getting entry count: %w", err)
+	}
 
 	if entryCount == 0 {
-		return fmt.Errorf("entry count is zero")
+		return 0, fmt.Errorf("entry count is zero")
 	}
 
-	var entry BinaryTick
+	var entry T
 
 	low := int64(0)
 	high := entryCount - 1
@@ -84,21 +114,16 @@ func (t *TickReader) lookupStartIndex() error {
 	for low <= high {
 		mid := (low + high) / 2
 
-		if err := t.source.Read(mid, &entry); err != nil {
-			return fmt.Errorf("error reading entry at index %d: %w", mid, err)
+		if err := source.Read(mid, &entry); err != nil {
+			return 0, fmt.Errorf("error reading entry at index %d: %w", mid, err)
 		}
 
-		if entry.TimeStamp < t.from {
+		if entry.unixNano() < ts {
 			low = mid + 1
 		} else {
 			high = mid - 1
 		}
 	}
 
-	if low >= entryCount {
-		return fmt.Errorf("no entry found with timestamp >= from")
-	}
-
-	t.idx = low
-	return nil
+	return low, nil
 }
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"os"
 	"sync"
 	"unsafe"
 
@@ -13,14 +12,34 @@ import (
 
 var ErrEof = errors.New("EOF")
 
+type SourceOption func(*sourceOptions)
+
+type sourceOptions struct {
+	skipChecksum bool
+}
+
+// WithoutChecksum skips verifying the checksum of the records on Open, which reads the whole file. The header
+// is still validated.
+func WithoutChecksum() SourceOption {
+	return func(o *sourceOptions) {
+		o.skipChecksum = true
+	}
+}
+
 type Source[T any] struct {
 	dataSourceName string
+	options        sourceOptions
 	reader         *mmap.ReaderAt
 	bufferPool     *sync.Pool
+
+	header    Header
+	hasHeader bool
+	offset    int64
+	count     int64
 }
 
-func NewSource[T any](dataSourceName string) *Source[T] {
-	return &Source[T]{
+func NewSource[T any](dataSourceName string, options ...SourceOption) *Source[T] {
+	s := &Source[T]{
 		dataSourceName: dataSourceName,
 		bufferPool: &sync.Pool{
 			New: func() interface{} {
@@ -29,17 +48,32 @@ func NewSource[T any](dataSourceName string) *Source[T] {
 			},
 		},
 	}
+	for _, option := range options {
+		option(&s.options)
+	}
+	return s
 }
 
+// Open maps the file and validates its header, files without a header are read as raw records
 func (s *Source[T]) Open() error {
 	var err error
 	s.reader, err = mmap.Open(s.dataSourceName)
 	if err != nil {
 		return fmt.Errorf("unable to open data source %q: %w", s.dataSourceName, err)
 	}
+
+	if err := s.readHeader(); err != nil {
+		_ = s.reader.Close()
+		return fmt.Errorf("unable to open data source %q: %w", s.dataSourceName, err)
+	}
 	return nil
 }
 
+// Header returns the header of the file, false for files without one
+func (s *Source[T]) Header() (Header, bool) {
+	return s.header, s.hasHeader
+}
+
 func (s *Source[T]) Close() {
 	_ = s.reader.Close()
 }
@@ -48,7 +82,10 @@ func (s *Source[T]) Read(index int64, data *T) error {
 	buffer := s.bufferPool.Get().(*[]byte)
 	defer s.bufferPool.Put(buffer)
 
-	offset := index * int64(len(*buffer))
+	if index < 0 || index >= s.count {
+		return ErrEof
+	}
+	offset := s.offset + index*int64(len(*buffer))
 
 	n, err := s.reader.ReadAt(*buffer, offset)
 	if err != nil && err != io.EOF {
@@ -63,22 +100,52 @@ func (s *Source[T]) Read(index int64, data *T) error {
 }
 
 func (s *Source[T]) EntryCount() (int64, error) {
+	if s.reader == nil {
+		return 0, fmt.Errorf("data source %q is not open", s.dataSourceName)
+	}
+	return s.count, nil
+}
 
+func (s *Source[T]) readHeader() error {
 	var entry T
 	entrySize := int64(unsafe.Sizeof(entry))
 	if entrySize == 0 {
-		return 0, fmt.Errorf("size of T is zero")
+		return fmt.Errorf("size of T is zero")
 	}
 
-	fileInfo, err := os.Stat(s.dataSourceName)
-	if err != nil {
-		return 0, fmt.Errorf("unable to get data source %q stats: %w", s.dataSourceName, err)
+	size := int64(s.reader.Len())
+	magic := make([]byte, len(headerMagic))
+	if size < int64(len(magic)) || !hasHeader(s.readBytes(magic, 0)) {
+		if size%entrySize != 0 {
+			return fmt.Errorf("%w: file size %d is not a multiple of entry size %d", ErrCorrupt, size, entrySize)
+		}
+		s.header, s.hasHeader = Header{}, false
+		s.offset, s.count = 0, size/entrySize
+		return nil
 	}
 
-	totalSize := fileInfo.Size()
-	if totalSize%entrySize != 0 {
-		return 0, fmt.Errorf("file size is not a multiple of entry size")
+	if size < HeaderSize {
+		return fmt.Errorf("%w: truncated header", ErrCorrupt)
 	}
+	var header Header
+	if err := header.UnmarshalBinary(s.readBytes(make([]byte, HeaderSize), 0)); err != nil {
+		return err
+	}
+	if err := checkHeader[T](header, size-HeaderSize); err != nil {
+		return err
+	}
+	if !s.options.skipChecksum {
+		if err := verifyChecksum(s.reader, header.CRC); err != nil {
+			return err
+		}
+	}
+
+	s.header, s.hasHeader = header, true
+	s.offset, s.count = HeaderSize, header.Count
+	return nil
+}
 
-	return totalSize / entrySize, nil
+func (s *Source[T]) readBytes(buffer []byte, offset int64) []byte {
+	n, _ := s.reader.ReadAt(buffer, offset)
+	return buffer[:n]
 }
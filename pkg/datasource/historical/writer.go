@@ -0,0 +1,57 @@
+package historical
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// WriteEntries writes entries in the raw layout Source reads them in, without a header
+func WriteEntries[T any](w io.Writer, entries []T) error {
+	if _, err := w.Write(entriesBytes(entries)); err != nil {
+		return fmt.Errorf("unable to write entries: %w", err)
+	}
+	return nil
+}
+
+// WriteFile writes entries ordered by timestamp to a file behind a header describing them, the file is replaced
+// only once it is complete
+func WriteFile[T timestamped](name, symbol string, digits int, entries []T) error {
+	header, err := NewHeader(symbol, digits, entries).MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("unable to write %q: %w", name, err)
+	}
+
+	return writeFile(name, func(w *os.File) error {
+		if _, err := w.Write(header); err != nil {
+			return fmt.Errorf("unable to write header: %w", err)
+		}
+		return WriteEntries(w, entries)
+	})
+}
+
+func writeFile(name string, write func(f *os.File) error) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("unable to create %q: %w", name, err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+
+	if err := f.Chmod(0o644); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("unable to create %q: %w", name, err)
+	}
+
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("unable to write %q: %w", name, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to write %q: %w", name, err)
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		return fmt.Errorf("unable to write %q: %w", name, err)
+	}
+	return nil
+}
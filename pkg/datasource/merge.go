@@ -0,0 +1,172 @@
+package datasource
+
+import (
+	"container/heap"
+	"errors"
+	"io"
+	"log/slog"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/datasource/historical"
+	"github.com/peter-kozarec/equinox/pkg/datasource/synthetic"
+	"github.com/peter-kozarec/equinox/pkg/utility"
+)
+
+type MergeOption func(*TickMerger)
+
+// WithExhaustionHandler is called once a source runs out of ticks, with its index and the symbol of its last tick
+func WithExhaustionHandler(handler func(source int, symbol string)) MergeOption {
+	return func(m *TickMerger) {
+		m.onExhausted = handler
+	}
+}
+
+// WithEndErrors adds errors marking the end of a source, the end errors of the historical and synthetic sources
+// and io.EOF are known already
+func WithEndErrors(errs ...error) MergeOption {
+	return func(m *TickMerger) {
+		m.endErrors = append(m.endErrors, errs...)
+	}
+}
+
+// TickMerger merges the ticks of several sources, e.g. one per symbol, in timestamp order. Ticks with the same
+// timestamp are returned in the order of their sources. A source running out of ticks is dropped, the merger
+// ends with the end error of the last source once all of them ran out.
+type TickMerger struct {
+	sources     []TickDataSource
+	symbols     []string
+	onExhausted func(source int, symbol string)
+	endErrors   []error
+
+	queue     mergeQueue
+	exhausted []int
+	// filled counts the sources read once, the first call reads all of them before merging
+	filled int
+	err    error
+}
+
+func NewTickMerger(sources []TickDataSource, options ...MergeOption) *TickMerger {
+	m := &TickMerger{
+		sources:   sources,
+		symbols:   make([]string, len(sources)),
+		endErrors: []error{historical.ErrEof, synthetic.ErrEof, io.EOF},
+		queue:     make(mergeQueue, 0, len(sources)),
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+// SetIDGenerator passes the generator on to the sources implementing IDStamper
+func (m *TickMerger) SetIDGenerator(ids *utility.IDGenerator) {
+	for _, source := range m.sources {
+		if s, ok := source.(IDStamper); ok {
+			s.SetIDGenerator(ids)
+		}
+	}
+}
+
+// Active returns the number of sources which did not run out of ticks yet
+func (m *TickMerger) Active() int {
+	return len(m.queue) + len(m.sources) - m.filled
+}
+
+func (m *TickMerger) GetNext() (common.Tick, error) {
+	m.reportExhausted()
+
+	// a failed source is read again on the next call, the sources before it are not
+	for ; m.filled < len(m.sources); m.filled++ {
+		if err := m.advance(m.filled); err != nil {
+			return common.Tick{}, err
+		}
+	}
+
+	if len(m.queue) == 0 {
+		m.reportExhausted()
+		if m.err == nil {
+			m.err = historical.ErrEof
+		}
+		return common.Tick{}, m.err
+	}
+
+	next := m.queue[0]
+	if err := m.advance(next.source); err != nil {
+		return common.Tick{}, err
+	}
+	return next.tick, nil
+}
+
+// advance reads the next tick of the source into the queue, replacing its current one. An exhausted source
+// leaves the queue.
+func (m *TickMerger) advance(source int) error {
+	tick, err := m.sources[source].GetNext()
+	if err != nil {
+		if !m.isEnd(err) {
+			return err
+		}
+		m.err = err
+		m.exhausted = append(m.exhausted, source)
+		if len(m.queue) > 0 && m.queue[0].source == source {
+			heap.Remove(&m.queue, 0)
+		}
+		return nil
+	}
+
+	m.symbols[source] = tick.Symbol
+	item := mergeItem{tick: tick, source: source}
+	if len(m.queue) > 0 && m.queue[0].source == source {
+		m.queue[0] = item
+		heap.Fix(&m.queue, 0)
+	} else {
+		heap.Push(&m.queue, item)
+	}
+	return nil
+}
+
+// reportExhausted reports the sources which ran out of ticks once their last tick was returned
+func (m *TickMerger) reportExhausted() {
+	for _, source := range m.exhausted {
+		slog.Info("tick source exhausted", "source", source, "symbol", m.symbols[source])
+		if m.onExhausted != nil {
+			m.onExhausted(source, m.symbols[source])
+		}
+	}
+	m.exhausted = m.exhausted[:0]
+}
+
+func (m *TickMerger) isEnd(err error) bool {
+	for _, end := range m.endErrors {
+		if errors.Is(err, end) {
+			return true
+		}
+	}
+	return false
+}
+
+type mergeItem struct {
+	tick   common.Tick
+	source int
+}
+
+type mergeQueue []mergeItem
+
+func (q mergeQueue) Len() int { return len(q) }
+
+func (q mergeQueue) Less(i, j int) bool {
+	if !q[i].tick.TimeStamp.Equal(q[j].tick.TimeStamp) {
+		return q[i].tick.TimeStamp.Before(q[j].tick.TimeStamp)
+	}
+	return q[i].source < q[j].source
+}
+
+func (q mergeQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *mergeQueue) Push(x any) { *q = append(*q, x.(mergeItem)) }
+
+func (q *mergeQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
@@ -0,0 +1,209 @@
+package datasource
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/datasource/historical"
+)
+
+// testTickSource returns its errors first, then its ticks and then its end error
+type testTickSource struct {
+	errs  []error
+	ticks []common.Tick
+	end   error
+	calls int
+}
+
+func newTestTickSource(symbol string, seconds ...int) *testTickSource {
+	s := &testTickSource{end: historical.ErrEof}
+	for _, second := range seconds {
+		s.ticks = append(s.ticks, common.Tick{Symbol: symbol, TimeStamp: time.Unix(int64(second), 0)})
+	}
+	return s
+}
+
+func (s *testTickSource) GetNext() (common.Tick, error) {
+	s.calls++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return common.Tick{}, err
+	}
+	if len(s.ticks) == 0 {
+		return common.Tick{}, s.end
+	}
+	tick := s.ticks[0]
+	s.ticks = s.ticks[1:]
+	return tick, nil
+}
+
+type mergedTick struct {
+	symbol string
+	second int64
+}
+
+func mergeAll(t *testing.T, merger *TickMerger) ([]mergedTick, error) {
+	t.Helper()
+
+	var merged []mergedTick
+	for {
+		tick, err := merger.GetNext()
+		if err != nil {
+			return merged, err
+		}
+		merged = append(merged, mergedTick{tick.Symbol, tick.TimeStamp.Unix()})
+	}
+}
+
+func TestTickMerger_Order(t *testing.T) {
+	merger := NewTickMerger([]TickDataSource{
+		newTestTickSource("EURUSD", 1, 4, 4, 9),
+		newTestTickSource("GBPUSD", 2, 3, 4, 8),
+		newTestTickSource("USDJPY", 1, 5, 6, 7, 10),
+	})
+
+	merged, err := mergeAll(t, merger)
+	assert.ErrorIs(t, err, historical.ErrEof)
+	assert.Equal(t, []mergedTick{
+		{"EURUSD", 1},
+		{"USDJPY", 1},
+		{"GBPUSD", 2},
+		{"GBPUSD", 3},
+		{"EURUSD", 4},
+		{"EURUSD", 4},
+		{"GBPUSD", 4},
+		{"USDJPY", 5},
+		{"USDJPY", 6},
+		{"USDJPY", 7},
+		{"GBPUSD", 8},
+		{"EURUSD", 9},
+		{"USDJPY", 10},
+	}, merged)
+	assert.Zero(t, merger.Active())
+
+	_, err = merger.GetNext()
+	assert.ErrorIs(t, err, historical.ErrEof, "the merger stays at its end")
+}
+
+func TestTickMerger_TiesFollowSourceOrder(t *testing.T) {
+	merger := NewTickMerger([]TickDataSource{
+		newTestTickSource("C", 1, 2),
+		newTestTickSource("A", 1, 2),
+		newTestTickSource("B", 1, 2),
+	})
+
+	merged, err := mergeAll(t, merger)
+	assert.ErrorIs(t, err, historical.ErrEof)
+	assert.Equal(t, []mergedTick{{"C", 1}, {"A", 1}, {"B", 1}, {"C", 2}, {"A", 2}, {"B", 2}}, merged)
+}
+
+func TestTickMerger_EmptySource(t *testing.T) {
+	type exhausted struct {
+		source int
+		symbol string
+	}
+	var calls []exhausted
+
+	merger := NewTickMerger([]TickDataSource{
+		newTestTickSource("EURUSD", 1, 3),
+		newTestTickSource("GBPUSD"),
+		newTestTickSource("USDJPY", 2),
+	}, WithExhaustionHandler(func(source int, symbol string) {
+		calls = append(calls, exhausted{source, symbol})
+	}))
+	assert.Equal(t, 3, merger.Active())
+
+	merged, err := mergeAll(t, merger)
+	assert.ErrorIs(t, err, historical.ErrEof)
+	assert.Equal(t, []mergedTick{{"EURUSD", 1}, {"USDJPY", 2}, {"EURUSD", 3}}, merged)
+	assert.Equal(t, []exhausted{{1, ""}, {2, "USDJPY"}, {0, "EURUSD"}}, calls)
+}
+
+func TestTickMerger_ExhaustionHandler(t *testing.T) {
+	type exhausted struct {
+		source   int
+		symbol   string
+		returned int
+	}
+	var calls []exhausted
+	returned := 0
+
+	merger := NewTickMerger([]TickDataSource{
+		newTestTickSource("EURUSD", 1, 2),
+		newTestTickSource("GBPUSD", 3, 4, 5),
+		newTestTickSource("USDJPY", 1, 2, 3, 4, 5, 6),
+	}, WithExhaustionHandler(func(source int, symbol string) {
+		calls = append(calls, exhausted{source, symbol, returned})
+	}))
+
+	var active []int
+	for {
+		_, err := merger.GetNext()
+		if err != nil {
+			assert.ErrorIs(t, err, historical.ErrEof)
+			break
+		}
+		returned++
+		active = append(active, merger.Active())
+	}
+
+	assert.Equal(t, 11, returned)
+	// each source is reported once, on the call after the one returning its last tick
+	assert.Equal(t, []exhausted{{0, "EURUSD", 3}, {1, "GBPUSD", 9}, {2, "USDJPY", 11}}, calls)
+	assert.Equal(t, []int{3, 3, 2, 2, 2, 2, 2, 2, 1, 1, 0}, active, "the other sources keep running")
+}
+
+func TestTickMerger_Errors(t *testing.T) {
+	errFailed := errors.New("failed")
+	errDone := errors.New("done")
+
+	t.Run("other errors are returned as they are", func(t *testing.T) {
+		source := newTestTickSource("GBPUSD", 2)
+		source.end = errFailed
+
+		merger := NewTickMerger([]TickDataSource{newTestTickSource("EURUSD", 1, 4, 5), source})
+
+		merged, err := mergeAll(t, merger)
+		assert.Equal(t, errFailed, err)
+		assert.Equal(t, []mergedTick{{"EURUSD", 1}}, merged)
+		assert.Equal(t, 2, merger.Active(), "the failed source is not dropped")
+	})
+
+	t.Run("end errors end the source", func(t *testing.T) {
+		eof := newTestTickSource("EURUSD", 1)
+		eof.end = io.EOF
+		done := newTestTickSource("GBPUSD", 2)
+		done.end = errDone
+
+		merger := NewTickMerger([]TickDataSource{eof, done}, WithEndErrors(errDone))
+
+		merged, err := mergeAll(t, merger)
+		assert.Equal(t, errDone, err, "the merger ends with the end error of the last source")
+		assert.Equal(t, []mergedTick{{"EURUSD", 1}, {"GBPUSD", 2}}, merged)
+	})
+
+	t.Run("first fill is resumed after a failure", func(t *testing.T) {
+		first := newTestTickSource("EURUSD", 1, 3)
+		failing := newTestTickSource("GBPUSD", 2)
+		failing.errs = []error{errFailed}
+		last := newTestTickSource("USDJPY", 1)
+
+		merger := NewTickMerger([]TickDataSource{first, failing, last})
+
+		_, err := merger.GetNext()
+		require.Equal(t, errFailed, err)
+		assert.Equal(t, 0, last.calls, "sources after the failed one are not read yet")
+
+		merged, err := mergeAll(t, merger)
+		assert.ErrorIs(t, err, historical.ErrEof)
+		assert.Equal(t, []mergedTick{{"EURUSD", 1}, {"USDJPY", 1}, {"GBPUSD", 2}, {"EURUSD", 3}}, merged)
+		assert.Equal(t, 3, first.calls, "the source read before the failure is not read again")
+	})
+}
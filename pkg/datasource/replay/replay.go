@@ -0,0 +1,122 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/utility"
+)
+
+type Option func(*Replay)
+
+// StopAtSequence stops the replay after the entry with the given sequence number was posted
+func StopAtSequence(seq uint64) Option {
+	return func(r *Replay) {
+		r.stopSeq = seq
+	}
+}
+
+// StopAtTraceID stops the replay after the first entry with the given trace id was posted
+func StopAtTraceID(tid utility.TraceID) Option {
+	return func(r *Replay) {
+		r.stopTid = tid
+	}
+}
+
+// WithEvents replays only the given events, the rest of the journal is skipped. It is meant for
+// re-driving the inputs of a run, e.g. ticks, while the components under test produce the rest again.
+func WithEvents(ids ...bus.EventId) Option {
+	return func(r *Replay) {
+		if r.events == nil {
+			r.events = make(map[bus.EventId]struct{}, len(ids))
+		}
+		for _, id := range ids {
+			r.events[id] = struct{}{}
+		}
+	}
+}
+
+// Replay posts the events recorded by a bus.Journal to a router
+type Replay struct {
+	router *bus.Router
+	reader *bus.JournalReader
+	closer io.Closer
+
+	stopSeq uint64
+	stopTid utility.TraceID
+	events  map[bus.EventId]struct{}
+
+	last bus.JournalEntry
+	done bool
+}
+
+func NewReplay(router *bus.Router, r io.Reader, options ...Option) *Replay {
+	rp := &Replay{
+		router: router,
+		reader: bus.NewJournalReader(r),
+	}
+
+	for _, option := range options {
+		option(rp)
+	}
+
+	return rp
+}
+
+// Open creates a replay reading the journal from the given file
+func Open(router *bus.Router, journalName string, options ...Option) (*Replay, error) {
+	f, err := os.Open(journalName) // #nosec G304
+	if err != nil {
+		return nil, fmt.Errorf("unable to open journal %q: %w", journalName, err)
+	}
+
+	rp := NewReplay(router, f, options...)
+	rp.closer = f
+	return rp, nil
+}
+
+func (r *Replay) Close() {
+	if r.closer != nil {
+		_ = r.closer.Close()
+	}
+}
+
+// PostNext posts the next replayed event to the router. It returns io.EOF when the journal is exhausted
+// or the stop condition was reached. It can be passed to bus.Router.ExecLoop directly.
+func (r *Replay) PostNext() error {
+	for !r.done {
+		entry, err := r.reader.Next()
+		if errors.Is(err, io.EOF) {
+			r.done = true
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		r.last = entry
+		r.done = r.stopSeq > 0 && entry.Sequence >= r.stopSeq ||
+			r.stopTid > 0 && entry.TraceID == r.stopTid
+
+		if r.events != nil {
+			if _, ok := r.events[entry.Event]; !ok {
+				continue
+			}
+		}
+
+		data, err := r.router.DecodePayload(entry)
+		if err != nil {
+			return fmt.Errorf("unable to replay entry %d: %w", entry.Sequence, err)
+		}
+		return r.router.Post(entry.Event, data)
+	}
+	return io.EOF
+}
+
+// Last returns the last entry read from the journal
+func (r *Replay) Last() bus.JournalEntry {
+	return r.last
+}
@@ -0,0 +1,109 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+type dispatched struct {
+	event bus.EventId
+	tid   uint64
+}
+
+func recordDispatch(r *bus.Router, log *[]dispatched) {
+	r.OnTick = func(_ context.Context, tick common.Tick) {
+		*log = append(*log, dispatched{bus.TickEvent, tick.TraceID})
+	}
+	r.OnOrder = func(_ context.Context, order common.Order) {
+		*log = append(*log, dispatched{bus.OrderEvent, order.TraceID})
+	}
+}
+
+func journalRun(t *testing.T) (*bytes.Buffer, []dispatched) {
+	var buf bytes.Buffer
+	journal := bus.NewJournal(&buf)
+	router := bus.NewRouter(100, bus.WithJournal(journal))
+
+	var live []dispatched
+	recordDispatch(router, &live)
+	onTick := router.OnTick
+	router.OnTick = func(ctx context.Context, tick common.Tick) {
+		onTick(ctx, tick)
+		if tick.TraceID%2 == 0 {
+			require.NoError(t, router.Post(bus.OrderEvent, common.Order{Symbol: "EURUSD", TraceID: tick.TraceID * 100}))
+		}
+	}
+
+	for tid := uint64(1); tid <= 6; tid++ {
+		require.NoError(t, router.Post(bus.TickEvent, common.Tick{Symbol: "EURUSD", Bid: fixed.FromFloat64(1.1), TraceID: tid}))
+		if tid%3 == 0 {
+			require.NoError(t, router.DrainEvents(context.Background()))
+		}
+	}
+	require.NoError(t, router.DrainEvents(context.Background()))
+	require.NoError(t, journal.Flush())
+	return &buf, live
+}
+
+func replayAll(t *testing.T, router *bus.Router, rp *Replay) {
+	for {
+		err := rp.PostNext()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		require.NoError(t, err)
+		require.NoError(t, router.DrainEvents(context.Background()))
+	}
+}
+
+func TestReplay_IdenticalDispatch(t *testing.T) {
+	journal, live := journalRun(t)
+	require.Len(t, live, 9)
+
+	router := bus.NewRouter(100)
+	var replayed []dispatched
+	recordDispatch(router, &replayed)
+
+	replayAll(t, router, NewReplay(router, journal))
+	assert.Equal(t, live, replayed)
+}
+
+func TestReplay_Options(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		want    int
+		last    uint64
+	}{
+		{name: "stop at sequence", options: []Option{StopAtSequence(4)}, want: 4, last: 4},
+		{name: "stop at trace id", options: []Option{StopAtTraceID(200)}, want: 4, last: 4},
+		{name: "ticks only", options: []Option{WithEvents(bus.TickEvent)}, want: 6, last: 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			journal, _ := journalRun(t)
+
+			router := bus.NewRouter(100)
+			var replayed []dispatched
+			recordDispatch(router, &replayed)
+
+			rp := NewReplay(router, journal, tt.options...)
+			replayAll(t, router, rp)
+
+			assert.Len(t, replayed, tt.want)
+			assert.Equal(t, tt.last, rp.Last().Sequence)
+			assert.ErrorIs(t, rp.PostNext(), io.EOF)
+		})
+	}
+}
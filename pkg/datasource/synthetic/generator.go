@@ -51,6 +51,8 @@ type TickGenerator struct {
 
 	normPriceDigits  int
 	normVolumeDigits int
+
+	ids *utility.IDGenerator
 }
 
 func NewTickGenerator(
@@ -113,6 +115,11 @@ func (e *TickGenerator) SetVolumeDigits(digits int) {
 	e.normVolumeDigits = digits
 }
 
+// SetIDGenerator stamps the ticks with the ids of the run, see datasource.IDStamper
+func (e *TickGenerator) SetIDGenerator(ids *utility.IDGenerator) {
+	e.ids = ids
+}
+
 func (e *TickGenerator) GetNext() (common.Tick, error) {
 	var tick common.Tick
 
@@ -148,8 +155,8 @@ func (e *TickGenerator) GetNext() (common.Tick, error) {
 
 	tick.Source = tickGeneratorComponentName
 	tick.Symbol = e.symbol
-	tick.ExecutionId = utility.GetExecutionID()
-	tick.TraceID = utility.CreateTraceID()
+	tick.ExecutionId = e.ids.ExecutionID()
+	tick.TraceID = e.ids.TraceID()
 
 	return tick, nil
 }
@@ -0,0 +1,111 @@
+package ctrader
+
+import (
+	"log/slog"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/exchange/ctrader/openapi"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+func (state *State) OnMarginChangedEvent(msg *openapi.ProtoMessage) {
+	var v openapi.ProtoOAMarginChangedEvent
+
+	if err := proto.Unmarshal(msg.GetPayload(), &v); err != nil {
+		slog.Warn("unable to unmarshal margin changed event", "error", err)
+		return
+	}
+
+	state.setUsedMargin(int64(v.GetPositionId()), fixed.FromUint64(v.GetUsedMargin(), int(v.GetMoneyDigits())))
+	state.postAccount()
+}
+
+func (state *State) OnTraderUpdateEvent(msg *openapi.ProtoMessage) {
+	var v openapi.ProtoOATraderUpdatedEvent
+
+	if err := proto.Unmarshal(msg.GetPayload(), &v); err != nil {
+		slog.Warn("unable to unmarshal trader updated event", "error", err)
+		return
+	}
+
+	trader := v.GetTrader()
+	if trader == nil {
+		return
+	}
+	state.setBalance(fixed.FromInt64(trader.GetBalance(), int(trader.GetMoneyDigits())))
+	state.postAccount()
+}
+
+// OnMarginCallTriggerEvent posts the margin call the server triggered, stop outs are reported by the server as
+// ordinary position closes
+func (state *State) OnMarginCallTriggerEvent(msg *openapi.ProtoMessage) {
+	var v openapi.ProtoOAMarginCallTriggerEvent
+
+	if err := proto.Unmarshal(msg.GetPayload(), &v); err != nil {
+		slog.Warn("unable to unmarshal margin call trigger event", "error", err)
+		return
+	}
+
+	usedMargin := state.usedMargin()
+	marginCall := common.MarginCall{
+		MarginLevel: common.MarginLevel(state.equity, usedMargin),
+		CallLevel:   fixed.FromFloat64(v.GetMarginCall().GetMarginLevelThreshold()),
+		Equity:      state.equity,
+		UsedMargin:  usedMargin,
+		Source:      openapiComponentName,
+		ExecutionId: state.router.IDs().ExecutionID(),
+		TraceID:     state.router.IDs().TraceID(),
+		TimeStamp:   state.clock.Now(),
+	}
+	if err := state.router.Post(bus.MarginCallEvent, marginCall); err != nil {
+		slog.Warn("unable to post margin call event", "error", err)
+	}
+}
+
+func (state *State) usedMargin() fixed.Point {
+	state.balanceMu.Lock()
+	defer state.balanceMu.Unlock()
+
+	usedMargin := fixed.Zero
+	for _, margin := range state.usedMargins {
+		usedMargin = usedMargin.Add(margin)
+	}
+	return usedMargin
+}
+
+func (state *State) setUsedMargin(positionId int64, usedMargin fixed.Point) {
+	state.balanceMu.Lock()
+	defer state.balanceMu.Unlock()
+
+	if usedMargin.IsZero() {
+		delete(state.usedMargins, positionId)
+		return
+	}
+	state.usedMargins[positionId] = usedMargin
+}
+
+// postAccount publishes the account snapshot, the used margin is the sum of the last margin reported per position
+func (state *State) postAccount() {
+	usedMargin := state.usedMargin()
+	var balance fixed.Point
+	state.getBalance(&balance)
+
+	account := common.Account{
+		Balance:       balance,
+		Equity:        state.equity,
+		UsedMargin:    usedMargin,
+		FreeMargin:    state.equity.Sub(usedMargin),
+		MarginLevel:   common.MarginLevel(state.equity, usedMargin),
+		OpenPositions: len(state.openPositions),
+		Source:        openapiComponentName,
+		ExecutionId:   state.router.IDs().ExecutionID(),
+		TraceID:       state.router.IDs().TraceID(),
+		TimeStamp:     state.clock.Now(),
+	}
+	if err := state.router.Post(bus.AccountEvent, account); err != nil {
+		slog.Warn("unable to post account event", "error", err)
+	}
+}
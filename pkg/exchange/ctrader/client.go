@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"strings"
 	"time"
 
@@ -155,6 +156,23 @@ func (client *Client) SubscribeSpots(ctx context.Context, accountId int64, symbo
 	return nil
 }
 
+func (client *Client) SubscribeDepthQuotes(ctx context.Context, accountId int64, symbolInfo exchange.SymbolInfo, cb func(*openapi.ProtoMessage)) error {
+
+	depthReq := &openapi.ProtoOASubscribeDepthQuotesReq{CtidTraderAccountId: &accountId, SymbolId: []int64{symbolInfo.SymbolId}}
+	depthResp := &openapi.ProtoOASubscribeDepthQuotesRes{}
+
+	if err := sendReceive(ctx, client.conn, depthReq, depthResp); err != nil {
+		return fmt.Errorf("unable to perform subscribe depth quotes request: %w", err)
+	}
+
+	_, err := subscribe(client.conn, openapi.ProtoOAPayloadType_PROTO_OA_DEPTH_EVENT, cb)
+	if err != nil {
+		slog.Warn("unable to subscribe", "error", err)
+	}
+
+	return nil
+}
+
 func (client *Client) GetOpenPositions(ctx context.Context, accountId int64) ([]*openapi.ProtoOAPosition, error) {
 
 	req := &openapi.ProtoOAReconcileReq{CtidTraderAccountId: &accountId}
@@ -185,10 +203,12 @@ func (client *Client) OpenPosition(
 	ctx context.Context,
 	accountId int64,
 	symbolInfo exchange.SymbolInfo,
-	openPrice, size, stopLoss, takeProfit fixed.Point,
-	orderType common.OrderType) error {
+	openPrice, stopPrice, size, stopLoss, takeProfit, trailingDistance fixed.Point,
+	orderType common.OrderType,
+	clientOrderId string) error {
 
-	var limitPrice, sl, tp *float64 = nil, nil, nil
+	var limitPrice, stopTrigger, sl, tp *float64 = nil, nil, nil, nil
+	var slippageInPoints *int32
 
 	var ot openapi.ProtoOAOrderType
 	switch orderType {
@@ -198,6 +218,19 @@ func (client *Client) OpenPosition(
 		ot = openapi.ProtoOAOrderType_LIMIT
 		price, _ := openPrice.Float64()
 		limitPrice = &price
+	case common.OrderTypeStop:
+		ot = openapi.ProtoOAOrderType_STOP
+		price, _ := stopPrice.Float64()
+		stopTrigger = &price
+	case common.OrderTypeStopLimit:
+		// cTrader expresses the limit of a stop limit order as the allowed distance from the stop price in points
+		ot = openapi.ProtoOAOrderType_STOP_LIMIT
+		price, _ := stopPrice.Float64()
+		stopTrigger = &price
+		points := pointDistance(symbolInfo, openPrice, stopPrice)
+		slippageInPoints = &points
+	default:
+		return fmt.Errorf("unsupported order type: %d", orderType)
 	}
 
 	var ts openapi.ProtoOATradeSide
@@ -219,6 +252,20 @@ func (client *Client) OpenPosition(
 		tp = &tpF
 	}
 
+	// The native trailing stop keeps the distance of the initial stop loss, with no stop loss given
+	// the distance is set relative to the entry, in 1/100000 of a price unit
+	var trailing *bool
+	var relativeSl *int64
+	if !trailingDistance.IsZero() {
+		t := true
+		trailing = &t
+		if sl == nil {
+			distance, _ := trailingDistance.Float64()
+			relative := int64(math.Round(distance * 100000))
+			relativeSl = &relative
+		}
+	}
+
 	req := &openapi.ProtoOANewOrderReq{
 		CtidTraderAccountId: &accountId,
 		SymbolId:            &symbolInfo.SymbolId,
@@ -228,11 +275,102 @@ func (client *Client) OpenPosition(
 		OrderType:           &ot,
 		Volume:              &volume,
 		LimitPrice:          limitPrice,
+		StopPrice:           stopTrigger,
+		SlippageInPoints:    slippageInPoints,
+		RelativeStopLoss:    relativeSl,
+		TrailingStopLoss:    trailing,
+	}
+	if clientOrderId != "" {
+		req.ClientOrderId = &clientOrderId
+	}
+
+	return send(ctx, client.conn, req)
+}
+
+// AmendPositionSLTP replaces the stop loss and take profit of an open position, a zero price removes it.
+// A non zero trailing distance makes the stop loss trail, keeping its distance from the market
+func (client *Client) AmendPositionSLTP(ctx context.Context, accountId, positionId int64, stopLoss, takeProfit, trailingDistance fixed.Point) error {
+	return send(ctx, client.conn, newAmendPositionSLTPReq(accountId, positionId, stopLoss, takeProfit, trailingDistance))
+}
+
+func newAmendPositionSLTPReq(accountId, positionId int64, stopLoss, takeProfit, trailingDistance fixed.Point) *openapi.ProtoOAAmendPositionSLTPReq {
+	req := &openapi.ProtoOAAmendPositionSLTPReq{
+		CtidTraderAccountId: &accountId,
+		PositionId:          &positionId,
+	}
+	if !stopLoss.IsZero() {
+		sl, _ := stopLoss.Float64()
+		req.StopLoss = &sl
+	}
+	if !takeProfit.IsZero() {
+		tp, _ := takeProfit.Float64()
+		req.TakeProfit = &tp
+	}
+	if !trailingDistance.IsZero() {
+		trailing := true
+		req.TrailingStopLoss = &trailing
+	}
+	return req
+}
+
+func (client *Client) CancelOrder(ctx context.Context, accountId, orderId int64) error {
+	req := &openapi.ProtoOACancelOrderReq{
+		CtidTraderAccountId: &accountId,
+		OrderId:             &orderId,
+	}
+
+	return send(ctx, client.conn, req)
+}
+
+// AmendOrder replaces the price, stop price, size and expire time of a pending order, a zero expire time
+// leaves the expiration unchanged
+func (client *Client) AmendOrder(
+	ctx context.Context,
+	accountId, orderId int64,
+	symbolInfo exchange.SymbolInfo,
+	price, stopPrice, size fixed.Point,
+	expireTime time.Time,
+	orderType common.OrderType) error {
+
+	vol, _ := size.Abs().MulInt(100).Float64()
+	volume := int64(vol)
+
+	req := &openapi.ProtoOAAmendOrderReq{
+		CtidTraderAccountId: &accountId,
+		OrderId:             &orderId,
+		Volume:              &volume,
+	}
+
+	switch orderType {
+	case common.OrderTypeLimit:
+		limitPrice, _ := price.Float64()
+		req.LimitPrice = &limitPrice
+	case common.OrderTypeStop:
+		stopTrigger, _ := stopPrice.Float64()
+		req.StopPrice = &stopTrigger
+	case common.OrderTypeStopLimit:
+		stopTrigger, _ := stopPrice.Float64()
+		req.StopPrice = &stopTrigger
+		points := pointDistance(symbolInfo, price, stopPrice)
+		req.SlippageInPoints = &points
+	default:
+		return fmt.Errorf("unsupported order type for amend: %d", orderType)
+	}
+
+	if !expireTime.IsZero() {
+		expiration := expireTime.UnixMilli()
+		req.ExpirationTimestamp = &expiration
 	}
 
 	return send(ctx, client.conn, req)
 }
 
+// pointDistance returns the distance of the prices in points, the smallest price increment of the symbol
+func pointDistance(symbolInfo exchange.SymbolInfo, a, b fixed.Point) int32 {
+	distance, _ := a.Sub(b).Abs().Float64()
+	return int32(math.Round(distance * math.Pow10(symbolInfo.Digits)))
+}
+
 func (client *Client) keepAlive(interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	go func() {
@@ -0,0 +1,20 @@
+package ctrader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+func TestNewAmendPositionSLTPReq(t *testing.T) {
+	req := newAmendPositionSLTPReq(7, 1, fixed.FromFloat64(1.0950), fixed.Zero, fixed.Zero)
+	assert.Equal(t, 1.0950, req.GetStopLoss())
+	assert.Nil(t, req.TakeProfit, "a zero price is removed")
+	assert.Nil(t, req.TrailingStopLoss)
+
+	req = newAmendPositionSLTPReq(7, 1, fixed.FromFloat64(1.0950), fixed.FromFloat64(1.1100), fixed.FromFloat64(0.0015))
+	assert.Equal(t, 1.1100, req.GetTakeProfit())
+	assert.True(t, req.GetTrailingStopLoss(), "a trailing distance makes the stop loss trail")
+}
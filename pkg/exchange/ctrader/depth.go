@@ -0,0 +1,86 @@
+package ctrader
+
+import (
+	"log/slog"
+	"sort"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/exchange/ctrader/openapi"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+// OnDepthEvent applies the incremental depth quotes to the book of the symbol and posts the resulting order book.
+// The events of a subscription are decoded one at a time, so the quotes need no locking.
+func (state *State) OnDepthEvent(msg *openapi.ProtoMessage) {
+	var v openapi.ProtoOADepthEvent
+
+	if err := proto.Unmarshal(msg.GetPayload(), &v); err != nil {
+		slog.Warn("unable to unmarshal depth event", "error", err)
+		return
+	}
+	if int64(v.GetSymbolId()) != state.symbolInfo.SymbolId {
+		return
+	}
+
+	for _, id := range v.GetDeletedQuotes() {
+		delete(state.depthQuotes, id)
+	}
+	for _, quote := range v.GetNewQuotes() {
+		state.depthQuotes[quote.GetId()] = quote
+	}
+
+	book := state.orderBook()
+	if err := state.router.Post(bus.DepthEvent, book); err != nil {
+		slog.Warn("unable to post depth event", "error", err)
+	}
+}
+
+// orderBook aggregates the depth quotes by price
+func (state *State) orderBook() common.OrderBook {
+	bids := make(map[uint64]uint64)
+	asks := make(map[uint64]uint64)
+	for _, quote := range state.depthQuotes {
+		if quote.GetBid() != 0 {
+			bids[quote.GetBid()] += quote.GetSize()
+		}
+		if quote.GetAsk() != 0 {
+			asks[quote.GetAsk()] += quote.GetSize()
+		}
+	}
+
+	return common.OrderBook{
+		Bids:        state.bookLevels(bids, true),
+		Asks:        state.bookLevels(asks, false),
+		Source:      openapiComponentName,
+		Symbol:      state.symbolInfo.SymbolName,
+		ExecutionId: state.router.IDs().ExecutionID(),
+		TraceID:     state.router.IDs().TraceID(),
+		TimeStamp:   state.clock.Now(),
+	}
+}
+
+// bookLevels sorts the aggregated quotes, the sizes are converted from cTrader volume in cents to lots
+func (state *State) bookLevels(sizes map[uint64]uint64, descending bool) []common.BookLevel {
+	prices := make([]uint64, 0, len(sizes))
+	for price := range sizes {
+		prices = append(prices, price)
+	}
+	sort.Slice(prices, func(i, j int) bool {
+		if descending {
+			return prices[i] > prices[j]
+		}
+		return prices[i] < prices[j]
+	})
+
+	levels := make([]common.BookLevel, 0, len(prices))
+	for _, price := range prices {
+		levels = append(levels, common.BookLevel{
+			Price: fixed.FromUint64(price, state.symbolInfo.Digits),
+			Size:  fixed.FromUint64(sizes[price], 2).Div(state.symbolInfo.ContractSize),
+		})
+	}
+	return levels
+}
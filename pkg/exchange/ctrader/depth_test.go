@@ -0,0 +1,94 @@
+package ctrader
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/exchange"
+	"github.com/peter-kozarec/equinox/pkg/exchange/ctrader/openapi"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+func depthQuote(id, size, bid, ask uint64) *openapi.ProtoOADepthQuote {
+	quote := &openapi.ProtoOADepthQuote{Id: proto.Uint64(id), Size: proto.Uint64(size)}
+	if bid != 0 {
+		quote.Bid = proto.Uint64(bid)
+	}
+	if ask != 0 {
+		quote.Ask = proto.Uint64(ask)
+	}
+	return quote
+}
+
+func depthMessage(t *testing.T, event *openapi.ProtoOADepthEvent) *openapi.ProtoMessage {
+	t.Helper()
+
+	payload, err := proto.Marshal(event)
+	require.NoError(t, err)
+	return &openapi.ProtoMessage{PayloadType: proto.Uint32(uint32(openapi.ProtoOAPayloadType_PROTO_OA_DEPTH_EVENT)), Payload: payload}
+}
+
+func TestState_OnDepthEvent(t *testing.T) {
+	router := bus.NewRouter(10)
+
+	var books []common.OrderBook
+	router.OnDepth = func(_ context.Context, book common.OrderBook) { books = append(books, book) }
+
+	state := NewState(router, exchange.SymbolInfo{
+		SymbolName:   "EURUSD",
+		SymbolId:     1,
+		Digits:       5,
+		ContractSize: fixed.FromInt64(10000000, 2), // 100000 units per lot
+	})
+
+	// quote sizes are in cents of the base currency, 1 lot is 10000000
+	state.OnDepthEvent(depthMessage(t, &openapi.ProtoOADepthEvent{
+		CtidTraderAccountId: proto.Int64(7),
+		SymbolId:            proto.Uint64(1),
+		NewQuotes: []*openapi.ProtoOADepthQuote{
+			depthQuote(1, 10000000, 110010, 0),
+			depthQuote(2, 5000000, 110010, 0),
+			depthQuote(3, 20000000, 110000, 0),
+			depthQuote(4, 2500000, 0, 110020),
+			depthQuote(5, 30000000, 0, 110030),
+		},
+	}))
+	state.OnDepthEvent(depthMessage(t, &openapi.ProtoOADepthEvent{
+		CtidTraderAccountId: proto.Int64(7),
+		SymbolId:            proto.Uint64(1),
+		DeletedQuotes:       []uint64{5},
+	}))
+	state.OnDepthEvent(depthMessage(t, &openapi.ProtoOADepthEvent{
+		CtidTraderAccountId: proto.Int64(7),
+		SymbolId:            proto.Uint64(2),
+		NewQuotes:           []*openapi.ProtoOADepthQuote{depthQuote(6, 10000000, 0, 110040)},
+	}))
+
+	require.NoError(t, router.DrainEvents(context.Background()))
+	require.Len(t, books, 2, "events of other symbols are ignored")
+
+	level := func(price, size float64) common.BookLevel {
+		return common.BookLevel{Price: fixed.FromFloat64(price), Size: fixed.FromFloat64(size)}
+	}
+	assertLevels := func(want, got []common.BookLevel) {
+		t.Helper()
+		require.Len(t, got, len(want))
+		for i := range want {
+			assert.True(t, want[i].Price.Eq(got[i].Price), "price %d: %s", i, got[i].Price)
+			assert.True(t, want[i].Size.Eq(got[i].Size), "size %d: %s", i, got[i].Size)
+		}
+	}
+
+	assert.Equal(t, "EURUSD", books[0].Symbol)
+	assertLevels([]common.BookLevel{level(1.10010, 1.5), level(1.10000, 2)}, books[0].Bids)
+	assertLevels([]common.BookLevel{level(1.10020, 0.25), level(1.10030, 3)}, books[0].Asks)
+
+	assertLevels([]common.BookLevel{level(1.10010, 1.5), level(1.10000, 2)}, books[1].Bids)
+	assertLevels([]common.BookLevel{level(1.10020, 0.25)}, books[1].Asks)
+}
@@ -0,0 +1,234 @@
+package ctrader
+
+import (
+	"log/slog"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/exchange/ctrader/openapi"
+	"github.com/peter-kozarec/equinox/pkg/utility"
+)
+
+// cTrader has no order groups, they are emulated client side. Orders of a group with a one-triggers-other parent
+// are held back until the parent fills, the fill of a one-cancels-other order cancels its siblings.
+
+// holdGroupOrder registers the one-triggers-other parents and reports whether the order has to wait for its parent
+func (state *State) holdGroupOrder(order common.Order) bool {
+	if order.GroupId == 0 {
+		return false
+	}
+
+	state.ordersMu.Lock()
+	defer state.ordersMu.Unlock()
+
+	if order.GroupRelation == common.OrderGroupRelationOneTriggersOther {
+		state.groupParents[order.GroupId] = order.TraceID
+		return false
+	}
+	if _, ok := state.groupParents[order.GroupId]; !ok {
+		return false
+	}
+
+	state.heldOrders[order.GroupId] = append(state.heldOrders[order.GroupId], order)
+	return true
+}
+
+// cancelHeldOrder removes an order waiting for its parent and reports whether it was found
+func (state *State) cancelHeldOrder(traceID utility.TraceID) bool {
+	state.ordersMu.Lock()
+
+	var cancelled []common.Order
+	for groupId := range state.heldOrders {
+		cancelled = append(cancelled, state.dropHeldOrders(groupId, func(o common.Order) bool { return o.TraceID == traceID })...)
+	}
+
+	state.ordersMu.Unlock()
+
+	for _, order := range cancelled {
+		state.postGroupCancel(order, traceID)
+	}
+	return len(cancelled) > 0
+}
+
+// amendHeldOrder amends an order waiting for its parent locally, it reports whether the order was found
+func (state *State) amendHeldOrder(cmd common.Order) bool {
+	state.ordersMu.Lock()
+
+	var original, amended common.Order
+	found := false
+	for _, held := range state.heldOrders {
+		for idx := range held {
+			if held[idx].TraceID != cmd.OrderTraceID {
+				continue
+			}
+			original = held[idx]
+			if !cmd.Price.IsZero() {
+				held[idx].Price = cmd.Price
+			}
+			if !cmd.StopPrice.IsZero() {
+				held[idx].StopPrice = cmd.StopPrice
+			}
+			if !cmd.Size.IsZero() {
+				held[idx].Size = cmd.Size
+			}
+			if !cmd.ExpireTime.IsZero() {
+				held[idx].ExpireTime = cmd.ExpireTime
+			}
+			amended = held[idx]
+			found = true
+		}
+	}
+
+	state.ordersMu.Unlock()
+
+	if !found {
+		return false
+	}
+
+	orderAmended := common.OrderAmended{
+		OriginalOrder: original,
+		AmendedOrder:  amended,
+		Source:        openapiComponentName,
+		ExecutionId:   state.router.IDs().ExecutionID(),
+		TraceID:       state.router.IDs().TraceID(),
+		ParentTraceID: cmd.TraceID,
+		TimeStamp:     state.clock.Now(),
+	}
+	if err := state.router.Post(bus.OrderAmendedEvent, orderAmended); err != nil {
+		slog.Warn("unable to post order amended event", "error", err)
+	}
+	return true
+}
+
+// onGroupExecution resolves the group of an executed order, it must be called without holding ordersMu
+func (state *State) onGroupExecution(order common.Order, v *openapi.ProtoOAExecutionEvent) {
+	var cancels []common.Order
+	var cancelled []common.Order
+	var released []common.Order
+
+	state.ordersMu.Lock()
+
+	switch v.GetExecutionType() {
+	case openapi.ProtoOAExecutionType_ORDER_FILLED, openapi.ProtoOAExecutionType_ORDER_PARTIAL_FILL:
+		if order.GroupRelation == common.OrderGroupRelationOneCancelsOther {
+			for traceID, pending := range state.pendingOrders {
+				if traceID != order.TraceID && pending.order.GroupId == order.GroupId &&
+					pending.order.GroupRelation == common.OrderGroupRelationOneCancelsOther {
+					cancels = append(cancels, pending.order)
+				}
+			}
+			cancelled = state.dropHeldOrders(order.GroupId, func(o common.Order) bool {
+				return o.GroupRelation == common.OrderGroupRelationOneCancelsOther
+			})
+		}
+		if state.groupParents[order.GroupId] == order.TraceID {
+			delete(state.groupParents, order.GroupId)
+			released = releaseGroupOrders(state.heldOrders[order.GroupId], v.GetPosition().GetPositionId())
+			delete(state.heldOrders, order.GroupId)
+		}
+	case openapi.ProtoOAExecutionType_ORDER_CANCELLED, openapi.ProtoOAExecutionType_ORDER_EXPIRED,
+		openapi.ProtoOAExecutionType_ORDER_REJECTED:
+		if state.groupParents[order.GroupId] == order.TraceID {
+			delete(state.groupParents, order.GroupId)
+			cancelled = state.dropHeldOrders(order.GroupId, func(common.Order) bool { return true })
+		}
+	}
+
+	state.ordersMu.Unlock()
+
+	for _, sibling := range cancels {
+		cancel := common.Order{
+			Command:       common.OrderCommandCancel,
+			OrderTraceID:  sibling.TraceID,
+			Symbol:        sibling.Symbol,
+			Source:        openapiComponentName,
+			ExecutionId:   state.router.IDs().ExecutionID(),
+			TraceID:       state.router.IDs().TraceID(),
+			ParentTraceID: order.TraceID,
+			TimeStamp:     state.clock.Now(),
+		}
+		if err := state.router.Post(bus.OrderEvent, cancel); err != nil {
+			slog.Warn("unable to post one-cancels-other cancel order", "error", err, "order", sibling)
+		}
+	}
+	for _, held := range cancelled {
+		state.postGroupCancel(held, order.TraceID)
+	}
+	for _, child := range released {
+		child.ParentTraceID = order.TraceID
+		child.TimeStamp = state.clock.Now()
+		if err := state.router.Post(bus.OrderEvent, child); err != nil {
+			slog.Warn("unable to post released group order", "error", err, "order", child)
+		}
+	}
+}
+
+func (state *State) dropHeldOrders(groupId common.OrderGroupId, match func(common.Order) bool) []common.Order {
+	var dropped []common.Order
+	kept := state.heldOrders[groupId][:0]
+	for _, o := range state.heldOrders[groupId] {
+		if match(o) {
+			dropped = append(dropped, o)
+		} else {
+			kept = append(kept, o)
+		}
+	}
+
+	if len(kept) == 0 {
+		delete(state.heldOrders, groupId)
+	} else {
+		state.heldOrders[groupId] = kept
+	}
+	return dropped
+}
+
+// releaseGroupOrders assigns the position opened by the parent to the held orders. Closing stop and limit
+// orders have no pending counterpart on cTrader, they become the stop loss and take profit of the position,
+// which the venue cancels together with the position.
+func releaseGroupOrders(held []common.Order, positionId common.PositionId) []common.Order {
+	var released []common.Order
+	var protection *common.Order
+
+	for _, o := range held {
+		if o.PositionId == 0 && (o.Command == common.OrderCommandPositionClose || o.Command == common.OrderCommandPositionModify) {
+			o.PositionId = positionId
+		}
+
+		if o.Command != common.OrderCommandPositionClose || o.Type == common.OrderTypeMarket {
+			released = append(released, o)
+			continue
+		}
+
+		if protection == nil {
+			modify := o
+			modify.Command = common.OrderCommandPositionModify
+			protection = &modify
+		}
+		switch o.Type {
+		case common.OrderTypeStop, common.OrderTypeStopLimit:
+			protection.StopLoss = o.StopPrice
+		case common.OrderTypeLimit:
+			protection.TakeProfit = o.Price
+		}
+	}
+
+	if protection != nil {
+		released = append(released, *protection)
+	}
+	return released
+}
+
+func (state *State) postGroupCancel(order common.Order, parentTraceID utility.TraceID) {
+	cancelled := common.OrderCancelled{
+		OriginalOrder: order,
+		CancelledSize: order.Size.Abs(),
+		Source:        openapiComponentName,
+		ExecutionId:   state.router.IDs().ExecutionID(),
+		TraceID:       state.router.IDs().TraceID(),
+		ParentTraceID: parentTraceID,
+		TimeStamp:     state.clock.Now(),
+	}
+	if err := state.router.Post(bus.OrderCancelledEvent, cancelled); err != nil {
+		slog.Warn("unable to post order cancelled event", "error", err)
+	}
+}
@@ -39,7 +39,8 @@ func InitTradeSession(
 	client *Client,
 	accountId int64,
 	symbol string,
-	router *bus.Router) (bus.OrderEventHandler, error) {
+	router *bus.Router,
+	options ...StateOption) (bus.OrderEventHandler, error) {
 
 	symbolInfoContext, symbolInfoCancel := context.WithTimeout(ctx, time.Second)
 	defer symbolInfoCancel()
@@ -55,7 +56,7 @@ func InitTradeSession(
 		"digits", symbolInfo.Digits,
 		"lot_size", symbolInfo.ContractSize.String())
 
-	state := NewState(router, symbolInfo)
+	state := NewState(router, symbolInfo, options...)
 
 	balanceContext, balanceCancel := context.WithTimeout(ctx, time.Second)
 	defer balanceCancel()
@@ -82,18 +83,44 @@ func InitTradeSession(
 	}
 	slog.Debug("subscribed to spot events")
 
+	depthContext, depthCancel := context.WithTimeout(ctx, time.Second)
+	defer depthCancel()
+	if err := client.SubscribeDepthQuotes(depthContext, accountId, symbolInfo, state.OnDepthEvent); err != nil {
+		slog.Warn("unable to subscribe to depth quotes, order book events are not available", "symbol", symbol, "error", err)
+	} else {
+		slog.Debug("subscribed to depth events")
+	}
+
 	_, err = subscribe(client.conn, openapi.ProtoOAPayloadType_PROTO_OA_EXECUTION_EVENT, state.OnExecutionEvent)
 	if err != nil {
 		return nil, fmt.Errorf("unable to subscribe to execution events: %w", err)
 	}
 	slog.Debug("subscribed to execution events")
 
+	_, err = subscribe(client.conn, openapi.ProtoOAPayloadType_PROTO_OA_MARGIN_CHANGED_EVENT, state.OnMarginChangedEvent)
+	if err != nil {
+		return nil, fmt.Errorf("unable to subscribe to margin changed events: %w", err)
+	}
+	_, err = subscribe(client.conn, openapi.ProtoOAPayloadType_PROTO_OA_TRADER_UPDATE_EVENT, state.OnTraderUpdateEvent)
+	if err != nil {
+		return nil, fmt.Errorf("unable to subscribe to trader updated events: %w", err)
+	}
+	_, err = subscribe(client.conn, openapi.ProtoOAPayloadType_PROTO_OA_MARGIN_CALL_TRIGGER_EVENT, state.OnMarginCallTriggerEvent)
+	if err != nil {
+		return nil, fmt.Errorf("unable to subscribe to margin call trigger events: %w", err)
+	}
+	slog.Debug("subscribed to account events")
+
 	state.StartBalancePolling(ctx, client, accountId, time.Second*10)
 	slog.Debug("started balance polling", "poll_interval", time.Second*10)
 
 	return func(ctx context.Context, order common.Order) {
 		switch order.Command {
 		case common.OrderCommandPositionClose:
+			if state.holdGroupOrder(order) {
+				return
+			}
+
 			closeContext, closeCancel := context.WithTimeout(ctx, time.Second)
 			defer closeCancel()
 
@@ -104,9 +131,70 @@ func InitTradeSession(
 			openContext, openCancel := context.WithTimeout(ctx, time.Second)
 			defer openCancel()
 
-			if err := client.OpenPosition(openContext, accountId, symbolInfo, order.Price, order.Size, order.StopLoss, order.TakeProfit, order.Type); err != nil {
+			if state.holdGroupOrder(order) {
+				return
+			}
+
+			state.trackOrder(order)
+			if err := client.OpenPosition(openContext, accountId, symbolInfo, order.Price, order.StopPrice, order.Size, order.StopLoss, order.TakeProfit, order.TrailingDistance, order.Type, formatClientOrderId(order.TraceID)); err != nil {
 				slog.Warn("unable to open position", "error", err)
 			}
+		case common.OrderCommandPositionModify:
+			if state.holdGroupOrder(order) {
+				return
+			}
+
+			modifyContext, modifyCancel := context.WithTimeout(ctx, time.Second)
+			defer modifyCancel()
+
+			stopLoss, takeProfit := state.positionStops(order)
+			if err := client.AmendPositionSLTP(modifyContext, accountId, order.PositionId, stopLoss, takeProfit, order.TrailingDistance); err != nil {
+				slog.Warn("unable to modify position", "error", err)
+			}
+		case common.OrderCommandCancel:
+			if state.cancelHeldOrder(order.OrderTraceID) {
+				return
+			}
+
+			orderId, _, ok := state.lookupOrder(order.OrderTraceID)
+			if !ok {
+				slog.Warn("unable to cancel order, pending order not found", "order", order)
+				return
+			}
+
+			cancelContext, cancelCancel := context.WithTimeout(ctx, time.Second)
+			defer cancelCancel()
+
+			if err := client.CancelOrder(cancelContext, accountId, orderId); err != nil {
+				slog.Warn("unable to cancel order", "error", err)
+			}
+		case common.OrderCommandAmend:
+			if state.amendHeldOrder(order) {
+				return
+			}
+
+			orderId, pending, ok := state.lookupOrder(order.OrderTraceID)
+			if !ok {
+				slog.Warn("unable to amend order, pending order not found", "order", order)
+				return
+			}
+
+			if !order.Price.IsZero() {
+				pending.Price = order.Price
+			}
+			if !order.StopPrice.IsZero() {
+				pending.StopPrice = order.StopPrice
+			}
+			if !order.Size.IsZero() {
+				pending.Size = order.Size
+			}
+
+			amendContext, amendCancel := context.WithTimeout(ctx, time.Second)
+			defer amendCancel()
+
+			if err := client.AmendOrder(amendContext, accountId, orderId, symbolInfo, pending.Price, pending.StopPrice, pending.Size, order.ExpireTime, pending.Type); err != nil {
+				slog.Warn("unable to amend order", "error", err)
+			}
 		default:
 			slog.Error("unsupported order command type", "order", order)
 		}
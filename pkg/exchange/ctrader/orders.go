@@ -0,0 +1,146 @@
+package ctrader
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/exchange/ctrader/openapi"
+	"github.com/peter-kozarec/equinox/pkg/utility"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+type pendingOrder struct {
+	orderId int64
+	order   common.Order
+}
+
+// trackOrder registers an order before it is sent, so that cancel and amend commands can address it by
+// its trace id once the server accepts it
+func (state *State) trackOrder(order common.Order) {
+	if order.TraceID == 0 {
+		return
+	}
+
+	state.ordersMu.Lock()
+	defer state.ordersMu.Unlock()
+
+	state.pendingOrders[order.TraceID] = &pendingOrder{order: order}
+}
+
+// lookupOrder returns the server id and the current state of an accepted pending order
+func (state *State) lookupOrder(traceID utility.TraceID) (int64, common.Order, bool) {
+	state.ordersMu.Lock()
+	defer state.ordersMu.Unlock()
+
+	pending, ok := state.pendingOrders[traceID]
+	if !ok || pending.orderId == 0 {
+		return 0, common.Order{}, false
+	}
+	return pending.orderId, pending.order, true
+}
+
+func (state *State) onOrderExecution(v *openapi.ProtoOAExecutionEvent) {
+	o := v.GetOrder()
+	if o == nil {
+		return
+	}
+	traceID, ok := parseClientOrderId(o.GetClientOrderId())
+	if !ok {
+		return
+	}
+
+	state.ordersMu.Lock()
+
+	pending, ok := state.pendingOrders[traceID]
+	if !ok {
+		state.ordersMu.Unlock()
+		return
+	}
+
+	var cancelled *common.OrderCancelled
+	var amended *common.OrderAmended
+
+	switch v.GetExecutionType() {
+	case openapi.ProtoOAExecutionType_ORDER_ACCEPTED:
+		pending.orderId = o.GetOrderId()
+	case openapi.ProtoOAExecutionType_ORDER_FILLED:
+		if o.GetOrderStatus() == openapi.ProtoOAOrderStatus_ORDER_STATUS_FILLED {
+			delete(state.pendingOrders, traceID)
+		}
+	case openapi.ProtoOAExecutionType_ORDER_REJECTED:
+		delete(state.pendingOrders, traceID)
+	case openapi.ProtoOAExecutionType_ORDER_CANCELLED, openapi.ProtoOAExecutionType_ORDER_EXPIRED:
+		delete(state.pendingOrders, traceID)
+		cancelled = &common.OrderCancelled{
+			OriginalOrder: pending.order,
+			CancelledSize: pending.order.Size.Abs().Sub(fixed.FromInt64(o.GetExecutedVolume(), 2)),
+			Source:        openapiComponentName,
+			ExecutionId:   state.router.IDs().ExecutionID(),
+			TraceID:       state.router.IDs().TraceID(),
+			ParentTraceID: traceID,
+			TimeStamp:     state.clock.Now(),
+		}
+	case openapi.ProtoOAExecutionType_ORDER_REPLACED:
+		original := pending.order
+		pending.order = state.replacedOrder(original, o)
+		amended = &common.OrderAmended{
+			OriginalOrder: original,
+			AmendedOrder:  pending.order,
+			Source:        openapiComponentName,
+			ExecutionId:   state.router.IDs().ExecutionID(),
+			TraceID:       state.router.IDs().TraceID(),
+			ParentTraceID: traceID,
+			TimeStamp:     state.clock.Now(),
+		}
+	}
+
+	order := pending.order
+	state.ordersMu.Unlock()
+
+	if order.GroupId != 0 {
+		state.onGroupExecution(order, v)
+	}
+	if cancelled != nil {
+		if err := state.router.Post(bus.OrderCancelledEvent, *cancelled); err != nil {
+			slog.Warn("unable to post order cancelled event", "error", err)
+		}
+	}
+	if amended != nil {
+		if err := state.router.Post(bus.OrderAmendedEvent, *amended); err != nil {
+			slog.Warn("unable to post order amended event", "error", err)
+		}
+	}
+}
+
+// replacedOrder applies the prices, volume and expiration of the replaced server order to the tracked order
+func (state *State) replacedOrder(order common.Order, o *openapi.ProtoOAOrder) common.Order {
+	switch o.GetOrderType() {
+	case openapi.ProtoOAOrderType_LIMIT:
+		order.Price = fixed.FromFloat64(o.GetLimitPrice())
+	case openapi.ProtoOAOrderType_STOP:
+		order.StopPrice = fixed.FromFloat64(o.GetStopPrice())
+	case openapi.ProtoOAOrderType_STOP_LIMIT:
+		order.StopPrice = fixed.FromFloat64(o.GetStopPrice())
+		slippage := fixed.FromInt64(o.GetSlippageInPoints(), state.symbolInfo.Digits)
+		if o.GetTradeData().GetTradeSide() == openapi.ProtoOATradeSide_SELL {
+			order.Price = order.StopPrice.Sub(slippage)
+		} else {
+			order.Price = order.StopPrice.Add(slippage)
+		}
+	}
+
+	if volume := o.GetTradeData().GetVolume(); volume != 0 {
+		size := fixed.FromInt64(volume, 2)
+		if order.Size.Lt(fixed.Zero) {
+			size = size.Neg()
+		}
+		order.Size = size
+	}
+	if expiration := o.GetExpirationTimestamp(); expiration != 0 {
+		order.ExpireTime = time.UnixMilli(expiration)
+	}
+
+	return order
+}
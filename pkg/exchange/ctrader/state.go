@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"sync"
 	"time"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/peter-kozarec/equinox/pkg/common"
 	"github.com/peter-kozarec/equinox/pkg/exchange"
 	"github.com/peter-kozarec/equinox/pkg/exchange/ctrader/openapi"
+	"github.com/peter-kozarec/equinox/pkg/tools/clock"
 	"github.com/peter-kozarec/equinox/pkg/utility"
 	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
 )
@@ -27,26 +29,56 @@ const (
 	positionStatusPendingOpen common.PositionStatus = "pending-open"
 )
 
+type StateOption func(*State)
+
+// WithClock sets the clock used to timestamp the events posted by the state, wall clock by default
+func WithClock(c clock.Clock) StateOption {
+	return func(state *State) {
+		state.clock = c
+	}
+}
+
 type State struct {
 	router     *bus.Router
 	symbolInfo exchange.SymbolInfo
+	clock      clock.Clock
 
 	lastTick common.Tick
 
+	depthQuotes map[uint64]*openapi.ProtoOADepthQuote
+
 	openPositions []common.Position
 
+	ordersMu      sync.Mutex
+	pendingOrders map[utility.TraceID]*pendingOrder
+	groupParents  map[common.OrderGroupId]utility.TraceID
+	heldOrders    map[common.OrderGroupId][]common.Order
+
 	balanceMu   sync.Mutex
 	postBalance bool
 	balance     fixed.Point
 	equity      fixed.Point
+	usedMargins map[int64]fixed.Point
 }
 
-func NewState(router *bus.Router, symbolInfo exchange.SymbolInfo) *State {
-	return &State{
-		router:      router,
-		symbolInfo:  symbolInfo,
-		postBalance: true, // Post balance on first poll, then only when position is closed
+func NewState(router *bus.Router, symbolInfo exchange.SymbolInfo, options ...StateOption) *State {
+	state := &State{
+		router:        router,
+		symbolInfo:    symbolInfo,
+		clock:         clock.WallClock{},
+		pendingOrders: make(map[utility.TraceID]*pendingOrder),
+		groupParents:  make(map[common.OrderGroupId]utility.TraceID),
+		heldOrders:    make(map[common.OrderGroupId][]common.Order),
+		depthQuotes:   make(map[uint64]*openapi.ProtoOADepthQuote),
+		usedMargins:   make(map[int64]fixed.Point),
+		postBalance:   true, // Post balance on first poll, then only when position is closed
+	}
+
+	for _, option := range options {
+		option(state)
 	}
+
+	return state
 }
 
 func (state *State) OnSpotsEvent(msg *openapi.ProtoMessage) {
@@ -61,8 +93,8 @@ func (state *State) OnSpotsEvent(msg *openapi.ProtoMessage) {
 	internalTick := common.Tick{}
 	internalTick.Symbol = state.symbolInfo.SymbolName
 	internalTick.Source = openapiComponentName
-	internalTick.ExecutionId = utility.GetExecutionID()
-	internalTick.TraceID = utility.CreateTraceID()
+	internalTick.ExecutionId = state.router.IDs().ExecutionID()
+	internalTick.TraceID = state.router.IDs().TraceID()
 	internalTick.Ask = fixed.FromUint64(v.GetAsk(), state.symbolInfo.Digits)
 	internalTick.Bid = fixed.FromUint64(v.GetBid(), state.symbolInfo.Digits)
 	internalTick.TimeStamp = time.UnixMilli(v.GetTimestamp())
@@ -106,6 +138,13 @@ func (state *State) OnExecutionEvent(msg *openapi.ProtoMessage) {
 		return
 	}
 
+	state.onOrderExecution(&v)
+
+	if v.GetExecutionType() == openapi.ProtoOAExecutionType_ORDER_REPLACED && v.GetPosition() != nil {
+		state.updatePositionStops(v.GetPosition())
+		return
+	}
+
 	if v.GetExecutionType() != openapi.ProtoOAExecutionType_ORDER_FILLED || v.GetPosition() == nil {
 		// Not interested in other execution types
 		return
@@ -133,6 +172,7 @@ func (state *State) OnExecutionEvent(msg *openapi.ProtoMessage) {
 
 				// Remove the closed position
 				state.openPositions = append(state.openPositions[:idx], state.openPositions[idx+1:]...)
+				state.setUsedMargin(internalPosition.Id, fixed.Zero)
 
 				if err := state.router.Post(bus.PositionCloseEvent, *internalPosition); err != nil {
 					slog.Warn("unable to post position closed event", "error", err)
@@ -152,14 +192,18 @@ func (state *State) OnExecutionEvent(msg *openapi.ProtoMessage) {
 
 		internalPosition.Symbol = state.symbolInfo.SymbolName
 		internalPosition.Source = openapiComponentName
-		internalPosition.ExecutionID = utility.GetExecutionID()
-		internalPosition.TraceID = utility.CreateTraceID()
-		internalPosition.TimeStamp = time.Now()
+		internalPosition.ExecutionID = state.router.IDs().ExecutionID()
+		internalPosition.TraceID = state.router.IDs().TraceID()
+		internalPosition.TimeStamp = state.clock.Now()
 		internalPosition.Side = common.PositionSideLong
 		internalPosition.Id = position.GetPositionId()
 		internalPosition.OpenTime = time.UnixMilli(*position.TradeData.OpenTimestamp)
 		internalPosition.OpenPrice = fixed.FromFloat64(position.GetPrice())
 		internalPosition.Status = positionStatusPendingOpen
+		if orderTraceID, ok := parseClientOrderId(v.GetOrder().GetClientOrderId()); ok {
+			internalPosition.ParentTraceID = orderTraceID
+			internalPosition.OrderTraceIDs = append(internalPosition.OrderTraceIDs, orderTraceID)
+		}
 		internalPosition.StopLoss = fixed.FromFloat64(position.GetStopLoss())
 		internalPosition.TakeProfit = fixed.FromFloat64(position.GetTakeProfit())
 		internalPosition.Size = fixed.FromInt64(position.TradeData.GetVolume(), 2).Div(state.symbolInfo.ContractSize)
@@ -171,6 +215,7 @@ func (state *State) OnExecutionEvent(msg *openapi.ProtoMessage) {
 		}
 
 		state.openPositions = append(state.openPositions, internalPosition)
+		state.setUsedMargin(internalPosition.Id, fixed.FromUint64(position.GetUsedMargin(), int(position.GetMoneyDigits())))
 
 		if err := state.router.Post(bus.PositionOpenEvent, internalPosition); err != nil {
 			slog.Warn("unable to post position opened event", "error", err)
@@ -192,9 +237,9 @@ func (state *State) LoadOpenPositions(ctx context.Context, client *Client, accou
 
 		internalPosition.Symbol = state.symbolInfo.SymbolName
 		internalPosition.Source = openapiComponentName
-		internalPosition.ExecutionID = utility.GetExecutionID()
-		internalPosition.TraceID = utility.CreateTraceID()
-		internalPosition.TimeStamp = time.Now()
+		internalPosition.ExecutionID = state.router.IDs().ExecutionID()
+		internalPosition.TraceID = state.router.IDs().TraceID()
+		internalPosition.TimeStamp = state.clock.Now()
 		internalPosition.Side = common.PositionSideLong
 		internalPosition.Id = position.GetPositionId()
 		internalPosition.OpenTime = time.UnixMilli(*position.TradeData.OpenTimestamp)
@@ -211,6 +256,7 @@ func (state *State) LoadOpenPositions(ctx context.Context, client *Client, accou
 		}
 
 		state.openPositions = append(state.openPositions, internalPosition)
+		state.setUsedMargin(internalPosition.Id, fixed.FromUint64(position.GetUsedMargin(), int(position.GetMoneyDigits())))
 	}
 
 	return nil
@@ -289,7 +335,7 @@ func (state *State) calcPnL() {
 		}
 
 		if !oldProfit.Eq(position.NetProfit) {
-			position.TimeStamp = time.Now()
+			position.TimeStamp = state.clock.Now()
 			if err := state.router.Post(bus.PositionUpdateEvent, *position); err != nil {
 				slog.Warn("unable to post position updated event", "error", err)
 			}
@@ -310,13 +356,14 @@ func (state *State) calcEquity() {
 	if !oldEquity.Eq(state.equity) {
 		if err := state.router.Post(bus.EquityEvent, common.Equity{
 			Source:      openapiComponentName,
-			ExecutionId: utility.GetExecutionID(),
-			TraceID:     utility.CreateTraceID(),
-			TimeStamp:   time.Now(),
+			ExecutionId: state.router.IDs().ExecutionID(),
+			TraceID:     state.router.IDs().TraceID(),
+			TimeStamp:   state.clock.Now(),
 			Value:       state.equity,
 		}); err != nil {
 			slog.Warn("unable to post equity event", "error", err)
 		}
+		state.postAccount()
 	}
 }
 
@@ -327,9 +374,9 @@ func (state *State) setBalance(newBalance fixed.Point) {
 		state.postBalance = false
 		if err := state.router.Post(bus.BalanceEvent, common.Balance{
 			Source:      openapiComponentName,
-			ExecutionId: utility.GetExecutionID(),
-			TraceID:     utility.CreateTraceID(),
-			TimeStamp:   time.Now(),
+			ExecutionId: state.router.IDs().ExecutionID(),
+			TraceID:     state.router.IDs().TraceID(),
+			TimeStamp:   state.clock.Now(),
 			Value:       state.balance,
 		}); err != nil {
 			slog.Warn("unable to post balance event", "error", err)
@@ -343,3 +390,51 @@ func (state *State) getBalance(balance *fixed.Point) {
 	*balance = state.balance
 	state.balanceMu.Unlock()
 }
+
+// positionStops fills the zero stop loss and take profit of a modify order from the tracked position. A zero leaves
+// the price unchanged, as in the sandbox, while cTrader removes a price missing from the amend request
+func (state *State) positionStops(order common.Order) (stopLoss, takeProfit fixed.Point) {
+	stopLoss, takeProfit = order.StopLoss, order.TakeProfit
+	for _, position := range state.openPositions {
+		if position.Id != order.PositionId {
+			continue
+		}
+		if stopLoss.IsZero() {
+			stopLoss = position.StopLoss
+		}
+		if takeProfit.IsZero() {
+			takeProfit = position.TakeProfit
+		}
+		break
+	}
+	return stopLoss, takeProfit
+}
+
+// updatePositionStops keeps the stop loss and take profit of the tracked position in line with the amended position
+func (state *State) updatePositionStops(position *openapi.ProtoOAPosition) {
+	for idx := range state.openPositions {
+		internalPosition := &state.openPositions[idx]
+		if internalPosition.Id == position.GetPositionId() {
+			internalPosition.StopLoss = fixed.FromFloat64(position.GetStopLoss())
+			internalPosition.TakeProfit = fixed.FromFloat64(position.GetTakeProfit())
+			return
+		}
+	}
+}
+
+// Orders are sent with their trace id as the client order id, so that the positions opened by them
+// can be linked back to the order
+func formatClientOrderId(traceID utility.TraceID) string {
+	if traceID == 0 {
+		return ""
+	}
+	return strconv.FormatUint(traceID, 10)
+}
+
+func parseClientOrderId(clientOrderId string) (utility.TraceID, bool) {
+	traceID, err := strconv.ParseUint(clientOrderId, 10, 64)
+	if err != nil || traceID == 0 {
+		return 0, false
+	}
+	return traceID, true
+}
@@ -0,0 +1,91 @@
+package ctrader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/exchange"
+	"github.com/peter-kozarec/equinox/pkg/exchange/ctrader/openapi"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+func TestState_PositionStops(t *testing.T) {
+	state := NewState(bus.NewRouter(10), exchange.SymbolInfo{SymbolName: "EURUSD", SymbolId: 1, Digits: 5})
+	state.openPositions = []common.Position{
+		{Id: 1, StopLoss: fixed.FromFloat64(1.0950), TakeProfit: fixed.FromFloat64(1.1100)},
+		{Id: 2},
+	}
+
+	tests := []struct {
+		name       string
+		order      common.Order
+		stopLoss   fixed.Point
+		takeProfit fixed.Point
+	}{
+		{
+			name:       "zero prices keep the position prices",
+			order:      common.Order{PositionId: 1},
+			stopLoss:   fixed.FromFloat64(1.0950),
+			takeProfit: fixed.FromFloat64(1.1100),
+		},
+		{
+			name:       "stop loss only",
+			order:      common.Order{PositionId: 1, StopLoss: fixed.FromFloat64(1.0970)},
+			stopLoss:   fixed.FromFloat64(1.0970),
+			takeProfit: fixed.FromFloat64(1.1100),
+		},
+		{
+			name:       "take profit only",
+			order:      common.Order{PositionId: 1, TakeProfit: fixed.FromFloat64(1.1200)},
+			stopLoss:   fixed.FromFloat64(1.0950),
+			takeProfit: fixed.FromFloat64(1.1200),
+		},
+		{
+			name:       "position without prices",
+			order:      common.Order{PositionId: 2, StopLoss: fixed.FromFloat64(1.0970)},
+			stopLoss:   fixed.FromFloat64(1.0970),
+			takeProfit: fixed.Zero,
+		},
+		{
+			name:       "unknown position",
+			order:      common.Order{PositionId: 3},
+			stopLoss:   fixed.Zero,
+			takeProfit: fixed.Zero,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stopLoss, takeProfit := state.positionStops(tt.order)
+			assert.True(t, tt.stopLoss.Eq(stopLoss), stopLoss.String())
+			assert.True(t, tt.takeProfit.Eq(takeProfit), takeProfit.String())
+		})
+	}
+
+	t.Run("amended position", func(t *testing.T) {
+		payload, err := proto.Marshal(&openapi.ProtoOAExecutionEvent{
+			CtidTraderAccountId: proto.Int64(7),
+			ExecutionType:       openapi.ProtoOAExecutionType_ORDER_REPLACED.Enum(),
+			Position: &openapi.ProtoOAPosition{
+				PositionId:     proto.Int64(1),
+				TradeData:      &openapi.ProtoOATradeData{SymbolId: proto.Int64(1), Volume: proto.Int64(100), TradeSide: openapi.ProtoOATradeSide_BUY.Enum()},
+				PositionStatus: openapi.ProtoOAPositionStatus_POSITION_STATUS_OPEN.Enum(),
+				Swap:           proto.Int64(0),
+				StopLoss:       proto.Float64(1.0980),
+				TakeProfit:     proto.Float64(1.1150),
+			},
+		})
+		require.NoError(t, err)
+		state.OnExecutionEvent(&openapi.ProtoMessage{Payload: payload})
+
+		require.Len(t, state.openPositions, 2, "an amended position is not opened again")
+		stopLoss, takeProfit := state.positionStops(common.Order{PositionId: 1})
+		assert.True(t, fixed.FromFloat64(1.0980).Eq(stopLoss), stopLoss.String())
+		assert.True(t, fixed.FromFloat64(1.1150).Eq(takeProfit), takeProfit.String())
+	})
+}
@@ -0,0 +1,63 @@
+package sandbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+// OnBar executes orders, stop losses and take profits on the synthetic ticks of the bar, see WithSyntheticTicks.
+// It has to run before the handlers reacting to the bar, otherwise their orders fill within the bar they saw
+// closing. The ticks are not posted on the router.
+func (s *Simulator) OnBar(ctx context.Context, bar common.Bar) {
+	if s.barPath == BarPathNone {
+		return
+	}
+	// bars without volume, like the HistData exports, do not limit the fills
+	s.unlimitedLiquidity = s.barVolume.IsZero() && bar.Volume.IsZero()
+	for _, tick := range s.syntheticTicks(bar) {
+		s.OnTick(ctx, tick)
+	}
+	s.unlimitedLiquidity = false
+}
+
+// syntheticTicks returns the open, the extremes in the order of the bar path and the close of the bar. The open
+// tick is at the open time, the close tick at the last nanosecond of the bar and the extremes evenly in between.
+// Without a configured volume the bar volume is split evenly, so the ticks of a bar do not trade more than it. A bar
+// without volume leaves the ticks without volume, OnBar fills them without a liquidity limit.
+func (s *Simulator) syntheticTicks(bar common.Bar) []common.Tick {
+	prices := [4]fixed.Point{bar.Open, bar.High, bar.Low, bar.Close}
+	if s.barPath == BarPathOLHC || (s.barPath == BarPathAuto && bar.Close.Gte(bar.Open)) {
+		prices[1], prices[2] = bar.Low, bar.High
+	}
+
+	volume := s.barVolume
+	if volume.IsZero() {
+		volume = bar.Volume.DivInt(len(prices))
+	}
+
+	var step, last time.Duration
+	if bar.Period > 0 {
+		step = bar.Period / 3
+		last = bar.Period - time.Nanosecond
+	}
+	offsets := [4]time.Duration{0, step, 2 * step, last}
+
+	ticks := make([]common.Tick, 0, len(prices))
+	for idx, price := range prices {
+		ticks = append(ticks, common.Tick{
+			Source:      simulatorComponentName,
+			Symbol:      bar.Symbol,
+			ExecutionId: s.router.IDs().ExecutionID(),
+			TraceID:     s.router.IDs().TraceID(),
+			TimeStamp:   bar.OpenTime.Add(offsets[idx]),
+			Bid:         price,
+			Ask:         price.Add(s.barSpread),
+			BidVolume:   volume,
+			AskVolume:   volume,
+		})
+	}
+	return ticks
+}
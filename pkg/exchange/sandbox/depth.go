@@ -0,0 +1,35 @@
+package sandbox
+
+import (
+	"context"
+	"strings"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+// OnDepth keeps the last order book of the symbol for fills in FillDepth mode
+func (s *Simulator) OnDepth(_ context.Context, book common.OrderBook) {
+	s.lastBookMap[strings.ToUpper(book.Symbol)] = book
+}
+
+// depthFill returns the size of the order fillable from the order book of its symbol and the volume weighted
+// average price of it. Limit orders only take levels up to their price. It reports false when the order fills
+// at the top of book instead.
+func (s *Simulator) depthFill(order common.Order, side common.OrderSide, size fixed.Point) (fixed.Point, fixed.Point, bool) {
+	if s.fillMode != FillDepth {
+		return fixed.Zero, fixed.Zero, false
+	}
+	book, ok := s.lastBookMap[strings.ToUpper(order.Symbol)]
+	if !ok {
+		return fixed.Zero, fixed.Zero, false
+	}
+
+	limit := fixed.Zero
+	if order.Type == common.OrderTypeLimit || order.Type == common.OrderTypeStopLimit {
+		limit = order.Price
+	}
+
+	filled, price := book.Fill(side, size, limit)
+	return filled, price, true
+}
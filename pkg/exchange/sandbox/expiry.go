@@ -0,0 +1,112 @@
+package sandbox
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/exchange"
+)
+
+// checkExpiries settles the positions and cancels the pending orders of futures contracts which expired by
+// the simulation time. With ExpiryRoll every settled position is followed by a market order for the same size
+// in the next contract of the chain.
+func (s *Simulator) checkExpiries() {
+	tmpOpenOrders := make([]*common.Order, 0, len(s.openOrders))
+	for _, order := range s.openOrders {
+		symbolInfo, ok := s.expiryInfo(order.Symbol)
+		if !ok || !symbolInfo.IsExpired(s.simulationTime) {
+			tmpOpenOrders = append(tmpOpenOrders, order)
+			continue
+		}
+		delete(s.triggeredOrders, order)
+		s.postOrderCancel(*order, order.Size.Sub(order.FilledSize))
+	}
+	s.openOrders = tmpOpenOrders
+
+	tmpOpenPositions := make([]*common.Position, 0, len(s.openPositions))
+	for _, position := range s.openPositions {
+		symbolInfo, ok := s.expiryInfo(position.Symbol)
+		if !ok || !symbolInfo.IsExpired(s.simulationTime) {
+			tmpOpenPositions = append(tmpOpenPositions, position)
+			continue
+		}
+
+		tick, ok := s.lastTickMap[strings.ToUpper(position.Symbol)]
+		if !ok {
+			slog.Warn("no tick for expired contract, skipping settlement",
+				"position", position)
+			tmpOpenPositions = append(tmpOpenPositions, position)
+			continue
+		}
+
+		openPrice, closePrice := tick.Ask, tick.Bid
+		if position.Side == common.PositionSideShort {
+			openPrice, closePrice = tick.Bid, tick.Ask
+		}
+		if position.Status == positionStatusPendingOpen {
+			position.OpenPrice = openPrice
+			position.OpenTime = tick.TimeStamp
+		}
+
+		position.Status = common.PositionStatusClosed
+		position.ClosePrice = closePrice
+		position.CloseTime = symbolInfo.Expiry
+		s.calcPositionProfits(position, closePrice)
+		position.TimeStamp = s.simulationTime
+		s.balance = s.balance.Add(position.NetProfit)
+		if err := s.router.Post(bus.PositionCloseEvent, *position); err != nil {
+			slog.Warn("unable to post position closed event", "error", err)
+		}
+
+		if s.expiryPolicy == ExpiryRoll {
+			s.rollPosition(*position, symbolInfo.Root)
+		}
+	}
+	s.openPositions = tmpOpenPositions
+}
+
+// expiryInfo returns the symbol info used for the expiry checks, cached per symbol as they run on every tick
+func (s *Simulator) expiryInfo(symbol string) (exchange.SymbolInfo, bool) {
+	if symbolInfo, ok := s.expiryInfos[symbol]; ok {
+		return symbolInfo, true
+	}
+	symbolInfo, err := s.symbolStore.Get(symbol)
+	if err != nil {
+		return exchange.SymbolInfo{}, false
+	}
+	s.expiryInfos[symbol] = symbolInfo
+	return symbolInfo, true
+}
+
+// rollPosition opens the position again in the next contract, the order is validated and accepted as any other
+func (s *Simulator) rollPosition(position common.Position, root string) {
+	next, ok := s.symbolStore.Chain(root).Next(position.Symbol)
+	if !ok {
+		slog.Warn("no next contract to roll into", "position", position)
+		return
+	}
+
+	side := common.OrderSideBuy
+	if position.Side == common.PositionSideShort {
+		side = common.OrderSideSell
+	}
+
+	s.acceptOrder(common.Order{
+		Command:          common.OrderCommandPositionOpen,
+		Type:             common.OrderTypeMarket,
+		Side:             side,
+		Size:             position.Size,
+		TimeInForce:      common.TimeInForceImmediateOrCancel,
+		TrailingDistance: position.TrailingDistance,
+		Comment:          fmt.Sprintf("rollover of position %d", position.Id),
+		Source:           simulatorComponentName,
+		Symbol:           next.SymbolName,
+		ExecutionId:      s.router.IDs().ExecutionID(),
+		TraceID:          s.router.IDs().TraceID(),
+		ParentTraceID:    position.TraceID,
+		TimeStamp:        s.simulationTime,
+	})
+}
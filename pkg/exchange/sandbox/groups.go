@@ -0,0 +1,150 @@
+package sandbox
+
+import (
+	"fmt"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+)
+
+type groupFill struct {
+	order      *common.Order
+	positionId common.PositionId
+}
+
+// fillOrder publishes the fill and records it, so that the group of the order is resolved at the end of the tick
+func (s *Simulator) fillOrder(order *common.Order, positionId common.PositionId) {
+	if order.GroupId != 0 {
+		s.groupFills = append(s.groupFills, groupFill{order: order, positionId: positionId})
+	}
+	s.postOrderFilled(*order, positionId)
+}
+
+// groupParent returns the open one-triggers-other parent of the order's group, if there is one
+func (s *Simulator) groupParent(order common.Order) *common.Order {
+	if order.GroupId == 0 {
+		return nil
+	}
+	for _, o := range s.openOrders {
+		if o.GroupId == order.GroupId && o.GroupRelation == common.OrderGroupRelationOneTriggersOther && o.TraceID != order.TraceID {
+			return o
+		}
+	}
+	return nil
+}
+
+// isOrderHeld reports whether the order waits for its one-triggers-other parent to fill
+func (s *Simulator) isOrderHeld(order common.Order) bool {
+	if order.GroupId == 0 || order.GroupRelation == common.OrderGroupRelationOneTriggersOther {
+		return false
+	}
+	if _, ok := s.releasedGroups[order.GroupId]; ok {
+		return false
+	}
+	return s.groupParent(order) != nil
+}
+
+// isOrderCancelledByGroup reports whether another one-cancels-other order of the group filled during this tick
+func (s *Simulator) isOrderCancelledByGroup(order *common.Order) bool {
+	if order.GroupId == 0 || order.GroupRelation != common.OrderGroupRelationOneCancelsOther {
+		return false
+	}
+	for _, fill := range s.groupFills {
+		if fill.order != order && fill.order.GroupId == order.GroupId && fill.order.GroupRelation == common.OrderGroupRelationOneCancelsOther {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveOrderGroups applies the fills of grouped orders recorded during the tick. One-cancels-other siblings
+// of filled orders are cancelled and the orders held by a filled parent are released from the next tick on.
+// Orders held by a parent which left the book without a fill are cancelled.
+func (s *Simulator) resolveOrderGroups(previous []*common.Order) {
+	for _, fill := range s.groupFills {
+		switch fill.order.GroupRelation {
+		case common.OrderGroupRelationOneCancelsOther:
+			s.cancelGroupOrders(fill.order.GroupId, func(o *common.Order) bool {
+				return o != fill.order && o.GroupRelation == common.OrderGroupRelationOneCancelsOther
+			})
+		case common.OrderGroupRelationOneTriggersOther:
+			if _, ok := s.releasedGroups[fill.order.GroupId]; ok {
+				continue
+			}
+			s.releasedGroups[fill.order.GroupId] = struct{}{}
+			for _, o := range s.openOrders {
+				if o == fill.order || o.GroupId != fill.order.GroupId || o.PositionId != 0 {
+					continue
+				}
+				if o.Command == common.OrderCommandPositionClose || o.Command == common.OrderCommandPositionModify {
+					o.PositionId = fill.positionId
+				}
+			}
+		}
+	}
+	s.groupFills = s.groupFills[:0]
+
+	open := make(map[*common.Order]struct{}, len(s.openOrders))
+	for _, o := range s.openOrders {
+		open[o] = struct{}{}
+	}
+	for _, o := range previous {
+		if o.GroupId == 0 || o.GroupRelation != common.OrderGroupRelationOneTriggersOther {
+			continue
+		}
+		if _, ok := open[o]; ok {
+			continue
+		}
+		if _, ok := s.releasedGroups[o.GroupId]; ok {
+			continue
+		}
+		s.cancelGroupOrders(o.GroupId, func(*common.Order) bool { return true })
+	}
+
+	for groupId := range s.releasedGroups {
+		if !s.hasGroupOrders(groupId) {
+			delete(s.releasedGroups, groupId)
+		}
+	}
+}
+
+func (s *Simulator) cancelGroupOrders(groupId common.OrderGroupId, match func(*common.Order) bool) {
+	tmpOpenOrders := make([]*common.Order, 0, len(s.openOrders))
+	for _, o := range s.openOrders {
+		if o.GroupId == groupId && match(o) {
+			delete(s.triggeredOrders, o)
+			s.postOrderCancel(*o, o.Size.Sub(o.FilledSize))
+			continue
+		}
+		tmpOpenOrders = append(tmpOpenOrders, o)
+	}
+	s.openOrders = tmpOpenOrders
+}
+
+func (s *Simulator) hasGroupOrders(groupId common.OrderGroupId) bool {
+	for _, o := range s.openOrders {
+		if o.GroupId == groupId {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Simulator) validateOrderGroup(order common.Order) error {
+	if order.GroupId == 0 {
+		if order.GroupRelation != common.OrderGroupRelationNone {
+			return fmt.Errorf("order group relation requires a group id")
+		}
+		return nil
+	}
+
+	switch order.GroupRelation {
+	case common.OrderGroupRelationNone, common.OrderGroupRelationOneCancelsOther:
+	case common.OrderGroupRelationOneTriggersOther:
+		if s.groupParent(order) != nil {
+			return fmt.Errorf("order group %d already has a parent order", order.GroupId)
+		}
+	default:
+		return fmt.Errorf("invalid order group relation: %d", order.GroupRelation)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package sandbox
+
+import (
+	"log/slog"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+func (s *Simulator) usedMargin() fixed.Point {
+	return s.equity.Sub(s.freeMargin)
+}
+
+// checkMarginCall posts a margin call when the margin level falls to the margin call level, once per breach
+func (s *Simulator) checkMarginCall() {
+	if s.marginCallLevel.IsZero() {
+		return
+	}
+
+	usedMargin := s.usedMargin()
+	marginLevel := common.MarginLevel(s.equity, usedMargin)
+	breached := usedMargin.Gt(fixed.Zero) && marginLevel.Lte(s.marginCallLevel)
+	if breached && !s.marginCalled {
+		marginCall := common.MarginCall{
+			MarginLevel: marginLevel,
+			CallLevel:   s.marginCallLevel,
+			Equity:      s.equity,
+			UsedMargin:  usedMargin,
+			Source:      simulatorComponentName,
+			ExecutionId: s.router.IDs().ExecutionID(),
+			TraceID:     s.router.IDs().TraceID(),
+			TimeStamp:   s.simulationTime,
+		}
+		if err := s.router.Post(bus.MarginCallEvent, marginCall); err != nil {
+			slog.Warn("unable to post margin call event", "error", err)
+		}
+	}
+	s.marginCalled = breached
+}
+
+// isStopOut reports whether a position has to be liquidated. Without a stop out level the free margin rate
+// is compared against the maintenance margin rate.
+func (s *Simulator) isStopOut() bool {
+	if s.stopOutLevel.IsZero() {
+		freeMarginRate := s.freeMargin.Div(s.equity).MulInt(100)
+		return freeMarginRate.Lte(s.maintenanceMarginRate)
+	}
+
+	usedMargin := s.usedMargin()
+	return usedMargin.Gt(fixed.Zero) && common.MarginLevel(s.equity, usedMargin).Lte(s.stopOutLevel)
+}
+
+// liquidationCandidate returns the index of the open position to stop out next
+func (s *Simulator) liquidationCandidate() int {
+	candidate := 0
+	for idx, position := range s.openPositions {
+		switch s.liquidationPolicy {
+		case LiquidateFIFO:
+			if position.OpenTime.Before(s.openPositions[candidate].OpenTime) {
+				candidate = idx
+			}
+		case LiquidateLargestLoss:
+			if position.NetProfit.Lt(s.openPositions[candidate].NetProfit) {
+				candidate = idx
+			}
+		case LiquidateLargestMargin:
+			if position.Margin.Gt(s.openPositions[candidate].Margin) {
+				candidate = idx
+			}
+		}
+	}
+	return candidate
+}
+
+func (s *Simulator) postStopOut(position common.Position, marginLevel fixed.Point) {
+	stopOut := common.StopOut{
+		Position:      position,
+		MarginLevel:   marginLevel,
+		StopOutLevel:  s.stopOutLevel,
+		Source:        simulatorComponentName,
+		ExecutionId:   s.router.IDs().ExecutionID(),
+		TraceID:       s.router.IDs().TraceID(),
+		ParentTraceID: position.TraceID,
+		TimeStamp:     s.simulationTime,
+	}
+	if err := s.router.Post(bus.StopOutEvent, stopOut); err != nil {
+		slog.Warn("unable to post stop out event", "error", err)
+	}
+}
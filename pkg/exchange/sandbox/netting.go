@@ -0,0 +1,180 @@
+package sandbox
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility"
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+// netPosition returns the position of the symbol a fill is netted against, in netting mode only
+func (s *Simulator) netPosition(symbol string) *common.Position {
+	if s.accountMode != AccountModeNetting {
+		return nil
+	}
+	for _, position := range s.openPositions {
+		if strings.EqualFold(position.Symbol, symbol) &&
+			(position.Status == common.PositionStatusOpen || position.Status == positionStatusPendingOpen) {
+			return position
+		}
+	}
+	return nil
+}
+
+// findNetPosition returns the open position of the symbol among positions, in netting mode only
+func (s *Simulator) findNetPosition(positions []*common.Position, symbol string) *common.Position {
+	if s.accountMode != AccountModeNetting {
+		return nil
+	}
+	for _, position := range positions {
+		if strings.EqualFold(position.Symbol, symbol) && position.Status == common.PositionStatusOpen {
+			return position
+		}
+	}
+	return nil
+}
+
+// nextPositionId returns the id of the position a fill results in. In netting mode a fill keeps the id of the
+// position it is netted against, unless it reverses it.
+func (s *Simulator) nextPositionId(symbol string, side common.PositionSide, size fixed.Point) common.PositionId {
+	if position := s.netPosition(symbol); position != nil {
+		if position.Side == side || size.Lte(position.Size) {
+			return position.Id
+		}
+	}
+	s.positionIdCounter++
+	return s.positionIdCounter
+}
+
+// exposureIncrease returns the size by which the order increases the exposure of the account, the part of
+// an opposite order in netting mode which only reduces the position requires no margin
+func (s *Simulator) exposureIncrease(order common.Order) fixed.Point {
+	position := s.netPosition(order.Symbol)
+	if position == nil {
+		return order.Size
+	}
+
+	side := common.PositionSideLong
+	if order.Side == common.OrderSideSell {
+		side = common.PositionSideShort
+	}
+	if position.Side == side {
+		return order.Size
+	}
+
+	size := order.Size.Sub(position.Size)
+	if size.Lt(fixed.Zero) {
+		return fixed.Zero
+	}
+	return size
+}
+
+// applyNetFill nets a just opened fill against the open position of its symbol. A same side fill increases
+// the position at the size weighted open price, an opposite fill realizes the overlapping size and what is
+// left of it opens the reversed position. It returns the open positions.
+func (s *Simulator) applyNetFill(position, fill *common.Position, tick common.Tick, positions []*common.Position) []*common.Position {
+	closePrice := tick.Bid
+	if position.Side == common.PositionSideShort {
+		closePrice = tick.Ask
+	}
+
+	s.equity = s.equity.Sub(position.NetProfit)
+
+	if position.Side == fill.Side {
+		size := position.Size.Add(fill.Size)
+		position.OpenPrice = position.OpenPrice.Mul(position.Size).Add(fill.OpenPrice.Mul(fill.Size)).Div(size)
+		position.Slippage = position.Slippage.Mul(position.Size).Add(fill.Slippage.Mul(fill.Size)).Div(size)
+		position.Size = size
+		position.OrderTraceIDs = append(position.OrderTraceIDs, fill.OrderTraceIDs...)
+		if !fill.StopLoss.IsZero() {
+			position.StopLoss = fill.StopLoss
+		}
+		if !fill.TakeProfit.IsZero() {
+			position.TakeProfit = fill.TakeProfit
+		}
+		if !fill.TrailingDistance.IsZero() {
+			position.TrailingDistance = fill.TrailingDistance
+		}
+		s.updateNetPosition(position, closePrice)
+		return positions
+	}
+
+	closeSize := fill.Size
+	if closeSize.Gt(position.Size) {
+		closeSize = position.Size
+	}
+	s.realizePosition(position, closeSize, closePrice, tick.TimeStamp, fill.OrderTraceIDs)
+
+	if position.Size.IsZero() {
+		for idx, p := range positions {
+			if p == position {
+				positions = append(positions[:idx], positions[idx+1:]...)
+				break
+			}
+		}
+	} else {
+		position.OrderTraceIDs = append(position.OrderTraceIDs, fill.OrderTraceIDs...)
+		s.updateNetPosition(position, closePrice)
+	}
+
+	remaining := fill.Size.Sub(closeSize)
+	if remaining.Gt(fixed.Zero) {
+		fill.Size = remaining
+		if fill.Id == position.Id {
+			s.positionIdCounter++
+			fill.Id = s.positionIdCounter
+		}
+		if err := s.router.Post(bus.PositionOpenEvent, *fill); err != nil {
+			slog.Warn("unable to post position opened event", "error", err)
+		}
+		positions = append(positions, fill)
+	}
+
+	return positions
+}
+
+// realizePosition closes the given size of the position, the closed part is published and booked to the balance.
+// Commissions and swaps accrued so far are split pro rata between the closed and the remaining part.
+func (s *Simulator) realizePosition(position *common.Position, size, closePrice fixed.Point, closeTime time.Time, orderTraceIDs []utility.TraceID) {
+	ratio := size.Div(position.Size)
+	commissions := position.Commissions.Mul(ratio)
+	swaps := position.Swaps.Mul(ratio)
+	conversionFee := position.OpenConversionFee.Mul(ratio)
+
+	closed := *position
+	closed.Size = size
+	closed.Commissions = commissions
+	closed.Swaps = swaps
+	closed.OpenConversionFee = conversionFee
+	closed.OrderTraceIDs = append(append([]utility.TraceID(nil), position.OrderTraceIDs...), orderTraceIDs...)
+	closed.Status = common.PositionStatusClosed
+	closed.ClosePrice = closePrice
+	closed.CloseTime = closeTime
+	if s.slippageHandler != nil {
+		closed.Slippage = closed.Slippage.Add(s.slippageHandler(closed))
+	}
+	s.calcPositionProfits(&closed, closePrice)
+	closed.TimeStamp = s.simulationTime
+	s.balance = s.balance.Add(closed.NetProfit)
+	if err := s.router.Post(bus.PositionCloseEvent, closed); err != nil {
+		slog.Warn("unable to post position closed event", "error", err)
+	}
+
+	position.Size = position.Size.Sub(size)
+	position.Commissions = position.Commissions.Sub(commissions)
+	position.Swaps = position.Swaps.Sub(swaps)
+	position.OpenConversionFee = position.OpenConversionFee.Sub(conversionFee)
+}
+
+func (s *Simulator) updateNetPosition(position *common.Position, closePrice fixed.Point) {
+	s.calcPositionProfits(position, closePrice)
+	position.TimeStamp = s.simulationTime
+	s.equity = s.equity.Add(position.NetProfit)
+	if err := s.router.Post(bus.PositionUpdateEvent, *position); err != nil {
+		slog.Warn("unable to post position pnl updated event", "error", err)
+	}
+}
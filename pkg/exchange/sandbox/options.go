@@ -10,6 +10,57 @@ type Option func(*Simulator)
 type CommissionHandler func(exchange.SymbolInfo, common.Position) fixed.Point
 type SwapHandler func(exchange.SymbolInfo, common.Position) fixed.Point
 type SlippageHandler func(common.Position) fixed.Point
+type AccountMode int
+
+const (
+	// AccountModeHedging opens a new position for every filled order
+	AccountModeHedging AccountMode = iota
+	// AccountModeNetting keeps a single position per symbol, opposite fills reduce, close or reverse it
+	AccountModeNetting
+)
+
+type ExpiryPolicy int
+
+const (
+	// ExpiryClose closes the positions of an expired futures contract at its last price
+	ExpiryClose ExpiryPolicy = iota
+	// ExpiryRoll closes them and reopens the same size in the next contract of the chain at its next tick
+	ExpiryRoll
+)
+
+type FillMode int
+
+const (
+	// FillTopOfBook fills up to the volume of the best price of the tick, the default
+	FillTopOfBook FillMode = iota
+	// FillDepth walks the levels of the last order book of the symbol and fills at their volume weighted average
+	// price, symbols without an order book fill at the top of book
+	FillDepth
+)
+
+type BarPath int
+
+const (
+	// BarPathNone ignores bars, the default
+	BarPathNone BarPath = iota
+	// BarPathOHLC trades every bar as open, high, low and close ticks
+	BarPathOHLC
+	// BarPathOLHC trades every bar as open, low, high and close ticks
+	BarPathOLHC
+	// BarPathAuto visits the low first on bullish bars and the high first on bearish ones
+	BarPathAuto
+)
+
+type LiquidationPolicy int
+
+const (
+	// LiquidateFIFO stops out the oldest position first, the default
+	LiquidateFIFO LiquidationPolicy = iota
+	// LiquidateLargestLoss stops out the position with the largest loss first
+	LiquidateLargestLoss
+	// LiquidateLargestMargin stops out the position using the most margin first
+	LiquidateLargestMargin
+)
 
 func WithRateProvider(rateProvider exchange.RateProvider) Option {
 	return func(s *Simulator) {
@@ -40,3 +91,53 @@ func WithMaintenanceMargin(maintenanceMarginRate fixed.Point) Option {
 		s.maintenanceMarginRate = maintenanceMarginRate
 	}
 }
+
+func WithAccountMode(mode AccountMode) Option {
+	return func(s *Simulator) {
+		s.accountMode = mode
+	}
+}
+
+func WithExpiryPolicy(policy ExpiryPolicy) Option {
+	return func(s *Simulator) {
+		s.expiryPolicy = policy
+	}
+}
+
+func WithFillMode(mode FillMode) Option {
+	return func(s *Simulator) {
+		s.fillMode = mode
+	}
+}
+
+// WithMarginCallLevel posts a margin call once the margin level, equity in percent of used margin, falls to level
+func WithMarginCallLevel(level fixed.Point) Option {
+	return func(s *Simulator) {
+		s.marginCallLevel = level
+	}
+}
+
+// WithStopOutLevel liquidates positions while the margin level is at or below level. It replaces the free
+// margin rate check of WithMaintenanceMargin.
+func WithStopOutLevel(level fixed.Point) Option {
+	return func(s *Simulator) {
+		s.stopOutLevel = level
+	}
+}
+
+func WithLiquidationPolicy(policy LiquidationPolicy) Option {
+	return func(s *Simulator) {
+		s.liquidationPolicy = policy
+	}
+}
+
+// WithSyntheticTicks makes OnBar derive ticks along path from every bar for backtests on bar data. The bar prices
+// are the bids, the asks are spread above them. Every tick quotes volume on both sides, when zero the four ticks
+// share the bar volume and bars without volume fill any size.
+func WithSyntheticTicks(path BarPath, spread, volume fixed.Point) Option {
+	return func(s *Simulator) {
+		s.barPath = path
+		s.barSpread = spread
+		s.barVolume = volume
+	}
+}
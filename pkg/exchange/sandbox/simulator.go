@@ -41,18 +41,36 @@ type Simulator struct {
 	swapHandler           SwapHandler
 	slippageHandler       SlippageHandler
 	maintenanceMarginRate fixed.Point
+	marginCallLevel       fixed.Point
+	stopOutLevel          fixed.Point
+	liquidationPolicy     LiquidationPolicy
+	accountMode           AccountMode
+	expiryPolicy          ExpiryPolicy
+	fillMode              FillMode
+	barPath               BarPath
+	barSpread             fixed.Point
+	barVolume             fixed.Point
+	unlimitedLiquidity    bool
+	expiryInfos           map[string]exchange.SymbolInfo
 
 	firstPostDone bool
 	equity        fixed.Point
 	balance       fixed.Point
 	freeMargin    fixed.Point
+	lastAccount   common.Account
+	marginCalled  bool
 
 	simulationTime time.Time
 	lastTickMap    map[string]common.Tick
+	lastBookMap    map[string]common.OrderBook
+	depthPrices    map[*common.Position]fixed.Point
 
 	positionIdCounter common.PositionId
 	openPositions     []*common.Position
 	openOrders        []*common.Order
+	triggeredOrders   map[*common.Order]struct{}
+	groupFills        []groupFill
+	releasedGroups    map[common.OrderGroupId]struct{}
 }
 
 func NewSimulator(router *bus.Router, accountCurrency string, startBalance fixed.Point, symbolStore store.SymbolStore, options ...Option) (*Simulator, error) {
@@ -75,6 +93,11 @@ func NewSimulator(router *bus.Router, accountCurrency string, startBalance fixed
 		balance:               startBalance,
 		freeMargin:            startBalance,
 		lastTickMap:           make(map[string]common.Tick),
+		lastBookMap:           make(map[string]common.OrderBook),
+		depthPrices:           make(map[*common.Position]fixed.Point),
+		triggeredOrders:       make(map[*common.Order]struct{}),
+		releasedGroups:        make(map[common.OrderGroupId]struct{}),
+		expiryInfos:           make(map[string]exchange.SymbolInfo),
 	}
 
 	for _, option := range options {
@@ -85,25 +108,40 @@ func NewSimulator(router *bus.Router, accountCurrency string, startBalance fixed
 }
 
 func (s *Simulator) OnOrder(_ context.Context, order common.Order) {
+	switch order.Command {
+	case common.OrderCommandCancel:
+		s.cancelOrder(order)
+		return
+	case common.OrderCommandAmend:
+		s.amendOrder(order)
+		return
+	}
+
+	s.acceptOrder(order)
+}
+
+// acceptOrder validates the order and either rejects it or accepts it into the open orders
+func (s *Simulator) acceptOrder(order common.Order) {
 	if err := s.validateOrder(order); err != nil {
 		s.postOrderRejected(order, fmt.Sprintf("order with trace id %d validation failed: %s", order.TraceID, err.Error()))
-	} else {
-		orderAccepted := common.OrderAccepted{
-			OriginalOrder: order,
-			Source:        simulatorComponentName,
-			ExecutionId:   utility.GetExecutionID(),
-			TraceID:       utility.CreateTraceID(),
-			TimeStamp:     s.simulationTime,
-		}
-		if err := s.router.Post(bus.OrderAcceptanceEvent, orderAccepted); err != nil {
-			slog.Error("unable to post order accepted event, dropping order...",
-				"error", err, "order_accepted", orderAccepted)
-			return
-		}
+		return
+	}
 
-		orderCopy := order
-		s.openOrders = append(s.openOrders, &orderCopy)
+	orderAccepted := common.OrderAccepted{
+		OriginalOrder: order,
+		Source:        simulatorComponentName,
+		ExecutionId:   s.router.IDs().ExecutionID(),
+		TraceID:       s.router.IDs().TraceID(),
+		ParentTraceID: order.TraceID,
+		TimeStamp:     s.simulationTime,
+	}
+	if err := s.router.Post(bus.OrderAcceptanceEvent, orderAccepted); err != nil {
+		slog.Error("unable to post order accepted event, dropping order...",
+			"error", err, "order_accepted", orderAccepted)
+		return
 	}
+
+	s.openOrders = append(s.openOrders, &order)
 }
 
 func (s *Simulator) OnTick(_ context.Context, tick common.Tick) {
@@ -125,6 +163,7 @@ func (s *Simulator) OnTick(_ context.Context, tick common.Tick) {
 	lastBalance := s.balance
 	lastEquity := s.equity
 
+	s.checkExpiries()
 	s.checkOrders(tick)
 	s.checkPositions(tick)
 	s.processPendingChanges(tick)
@@ -136,6 +175,96 @@ func (s *Simulator) OnTick(_ context.Context, tick common.Tick) {
 	if !lastEquity.Eq(s.equity) {
 		s.postEquity()
 	}
+	if account := s.snapshotAccount(); accountChanged(s.lastAccount, account) {
+		s.postAccount(account)
+	}
+}
+
+// cancelOrder removes the pending order addressed by the command and publishes its cancellation
+func (s *Simulator) cancelOrder(cmd common.Order) {
+	idx := s.findOpenOrder(cmd.OrderTraceID)
+	if idx < 0 {
+		s.postOrderRejected(cmd, fmt.Sprintf("cancel failed: pending order with trace id %d not found", cmd.OrderTraceID))
+		return
+	}
+
+	order := s.openOrders[idx]
+	s.openOrders = append(s.openOrders[:idx], s.openOrders[idx+1:]...)
+	delete(s.triggeredOrders, order)
+
+	s.postOrderCancel(*order, order.Size.Sub(order.FilledSize))
+
+	if order.GroupRelation == common.OrderGroupRelationOneTriggersOther {
+		if _, ok := s.releasedGroups[order.GroupId]; !ok {
+			s.cancelGroupOrders(order.GroupId, func(*common.Order) bool { return true })
+		}
+	}
+}
+
+// amendOrder applies the non-zero price, stop price, size and expire time of the command to the pending
+// order it addresses, the amended order must pass the same validation as a new one
+func (s *Simulator) amendOrder(cmd common.Order) {
+	idx := s.findOpenOrder(cmd.OrderTraceID)
+	if idx < 0 {
+		s.postOrderRejected(cmd, fmt.Sprintf("amend failed: pending order with trace id %d not found", cmd.OrderTraceID))
+		return
+	}
+
+	order := s.openOrders[idx]
+	amended := *order
+	if !cmd.Price.IsZero() {
+		amended.Price = cmd.Price
+	}
+	if !cmd.StopPrice.IsZero() {
+		amended.StopPrice = cmd.StopPrice
+	}
+	if !cmd.Size.IsZero() {
+		amended.Size = cmd.Size
+	}
+	if !cmd.ExpireTime.IsZero() {
+		amended.ExpireTime = cmd.ExpireTime
+	}
+
+	if amended.Size.Lte(amended.FilledSize) {
+		s.postOrderRejected(cmd, fmt.Sprintf("amend failed: size %s does not exceed filled size %s", amended.Size, amended.FilledSize))
+		return
+	}
+
+	// A triggered stop limit order rests as a limit order, its stop price no longer applies
+	validated := amended
+	if _, triggered := s.triggeredOrders[order]; triggered {
+		if order.Type != common.OrderTypeStopLimit {
+			s.postOrderRejected(cmd, "amend failed: triggered stop order is being executed")
+			return
+		}
+		validated.Type = common.OrderTypeLimit
+	}
+	if err := s.validateOrder(validated); err != nil {
+		s.postOrderRejected(cmd, fmt.Sprintf("amend failed: %s", err.Error()))
+		return
+	}
+
+	original := *order
+	*order = amended
+	s.postOrderAmended(original, amended)
+}
+
+func (s *Simulator) findOpenOrder(traceID utility.TraceID) int {
+	if traceID == 0 {
+		return -1
+	}
+	for idx, order := range s.openOrders {
+		if order.TraceID == traceID {
+			return idx
+		}
+	}
+	return -1
+}
+
+// Now returns the simulation time, the timestamp of the last processed tick. It makes the simulator
+// usable as a clock.Clock in backtests
+func (s *Simulator) Now() time.Time {
+	return s.simulationTime
 }
 
 func (s *Simulator) CloseAllOpenPositions() {
@@ -174,6 +303,7 @@ func (s *Simulator) CloseAllOpenPositions() {
 
 func (s *Simulator) checkPositions(tick common.Tick) {
 	for _, position := range s.openPositions {
+		s.trailStopLoss(position, tick)
 		if s.shouldClosePosition(*position, tick) {
 			position.Status = positionStatusPendingClose
 		}
@@ -181,6 +311,7 @@ func (s *Simulator) checkPositions(tick common.Tick) {
 }
 
 func (s *Simulator) checkOrders(tick common.Tick) {
+	previousOrders := s.openOrders
 	tmpOpenOrders := make([]*common.Order, 0, len(s.openOrders))
 
 	for _, order := range s.openOrders {
@@ -188,10 +319,41 @@ func (s *Simulator) checkOrders(tick common.Tick) {
 			tmpOpenOrders = append(tmpOpenOrders, order)
 			continue
 		}
+		if s.isOrderHeld(*order) {
+			// orders waiting for their parent still expire
+			if order.TimeInForce == common.TimeInForceGoodTillDate && s.simulationTime.After(order.ExpireTime) {
+				s.postOrderCancel(*order, order.Size.Sub(order.FilledSize))
+			} else {
+				tmpOpenOrders = append(tmpOpenOrders, order)
+			}
+			continue
+		}
+		if s.isOrderCancelledByGroup(order) {
+			s.postOrderCancel(*order, order.Size.Sub(order.FilledSize))
+			continue
+		}
+
+		orderType := order.Type
+		if order.Type == common.OrderTypeStop || order.Type == common.OrderTypeStopLimit {
+			// immediate or cancel and fill or kill apply once the stop triggered, until then only the expiry does
+			if !s.triggerStopOrder(order, tick) {
+				if order.TimeInForce == common.TimeInForceGoodTillDate && s.simulationTime.After(order.ExpireTime) {
+					s.postOrderCancel(*order, order.Size.Sub(order.FilledSize))
+				} else {
+					tmpOpenOrders = append(tmpOpenOrders, order)
+				}
+				continue
+			}
+
+			orderType = common.OrderTypeMarket
+			if order.Type == common.OrderTypeStopLimit {
+				orderType = common.OrderTypeLimit
+			}
+		}
 
 		switch order.Command {
 		case common.OrderCommandPositionOpen:
-			switch order.Type {
+			switch orderType {
 			case common.OrderTypeMarket:
 				position, err := s.executeOpenOrder(*order, tick)
 				if err != nil {
@@ -206,7 +368,7 @@ func (s *Simulator) checkOrders(tick common.Tick) {
 				switch order.TimeInForce {
 				case common.TimeInForceImmediateOrCancel:
 					if order.FilledSize.Gt(fixed.Zero) {
-						s.postOrderFilled(*order, position.Id)
+						s.fillOrder(order, position.Id)
 					}
 					if remaining.Gt(fixed.Zero) {
 						s.postOrderCancel(*order, remaining)
@@ -216,10 +378,10 @@ func (s *Simulator) checkOrders(tick common.Tick) {
 						s.openPositions = s.openPositions[:len(s.openPositions)-1]
 						s.postOrderCancel(*order, order.Size)
 					} else {
-						s.postOrderFilled(*order, position.Id)
+						s.fillOrder(order, position.Id)
 					}
 				default:
-					s.postOrderFilled(*order, position.Id)
+					s.fillOrder(order, position.Id)
 					if remaining.Gt(fixed.Zero) {
 						tmpOpenOrders = append(tmpOpenOrders, order)
 					}
@@ -248,7 +410,7 @@ func (s *Simulator) checkOrders(tick common.Tick) {
 				switch order.TimeInForce {
 				case common.TimeInForceImmediateOrCancel:
 					if order.FilledSize.Gt(fixed.Zero) {
-						s.postOrderFilled(*order, position.Id)
+						s.fillOrder(order, position.Id)
 					}
 					if remaining.Gt(fixed.Zero) {
 						s.postOrderCancel(*order, remaining)
@@ -258,7 +420,7 @@ func (s *Simulator) checkOrders(tick common.Tick) {
 						s.openPositions = s.openPositions[:len(s.openPositions)-1]
 						s.postOrderCancel(*order, order.Size)
 					} else {
-						s.postOrderFilled(*order, position.Id)
+						s.fillOrder(order, position.Id)
 					}
 				case common.TimeInForceGoodTillDate:
 					if s.simulationTime.After(order.ExpireTime) {
@@ -267,18 +429,18 @@ func (s *Simulator) checkOrders(tick common.Tick) {
 					} else if remaining.Gt(fixed.Zero) {
 						tmpOpenOrders = append(tmpOpenOrders, order)
 					} else {
-						s.postOrderFilled(*order, position.Id)
+						s.fillOrder(order, position.Id)
 					}
 				case common.TimeInForceGoodTillCancel:
 					if remaining.Gt(fixed.Zero) {
 						tmpOpenOrders = append(tmpOpenOrders, order)
 					} else {
-						s.postOrderFilled(*order, position.Id)
+						s.fillOrder(order, position.Id)
 					}
 				}
 			}
 		case common.OrderCommandPositionClose:
-			switch order.Type {
+			switch orderType {
 			case common.OrderTypeMarket:
 				position, filledSize, err := s.executeCloseOrder(*order, tick)
 				if err != nil {
@@ -299,7 +461,7 @@ func (s *Simulator) checkOrders(tick common.Tick) {
 						position.Size = filledSize
 					}
 
-					s.postOrderFilled(*order, position.Id)
+					s.fillOrder(order, position.Id)
 				}
 
 				switch order.TimeInForce {
@@ -348,7 +510,7 @@ func (s *Simulator) checkOrders(tick common.Tick) {
 
 							position.Size = filledSize
 						}
-						s.postOrderFilled(*order, position.Id)
+						s.fillOrder(order, position.Id)
 					}
 					if remaining.Gt(fixed.Zero) {
 						s.postOrderCancel(*order, remaining)
@@ -359,7 +521,7 @@ func (s *Simulator) checkOrders(tick common.Tick) {
 						s.postOrderCancel(*order, order.Size)
 					} else {
 						position.Status = positionStatusPendingClose
-						s.postOrderFilled(*order, position.Id)
+						s.fillOrder(order, position.Id)
 					}
 				case common.TimeInForceGoodTillDate:
 					if s.simulationTime.After(order.ExpireTime) {
@@ -372,12 +534,12 @@ func (s *Simulator) checkOrders(tick common.Tick) {
 							s.openPositions = append(s.openPositions, &newPosition)
 
 							position.Size = filledSize
-							s.postOrderFilled(*order, position.Id)
+							s.fillOrder(order, position.Id)
 						}
 						tmpOpenOrders = append(tmpOpenOrders, order)
 					} else {
 						position.Status = positionStatusPendingClose
-						s.postOrderFilled(*order, position.Id)
+						s.fillOrder(order, position.Id)
 					}
 				case common.TimeInForceGoodTillCancel:
 					if remaining.Gt(fixed.Zero) {
@@ -388,12 +550,12 @@ func (s *Simulator) checkOrders(tick common.Tick) {
 							s.openPositions = append(s.openPositions, &newPosition)
 
 							position.Size = filledSize
-							s.postOrderFilled(*order, position.Id)
+							s.fillOrder(order, position.Id)
 						}
 						tmpOpenOrders = append(tmpOpenOrders, order)
 					} else {
 						position.Status = positionStatusPendingClose
-						s.postOrderFilled(*order, position.Id)
+						s.fillOrder(order, position.Id)
 					}
 				}
 			}
@@ -407,6 +569,37 @@ func (s *Simulator) checkOrders(tick common.Tick) {
 	}
 
 	s.openOrders = tmpOpenOrders
+	s.resolveOrderGroups(previousOrders)
+
+	if len(s.triggeredOrders) > 0 {
+		pending := make(map[*common.Order]struct{}, len(s.triggeredOrders))
+		for _, order := range s.openOrders {
+			if _, ok := s.triggeredOrders[order]; ok {
+				pending[order] = struct{}{}
+			}
+		}
+		s.triggeredOrders = pending
+	}
+}
+
+// triggerStopOrder reports whether the stop price of the order has been reached, on this or an earlier tick.
+// Buy stops trigger on the ask, sell stops on the bid.
+func (s *Simulator) triggerStopOrder(order *common.Order, tick common.Tick) bool {
+	if _, ok := s.triggeredOrders[order]; ok {
+		return true
+	}
+
+	var triggered bool
+	if order.Side == common.OrderSideBuy {
+		triggered = tick.Ask.Gte(order.StopPrice)
+	} else {
+		triggered = tick.Bid.Lte(order.StopPrice)
+	}
+
+	if triggered {
+		s.triggeredOrders[order] = struct{}{}
+	}
+	return triggered
 }
 
 func (s *Simulator) checkMargin(tick common.Tick) {
@@ -414,23 +607,32 @@ func (s *Simulator) checkMargin(tick common.Tick) {
 	if s.equity.IsZero() {
 		s.equity = fixed.FromFloat64(0.0001)
 	}
-	freeMarginRate := s.freeMargin.Div(s.equity).MulInt(100)
-	if freeMarginRate.Lte(s.maintenanceMarginRate) {
+	s.checkMarginCall()
+
+	if s.isStopOut() {
+		marginLevel := common.MarginLevel(s.equity, s.usedMargin())
 		if len(s.openPositions) == 0 {
 			slog.Error("no open positions to close",
-				"free_margin_rate", freeMarginRate,
-				"maintenance_margin_rate", s.maintenanceMarginRate)
+				"free_margin", s.freeMargin,
+				"equity", s.equity)
 			return
 		}
-		positionToClose := s.openPositions[0]
-		s.openPositions = s.openPositions[1:]
+		idx := s.liquidationCandidate()
+		positionToClose := s.openPositions[idx]
+		s.openPositions = append(s.openPositions[:idx], s.openPositions[idx+1:]...)
 
 		tmpPosition := *positionToClose
 		s.equity = s.equity.Sub(tmpPosition.NetProfit)
 
-		closePrice := tick.Ask
+		closeTick := tick
+		if !strings.EqualFold(positionToClose.Symbol, tick.Symbol) {
+			if last, ok := s.lastTickMap[strings.ToUpper(positionToClose.Symbol)]; ok {
+				closeTick = last
+			}
+		}
+		closePrice := closeTick.Ask
 		if positionToClose.Side == common.PositionSideLong {
-			closePrice = tick.Bid
+			closePrice = closeTick.Bid
 		}
 
 		positionToClose.ClosePrice = closePrice
@@ -450,6 +652,7 @@ func (s *Simulator) checkMargin(tick common.Tick) {
 		}
 		s.equity = s.equity.Add(positionToClose.NetProfit)
 		s.balance = s.balance.Add(positionToClose.NetProfit)
+		s.postStopOut(*positionToClose, marginLevel)
 		s.checkMargin(tick)
 	}
 }
@@ -470,6 +673,14 @@ func (s *Simulator) processPendingChanges(tick common.Tick) {
 			openPrice = tick.Ask
 			closePrice = tick.Bid
 		}
+		if price, ok := s.depthPrices[position]; ok {
+			switch position.Status {
+			case positionStatusPendingOpen:
+				openPrice = price
+			case positionStatusPendingClose:
+				closePrice = price
+			}
+		}
 
 		switch position.Status {
 		case positionStatusPendingOpen:
@@ -479,6 +690,10 @@ func (s *Simulator) processPendingChanges(tick common.Tick) {
 			if s.slippageHandler != nil {
 				position.Slippage = s.slippageHandler(*position)
 			}
+			if net := s.findNetPosition(tmpOpenPositions, position.Symbol); net != nil {
+				tmpOpenPositions = s.applyNetFill(net, position, tick, tmpOpenPositions)
+				continue
+			}
 			if err := s.router.Post(bus.PositionOpenEvent, *position); err != nil {
 				slog.Warn("unable to post position opened event", "error", err)
 			}
@@ -508,6 +723,7 @@ func (s *Simulator) processPendingChanges(tick common.Tick) {
 	}
 
 	s.openPositions = tmpOpenPositions
+	clear(s.depthPrices)
 }
 
 func (s *Simulator) executeCloseOrder(order common.Order, tick common.Tick) (*common.Position, fixed.Point, error) {
@@ -521,6 +737,18 @@ func (s *Simulator) executeCloseOrder(order common.Order, tick common.Tick) (*co
 			}
 
 			size := order.Size.Sub(order.FilledSize)
+			side := common.OrderSideSell
+			if position.Side == common.PositionSideShort {
+				side = common.OrderSideBuy
+			}
+			filled, depthPrice, depth := s.depthFill(order, side, size)
+			if depth {
+				availableLiquidity = filled
+			}
+			if !depth && s.unlimitedLiquidity {
+				availableLiquidity = size
+			}
+
 			if availableLiquidity.IsZero() {
 				return nil, fixed.Zero, fmt.Errorf("available liquidity is zero")
 			}
@@ -528,8 +756,15 @@ func (s *Simulator) executeCloseOrder(order common.Order, tick common.Tick) (*co
 			if size.Gt(availableLiquidity) {
 				size = availableLiquidity
 				size = size.Rescale(2)
+				if depth {
+					// the rounded size takes a different share of the last level
+					_, depthPrice, _ = s.depthFill(order, side, size)
+				}
 			}
 			position.Status = positionStatusPendingClose
+			if depth {
+				s.depthPrices[position] = depthPrice
+			}
 			return position, size, nil
 		}
 	}
@@ -569,6 +804,16 @@ func (s *Simulator) executeOpenOrder(order common.Order, tick common.Tick) (*com
 	}
 
 	size := order.Size.Sub(order.FilledSize)
+	depthPrice := fixed.Zero
+	filled, price, depth := s.depthFill(order, order.Side, size)
+	if depth {
+		availableLiquidity = filled
+		depthPrice = price
+	}
+	if !depth && s.unlimitedLiquidity {
+		availableLiquidity = size
+	}
+
 	if availableLiquidity.IsZero() {
 		return nil, fmt.Errorf("available liquidity is zero")
 	}
@@ -576,24 +821,33 @@ func (s *Simulator) executeOpenOrder(order common.Order, tick common.Tick) (*com
 	if size.Gt(availableLiquidity) {
 		size = availableLiquidity
 		size = size.Rescale(2)
+		if depth {
+			// the rounded size takes a different share of the last level
+			_, depthPrice, _ = s.depthFill(order, order.Side, size)
+		}
 	}
 
-	s.positionIdCounter++
-	return &common.Position{
-		Source:        simulatorComponentName,
-		Symbol:        order.Symbol,
-		ExecutionID:   utility.GetExecutionID(),
-		TraceID:       utility.CreateTraceID(),
-		OrderTraceIDs: []utility.TraceID{order.TraceID},
-		Id:            s.positionIdCounter,
-		Status:        positionStatusPendingOpen,
-		Side:          positionSide,
-		Size:          size,
-		StopLoss:      order.StopLoss,
-		TakeProfit:    order.TakeProfit,
-		Currency:      s.accountCurrency,
-		TimeStamp:     s.simulationTime,
-	}, nil
+	position := &common.Position{
+		Source:           simulatorComponentName,
+		Symbol:           order.Symbol,
+		ExecutionID:      s.router.IDs().ExecutionID(),
+		TraceID:          s.router.IDs().TraceID(),
+		ParentTraceID:    order.TraceID,
+		OrderTraceIDs:    []utility.TraceID{order.TraceID},
+		Id:               s.nextPositionId(order.Symbol, positionSide, size),
+		Status:           positionStatusPendingOpen,
+		Side:             positionSide,
+		Size:             size,
+		StopLoss:         order.StopLoss,
+		TakeProfit:       order.TakeProfit,
+		TrailingDistance: order.TrailingDistance,
+		Currency:         s.accountCurrency,
+		TimeStamp:        s.simulationTime,
+	}
+	if !depthPrice.IsZero() {
+		s.depthPrices[position] = depthPrice
+	}
+	return position, nil
 }
 
 func (s *Simulator) modifyPosition(order common.Order, tick common.Tick) error {
@@ -626,6 +880,9 @@ func (s *Simulator) modifyPosition(order common.Order, tick common.Tick) error {
 			if !order.TakeProfit.IsZero() {
 				position.TakeProfit = order.TakeProfit
 			}
+			if !order.TrailingDistance.IsZero() {
+				position.TrailingDistance = order.TrailingDistance
+			}
 			position.OrderTraceIDs = append(position.OrderTraceIDs, order.TraceID)
 			return nil
 		}
@@ -650,6 +907,27 @@ func (s *Simulator) shouldExecuteLimitOrder(order common.Order, tick common.Tick
 	return false
 }
 
+// trailStopLoss moves the stop loss of an open trailing position to the trailing distance from the close
+// price, whenever that tightens it. The new stop loss is published with the next position update.
+func (s *Simulator) trailStopLoss(position *common.Position, tick common.Tick) {
+	if position.TrailingDistance.IsZero() || position.Status != common.PositionStatusOpen ||
+		!strings.EqualFold(position.Symbol, tick.Symbol) {
+		return
+	}
+
+	if position.Side == common.PositionSideLong {
+		stopLoss := tick.Bid.Sub(position.TrailingDistance)
+		if position.StopLoss.IsZero() || stopLoss.Gt(position.StopLoss) {
+			position.StopLoss = stopLoss
+		}
+	} else {
+		stopLoss := tick.Ask.Add(position.TrailingDistance)
+		if position.StopLoss.IsZero() || stopLoss.Lt(position.StopLoss) {
+			position.StopLoss = stopLoss
+		}
+	}
+}
+
 func (s *Simulator) shouldClosePosition(position common.Position, tick common.Tick) bool {
 	if !strings.EqualFold(position.Symbol, tick.Symbol) {
 		return false
@@ -714,10 +992,15 @@ func (s *Simulator) calcPositionProfits(position *common.Position, closePrice fi
 		}
 	}
 
+	marginExchangeRate := exchangeRate
+	if s.rateProvider != nil && symbolInfo.MarginCurrency() != symbolInfo.QuoteCurrency {
+		marginExchangeRate, _, _ = s.rateProvider.ExchangeRate(s.accountCurrency, symbolInfo.MarginCurrency(), s.simulationTime)
+	}
+
 	priceDiff = priceDiff.Sub(position.Slippage)
-	position.GrossProfit = priceDiff.Mul(position.Size).Mul(symbolInfo.ContractSize).Mul(exchangeRate)
+	position.GrossProfit = symbolInfo.Profit(priceDiff, position.Size).Mul(exchangeRate)
 	position.NetProfit = position.GrossProfit.Sub(position.Commissions).Sub(position.Swaps).Sub(position.OpenConversionFee).Sub(position.CloseConversionFee)
-	position.Margin = position.Size.Mul(symbolInfo.ContractSize).Mul(closePrice).Mul(exchangeRate).Div(symbolInfo.Leverage)
+	position.Margin = symbolInfo.Margin(closePrice, position.Size).Mul(marginExchangeRate)
 }
 
 func (s *Simulator) calcFreeMargin() {
@@ -731,6 +1014,9 @@ func (s *Simulator) validateOrder(order common.Order) error {
 	if order.Size.Lte(fixed.Zero) {
 		return errors.New("order size is zero or negative")
 	}
+	if order.TrailingDistance.Lt(fixed.Zero) {
+		return errors.New("trailing distance is negative")
+	}
 
 	switch order.Type {
 	case common.OrderTypeLimit:
@@ -741,6 +1027,14 @@ func (s *Simulator) validateOrder(order common.Order) error {
 		if err := s.validateMarketOrder(order); err != nil {
 			return fmt.Errorf("unable to validate market order: %w", err)
 		}
+	case common.OrderTypeStop:
+		if err := s.validateStopOrder(order); err != nil {
+			return fmt.Errorf("unable to validate stop order: %w", err)
+		}
+	case common.OrderTypeStopLimit:
+		if err := s.validateStopLimitOrder(order); err != nil {
+			return fmt.Errorf("unable to validate stop limit order: %w", err)
+		}
 	default:
 		return fmt.Errorf("invalid order type: %d", order.Type)
 	}
@@ -765,6 +1059,9 @@ func (s *Simulator) validateOrder(order common.Order) error {
 	if err := s.validateStopLossAndTakeProfit(order); err != nil {
 		return fmt.Errorf("unable to validate stop loss or take profit: %w", err)
 	}
+	if err := s.validateOrderGroup(order); err != nil {
+		return fmt.Errorf("unable to validate order group: %w", err)
+	}
 
 	return nil
 }
@@ -792,22 +1089,74 @@ func (s *Simulator) validateMarketOrder(order common.Order) error {
 	return nil
 }
 
+func (s *Simulator) validateStopOrder(order common.Order) error {
+	if order.Size.IsZero() {
+		return fmt.Errorf("order size cannot be zero")
+	}
+	if order.StopPrice.IsZero() {
+		return fmt.Errorf("stop order must have a stop price")
+	}
+	if order.Command == common.OrderCommandPositionModify {
+		return fmt.Errorf("stop order cannot modify a position")
+	}
+
+	tick, ok := s.lastTickMap[strings.ToUpper(order.Symbol)]
+	if !ok {
+		return fmt.Errorf("no tick found for symbol %s", order.Symbol)
+	}
+	if order.Side == common.OrderSideBuy {
+		if order.StopPrice.Lte(tick.Ask) {
+			return fmt.Errorf("buy stop price must be greater than ask")
+		}
+	} else {
+		if order.StopPrice.Gte(tick.Bid) {
+			return fmt.Errorf("sell stop price must be less than bid")
+		}
+	}
+	return nil
+}
+
+func (s *Simulator) validateStopLimitOrder(order common.Order) error {
+	if err := s.validateStopOrder(order); err != nil {
+		return err
+	}
+	if order.Price.IsZero() {
+		return fmt.Errorf("stop limit order must have a price")
+	}
+	if order.Side == common.OrderSideBuy {
+		if order.Price.Lt(order.StopPrice) {
+			return fmt.Errorf("buy limit price must not be less than stop price")
+		}
+	} else {
+		if order.Price.Gt(order.StopPrice) {
+			return fmt.Errorf("sell limit price must not be greater than stop price")
+		}
+	}
+	return nil
+}
+
 func (s *Simulator) validatePositionOpenOrder(order common.Order) error {
 	symbolInfo, err := s.symbolStore.Get(order.Symbol)
 	if err != nil {
 		return fmt.Errorf("order validation failed: %w", err)
 	}
+	if symbolInfo.IsExpired(s.simulationTime) {
+		return fmt.Errorf("contract %s expired at %s", symbolInfo.SymbolName, symbolInfo.Expiry.Format(time.DateTime))
+	}
 
 	exchangeRate := fixed.One
 	if s.rateProvider != nil {
 		var err error
-		exchangeRate, _, err = s.rateProvider.ExchangeRate(s.accountCurrency, symbolInfo.QuoteCurrency, s.simulationTime)
+		exchangeRate, _, err = s.rateProvider.ExchangeRate(s.accountCurrency, symbolInfo.MarginCurrency(), s.simulationTime)
 		if err != nil {
-			return fmt.Errorf("unable to retrieve exchange rate for %s", symbolInfo.QuoteCurrency)
+			return fmt.Errorf("unable to retrieve exchange rate for %s", symbolInfo.MarginCurrency())
 		}
 	}
 
 	price := order.Price
+	if price.IsZero() {
+		price = order.StopPrice
+	}
 	if price.IsZero() {
 		tick, ok := s.lastTickMap[strings.ToUpper(order.Symbol)]
 		if !ok {
@@ -819,7 +1168,7 @@ func (s *Simulator) validatePositionOpenOrder(order common.Order) error {
 		}
 	}
 
-	requiredMargin := order.Size.Mul(symbolInfo.ContractSize).Mul(price).Mul(exchangeRate).Div(symbolInfo.Leverage)
+	requiredMargin := symbolInfo.Margin(price, s.exposureIncrease(order)).Mul(exchangeRate)
 	availableMarginAfter := s.freeMargin.Sub(requiredMargin)
 	availableMarginAfterRate := availableMarginAfter.Div(s.equity).MulInt(100)
 	if availableMarginAfterRate.Lte(s.maintenanceMarginRate) {
@@ -830,6 +1179,10 @@ func (s *Simulator) validatePositionOpenOrder(order common.Order) error {
 
 func (s *Simulator) validatePositionCloseOrder(order common.Order) error {
 	if order.PositionId == 0 {
+		// The position is assigned once the one-triggers-other parent fills
+		if s.isOrderHeld(order) {
+			return nil
+		}
 		return fmt.Errorf("position ID required for close order")
 	}
 	for _, position := range s.openPositions {
@@ -842,6 +1195,10 @@ func (s *Simulator) validatePositionCloseOrder(order common.Order) error {
 
 func (s *Simulator) validatePositionModifyOrder(order common.Order) error {
 	if order.PositionId == 0 {
+		// The position is assigned once the one-triggers-other parent fills
+		if s.isOrderHeld(order) {
+			return nil
+		}
 		return fmt.Errorf("position ID required for modify order")
 	}
 	for _, position := range s.openPositions {
@@ -857,6 +1214,12 @@ func (s *Simulator) validateStopLossAndTakeProfit(order common.Order) error {
 	if !ok {
 		return fmt.Errorf("no tick found for symbol %s", order.Symbol)
 	}
+
+	// Stop entries are validated against the stop price, the market is on the other side of it until they trigger
+	if order.Command == common.OrderCommandPositionOpen && (order.Type == common.OrderTypeStop || order.Type == common.OrderTypeStopLimit) {
+		tick.Bid, tick.Ask = order.StopPrice, order.StopPrice
+	}
+
 	if order.Side == common.OrderSideBuy {
 		if !order.StopLoss.IsZero() && !order.TakeProfit.IsZero() && order.StopLoss.Gte(order.TakeProfit) {
 			return fmt.Errorf("stop loss must be less than take profit")
@@ -892,8 +1255,8 @@ func (s *Simulator) validateTick(tick common.Tick) error {
 func (s *Simulator) postBalance() {
 	balance := common.Balance{
 		Source:      simulatorComponentName,
-		ExecutionId: utility.GetExecutionID(),
-		TraceID:     utility.CreateTraceID(),
+		ExecutionId: s.router.IDs().ExecutionID(),
+		TraceID:     s.router.IDs().TraceID(),
 		TimeStamp:   s.simulationTime,
 		Value:       s.balance,
 	}
@@ -906,8 +1269,8 @@ func (s *Simulator) postBalance() {
 func (s *Simulator) postEquity() {
 	equity := common.Equity{
 		Source:      simulatorComponentName,
-		ExecutionId: utility.GetExecutionID(),
-		TraceID:     utility.CreateTraceID(),
+		ExecutionId: s.router.IDs().ExecutionID(),
+		TraceID:     s.router.IDs().TraceID(),
 		TimeStamp:   s.simulationTime,
 		Value:       s.equity,
 	}
@@ -917,11 +1280,79 @@ func (s *Simulator) postEquity() {
 	}
 }
 
+// snapshotAccount returns the state of the account, the margin level is taken against the margin of all open positions
+func (s *Simulator) snapshotAccount() common.Account {
+	usedMargin := fixed.Zero
+	for _, position := range s.openPositions {
+		usedMargin = usedMargin.Add(position.Margin)
+	}
+
+	return common.Account{
+		Balance:       s.balance,
+		Equity:        s.equity,
+		UsedMargin:    usedMargin,
+		FreeMargin:    s.equity.Sub(usedMargin),
+		MarginLevel:   common.MarginLevel(s.equity, usedMargin),
+		OpenPositions: len(s.openPositions),
+		Exposure:      s.calcExposure(),
+		Source:        simulatorComponentName,
+		TimeStamp:     s.simulationTime,
+	}
+}
+
+func (s *Simulator) calcExposure() map[string]fixed.Point {
+	if len(s.openPositions) == 0 {
+		return nil
+	}
+
+	exposure := make(map[string]fixed.Point)
+	for _, position := range s.openPositions {
+		symbolInfo, err := s.symbolStore.Get(position.Symbol)
+		if err != nil {
+			continue
+		}
+
+		units := position.Size.Mul(symbolInfo.ContractSize)
+		if position.Side == common.PositionSideShort {
+			units = units.Neg()
+		}
+		price := position.OpenPrice
+		if tick, ok := s.lastTickMap[strings.ToUpper(position.Symbol)]; ok {
+			price = tick.Bid.Add(tick.Ask).DivInt(2)
+		}
+
+		if symbolInfo.Class == exchange.Forex && symbolInfo.BaseCurrency != "" {
+			exposure[symbolInfo.BaseCurrency] = exposure[symbolInfo.BaseCurrency].Add(units)
+			exposure[symbolInfo.QuoteCurrency] = exposure[symbolInfo.QuoteCurrency].Sub(units.Mul(price))
+		} else {
+			exposure[symbolInfo.QuoteCurrency] = exposure[symbolInfo.QuoteCurrency].Add(units.Mul(price))
+		}
+	}
+	return exposure
+}
+
+func accountChanged(last, account common.Account) bool {
+	return !last.Balance.Eq(account.Balance) || !last.Equity.Eq(account.Equity) ||
+		!last.UsedMargin.Eq(account.UsedMargin) || last.OpenPositions != account.OpenPositions
+}
+
+func (s *Simulator) postAccount(account common.Account) {
+	account.ExecutionId = s.router.IDs().ExecutionID()
+	account.TraceID = s.router.IDs().TraceID()
+	if err := s.router.Post(bus.AccountEvent, account); err != nil {
+		slog.Error("unable to post account event",
+			"error", err, "account", account)
+		return
+	}
+	s.lastAccount = account
+}
+
 func (s *Simulator) postOrderRejected(order common.Order, reason string) {
 	rejectOrder := common.OrderRejected{
 		Source:        simulatorComponentName,
-		ExecutionId:   utility.GetExecutionID(),
-		TraceID:       utility.CreateTraceID(),
+		ExecutionId:   s.router.IDs().ExecutionID(),
+		TraceID:       s.router.IDs().TraceID(),
+		ParentTraceID: order.TraceID,
 		TimeStamp:     s.simulationTime,
 		OriginalOrder: order,
 		Reason:        reason,
@@ -935,8 +1366,9 @@ func (s *Simulator) postOrderRejected(order common.Order, reason string) {
 func (s *Simulator) postOrderFilled(order common.Order, positionId common.PositionId) {
 	filledOrder := common.OrderFilled{
 		Source:        simulatorComponentName,
-		ExecutionId:   utility.GetExecutionID(),
-		TraceID:       utility.CreateTraceID(),
+		ExecutionId:   s.router.IDs().ExecutionID(),
+		TraceID:       s.router.IDs().TraceID(),
+		ParentTraceID: order.TraceID,
 		TimeStamp:     s.simulationTime,
 		OriginalOrder: order,
 		PositionId:    positionId,
@@ -950,8 +1382,9 @@ func (s *Simulator) postOrderFilled(order common.Order, positionId common.Positi
 func (s *Simulator) postOrderCancel(order common.Order, cancelSize fixed.Point) {
 	cancelledOrder := common.OrderCancelled{
 		Source:        simulatorComponentName,
-		ExecutionId:   utility.GetExecutionID(),
-		TraceID:       utility.CreateTraceID(),
+		ExecutionId:   s.router.IDs().ExecutionID(),
+		TraceID:       s.router.IDs().TraceID(),
+		ParentTraceID: order.TraceID,
 		TimeStamp:     s.simulationTime,
 		OriginalOrder: order,
 		CancelledSize: cancelSize,
@@ -961,3 +1394,19 @@ func (s *Simulator) postOrderCancel(order common.Order, cancelSize fixed.Point)
 			"error", err, "order", order)
 	}
 }
+
+func (s *Simulator) postOrderAmended(original, amended common.Order) {
+	amendedOrder := common.OrderAmended{
+		Source:        simulatorComponentName,
+		ExecutionId:   s.router.IDs().ExecutionID(),
+		TraceID:       s.router.IDs().TraceID(),
+		ParentTraceID: amended.TraceID,
+		TimeStamp:     s.simulationTime,
+		OriginalOrder: original,
+		AmendedOrder:  amended,
+	}
+	if err := s.router.Post(bus.OrderAmendedEvent, amendedOrder); err != nil {
+		slog.Error("unable to post order amended event",
+			"error", err, "order", amended)
+	}
+}
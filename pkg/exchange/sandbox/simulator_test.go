@@ -754,6 +754,133 @@ func TestSandboxSimulator_shouldClosePosition(t *testing.T) {
 	}
 }
 
+func TestSandboxSimulator_trailStopLoss(t *testing.T) {
+	tests := []struct {
+		name     string
+		position common.Position
+		ticks    []common.Tick
+		expected fixed.Point
+	}{
+		{
+			name: "long position sets and ratchets stop loss up",
+			position: common.Position{
+				Symbol:           "EURUSD",
+				Side:             common.PositionSideLong,
+				Status:           common.PositionStatusOpen,
+				TrailingDistance: fixed.FromFloat64(0.0020),
+			},
+			ticks: []common.Tick{
+				{Symbol: "EURUSD", Bid: fixed.FromFloat64(1.1000), Ask: fixed.FromFloat64(1.1002)},
+				{Symbol: "EURUSD", Bid: fixed.FromFloat64(1.1030), Ask: fixed.FromFloat64(1.1032)},
+				{Symbol: "EURUSD", Bid: fixed.FromFloat64(1.1015), Ask: fixed.FromFloat64(1.1017)},
+			},
+			expected: fixed.FromFloat64(1.1010),
+		},
+		{
+			name: "short position ratchets stop loss down",
+			position: common.Position{
+				Symbol:           "EURUSD",
+				Side:             common.PositionSideShort,
+				Status:           common.PositionStatusOpen,
+				StopLoss:         fixed.FromFloat64(1.1050),
+				TrailingDistance: fixed.FromFloat64(0.0020),
+			},
+			ticks: []common.Tick{
+				{Symbol: "EURUSD", Bid: fixed.FromFloat64(1.0998), Ask: fixed.FromFloat64(1.1000)},
+				{Symbol: "EURUSD", Bid: fixed.FromFloat64(1.1008), Ask: fixed.FromFloat64(1.1010)},
+			},
+			expected: fixed.FromFloat64(1.1020),
+		},
+		{
+			name: "looser trailing stop does not replace stop loss",
+			position: common.Position{
+				Symbol:           "EURUSD",
+				Side:             common.PositionSideLong,
+				Status:           common.PositionStatusOpen,
+				StopLoss:         fixed.FromFloat64(1.0990),
+				TrailingDistance: fixed.FromFloat64(0.0020),
+			},
+			ticks: []common.Tick{
+				{Symbol: "EURUSD", Bid: fixed.FromFloat64(1.1000), Ask: fixed.FromFloat64(1.1002)},
+			},
+			expected: fixed.FromFloat64(1.0990),
+		},
+		{
+			name: "position without trailing distance",
+			position: common.Position{
+				Symbol:   "EURUSD",
+				Side:     common.PositionSideLong,
+				Status:   common.PositionStatusOpen,
+				StopLoss: fixed.FromFloat64(1.0950),
+			},
+			ticks: []common.Tick{
+				{Symbol: "EURUSD", Bid: fixed.FromFloat64(1.1100), Ask: fixed.FromFloat64(1.1102)},
+			},
+			expected: fixed.FromFloat64(1.0950),
+		},
+		{
+			name: "different symbol",
+			position: common.Position{
+				Symbol:           "GBPUSD",
+				Side:             common.PositionSideLong,
+				Status:           common.PositionStatusOpen,
+				TrailingDistance: fixed.FromFloat64(0.0020),
+			},
+			ticks: []common.Tick{
+				{Symbol: "EURUSD", Bid: fixed.FromFloat64(1.1000), Ask: fixed.FromFloat64(1.1002)},
+			},
+			expected: fixed.Zero,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, _ := createTestSimulator(t)
+			position := tt.position
+			for _, tick := range tt.ticks {
+				sim.trailStopLoss(&position, tick)
+			}
+			assert.True(t, tt.expected.Eq(position.StopLoss), "expected stop loss %s, got %s", tt.expected, position.StopLoss)
+		})
+	}
+}
+
+func TestSandboxSimulator_TrailingStopClosesPosition(t *testing.T) {
+	sim, router := createTestSimulator(t)
+	sim.openPositions = append(sim.openPositions, &common.Position{
+		Id:               1,
+		Symbol:           "EURUSD",
+		Side:             common.PositionSideLong,
+		Size:             fixed.FromFloat64(0.1),
+		Status:           common.PositionStatusOpen,
+		OpenPrice:        fixed.FromFloat64(1.1002),
+		TrailingDistance: fixed.FromFloat64(0.0010),
+	})
+
+	var updates []common.Position
+	closed := 0
+	router.OnPositionUpdate = func(_ context.Context, p common.Position) { updates = append(updates, p) }
+	router.OnPositionClose = func(_ context.Context, _ common.Position) { closed++ }
+
+	for _, bid := range []float64{1.1000, 1.1030, 1.1019} {
+		sim.OnTick(context.Background(), common.Tick{
+			Symbol:    "EURUSD",
+			Bid:       fixed.FromFloat64(bid),
+			Ask:       fixed.FromFloat64(bid + 0.0002),
+			BidVolume: fixed.FromInt(10, 0),
+			AskVolume: fixed.FromInt(10, 0),
+			TimeStamp: time.Now(),
+		})
+		require.NoError(t, router.DrainEvents(context.Background()))
+	}
+
+	require.Len(t, updates, 2)
+	assert.True(t, fixed.FromFloat64(1.0990).Eq(updates[0].StopLoss), "got %s", updates[0].StopLoss)
+	assert.True(t, fixed.FromFloat64(1.1020).Eq(updates[1].StopLoss), "got %s", updates[1].StopLoss)
+	assert.Equal(t, 1, closed)
+	assert.Empty(t, sim.openPositions)
+}
+
 func TestSandboxSimulator_calcPositionProfits(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -889,6 +1016,58 @@ func TestSandboxSimulator_calcPositionProfits(t *testing.T) {
 	}
 }
 
+func TestSandboxSimulator_calcPositionProfits_InstrumentClasses(t *testing.T) {
+	router := bus.NewRouter(1000)
+	symbolStore := store.CreateSymbolStore([]exchange.SymbolInfo{
+		{
+			SymbolName:    "US500",
+			Class:         exchange.Index,
+			QuoteCurrency: "USD",
+			ContractSize:  fixed.One,
+			TickSize:      fixed.FromFloat64(0.25),
+			TickValue:     fixed.FromFloat64(12.5),
+			MarginMode:    exchange.MarginFixed,
+			FixedMargin:   fixed.FromInt(1000, 0),
+		},
+		{
+			SymbolName:    "AAPL",
+			Class:         exchange.Stock,
+			QuoteCurrency: "USD",
+			ContractSize:  fixed.One,
+			MarginMode:    exchange.MarginPercentage,
+			MarginRate:    fixed.FromInt(20, 0),
+		},
+	}...)
+
+	sim, err := NewSimulator(router, "USD", fixed.FromInt(10000, 0), symbolStore)
+	require.NoError(t, err)
+	sim.simulationTime = time.Now()
+
+	index := &common.Position{
+		Symbol:    "US500",
+		Side:      common.PositionSideLong,
+		Size:      fixed.FromInt(2, 0),
+		OpenPrice: fixed.FromFloat64(4000),
+		Status:    common.PositionStatusOpen,
+		TimeStamp: sim.simulationTime,
+	}
+	sim.calcPositionProfits(index, fixed.FromFloat64(4002))
+	assert.True(t, fixed.FromInt(200, 0).Eq(index.GrossProfit), index.GrossProfit.String())
+	assert.True(t, fixed.FromInt(2000, 0).Eq(index.Margin), index.Margin.String())
+
+	stock := &common.Position{
+		Symbol:    "AAPL",
+		Side:      common.PositionSideShort,
+		Size:      fixed.FromInt(10, 0),
+		OpenPrice: fixed.FromFloat64(150),
+		Status:    common.PositionStatusOpen,
+		TimeStamp: sim.simulationTime,
+	}
+	sim.calcPositionProfits(stock, fixed.FromFloat64(148))
+	assert.True(t, fixed.FromInt(20, 0).Eq(stock.GrossProfit), stock.GrossProfit.String())
+	assert.True(t, fixed.FromInt(296, 0).Eq(stock.Margin), stock.Margin.String())
+}
+
 func TestSandboxSimulator_validateOrder(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -986,6 +1165,92 @@ func TestSandboxSimulator_validateOrder(t *testing.T) {
 			},
 			expectedError: "position ID required for close order",
 		},
+		{
+			name: "valid buy stop order",
+			order: common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeStop,
+				StopPrice:   fixed.FromFloat64(1.1050),
+				StopLoss:    fixed.FromFloat64(1.1020),
+				Size:        fixed.FromFloat64(0.1),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceGoodTillCancel,
+			},
+			setup: func(sim *Simulator) {
+				sim.lastTickMap["EURUSD"] = common.Tick{
+					Symbol: "EURUSD",
+					Bid:    fixed.FromFloat64(1.1000),
+					Ask:    fixed.FromFloat64(1.1002),
+				}
+			},
+		},
+		{
+			name: "negative trailing distance",
+			order: common.Order{
+				Symbol:           "EURUSD",
+				Side:             common.OrderSideBuy,
+				Type:             common.OrderTypeMarket,
+				Size:             fixed.FromFloat64(0.1),
+				TrailingDistance: fixed.FromFloat64(-0.0010),
+				Command:          common.OrderCommandPositionOpen,
+				TimeInForce:      common.TimeInForceImmediateOrCancel,
+			},
+			expectedError: "trailing distance is negative",
+		},
+		{
+			name: "stop order without stop price",
+			order: common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeStop,
+				Size:        fixed.FromFloat64(0.1),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceGoodTillCancel,
+			},
+			expectedError: "stop order must have a stop price",
+		},
+		{
+			name: "buy stop below ask",
+			order: common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeStop,
+				StopPrice:   fixed.FromFloat64(1.0990),
+				Size:        fixed.FromFloat64(0.1),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceGoodTillCancel,
+			},
+			setup: func(sim *Simulator) {
+				sim.lastTickMap["EURUSD"] = common.Tick{
+					Symbol: "EURUSD",
+					Bid:    fixed.FromFloat64(1.1000),
+					Ask:    fixed.FromFloat64(1.1002),
+				}
+			},
+			expectedError: "buy stop price must be greater than ask",
+		},
+		{
+			name: "sell stop limit with limit above stop",
+			order: common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideSell,
+				Type:        common.OrderTypeStopLimit,
+				StopPrice:   fixed.FromFloat64(1.0950),
+				Price:       fixed.FromFloat64(1.0960),
+				Size:        fixed.FromFloat64(0.1),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceGoodTillCancel,
+			},
+			setup: func(sim *Simulator) {
+				sim.lastTickMap["EURUSD"] = common.Tick{
+					Symbol: "EURUSD",
+					Bid:    fixed.FromFloat64(1.1000),
+					Ask:    fixed.FromFloat64(1.1002),
+				}
+			},
+			expectedError: "sell limit price must not be greater than stop price",
+		},
 		{
 			name: "insufficient margin",
 			order: common.Order{
@@ -1471,6 +1736,100 @@ func TestSandboxSimulator_checkMargin(t *testing.T) {
 	}
 }
 
+func TestSandboxSimulator_MarginCallAndStopOut(t *testing.T) {
+	tick := common.Tick{
+		Symbol: "EURUSD",
+		Bid:    fixed.FromFloat64(1.1000),
+		Ask:    fixed.FromFloat64(1.1002),
+	}
+	position := func(id common.PositionId, openTime time.Time, netProfit, margin float64) *common.Position {
+		return &common.Position{
+			Id:        id,
+			Symbol:    "EURUSD",
+			Side:      common.PositionSideLong,
+			Size:      fixed.FromFloat64(0.01),
+			OpenPrice: fixed.FromFloat64(1.1000),
+			OpenTime:  openTime,
+			Status:    common.PositionStatusOpen,
+			NetProfit: fixed.FromFloat64(netProfit),
+			Margin:    fixed.FromFloat64(margin),
+		}
+	}
+
+	type events struct {
+		marginCalls []common.MarginCall
+		stopOuts    []common.StopOut
+	}
+	setup := func(t *testing.T, options ...Option) (*Simulator, *bus.Router, *events) {
+		sim, router := createTestSimulator(t)
+		WithMarginCallLevel(fixed.FromInt(100, 0))(sim)
+		WithStopOutLevel(fixed.FromInt(50, 0))(sim)
+		for _, option := range options {
+			option(sim)
+		}
+		sim.balance = fixed.FromFloat64(100)
+		sim.equity = fixed.FromFloat64(100)
+
+		ev := &events{}
+		router.OnMarginCall = func(_ context.Context, m common.MarginCall) { ev.marginCalls = append(ev.marginCalls, m) }
+		router.OnStopOut = func(_ context.Context, s common.StopOut) { ev.stopOuts = append(ev.stopOuts, s) }
+		return sim, router, ev
+	}
+
+	t.Run("margin call is posted once per breach", func(t *testing.T) {
+		sim, router, ev := setup(t)
+		sim.openPositions = append(sim.openPositions, position(1, sim.simulationTime, 0, 120))
+
+		sim.checkMargin(tick)
+		sim.checkMargin(tick)
+		require.NoError(t, router.DrainEvents(context.Background()))
+
+		require.Len(t, ev.marginCalls, 1)
+		assert.True(t, fixed.FromInt(100, 0).Eq(ev.marginCalls[0].CallLevel))
+		assert.True(t, fixed.FromFloat64(120).Eq(ev.marginCalls[0].UsedMargin))
+		assert.Empty(t, ev.stopOuts)
+		assert.Len(t, sim.openPositions, 1)
+
+		sim.openPositions[0].Margin = fixed.FromFloat64(50)
+		sim.checkMargin(tick)
+		sim.openPositions[0].Margin = fixed.FromFloat64(120)
+		sim.checkMargin(tick)
+		require.NoError(t, router.DrainEvents(context.Background()))
+		assert.Len(t, ev.marginCalls, 2)
+	})
+
+	policies := []struct {
+		name   string
+		policy LiquidationPolicy
+		wantId common.PositionId
+	}{
+		{name: "fifo", policy: LiquidateFIFO, wantId: 2},
+		{name: "largest loss", policy: LiquidateLargestLoss, wantId: 3},
+		{name: "largest margin", policy: LiquidateLargestMargin, wantId: 1},
+	}
+	for _, tt := range policies {
+		t.Run("stop out "+tt.name, func(t *testing.T) {
+			sim, router, ev := setup(t, WithLiquidationPolicy(tt.policy))
+			now := sim.simulationTime
+			sim.openPositions = append(sim.openPositions,
+				position(1, now.Add(-time.Hour), 0, 200),
+				position(2, now.Add(-2*time.Hour), -5, 100),
+				position(3, now.Add(-30*time.Minute), -50, 50),
+			)
+
+			sim.checkMargin(tick)
+			require.NoError(t, router.DrainEvents(context.Background()))
+
+			require.NotEmpty(t, ev.stopOuts)
+			assert.Equal(t, tt.wantId, ev.stopOuts[0].Position.Id)
+			assert.Equal(t, common.PositionStatusClosed, ev.stopOuts[0].Position.Status)
+			assert.True(t, fixed.FromInt(50, 0).Eq(ev.stopOuts[0].StopOutLevel))
+			assert.True(t, ev.stopOuts[0].MarginLevel.Lte(fixed.FromInt(50, 0)))
+			assert.Len(t, ev.marginCalls, 1)
+		})
+	}
+}
+
 func TestSandboxSimulator_CloseAllOpenPositions(t *testing.T) {
 	sim, router := createTestSimulator(t)
 
@@ -1519,143 +1878,1248 @@ func TestSandboxSimulator_CloseAllOpenPositions(t *testing.T) {
 	assert.Equal(t, sim.balance, sim.equity)
 	assert.Len(t, positions, 2)
 
-	closedPos1 := positions[0]
-	assert.Equal(t, common.PositionStatusClosed, closedPos1.Status)
-	assert.Equal(t, fixed.FromFloat64(1.1000).String(), closedPos1.ClosePrice.String())
+	closedPos1 := positions[0]
+	assert.Equal(t, common.PositionStatusClosed, closedPos1.Status)
+	assert.Equal(t, fixed.FromFloat64(1.1000).String(), closedPos1.ClosePrice.String())
+
+	closedPos2 := positions[1]
+	assert.Equal(t, common.PositionStatusClosed, closedPos2.Status)
+	assert.Equal(t, fixed.FromFloat64(1.2502).String(), closedPos2.ClosePrice.String())
+}
+
+func TestSandboxSimulator_checkOrders_TimeInForce(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    *common.Order
+		tick     common.Tick
+		validate func(*testing.T, *Simulator, int, int)
+	}{
+		{
+			name: "IOC partial fill and cancel",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeMarket,
+				Size:        fixed.FromFloat64(5.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceImmediateOrCancel,
+			},
+			tick: common.Tick{
+				Symbol:    "EURUSD",
+				Bid:       fixed.FromFloat64(1.1000),
+				Ask:       fixed.FromFloat64(1.1002),
+				BidVolume: fixed.FromInt(10, 0),
+				AskVolume: fixed.FromFloat64(2.0),
+			},
+			validate: func(t *testing.T, sim *Simulator, filledCount, canceledCount int) {
+				assert.Empty(t, sim.openOrders)
+				assert.Len(t, sim.openPositions, 1)
+				f1, _ := fixed.Two.Float64()
+				f2, _ := sim.openPositions[0].Size.Float64()
+				assert.Equal(t, f1, f2)
+				assert.Equal(t, filledCount, 1)
+				assert.Equal(t, canceledCount, 1)
+			},
+		},
+		{
+			name: "FOK all or nothing - filled",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeMarket,
+				Size:        fixed.FromFloat64(1.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceFillOrKill,
+			},
+			tick: common.Tick{
+				Symbol:    "EURUSD",
+				Bid:       fixed.FromFloat64(1.1000),
+				Ask:       fixed.FromFloat64(1.1002),
+				BidVolume: fixed.FromInt(10, 0),
+				AskVolume: fixed.FromFloat64(2.0),
+			},
+			validate: func(t *testing.T, sim *Simulator, filledCount, canceledCount int) {
+				assert.Empty(t, sim.openOrders)
+				assert.Len(t, sim.openPositions, 1)
+				assert.Equal(t, filledCount, 1)
+				assert.Equal(t, canceledCount, 0)
+			},
+		},
+		{
+			name: "FOK all or nothing - cancelled",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeMarket,
+				Size:        fixed.FromFloat64(5.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceFillOrKill,
+			},
+			tick: common.Tick{
+				Symbol:    "EURUSD",
+				Bid:       fixed.FromFloat64(1.1000),
+				Ask:       fixed.FromFloat64(1.1002),
+				BidVolume: fixed.FromInt(10, 0),
+				AskVolume: fixed.FromFloat64(2.0),
+			},
+			validate: func(t *testing.T, sim *Simulator, filledCount, canceledCount int) {
+				assert.Empty(t, sim.openOrders)
+				assert.Empty(t, sim.openPositions)
+				assert.Equal(t, filledCount, 0)
+				assert.Equal(t, canceledCount, 1)
+			},
+		},
+		{
+			name: "GTD expired order cancelled",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeLimit,
+				Price:       fixed.FromFloat64(1.0995),
+				Size:        fixed.FromFloat64(1.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceGoodTillDate,
+				ExpireTime:  time.Now().Add(-1 * time.Hour),
+			},
+			tick: common.Tick{
+				Symbol:    "EURUSD",
+				Bid:       fixed.FromFloat64(1.1000),
+				Ask:       fixed.FromFloat64(1.1002),
+				BidVolume: fixed.FromInt(10, 0),
+				AskVolume: fixed.FromInt(10, 0),
+			},
+			validate: func(t *testing.T, sim *Simulator, _, canceledCount int) {
+				assert.Empty(t, sim.openOrders)
+				assert.Empty(t, sim.openPositions)
+				assert.Equal(t, canceledCount, 1)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, router := createTestSimulator(t)
+			sim.openOrders = append(sim.openOrders, tt.order)
+
+			filledCount := 0
+			router.OnOrderFilled = func(_ context.Context, _ common.OrderFilled) { filledCount++ }
+
+			canceledCount := 0
+			router.OnOrderCancel = func(_ context.Context, _ common.OrderCancelled) { canceledCount++ }
+
+			sim.checkOrders(tt.tick)
+			_ = router.DrainEvents(context.Background())
+
+			if tt.validate != nil {
+				tt.validate(t, sim, filledCount, canceledCount)
+			}
+		})
+	}
+}
+
+func TestSandboxSimulator_checkOrders_StopOrders(t *testing.T) {
+	tick := func(bid, ask float64) common.Tick {
+		return common.Tick{
+			Symbol:    "EURUSD",
+			Bid:       fixed.FromFloat64(bid),
+			Ask:       fixed.FromFloat64(ask),
+			BidVolume: fixed.FromInt(10, 0),
+			AskVolume: fixed.FromInt(10, 0),
+		}
+	}
+
+	tests := []struct {
+		name          string
+		order         *common.Order
+		ticks         []common.Tick
+		openOrders    int
+		openPositions int
+		filledCount   int
+		canceledCount int
+	}{
+		{
+			name: "buy stop waits for the ask to reach the stop price",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeStop,
+				StopPrice:   fixed.FromFloat64(1.1010),
+				Size:        fixed.FromFloat64(1.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceGoodTillCancel,
+			},
+			ticks:         []common.Tick{tick(1.1000, 1.1002), tick(1.1005, 1.1007)},
+			openOrders:    1,
+			openPositions: 0,
+		},
+		{
+			name: "buy stop triggers as market order",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeStop,
+				StopPrice:   fixed.FromFloat64(1.1010),
+				Size:        fixed.FromFloat64(1.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceGoodTillCancel,
+			},
+			ticks:         []common.Tick{tick(1.1000, 1.1002), tick(1.1010, 1.1012)},
+			openOrders:    0,
+			openPositions: 1,
+			filledCount:   1,
+		},
+		{
+			name: "sell stop triggers on the bid",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideSell,
+				Type:        common.OrderTypeStop,
+				StopPrice:   fixed.FromFloat64(1.0990),
+				Size:        fixed.FromFloat64(1.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceGoodTillCancel,
+			},
+			ticks:         []common.Tick{tick(1.0991, 1.0989), tick(1.0990, 1.0992)},
+			openOrders:    0,
+			openPositions: 1,
+			filledCount:   1,
+		},
+		{
+			name: "IOC stop not triggered keeps waiting",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideSell,
+				Type:        common.OrderTypeStop,
+				StopPrice:   fixed.FromFloat64(1.0990),
+				Size:        fixed.FromFloat64(1.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceImmediateOrCancel,
+			},
+			ticks:      []common.Tick{tick(1.1000, 1.1002)},
+			openOrders: 1,
+		},
+		{
+			name: "IOC stop triggers on a later tick",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideSell,
+				Type:        common.OrderTypeStop,
+				StopPrice:   fixed.FromFloat64(1.0990),
+				Size:        fixed.FromFloat64(1.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceImmediateOrCancel,
+			},
+			ticks:         []common.Tick{tick(1.1000, 1.1002), tick(1.0995, 1.0997), tick(1.0989, 1.0991)},
+			openOrders:    0,
+			openPositions: 1,
+			filledCount:   1,
+		},
+		{
+			name: "IOC stop limit gapping over the limit is cancelled once triggered",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeStopLimit,
+				StopPrice:   fixed.FromFloat64(1.1010),
+				Price:       fixed.FromFloat64(1.1015),
+				Size:        fixed.FromFloat64(1.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceImmediateOrCancel,
+			},
+			ticks:         []common.Tick{tick(1.1000, 1.1002), tick(1.1020, 1.1022)},
+			canceledCount: 1,
+		},
+		{
+			name: "GTD stop expired before trigger is cancelled",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeStop,
+				StopPrice:   fixed.FromFloat64(1.1010),
+				Size:        fixed.FromFloat64(1.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceGoodTillDate,
+				ExpireTime:  time.Now().Add(-1 * time.Hour),
+			},
+			ticks:         []common.Tick{tick(1.1000, 1.1002)},
+			canceledCount: 1,
+		},
+		{
+			name: "stop limit gapping over the limit rests until the limit is reached",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeStopLimit,
+				StopPrice:   fixed.FromFloat64(1.1010),
+				Price:       fixed.FromFloat64(1.1015),
+				Size:        fixed.FromFloat64(1.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceGoodTillCancel,
+			},
+			ticks:         []common.Tick{tick(1.1020, 1.1022)},
+			openOrders:    1,
+			openPositions: 0,
+		},
+		{
+			name: "stop limit stays triggered when the market returns below the stop",
+			order: &common.Order{
+				Symbol:      "EURUSD",
+				Side:        common.OrderSideBuy,
+				Type:        common.OrderTypeStopLimit,
+				StopPrice:   fixed.FromFloat64(1.1010),
+				Price:       fixed.FromFloat64(1.1015),
+				Size:        fixed.FromFloat64(1.0),
+				Command:     common.OrderCommandPositionOpen,
+				TimeInForce: common.TimeInForceGoodTillCancel,
+			},
+			ticks:         []common.Tick{tick(1.1020, 1.1022), tick(1.1003, 1.1005)},
+			openOrders:    0,
+			openPositions: 1,
+			filledCount:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, router := createTestSimulator(t)
+			sim.openOrders = append(sim.openOrders, tt.order)
+
+			filledCount := 0
+			router.OnOrderFilled = func(_ context.Context, _ common.OrderFilled) { filledCount++ }
+
+			canceledCount := 0
+			router.OnOrderCancel = func(_ context.Context, _ common.OrderCancelled) { canceledCount++ }
+
+			for _, tick := range tt.ticks {
+				sim.checkOrders(tick)
+			}
+			_ = router.DrainEvents(context.Background())
+
+			assert.Len(t, sim.openOrders, tt.openOrders)
+			assert.Len(t, sim.openPositions, tt.openPositions)
+			assert.Equal(t, tt.filledCount, filledCount)
+			assert.Equal(t, tt.canceledCount, canceledCount)
+			assert.LessOrEqual(t, len(sim.triggeredOrders), len(sim.openOrders))
+		})
+	}
+}
+
+func TestSandboxSimulator_CancelOrder(t *testing.T) {
+	sim, router := createTestSimulator(t)
+	sim.lastTickMap["EURUSD"] = common.Tick{Symbol: "EURUSD", Bid: fixed.FromFloat64(1.1000), Ask: fixed.FromFloat64(1.1002)}
+
+	pending := &common.Order{
+		Symbol:      "EURUSD",
+		Side:        common.OrderSideBuy,
+		Type:        common.OrderTypeLimit,
+		Price:       fixed.FromFloat64(1.0950),
+		Size:        fixed.FromFloat64(1.0),
+		FilledSize:  fixed.FromFloat64(0.4),
+		Command:     common.OrderCommandPositionOpen,
+		TimeInForce: common.TimeInForceGoodTillCancel,
+		TraceID:     100,
+	}
+	sim.openOrders = append(sim.openOrders, pending)
+
+	var cancelled []common.OrderCancelled
+	var rejected []common.OrderRejected
+	router.OnOrderCancel = func(_ context.Context, c common.OrderCancelled) { cancelled = append(cancelled, c) }
+	router.OnOrderRejection = func(_ context.Context, r common.OrderRejected) { rejected = append(rejected, r) }
+
+	sim.OnOrder(context.Background(), common.Order{Command: common.OrderCommandCancel, OrderTraceID: 101, TraceID: 200})
+	require.NoError(t, router.DrainEvents(context.Background()))
+	require.Len(t, rejected, 1)
+	assert.Contains(t, rejected[0].Reason, "not found")
+	assert.Len(t, sim.openOrders, 1)
+
+	sim.OnOrder(context.Background(), common.Order{Command: common.OrderCommandCancel, OrderTraceID: 100, TraceID: 201})
+	require.NoError(t, router.DrainEvents(context.Background()))
+	require.Len(t, cancelled, 1)
+	assert.Equal(t, utility.TraceID(100), cancelled[0].OriginalOrder.TraceID)
+	assert.Equal(t, utility.TraceID(100), cancelled[0].ParentTraceID)
+	assert.True(t, fixed.FromFloat64(0.6).Eq(cancelled[0].CancelledSize))
+	assert.Empty(t, sim.openOrders)
+}
+
+func TestSandboxSimulator_AmendOrder(t *testing.T) {
+	newPending := func() *common.Order {
+		return &common.Order{
+			Symbol:      "EURUSD",
+			Side:        common.OrderSideBuy,
+			Type:        common.OrderTypeLimit,
+			Price:       fixed.FromFloat64(1.0950),
+			Size:        fixed.FromFloat64(1.0),
+			FilledSize:  fixed.FromFloat64(0.4),
+			Command:     common.OrderCommandPositionOpen,
+			TimeInForce: common.TimeInForceGoodTillCancel,
+			TraceID:     100,
+		}
+	}
+	expire := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		pending       *common.Order
+		triggered     bool
+		cmd           common.Order
+		expectedError string
+		validate      func(*testing.T, common.Order)
+	}{
+		{
+			name:    "reprice and resize",
+			pending: newPending(),
+			cmd:     common.Order{Price: fixed.FromFloat64(1.0960), Size: fixed.FromFloat64(2.0)},
+			validate: func(t *testing.T, o common.Order) {
+				assert.True(t, fixed.FromFloat64(1.0960).Eq(o.Price))
+				assert.True(t, fixed.FromFloat64(2.0).Eq(o.Size))
+				assert.True(t, fixed.FromFloat64(0.4).Eq(o.FilledSize))
+			},
+		},
+		{
+			name: "change expiry",
+			pending: func() *common.Order {
+				o := newPending()
+				o.TimeInForce = common.TimeInForceGoodTillDate
+				return o
+			}(),
+			cmd: common.Order{ExpireTime: expire},
+			validate: func(t *testing.T, o common.Order) {
+				assert.True(t, fixed.FromFloat64(1.0950).Eq(o.Price))
+				assert.Equal(t, expire, o.ExpireTime)
+			},
+		},
+		{
+			name:          "size below filled size",
+			pending:       newPending(),
+			cmd:           common.Order{Size: fixed.FromFloat64(0.3)},
+			expectedError: "does not exceed filled size",
+		},
+		{
+			name:          "unknown order",
+			pending:       newPending(),
+			cmd:           common.Order{OrderTraceID: 101, Price: fixed.FromFloat64(1.0960)},
+			expectedError: "not found",
+		},
+		{
+			name: "untriggered stop moved below ask",
+			pending: func() *common.Order {
+				o := newPending()
+				o.Type = common.OrderTypeStop
+				o.StopPrice = fixed.FromFloat64(1.1050)
+				return o
+			}(),
+			cmd:           common.Order{StopPrice: fixed.FromFloat64(1.0990)},
+			expectedError: "buy stop price must be greater than ask",
+		},
+		{
+			name: "triggered stop limit repriced as limit",
+			pending: func() *common.Order {
+				o := newPending()
+				o.Type = common.OrderTypeStopLimit
+				o.StopPrice = fixed.FromFloat64(1.0990)
+				o.Price = fixed.FromFloat64(1.0995)
+				return o
+			}(),
+			triggered: true,
+			cmd:       common.Order{Price: fixed.FromFloat64(1.0998)},
+			validate: func(t *testing.T, o common.Order) {
+				assert.Equal(t, common.OrderTypeStopLimit, o.Type)
+				assert.True(t, fixed.FromFloat64(1.0998).Eq(o.Price))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, router := createTestSimulator(t)
+			sim.lastTickMap["EURUSD"] = common.Tick{Symbol: "EURUSD", Bid: fixed.FromFloat64(1.1000), Ask: fixed.FromFloat64(1.1002)}
+			sim.openOrders = append(sim.openOrders, tt.pending)
+			if tt.triggered {
+				sim.triggeredOrders[tt.pending] = struct{}{}
+			}
+			original := *tt.pending
+
+			var amended []common.OrderAmended
+			var rejected []common.OrderRejected
+			router.OnOrderAmended = func(_ context.Context, a common.OrderAmended) { amended = append(amended, a) }
+			router.OnOrderRejection = func(_ context.Context, r common.OrderRejected) { rejected = append(rejected, r) }
+
+			cmd := tt.cmd
+			cmd.Command = common.OrderCommandAmend
+			if cmd.OrderTraceID == 0 {
+				cmd.OrderTraceID = tt.pending.TraceID
+			}
+			sim.OnOrder(context.Background(), cmd)
+			require.NoError(t, router.DrainEvents(context.Background()))
+
+			require.Len(t, sim.openOrders, 1)
+			if tt.expectedError != "" {
+				require.Len(t, rejected, 1)
+				assert.Contains(t, rejected[0].Reason, tt.expectedError)
+				assert.Empty(t, amended)
+				assert.Equal(t, original, *sim.openOrders[0])
+				return
+			}
+
+			require.Empty(t, rejected)
+			require.Len(t, amended, 1)
+			assert.Equal(t, original, amended[0].OriginalOrder)
+			assert.Equal(t, *sim.openOrders[0], amended[0].AmendedOrder)
+			assert.Equal(t, original.TraceID, amended[0].ParentTraceID)
+			tt.validate(t, *sim.openOrders[0])
+		})
+	}
+}
+
+func TestSandboxSimulator_checkOrders_OneCancelsOther(t *testing.T) {
+	tick := func(bid, ask float64) common.Tick {
+		return common.Tick{
+			Symbol:    "EURUSD",
+			Bid:       fixed.FromFloat64(bid),
+			Ask:       fixed.FromFloat64(ask),
+			BidVolume: fixed.FromInt(10, 0),
+			AskVolume: fixed.FromInt(10, 0),
+		}
+	}
+	order := func(traceID utility.TraceID, side common.OrderSide, orderType common.OrderType, price float64) *common.Order {
+		o := &common.Order{
+			Symbol:        "EURUSD",
+			Side:          side,
+			Type:          orderType,
+			Size:          fixed.FromFloat64(0.1),
+			Command:       common.OrderCommandPositionOpen,
+			TimeInForce:   common.TimeInForceGoodTillCancel,
+			GroupId:       1,
+			GroupRelation: common.OrderGroupRelationOneCancelsOther,
+			TraceID:       traceID,
+		}
+		if orderType == common.OrderTypeStop {
+			o.StopPrice = fixed.FromFloat64(price)
+		} else {
+			o.Price = fixed.FromFloat64(price)
+		}
+		return o
+	}
+
+	tests := []struct {
+		name      string
+		orders    []*common.Order
+		ticks     []common.Tick
+		filled    []utility.TraceID
+		cancelled []utility.TraceID
+	}{
+		{
+			name: "breakout fill cancels the other side",
+			orders: []*common.Order{
+				order(10, common.OrderSideSell, common.OrderTypeStop, 1.0990),
+				order(11, common.OrderSideBuy, common.OrderTypeStop, 1.1010),
+			},
+			ticks:     []common.Tick{tick(1.1000, 1.1002), tick(1.1010, 1.1012)},
+			filled:    []utility.TraceID{11},
+			cancelled: []utility.TraceID{10},
+		},
+		{
+			name: "only the first order executable on the same tick fills",
+			orders: []*common.Order{
+				order(20, common.OrderSideBuy, common.OrderTypeLimit, 1.1005),
+				order(21, common.OrderSideBuy, common.OrderTypeLimit, 1.1004),
+			},
+			ticks:     []common.Tick{tick(1.0998, 1.1000)},
+			filled:    []utility.TraceID{20},
+			cancelled: []utility.TraceID{21},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, router := createTestSimulator(t)
+			sim.openOrders = append(sim.openOrders, tt.orders...)
+
+			var filled, cancelled []utility.TraceID
+			router.OnOrderFilled = func(_ context.Context, f common.OrderFilled) { filled = append(filled, f.OriginalOrder.TraceID) }
+			router.OnOrderCancel = func(_ context.Context, c common.OrderCancelled) {
+				cancelled = append(cancelled, c.OriginalOrder.TraceID)
+			}
+
+			for _, tick := range tt.ticks {
+				sim.checkOrders(tick)
+			}
+			require.NoError(t, router.DrainEvents(context.Background()))
+
+			assert.Equal(t, tt.filled, filled)
+			assert.Equal(t, tt.cancelled, cancelled)
+			assert.Empty(t, sim.openOrders)
+			assert.Len(t, sim.openPositions, len(tt.filled))
+		})
+	}
+}
+
+func TestSandboxSimulator_OrderGroupBracket(t *testing.T) {
+	tick := func(bid, ask float64) common.Tick {
+		return common.Tick{
+			Symbol:    "EURUSD",
+			Bid:       fixed.FromFloat64(bid),
+			Ask:       fixed.FromFloat64(ask),
+			BidVolume: fixed.FromInt(10, 0),
+			AskVolume: fixed.FromInt(10, 0),
+			TimeStamp: time.Now(),
+		}
+	}
+	entry := common.Order{
+		Symbol:        "EURUSD",
+		Side:          common.OrderSideBuy,
+		Type:          common.OrderTypeLimit,
+		Price:         fixed.FromFloat64(1.0990),
+		Size:          fixed.FromFloat64(0.1),
+		Command:       common.OrderCommandPositionOpen,
+		TimeInForce:   common.TimeInForceGoodTillCancel,
+		GroupId:       7,
+		GroupRelation: common.OrderGroupRelationOneTriggersOther,
+		TraceID:       20,
+	}
+	stopLoss := common.Order{
+		Symbol:        "EURUSD",
+		Side:          common.OrderSideSell,
+		Type:          common.OrderTypeStop,
+		StopPrice:     fixed.FromFloat64(1.0950),
+		Size:          fixed.FromFloat64(0.1),
+		Command:       common.OrderCommandPositionClose,
+		TimeInForce:   common.TimeInForceGoodTillCancel,
+		GroupId:       7,
+		GroupRelation: common.OrderGroupRelationOneCancelsOther,
+		TraceID:       21,
+	}
+
+	t.Run("children are released when the parent fills", func(t *testing.T) {
+		sim, router := createTestSimulator(t)
+
+		var filled []common.OrderFilled
+		var rejected []common.OrderRejected
+		var closed []common.Position
+		router.OnOrderFilled = func(_ context.Context, f common.OrderFilled) { filled = append(filled, f) }
+		router.OnOrderRejection = func(_ context.Context, r common.OrderRejected) { rejected = append(rejected, r) }
+		router.OnPositionClose = func(_ context.Context, p common.Position) { closed = append(closed, p) }
+
+		sim.OnTick(context.Background(), tick(1.1000, 1.1002))
+		sim.OnOrder(context.Background(), entry)
+		sim.OnOrder(context.Background(), stopLoss)
+		require.NoError(t, router.DrainEvents(context.Background()))
+		require.Empty(t, rejected)
+		require.Len(t, sim.openOrders, 2)
+
+		sim.OnTick(context.Background(), tick(1.0985, 1.0988))
+		require.NoError(t, router.DrainEvents(context.Background()))
+		require.Len(t, filled, 1)
+		require.Len(t, sim.openPositions, 1)
+		require.Len(t, sim.openOrders, 1)
+		assert.Equal(t, sim.openPositions[0].Id, sim.openOrders[0].PositionId)
+
+		sim.OnTick(context.Background(), tick(1.0940, 1.0942))
+		require.NoError(t, router.DrainEvents(context.Background()))
+		require.Len(t, filled, 2)
+		assert.Equal(t, utility.TraceID(21), filled[1].OriginalOrder.TraceID)
+		assert.Len(t, closed, 1)
+		assert.Empty(t, sim.openOrders)
+		assert.Empty(t, sim.releasedGroups)
+	})
+
+	t.Run("cancelling the parent cancels its children", func(t *testing.T) {
+		sim, router := createTestSimulator(t)
+
+		var cancelled []utility.TraceID
+		router.OnOrderCancel = func(_ context.Context, c common.OrderCancelled) {
+			cancelled = append(cancelled, c.OriginalOrder.TraceID)
+		}
+
+		sim.OnTick(context.Background(), tick(1.1000, 1.1002))
+		sim.OnOrder(context.Background(), entry)
+		sim.OnOrder(context.Background(), stopLoss)
+		sim.OnOrder(context.Background(), common.Order{Command: common.OrderCommandCancel, OrderTraceID: 20, TraceID: 22})
+		require.NoError(t, router.DrainEvents(context.Background()))
+
+		assert.Equal(t, []utility.TraceID{20, 21}, cancelled)
+		assert.Empty(t, sim.openOrders)
+	})
+
+	t.Run("parent cancelled by time in force cancels its children", func(t *testing.T) {
+		sim, router := createTestSimulator(t)
+
+		var cancelled []utility.TraceID
+		router.OnOrderCancel = func(_ context.Context, c common.OrderCancelled) {
+			cancelled = append(cancelled, c.OriginalOrder.TraceID)
+		}
+
+		parent := entry
+		parent.TimeInForce = common.TimeInForceImmediateOrCancel
+
+		sim.OnTick(context.Background(), tick(1.1000, 1.1002))
+		sim.OnOrder(context.Background(), parent)
+		sim.OnOrder(context.Background(), stopLoss)
+		sim.OnTick(context.Background(), tick(1.1000, 1.1002))
+		require.NoError(t, router.DrainEvents(context.Background()))
+
+		assert.Equal(t, []utility.TraceID{20, 21}, cancelled)
+		assert.Empty(t, sim.openOrders)
+	})
+
+	t.Run("held child expires before the parent fills", func(t *testing.T) {
+		sim, router := createTestSimulator(t)
+
+		var filled []common.OrderFilled
+		var cancelled []utility.TraceID
+		router.OnOrderFilled = func(_ context.Context, f common.OrderFilled) { filled = append(filled, f) }
+		router.OnOrderCancel = func(_ context.Context, c common.OrderCancelled) {
+			cancelled = append(cancelled, c.OriginalOrder.TraceID)
+		}
+
+		start := time.Now()
+		expiring := stopLoss
+		expiring.TimeInForce = common.TimeInForceGoodTillDate
+		expiring.ExpireTime = start.Add(time.Minute)
+
+		first := tick(1.1000, 1.1002)
+		first.TimeStamp = start
+		sim.OnTick(context.Background(), first)
+		sim.OnOrder(context.Background(), entry)
+		sim.OnOrder(context.Background(), expiring)
+		require.NoError(t, router.DrainEvents(context.Background()))
+		require.Len(t, sim.openOrders, 2)
+
+		expired := tick(1.1000, 1.1002)
+		expired.TimeStamp = start.Add(2 * time.Minute)
+		sim.OnTick(context.Background(), expired)
+		require.NoError(t, router.DrainEvents(context.Background()))
+		assert.Equal(t, []utility.TraceID{21}, cancelled)
+		require.Len(t, sim.openOrders, 1)
+
+		fill := tick(1.0985, 1.0988)
+		fill.TimeStamp = start.Add(3 * time.Minute)
+		sim.OnTick(context.Background(), fill)
+		sim.OnTick(context.Background(), tick(1.0940, 1.0942))
+		require.NoError(t, router.DrainEvents(context.Background()))
+		require.Len(t, filled, 1)
+		assert.Equal(t, utility.TraceID(20), filled[0].OriginalOrder.TraceID)
+		assert.Len(t, sim.openPositions, 1)
+	})
+
+	t.Run("invalid groups are rejected", func(t *testing.T) {
+		sim, router := createTestSimulator(t)
+
+		var rejected []common.OrderRejected
+		router.OnOrderRejection = func(_ context.Context, r common.OrderRejected) { rejected = append(rejected, r) }
+
+		noGroup := entry
+		noGroup.GroupId = 0
+		secondParent := entry
+		secondParent.TraceID = 23
+
+		sim.OnTick(context.Background(), tick(1.1000, 1.1002))
+		sim.OnOrder(context.Background(), noGroup)
+		sim.OnOrder(context.Background(), entry)
+		sim.OnOrder(context.Background(), secondParent)
+		require.NoError(t, router.DrainEvents(context.Background()))
+
+		require.Len(t, rejected, 2)
+		assert.Contains(t, rejected[0].Reason, "order group relation requires a group id")
+		assert.Contains(t, rejected[1].Reason, "order group 7 already has a parent order")
+		assert.Len(t, sim.openOrders, 1)
+	})
+}
+
+func TestSandboxSimulator_AccountMode(t *testing.T) {
+	tick := func(bid, ask float64) common.Tick {
+		return common.Tick{
+			Symbol:    "EURUSD",
+			Bid:       fixed.FromFloat64(bid),
+			Ask:       fixed.FromFloat64(ask),
+			BidVolume: fixed.FromInt(10, 0),
+			AskVolume: fixed.FromInt(10, 0),
+			TimeStamp: time.Now(),
+		}
+	}
+	order := func(side common.OrderSide, size float64) common.Order {
+		return common.Order{
+			Symbol:      "EURUSD",
+			Side:        side,
+			Type:        common.OrderTypeMarket,
+			Size:        fixed.FromFloat64(size),
+			Command:     common.OrderCommandPositionOpen,
+			TimeInForce: common.TimeInForceImmediateOrCancel,
+		}
+	}
+
+	type events struct {
+		opened  []common.Position
+		updated []common.Position
+		closed  []common.Position
+	}
+
+	// run opens a long position of 1 lot at 1.1002 and then executes the second order at bid 1.1010
+	run := func(t *testing.T, mode AccountMode, second common.Order) (*Simulator, *events) {
+		sim, router := createTestSimulator(t)
+		WithAccountMode(mode)(sim)
+
+		ev := &events{}
+		router.OnPositionOpen = func(_ context.Context, p common.Position) { ev.opened = append(ev.opened, p) }
+		router.OnPositionUpdate = func(_ context.Context, p common.Position) { ev.updated = append(ev.updated, p) }
+		router.OnPositionClose = func(_ context.Context, p common.Position) { ev.closed = append(ev.closed, p) }
+
+		sim.OnTick(context.Background(), tick(1.1000, 1.1002))
+		sim.OnOrder(context.Background(), order(common.OrderSideBuy, 1.0))
+		sim.OnTick(context.Background(), tick(1.1000, 1.1002))
+		sim.OnOrder(context.Background(), second)
+		sim.OnTick(context.Background(), tick(1.1010, 1.1012))
+		require.NoError(t, router.DrainEvents(context.Background()))
+		require.NotEmpty(t, ev.opened)
+		return sim, ev
+	}
+
+	t.Run("hedging opens a position per fill", func(t *testing.T) {
+		sim, ev := run(t, AccountModeHedging, order(common.OrderSideSell, 0.4))
+
+		assert.Len(t, sim.openPositions, 2)
+		assert.Len(t, ev.opened, 2)
+		assert.Empty(t, ev.closed)
+	})
+
+	t.Run("netting increases the position", func(t *testing.T) {
+		sim, ev := run(t, AccountModeNetting, order(common.OrderSideBuy, 1.0))
+
+		require.Len(t, sim.openPositions, 1)
+		require.Len(t, ev.opened, 1)
+		position := sim.openPositions[0]
+		assert.Equal(t, ev.opened[0].Id, position.Id)
+		assert.True(t, fixed.FromFloat64(2.0).Eq(position.Size))
+		assert.True(t, fixed.FromFloat64(1.1007).Eq(position.OpenPrice), position.OpenPrice.String())
+		assert.Len(t, position.OrderTraceIDs, 2)
+		assert.Empty(t, ev.closed)
+		require.NotEmpty(t, ev.updated)
+		assert.True(t, fixed.FromFloat64(2.0).Eq(ev.updated[len(ev.updated)-1].Size))
+	})
+
+	t.Run("netting reduces the position", func(t *testing.T) {
+		sim, ev := run(t, AccountModeNetting, order(common.OrderSideSell, 0.4))
+
+		require.Len(t, ev.closed, 1)
+		assert.True(t, fixed.FromFloat64(0.4).Eq(ev.closed[0].Size))
+		assert.True(t, fixed.FromFloat64(32).Eq(ev.closed[0].GrossProfit), ev.closed[0].GrossProfit.String())
+		assert.True(t, fixed.FromFloat64(10032).Eq(sim.balance), sim.balance.String())
+
+		require.Len(t, sim.openPositions, 1)
+		require.Len(t, ev.opened, 1)
+		assert.Equal(t, ev.opened[0].Id, sim.openPositions[0].Id)
+		assert.Equal(t, common.PositionSideLong, sim.openPositions[0].Side)
+		assert.True(t, fixed.FromFloat64(0.6).Eq(sim.openPositions[0].Size))
+	})
+
+	t.Run("netting closes the position", func(t *testing.T) {
+		sim, ev := run(t, AccountModeNetting, order(common.OrderSideSell, 1.0))
+
+		require.Len(t, ev.closed, 1)
+		assert.True(t, fixed.FromFloat64(80).Eq(ev.closed[0].GrossProfit), ev.closed[0].GrossProfit.String())
+		assert.Empty(t, sim.openPositions)
+	})
+
+	t.Run("netting reverses the position", func(t *testing.T) {
+		sim, ev := run(t, AccountModeNetting, order(common.OrderSideSell, 1.5))
+
+		require.Len(t, ev.closed, 1)
+		assert.True(t, fixed.FromFloat64(1.0).Eq(ev.closed[0].Size))
+		require.Len(t, sim.openPositions, 1)
+		position := sim.openPositions[0]
+		assert.Equal(t, common.PositionSideShort, position.Side)
+		assert.True(t, fixed.FromFloat64(0.5).Eq(position.Size))
+		assert.True(t, fixed.FromFloat64(1.1010).Eq(position.OpenPrice))
+		assert.NotEqual(t, ev.closed[0].Id, position.Id)
+		require.Len(t, ev.opened, 2)
+		assert.Equal(t, position.Id, ev.opened[1].Id)
+	})
+
+	t.Run("netting requires margin for the net exposure only", func(t *testing.T) {
+		sim, _ := createTestSimulator(t)
+		WithAccountMode(AccountModeNetting)(sim)
+		sim.openPositions = append(sim.openPositions, &common.Position{
+			Id:     1,
+			Symbol: "EURUSD",
+			Side:   common.PositionSideLong,
+			Size:   fixed.FromFloat64(1.0),
+			Status: common.PositionStatusOpen,
+		})
+
+		assert.True(t, fixed.FromFloat64(0.5).Eq(sim.exposureIncrease(order(common.OrderSideBuy, 0.5))))
+		assert.True(t, fixed.Zero.Eq(sim.exposureIncrease(order(common.OrderSideSell, 0.5))))
+		assert.True(t, fixed.FromFloat64(0.5).Eq(sim.exposureIncrease(order(common.OrderSideSell, 1.5))))
+	})
+}
+
+func TestSandboxSimulator_Expiry(t *testing.T) {
+	expiry := time.Date(2025, 12, 19, 14, 30, 0, 0, time.UTC)
+	tick := func(symbol string, ts time.Time, bid, ask float64) common.Tick {
+		return common.Tick{
+			Symbol:    symbol,
+			Bid:       fixed.FromFloat64(bid),
+			Ask:       fixed.FromFloat64(ask),
+			BidVolume: fixed.FromInt(10, 0),
+			AskVolume: fixed.FromInt(10, 0),
+			TimeStamp: ts,
+		}
+	}
+	order := func(symbol string) common.Order {
+		return common.Order{
+			Symbol:      symbol,
+			Side:        common.OrderSideBuy,
+			Type:        common.OrderTypeMarket,
+			Size:        fixed.One,
+			Command:     common.OrderCommandPositionOpen,
+			TimeInForce: common.TimeInForceImmediateOrCancel,
+		}
+	}
+
+	type events struct {
+		opened   []common.Position
+		closed   []common.Position
+		accepted []common.OrderAccepted
+		rejected []common.OrderRejected
+	}
+
+	// run opens a long ESZ5 position at 5000.25, marks it at 5010 and then ticks the next contract after expiry
+	run := func(t *testing.T, policy ExpiryPolicy) (*Simulator, *events) {
+		router := bus.NewRouter(1000)
+		contract := exchange.SymbolInfo{
+			Class:         exchange.Future,
+			QuoteCurrency: "USD",
+			Digits:        2,
+			ContractSize:  fixed.FromInt(50, 0),
+			Leverage:      fixed.FromInt(20, 0),
+			Root:          "ES",
+		}
+		front, next := contract, contract
+		front.SymbolName, front.Expiry = "ESZ5", expiry
+		next.SymbolName, next.Expiry = "ESH6", expiry.AddDate(0, 3, 1)
+
+		sim, err := NewSimulator(router, "USD", fixed.FromInt(100000, 0), store.CreateSymbolStore(front, next), WithExpiryPolicy(policy))
+		require.NoError(t, err)
+
+		ev := &events{}
+		router.OnPositionOpen = func(_ context.Context, p common.Position) { ev.opened = append(ev.opened, p) }
+		router.OnPositionClose = func(_ context.Context, p common.Position) { ev.closed = append(ev.closed, p) }
+		router.OnOrderAcceptance = func(_ context.Context, a common.OrderAccepted) { ev.accepted = append(ev.accepted, a) }
+		router.OnOrderRejection = func(_ context.Context, r common.OrderRejected) { ev.rejected = append(ev.rejected, r) }
+
+		sim.OnTick(context.Background(), tick("ESZ5", expiry.Add(-2*time.Hour), 5000, 5000.25))
+		sim.OnOrder(context.Background(), order("ESZ5"))
+		sim.OnTick(context.Background(), tick("ESZ5", expiry.Add(-time.Hour), 5000, 5000.25))
+		sim.OnTick(context.Background(), tick("ESZ5", expiry.Add(-time.Minute), 5010, 5010.25))
+		sim.OnTick(context.Background(), tick("ESH6", expiry.Add(time.Minute), 5050, 5050.25))
+		require.NoError(t, router.DrainEvents(context.Background()))
+		require.Len(t, ev.closed, 1)
+		return sim, ev
+	}
+
+	t.Run("close settles at the last price", func(t *testing.T) {
+		sim, ev := run(t, ExpiryClose)
+
+		assert.Equal(t, "ESZ5", ev.closed[0].Symbol)
+		assert.Equal(t, expiry, ev.closed[0].CloseTime)
+		assert.True(t, fixed.FromFloat64(487.5).Eq(ev.closed[0].GrossProfit), ev.closed[0].GrossProfit.String())
+		assert.Empty(t, sim.openPositions)
+		assert.Len(t, ev.opened, 1)
+	})
+
+	t.Run("roll reopens in the next contract", func(t *testing.T) {
+		sim, ev := run(t, ExpiryRoll)
+
+		require.Len(t, sim.openPositions, 1)
+		position := sim.openPositions[0]
+		assert.Equal(t, "ESH6", position.Symbol)
+		assert.Equal(t, common.PositionSideLong, position.Side)
+		assert.True(t, fixed.One.Eq(position.Size))
+		assert.True(t, fixed.FromFloat64(5050.25).Eq(position.OpenPrice), position.OpenPrice.String())
+		require.Len(t, ev.opened, 2)
+		assert.NotEqual(t, ev.closed[0].Id, ev.opened[1].Id)
+
+		require.Len(t, ev.accepted, 2, "the rollover order is accepted as any other")
+		assert.Equal(t, "ESH6", ev.accepted[1].OriginalOrder.Symbol)
+		assert.Equal(t, ev.closed[0].TraceID, ev.accepted[1].OriginalOrder.ParentTraceID)
+		assert.Contains(t, sim.expiryInfos, "ESZ5", "the expiry of the contracts is cached")
+	})
+
+	t.Run("roll is validated", func(t *testing.T) {
+		router := bus.NewRouter(1000)
+		front := exchange.SymbolInfo{SymbolName: "ESZ5", Class: exchange.Future, QuoteCurrency: "USD", Digits: 2, ContractSize: fixed.FromInt(50, 0), Leverage: fixed.FromInt(20, 0), Root: "ES", Expiry: expiry}
+		next := front
+		next.SymbolName, next.Expiry = "ESH6", expiry.AddDate(0, 3, 1)
+
+		sim, err := NewSimulator(router, "USD", fixed.FromInt(100000, 0), store.CreateSymbolStore(front, next), WithExpiryPolicy(ExpiryRoll))
+		require.NoError(t, err)
+
+		var rejected []common.OrderRejected
+		router.OnOrderRejection = func(_ context.Context, r common.OrderRejected) { rejected = append(rejected, r) }
+
+		sim.OnTick(context.Background(), tick("ESZ5", expiry.Add(-2*time.Hour), 5000, 5000.25))
+		sim.OnOrder(context.Background(), order("ESZ5"))
+		sim.OnTick(context.Background(), tick("ESZ5", expiry.Add(-time.Hour), 5000, 5000.25))
+		// the next contract has no price yet, so the rollover order cannot be validated
+		sim.OnTick(context.Background(), tick("ESZ5", expiry, 5010, 5010.25))
+		require.NoError(t, router.DrainEvents(context.Background()))
+
+		require.Len(t, rejected, 1)
+		assert.Equal(t, "ESH6", rejected[0].OriginalOrder.Symbol)
+		assert.Empty(t, sim.openOrders)
+	})
+
+	t.Run("orders on expired contracts are rejected", func(t *testing.T) {
+		sim, ev := run(t, ExpiryClose)
 
-	closedPos2 := positions[1]
-	assert.Equal(t, common.PositionStatusClosed, closedPos2.Status)
-	assert.Equal(t, fixed.FromFloat64(1.2502).String(), closedPos2.ClosePrice.String())
+		sim.OnOrder(context.Background(), order("ESZ5"))
+		sim.OnOrder(context.Background(), order("ESH6"))
+		require.NoError(t, sim.router.DrainEvents(context.Background()))
+
+		require.Len(t, ev.rejected, 1)
+		assert.Equal(t, "ESZ5", ev.rejected[0].OriginalOrder.Symbol)
+	})
 }
 
-func TestSandboxSimulator_checkOrders_TimeInForce(t *testing.T) {
+func TestSandboxSimulator_FillDepth(t *testing.T) {
+	tick := common.Tick{
+		Symbol:    "EURUSD",
+		Bid:       fixed.FromFloat64(1.1000),
+		Ask:       fixed.FromFloat64(1.1002),
+		BidVolume: fixed.FromInt(10, 0),
+		AskVolume: fixed.FromInt(10, 0),
+		TimeStamp: time.Now(),
+	}
+	book := common.OrderBook{
+		Symbol: "EURUSD",
+		Bids: []common.BookLevel{
+			{Price: fixed.FromFloat64(1.1000), Size: fixed.FromFloat64(1.0)},
+			{Price: fixed.FromFloat64(1.0998), Size: fixed.FromFloat64(1.0)},
+		},
+		Asks: []common.BookLevel{
+			{Price: fixed.FromFloat64(1.1002), Size: fixed.FromFloat64(1.0)},
+			{Price: fixed.FromFloat64(1.1004), Size: fixed.FromFloat64(1.0)},
+			{Price: fixed.FromFloat64(1.1006), Size: fixed.FromFloat64(2.0)},
+		},
+	}
+
 	tests := []struct {
-		name     string
-		order    *common.Order
-		tick     common.Tick
-		validate func(*testing.T, *Simulator, int, int)
+		name      string
+		mode      FillMode
+		order     common.Order
+		wantSize  fixed.Point
+		wantPrice fixed.Point
 	}{
 		{
-			name: "IOC partial fill and cancel",
-			order: &common.Order{
-				Symbol:      "EURUSD",
-				Side:        common.OrderSideBuy,
-				Type:        common.OrderTypeMarket,
-				Size:        fixed.FromFloat64(5.0),
-				Command:     common.OrderCommandPositionOpen,
-				TimeInForce: common.TimeInForceImmediateOrCancel,
-			},
-			tick: common.Tick{
-				Symbol:    "EURUSD",
-				Bid:       fixed.FromFloat64(1.1000),
-				Ask:       fixed.FromFloat64(1.1002),
-				BidVolume: fixed.FromInt(10, 0),
-				AskVolume: fixed.FromFloat64(2.0),
-			},
-			validate: func(t *testing.T, sim *Simulator, filledCount, canceledCount int) {
-				assert.Empty(t, sim.openOrders)
-				assert.Len(t, sim.openPositions, 1)
-				f1, _ := fixed.Two.Float64()
-				f2, _ := sim.openPositions[0].Size.Float64()
-				assert.Equal(t, f1, f2)
-				assert.Equal(t, filledCount, 1)
-				assert.Equal(t, canceledCount, 1)
+			name: "top of book ignores the order book",
+			mode: FillTopOfBook,
+			order: common.Order{
+				Type: common.OrderTypeMarket, Side: common.OrderSideBuy, Size: fixed.FromFloat64(3.0),
 			},
+			wantSize:  fixed.FromFloat64(3.0),
+			wantPrice: fixed.FromFloat64(1.1002),
 		},
 		{
-			name: "FOK all or nothing - filled",
-			order: &common.Order{
-				Symbol:      "EURUSD",
-				Side:        common.OrderSideBuy,
-				Type:        common.OrderTypeMarket,
-				Size:        fixed.FromFloat64(1.0),
-				Command:     common.OrderCommandPositionOpen,
-				TimeInForce: common.TimeInForceFillOrKill,
-			},
-			tick: common.Tick{
-				Symbol:    "EURUSD",
-				Bid:       fixed.FromFloat64(1.1000),
-				Ask:       fixed.FromFloat64(1.1002),
-				BidVolume: fixed.FromInt(10, 0),
-				AskVolume: fixed.FromFloat64(2.0),
-			},
-			validate: func(t *testing.T, sim *Simulator, filledCount, canceledCount int) {
-				assert.Empty(t, sim.openOrders)
-				assert.Len(t, sim.openPositions, 1)
-				assert.Equal(t, filledCount, 1)
-				assert.Equal(t, canceledCount, 0)
+			name: "market buy walks the asks",
+			mode: FillDepth,
+			order: common.Order{
+				Type: common.OrderTypeMarket, Side: common.OrderSideBuy, Size: fixed.FromFloat64(3.0),
 			},
+			wantSize:  fixed.FromFloat64(3.0),
+			wantPrice: fixed.FromFloat64(1.1004),
 		},
 		{
-			name: "FOK all or nothing - cancelled",
-			order: &common.Order{
-				Symbol:      "EURUSD",
-				Side:        common.OrderSideBuy,
-				Type:        common.OrderTypeMarket,
-				Size:        fixed.FromFloat64(5.0),
-				Command:     common.OrderCommandPositionOpen,
-				TimeInForce: common.TimeInForceFillOrKill,
-			},
-			tick: common.Tick{
-				Symbol:    "EURUSD",
-				Bid:       fixed.FromFloat64(1.1000),
-				Ask:       fixed.FromFloat64(1.1002),
-				BidVolume: fixed.FromInt(10, 0),
-				AskVolume: fixed.FromFloat64(2.0),
-			},
-			validate: func(t *testing.T, sim *Simulator, filledCount, canceledCount int) {
-				assert.Empty(t, sim.openOrders)
-				assert.Empty(t, sim.openPositions)
-				assert.Equal(t, filledCount, 0)
-				assert.Equal(t, canceledCount, 1)
+			name: "market sell is capped by the bids",
+			mode: FillDepth,
+			order: common.Order{
+				Type: common.OrderTypeMarket, Side: common.OrderSideSell, Size: fixed.FromFloat64(3.0),
 			},
+			wantSize:  fixed.FromFloat64(2.0),
+			wantPrice: fixed.FromFloat64(1.0999),
 		},
 		{
-			name: "GTD expired order cancelled",
-			order: &common.Order{
-				Symbol:      "EURUSD",
-				Side:        common.OrderSideBuy,
-				Type:        common.OrderTypeLimit,
-				Price:       fixed.FromFloat64(1.0995),
-				Size:        fixed.FromFloat64(1.0),
-				Command:     common.OrderCommandPositionOpen,
-				TimeInForce: common.TimeInForceGoodTillDate,
-				ExpireTime:  time.Now().Add(-1 * time.Hour),
-			},
-			tick: common.Tick{
-				Symbol:    "EURUSD",
-				Bid:       fixed.FromFloat64(1.1000),
-				Ask:       fixed.FromFloat64(1.1002),
-				BidVolume: fixed.FromInt(10, 0),
-				AskVolume: fixed.FromInt(10, 0),
-			},
-			validate: func(t *testing.T, sim *Simulator, _, canceledCount int) {
-				assert.Empty(t, sim.openOrders)
-				assert.Empty(t, sim.openPositions)
-				assert.Equal(t, canceledCount, 1)
+			name: "limit buy stops at its price",
+			mode: FillDepth,
+			order: common.Order{
+				Type: common.OrderTypeLimit, Side: common.OrderSideBuy, Size: fixed.FromFloat64(3.0), Price: fixed.FromFloat64(1.1004),
 			},
+			wantSize:  fixed.FromFloat64(2.0),
+			wantPrice: fixed.FromFloat64(1.1003),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			sim, router := createTestSimulator(t)
-			sim.openOrders = append(sim.openOrders, tt.order)
+			WithFillMode(tt.mode)(sim)
+
+			var opened []common.Position
+			router.OnPositionOpen = func(_ context.Context, p common.Position) { opened = append(opened, p) }
+
+			order := tt.order
+			order.Symbol = "EURUSD"
+			order.Command = common.OrderCommandPositionOpen
+			order.TimeInForce = common.TimeInForceImmediateOrCancel
+
+			sim.OnTick(context.Background(), tick)
+			sim.OnDepth(context.Background(), book)
+			sim.OnOrder(context.Background(), order)
+			sim.OnTick(context.Background(), tick)
+			require.NoError(t, router.DrainEvents(context.Background()))
+
+			require.Len(t, opened, 1)
+			assert.True(t, tt.wantSize.Eq(opened[0].Size), opened[0].Size.String())
+			assert.True(t, tt.wantPrice.Eq(opened[0].OpenPrice), opened[0].OpenPrice.String())
+			assert.Empty(t, sim.depthPrices)
+		})
+	}
 
-			filledCount := 0
-			router.OnOrderFilled = func(_ context.Context, _ common.OrderFilled) { filledCount++ }
+	t.Run("clipped size is priced after rounding", func(t *testing.T) {
+		sim, router := createTestSimulator(t)
+		WithFillMode(FillDepth)(sim)
 
-			canceledCount := 0
-			router.OnOrderCancel = func(_ context.Context, _ common.OrderCancelled) { canceledCount++ }
+		var opened []common.Position
+		router.OnPositionOpen = func(_ context.Context, p common.Position) { opened = append(opened, p) }
 
-			sim.checkOrders(tt.tick)
-			_ = router.DrainEvents(context.Background())
+		sim.OnTick(context.Background(), tick)
+		sim.OnDepth(context.Background(), common.OrderBook{
+			Symbol: "EURUSD",
+			Asks: []common.BookLevel{
+				{Price: fixed.FromFloat64(1.1002), Size: fixed.FromFloat64(1.0)},
+				{Price: fixed.FromFloat64(1.1010), Size: fixed.FromFloat64(0.004)},
+			},
+		})
+		sim.OnOrder(context.Background(), common.Order{
+			Symbol:      "EURUSD",
+			Command:     common.OrderCommandPositionOpen,
+			Type:        common.OrderTypeMarket,
+			Side:        common.OrderSideBuy,
+			Size:        fixed.FromFloat64(3.0),
+			TimeInForce: common.TimeInForceImmediateOrCancel,
+		})
+		sim.OnTick(context.Background(), tick)
+		require.NoError(t, router.DrainEvents(context.Background()))
 
-			if tt.validate != nil {
-				tt.validate(t, sim, filledCount, canceledCount)
-			}
+		require.Len(t, opened, 1)
+		assert.True(t, fixed.FromFloat64(1.0).Eq(opened[0].Size), opened[0].Size.String())
+		assert.True(t, fixed.FromFloat64(1.1002).Eq(opened[0].OpenPrice), opened[0].OpenPrice.String())
+	})
+
+	t.Run("close clipped by the bids is priced after rounding", func(t *testing.T) {
+		sim, router := createTestSimulator(t)
+		WithFillMode(FillDepth)(sim)
+
+		var closed []common.Position
+		router.OnPositionClose = func(_ context.Context, p common.Position) { closed = append(closed, p) }
+
+		sim.OnTick(context.Background(), tick)
+		sim.OnDepth(context.Background(), common.OrderBook{
+			Symbol: "EURUSD",
+			Bids: []common.BookLevel{
+				{Price: fixed.FromFloat64(1.1000), Size: fixed.FromFloat64(1.0)},
+				{Price: fixed.FromFloat64(1.0990), Size: fixed.FromFloat64(0.004)},
+			},
+		})
+		sim.openPositions = append(sim.openPositions, &common.Position{
+			Id:        1,
+			Symbol:    "EURUSD",
+			Side:      common.PositionSideLong,
+			Size:      fixed.FromFloat64(2.0),
+			OpenPrice: fixed.FromFloat64(1.0990),
+			Status:    common.PositionStatusOpen,
+		})
+		sim.OnOrder(context.Background(), common.Order{
+			Symbol:      "EURUSD",
+			Command:     common.OrderCommandPositionClose,
+			Type:        common.OrderTypeMarket,
+			Side:        common.OrderSideSell,
+			Size:        fixed.FromFloat64(2.0),
+			PositionId:  1,
+			TimeInForce: common.TimeInForceImmediateOrCancel,
+		})
+		sim.OnTick(context.Background(), tick)
+		require.NoError(t, router.DrainEvents(context.Background()))
+
+		require.Len(t, closed, 1)
+		assert.True(t, fixed.FromFloat64(1.1000).Eq(closed[0].ClosePrice), closed[0].ClosePrice.String())
+	})
+
+	t.Run("close walks the bids", func(t *testing.T) {
+		sim, router := createTestSimulator(t)
+		WithFillMode(FillDepth)(sim)
+
+		var closed []common.Position
+		router.OnPositionClose = func(_ context.Context, p common.Position) { closed = append(closed, p) }
+
+		sim.OnTick(context.Background(), tick)
+		sim.OnDepth(context.Background(), book)
+		sim.openPositions = append(sim.openPositions, &common.Position{
+			Id:        1,
+			Symbol:    "EURUSD",
+			Side:      common.PositionSideLong,
+			Size:      fixed.FromFloat64(2.0),
+			OpenPrice: fixed.FromFloat64(1.0990),
+			Status:    common.PositionStatusOpen,
+		})
+		sim.OnOrder(context.Background(), common.Order{
+			Symbol:      "EURUSD",
+			Command:     common.OrderCommandPositionClose,
+			Type:        common.OrderTypeMarket,
+			Side:        common.OrderSideSell,
+			Size:        fixed.FromFloat64(2.0),
+			PositionId:  1,
+			TimeInForce: common.TimeInForceImmediateOrCancel,
 		})
+		sim.OnTick(context.Background(), tick)
+		require.NoError(t, router.DrainEvents(context.Background()))
+
+		require.Len(t, closed, 1)
+		assert.True(t, fixed.FromFloat64(1.0999).Eq(closed[0].ClosePrice), closed[0].ClosePrice.String())
+	})
+}
+
+func TestSandboxSimulator_AccountEvent(t *testing.T) {
+	sim, router := createTestSimulator(t)
+
+	var accounts []common.Account
+	router.OnAccount = func(_ context.Context, a common.Account) { accounts = append(accounts, a) }
+
+	tick := common.Tick{
+		Symbol:    "EURUSD",
+		Bid:       fixed.FromFloat64(1.1000),
+		Ask:       fixed.FromFloat64(1.1002),
+		BidVolume: fixed.FromInt(10, 0),
+		AskVolume: fixed.FromInt(10, 0),
+		TimeStamp: time.Now(),
 	}
+
+	sim.OnTick(context.Background(), tick)
+	sim.OnTick(context.Background(), tick)
+	require.NoError(t, router.DrainEvents(context.Background()))
+	require.Len(t, accounts, 1, "unchanged account is not published again")
+	assert.True(t, fixed.FromInt(10000, 0).Eq(accounts[0].Balance))
+	assert.True(t, accounts[0].MarginLevel.IsZero())
+	assert.Zero(t, accounts[0].OpenPositions)
+
+	sim.OnOrder(context.Background(), common.Order{
+		Symbol:      "EURUSD",
+		Command:     common.OrderCommandPositionOpen,
+		Type:        common.OrderTypeMarket,
+		Side:        common.OrderSideBuy,
+		Size:        fixed.One,
+		TimeInForce: common.TimeInForceImmediateOrCancel,
+	})
+	sim.OnTick(context.Background(), tick)
+	require.NoError(t, router.DrainEvents(context.Background()))
+
+	require.Len(t, accounts, 2)
+	account := accounts[1]
+	require.Len(t, sim.openPositions, 1)
+	position := sim.openPositions[0]
+	assert.Equal(t, 1, account.OpenPositions)
+	assert.True(t, position.Margin.Eq(account.UsedMargin), account.UsedMargin.String())
+	assert.True(t, account.Equity.Sub(position.Margin).Eq(account.FreeMargin))
+	assert.True(t, common.MarginLevel(account.Equity, position.Margin).Eq(account.MarginLevel))
+	assert.True(t, fixed.FromFloat64(110010).Eq(account.Exposure["USD"]), account.Exposure["USD"].String())
 }
 
 func TestSandboxSimulator_validateTick(t *testing.T) {
@@ -1807,6 +3271,7 @@ func TestSandboxSimulator_postOrderRejected(t *testing.T) {
 	assert.Equal(t, sim.simulationTime, receivedRejection.TimeStamp)
 	assert.NotZero(t, receivedRejection.ExecutionId)
 	assert.NotZero(t, receivedRejection.TraceID)
+	assert.Equal(t, order.TraceID, receivedRejection.ParentTraceID)
 }
 
 func TestSandboxSimulator_postOrderFilled(t *testing.T) {
@@ -1842,6 +3307,7 @@ func TestSandboxSimulator_postOrderFilled(t *testing.T) {
 	assert.Equal(t, sim.simulationTime, receivedFilled.TimeStamp)
 	assert.NotZero(t, receivedFilled.ExecutionId)
 	assert.NotZero(t, receivedFilled.TraceID)
+	assert.Equal(t, order.TraceID, receivedFilled.ParentTraceID)
 }
 
 func TestSandboxSimulator_postOrderCancel(t *testing.T) {
@@ -1878,6 +3344,7 @@ func TestSandboxSimulator_postOrderCancel(t *testing.T) {
 	assert.Equal(t, sim.simulationTime, receivedCancel.TimeStamp)
 	assert.NotZero(t, receivedCancel.ExecutionId)
 	assert.NotZero(t, receivedCancel.TraceID)
+	assert.Equal(t, order.TraceID, receivedCancel.ParentTraceID)
 }
 
 func TestSandboxSimulator_checkPositions(t *testing.T) {
@@ -3046,3 +4513,171 @@ func BenchmarkSandboxSimulator_OnTick(b *testing.B) {
 		}
 	}
 }
+
+func TestSandboxSimulator_SyntheticTicks(t *testing.T) {
+	openTime := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	first := common.Bar{
+		Symbol:   "EURUSD",
+		OpenTime: openTime,
+		Period:   common.BarPeriodM1,
+		Open:     fixed.FromFloat64(1.1000),
+		High:     fixed.FromFloat64(1.1005),
+		Low:      fixed.FromFloat64(1.0995),
+		Close:    fixed.FromFloat64(1.1000),
+	}
+	second := common.Bar{
+		Symbol:   "EURUSD",
+		OpenTime: openTime.Add(common.BarPeriodM1),
+		Period:   common.BarPeriodM1,
+		Open:     fixed.FromFloat64(1.1000),
+		High:     fixed.FromFloat64(1.1050),
+		Low:      fixed.FromFloat64(1.0950),
+		Close:    fixed.FromFloat64(1.1010),
+	}
+	stopLoss := fixed.FromFloat64(1.0960)
+	takeProfit := fixed.FromFloat64(1.1040)
+
+	tests := []struct {
+		name       string
+		path       BarPath
+		wantClosed bool
+		wantProfit bool
+	}{
+		{name: "no path ignores bars", path: BarPathNone},
+		{name: "high first hits the take profit", path: BarPathOHLC, wantClosed: true, wantProfit: true},
+		{name: "low first hits the stop loss", path: BarPathOLHC, wantClosed: true},
+		{name: "bullish bar visits the low first", path: BarPathAuto, wantClosed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, router := createTestSimulator(t)
+			WithSyntheticTicks(tt.path, fixed.FromFloat64(0.0002), fixed.FromInt(10, 0))(sim)
+
+			var opened, closed []common.Position
+			router.OnPositionOpen = func(_ context.Context, p common.Position) { opened = append(opened, p) }
+			router.OnPositionClose = func(_ context.Context, p common.Position) { closed = append(closed, p) }
+
+			sim.OnBar(context.Background(), first)
+			sim.OnOrder(context.Background(), common.Order{
+				Symbol:      "EURUSD",
+				Command:     common.OrderCommandPositionOpen,
+				Type:        common.OrderTypeMarket,
+				Side:        common.OrderSideBuy,
+				Size:        fixed.FromFloat64(1.0),
+				StopLoss:    stopLoss,
+				TakeProfit:  takeProfit,
+				TimeInForce: common.TimeInForceImmediateOrCancel,
+			})
+			sim.OnBar(context.Background(), second)
+			require.NoError(t, router.DrainEvents(context.Background()))
+
+			if tt.path == BarPathNone {
+				assert.Empty(t, opened)
+				assert.Empty(t, sim.lastTickMap)
+				return
+			}
+
+			require.Len(t, opened, 1)
+			assert.True(t, fixed.FromFloat64(1.1002).Eq(opened[0].OpenPrice), opened[0].OpenPrice.String())
+			assert.True(t, second.OpenTime.Equal(opened[0].OpenTime))
+
+			require.Len(t, closed, 1)
+			if tt.wantProfit {
+				assert.True(t, closed[0].ClosePrice.Gte(takeProfit), closed[0].ClosePrice.String())
+			} else {
+				assert.True(t, closed[0].ClosePrice.Lte(stopLoss), closed[0].ClosePrice.String())
+			}
+			assert.True(t, sim.simulationTime.Before(second.OpenTime.Add(second.Period)))
+		})
+	}
+}
+
+func TestSandboxSimulator_SyntheticTickVolume(t *testing.T) {
+	bar := common.Bar{
+		Symbol:   "EURUSD",
+		OpenTime: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC),
+		Period:   common.BarPeriodM1,
+		Open:     fixed.FromFloat64(1.1000),
+		High:     fixed.FromFloat64(1.1005),
+		Low:      fixed.FromFloat64(1.0995),
+		Close:    fixed.FromFloat64(1.1002),
+		Volume:   fixed.FromInt(10, 0),
+	}
+
+	tests := []struct {
+		name   string
+		volume fixed.Point
+		want   fixed.Point
+	}{
+		{name: "bar volume is split across the ticks", volume: fixed.Zero, want: fixed.FromFloat64(2.5)},
+		{name: "configured volume is quoted by every tick", volume: fixed.FromInt(10, 0), want: fixed.FromInt(10, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, _ := createTestSimulator(t)
+			WithSyntheticTicks(BarPathOHLC, fixed.FromFloat64(0.0002), tt.volume)(sim)
+
+			ticks := sim.syntheticTicks(bar)
+			require.Len(t, ticks, 4)
+			for _, tick := range ticks {
+				assert.True(t, tt.want.Eq(tick.BidVolume), tick.BidVolume.String())
+				assert.True(t, tt.want.Eq(tick.AskVolume), tick.AskVolume.String())
+			}
+			assert.True(t, bar.OpenTime.Equal(ticks[0].TimeStamp))
+			assert.True(t, bar.OpenTime.Add(bar.Period-time.Nanosecond).Equal(ticks[3].TimeStamp))
+		})
+	}
+}
+
+func TestSandboxSimulator_ZeroVolumeBar(t *testing.T) {
+	openTime := time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC)
+	bar := func(minute int, volume fixed.Point) common.Bar {
+		return common.Bar{
+			Symbol:   "EURUSD",
+			OpenTime: openTime.Add(time.Duration(minute) * time.Minute),
+			Period:   common.BarPeriodM1,
+			Open:     fixed.FromFloat64(1.1000),
+			High:     fixed.FromFloat64(1.1005),
+			Low:      fixed.FromFloat64(1.0995),
+			Close:    fixed.FromFloat64(1.1002),
+			Volume:   volume,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		volume fixed.Point
+		want   fixed.Point
+	}{
+		{name: "bar without volume fills the whole order", volume: fixed.Zero, want: fixed.FromFloat64(1.0)},
+		{name: "bar volume limits the fill", volume: fixed.FromInt(2, 0), want: fixed.FromFloat64(0.5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sim, router := createTestSimulator(t)
+			WithSyntheticTicks(BarPathOHLC, fixed.FromFloat64(0.0002), fixed.Zero)(sim)
+
+			var opened []common.Position
+			router.OnPositionOpen = func(_ context.Context, p common.Position) { opened = append(opened, p) }
+
+			sim.OnBar(context.Background(), bar(0, tt.volume))
+			sim.OnOrder(context.Background(), common.Order{
+				Symbol:      "EURUSD",
+				Command:     common.OrderCommandPositionOpen,
+				Type:        common.OrderTypeMarket,
+				Side:        common.OrderSideBuy,
+				Size:        fixed.FromFloat64(1.0),
+				TimeInForce: common.TimeInForceImmediateOrCancel,
+			})
+			sim.OnBar(context.Background(), bar(1, tt.volume))
+			require.NoError(t, router.DrainEvents(context.Background()))
+
+			require.Len(t, opened, 1)
+			assert.True(t, tt.want.Eq(opened[0].Size), opened[0].Size.String())
+			assert.False(t, sim.unlimitedLiquidity, "only the ticks of the bar are unlimited")
+		})
+	}
+}
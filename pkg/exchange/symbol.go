@@ -1,22 +1,162 @@
 package exchange
 
 import (
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
 )
 
 type SymbolClass string
+type MarginMode string
+
+const (
+	Forex  SymbolClass = "forex"
+	Index  SymbolClass = "index"
+	Metal  SymbolClass = "metal"
+	Crypto SymbolClass = "crypto"
+	Stock  SymbolClass = "stock"
+	Future SymbolClass = "future"
+)
 
 const (
-	Forex SymbolClass = "forex"
+	// MarginLeverage requires the notional divided by Leverage, the default
+	MarginLeverage MarginMode = "leverage"
+	// MarginPercentage requires MarginRate percent of the notional
+	MarginPercentage MarginMode = "percentage"
+	// MarginFixed requires FixedMargin per lot
+	MarginFixed MarginMode = "fixed"
+	// MarginTiered requires the rate of each of the MarginTiers for the part of the size falling into it
+	MarginTiered MarginMode = "tiered"
 )
 
+// MarginTier charges Rate percent of the notional for the lots up to MaxSize, a zero MaxSize is unbounded
+type MarginTier struct {
+	MaxSize fixed.Point
+	Rate    fixed.Point
+}
+
 type SymbolInfo struct {
 	SymbolName    string
 	SymbolId      int64
 	Class         SymbolClass
+	BaseCurrency  string
 	QuoteCurrency string
 	Digits        int
 	PipSize       fixed.Point
 	ContractSize  fixed.Point
 	Leverage      fixed.Point
+
+	// TickSize and TickValue give the quote currency value of the smallest price move of one lot. When not set
+	// the value is derived from ContractSize.
+	TickSize  fixed.Point
+	TickValue fixed.Point
+
+	MarginMode  MarginMode
+	MarginRate  fixed.Point
+	FixedMargin fixed.Point
+	MarginTiers []MarginTier
+
+	// Root groups the futures contracts of one underlying into a chain, Expiry is the end of trading of the contract
+	Root   string
+	Expiry time.Time
+}
+
+// ContractChain holds the futures contracts of one root ordered by expiry
+type ContractChain []SymbolInfo
+
+func NewContractChain(contracts ...SymbolInfo) ContractChain {
+	chain := append(ContractChain(nil), contracts...)
+	sort.SliceStable(chain, func(i, j int) bool {
+		return chain[i].Expiry.Before(chain[j].Expiry)
+	})
+	return chain
+}
+
+// Active returns the front contract at t, the first one not expired yet
+func (c ContractChain) Active(t time.Time) (SymbolInfo, bool) {
+	for _, contract := range c {
+		if !contract.IsExpired(t) {
+			return contract, true
+		}
+	}
+	return SymbolInfo{}, false
+}
+
+// Next returns the contract following the given one in the chain
+func (c ContractChain) Next(symbolName string) (SymbolInfo, bool) {
+	for idx, contract := range c {
+		if strings.EqualFold(contract.SymbolName, symbolName) && idx+1 < len(c) {
+			return c[idx+1], true
+		}
+	}
+	return SymbolInfo{}, false
+}
+
+// IsExpired reports whether the contract stopped trading at t, symbols without expiry never do
+func (s SymbolInfo) IsExpired(t time.Time) bool {
+	return !s.Expiry.IsZero() && !t.Before(s.Expiry)
+}
+
+// Pips converts a distance in pips to a price distance, e.g. for Order.TrailingDistance
+func (s SymbolInfo) Pips(pips fixed.Point) fixed.Point {
+	return pips.Mul(s.PipSize)
+}
+
+// MarginCurrency is the currency the margin is held in. Forex margin is held in the base currency when it is
+// known, margin of the other classes in the quote currency.
+func (s SymbolInfo) MarginCurrency() string {
+	if s.Class == Forex && s.BaseCurrency != "" {
+		return s.BaseCurrency
+	}
+	return s.QuoteCurrency
+}
+
+// Profit returns the value of a price move of size lots in the quote currency
+func (s SymbolInfo) Profit(priceDiff, size fixed.Point) fixed.Point {
+	if !s.TickSize.IsZero() && !s.TickValue.IsZero() {
+		return priceDiff.Div(s.TickSize).Mul(s.TickValue).Mul(size)
+	}
+	return priceDiff.Mul(size).Mul(s.ContractSize)
+}
+
+// Margin returns the margin required by size lots at price in the margin currency
+func (s SymbolInfo) Margin(price, size fixed.Point) fixed.Point {
+	size = size.Abs()
+
+	notional := size.Mul(s.ContractSize)
+	if s.MarginCurrency() == s.QuoteCurrency {
+		notional = notional.Mul(price)
+	}
+
+	switch s.MarginMode {
+	case MarginPercentage:
+		return notional.Mul(s.MarginRate).DivInt(100)
+	case MarginFixed:
+		return size.Mul(s.FixedMargin)
+	case MarginTiered:
+		if size.IsZero() {
+			return fixed.Zero
+		}
+		lotNotional := notional.Div(size)
+		margin := fixed.Zero
+		lower := fixed.Zero
+		for _, tier := range s.MarginTiers {
+			upper := size
+			if !tier.MaxSize.IsZero() && tier.MaxSize.Lt(size) {
+				upper = tier.MaxSize
+			}
+			if upper.Gt(lower) {
+				margin = margin.Add(upper.Sub(lower).Mul(lotNotional).Mul(tier.Rate).DivInt(100))
+				lower = upper
+			}
+			if lower.Eq(size) {
+				break
+			}
+		}
+		return margin
+	default:
+		return notional.Div(s.Leverage)
+	}
 }
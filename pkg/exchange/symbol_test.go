@@ -0,0 +1,141 @@
+package exchange
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
+)
+
+func TestSymbolInfo_Profit(t *testing.T) {
+	tests := []struct {
+		name      string
+		symbol    SymbolInfo
+		priceDiff fixed.Point
+		size      fixed.Point
+		expected  fixed.Point
+	}{
+		{
+			name:      "forex derives the value from the contract size",
+			symbol:    SymbolInfo{Class: Forex, ContractSize: fixed.FromInt(100_000, 0)},
+			priceDiff: fixed.FromFloat64(0.0050),
+			size:      fixed.FromFloat64(0.1),
+			expected:  fixed.FromInt(50, 0),
+		},
+		{
+			name: "index uses tick value per tick size",
+			symbol: SymbolInfo{
+				Class:        Index,
+				ContractSize: fixed.One,
+				TickSize:     fixed.FromFloat64(0.25),
+				TickValue:    fixed.FromFloat64(12.5),
+			},
+			priceDiff: fixed.FromFloat64(2.0),
+			size:      fixed.FromInt(2, 0),
+			expected:  fixed.FromInt(200, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.symbol.Profit(tt.priceDiff, tt.size)
+			assert.True(t, tt.expected.Eq(got), "expected %s, got %s", tt.expected, got)
+		})
+	}
+}
+
+func TestSymbolInfo_Margin(t *testing.T) {
+	tests := []struct {
+		name     string
+		symbol   SymbolInfo
+		price    fixed.Point
+		size     fixed.Point
+		expected fixed.Point
+		currency string
+	}{
+		{
+			name: "leverage on the quote notional",
+			symbol: SymbolInfo{
+				Class:         Forex,
+				QuoteCurrency: "USD",
+				ContractSize:  fixed.FromInt(100_000, 0),
+				Leverage:      fixed.FromInt(100, 0),
+			},
+			price:    fixed.FromFloat64(1.2),
+			size:     fixed.One,
+			expected: fixed.FromInt(1200, 0),
+			currency: "USD",
+		},
+		{
+			name: "forex with base currency is margined in the base currency",
+			symbol: SymbolInfo{
+				Class:         Forex,
+				BaseCurrency:  "EUR",
+				QuoteCurrency: "USD",
+				ContractSize:  fixed.FromInt(100_000, 0),
+				Leverage:      fixed.FromInt(100, 0),
+			},
+			price:    fixed.FromFloat64(1.2),
+			size:     fixed.One,
+			expected: fixed.FromInt(1000, 0),
+			currency: "EUR",
+		},
+		{
+			name: "percentage of the notional",
+			symbol: SymbolInfo{
+				Class:         Stock,
+				QuoteCurrency: "USD",
+				ContractSize:  fixed.One,
+				MarginMode:    MarginPercentage,
+				MarginRate:    fixed.FromInt(20, 0),
+			},
+			price:    fixed.FromInt(150, 0),
+			size:     fixed.FromInt(10, 0),
+			expected: fixed.FromInt(300, 0),
+			currency: "USD",
+		},
+		{
+			name: "fixed margin per lot",
+			symbol: SymbolInfo{
+				Class:         Index,
+				QuoteCurrency: "USD",
+				ContractSize:  fixed.One,
+				MarginMode:    MarginFixed,
+				FixedMargin:   fixed.FromInt(500, 0),
+			},
+			price:    fixed.FromInt(4000, 0),
+			size:     fixed.FromInt(-3, 0),
+			expected: fixed.FromInt(1500, 0),
+			currency: "USD",
+		},
+		{
+			name: "tiered margin charges each tier its rate",
+			symbol: SymbolInfo{
+				Class:         Crypto,
+				BaseCurrency:  "BTC",
+				QuoteCurrency: "USD",
+				ContractSize:  fixed.One,
+				MarginMode:    MarginTiered,
+				MarginTiers: []MarginTier{
+					{MaxSize: fixed.FromInt(2, 0), Rate: fixed.FromInt(10, 0)},
+					{MaxSize: fixed.FromInt(5, 0), Rate: fixed.FromInt(20, 0)},
+					{Rate: fixed.FromInt(50, 0)},
+				},
+			},
+			price: fixed.FromInt(1000, 0),
+			size:  fixed.FromInt(6, 0),
+			// 2 * 1000 * 10% + 3 * 1000 * 20% + 1 * 1000 * 50%
+			expected: fixed.FromInt(1300, 0),
+			currency: "USD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.symbol.Margin(tt.price, tt.size)
+			assert.True(t, tt.expected.Eq(got), "expected %s, got %s", tt.expected, got)
+			assert.Equal(t, tt.currency, tt.symbol.MarginCurrency())
+		})
+	}
+}
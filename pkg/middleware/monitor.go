@@ -28,6 +28,12 @@ const (
 	MonitorSignal
 	MonitorSignalRejection
 	MonitorSignalAcceptance
+	MonitorTimer
+	MonitorOrderAmended
+	MonitorDepth
+	MonitorAccount
+	MonitorMarginCall
+	MonitorStopOut
 )
 
 type Monitor struct {
@@ -174,3 +180,57 @@ func (m *Monitor) WithSignalAcceptance(handler bus.SignalAcceptanceEventHandler)
 		handler(ctx, accepted)
 	}
 }
+
+func (m *Monitor) WithTimer(handler bus.TimerEventHandler) bus.TimerEventHandler {
+	return func(ctx context.Context, timer common.Timer) {
+		if m.flags&MonitorTimer != 0 || m.flags&MonitorAll != 0 {
+			slog.Info("event", "timer", timer)
+		}
+		handler(ctx, timer)
+	}
+}
+
+func (m *Monitor) WithOrderAmended(handler bus.OrderAmendedHandler) bus.OrderAmendedHandler {
+	return func(ctx context.Context, amended common.OrderAmended) {
+		if m.flags&MonitorOrderAmended != 0 || m.flags&MonitorAll != 0 {
+			slog.Info("event", "order_amended", amended)
+		}
+		handler(ctx, amended)
+	}
+}
+
+func (m *Monitor) WithDepth(handler bus.DepthEventHandler) bus.DepthEventHandler {
+	return func(ctx context.Context, book common.OrderBook) {
+		if m.flags&MonitorDepth != 0 || m.flags&MonitorAll != 0 {
+			slog.Info("event", "depth", book)
+		}
+		handler(ctx, book)
+	}
+}
+
+func (m *Monitor) WithAccount(handler bus.AccountEventHandler) bus.AccountEventHandler {
+	return func(ctx context.Context, account common.Account) {
+		if m.flags&MonitorAccount != 0 || m.flags&MonitorAll != 0 {
+			slog.Info("event", "account", account)
+		}
+		handler(ctx, account)
+	}
+}
+
+func (m *Monitor) WithMarginCall(handler bus.MarginCallEventHandler) bus.MarginCallEventHandler {
+	return func(ctx context.Context, marginCall common.MarginCall) {
+		if m.flags&MonitorMarginCall != 0 || m.flags&MonitorAll != 0 {
+			slog.Info("event", "margin_call", marginCall)
+		}
+		handler(ctx, marginCall)
+	}
+}
+
+func (m *Monitor) WithStopOut(handler bus.StopOutEventHandler) bus.StopOutEventHandler {
+	return func(ctx context.Context, stopOut common.StopOut) {
+		if m.flags&MonitorStopOut != 0 || m.flags&MonitorAll != 0 {
+			slog.Info("event", "stop_out", stopOut)
+		}
+		handler(ctx, stopOut)
+	}
+}
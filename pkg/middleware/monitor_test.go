@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/peter-kozarec/equinox/pkg/common"
 )
@@ -24,370 +25,65 @@ func TestMiddlewareMonitor_NewMonitor(t *testing.T) {
 	}
 }
 
-func TestMiddlewareMonitor_WithTick(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, tick common.Tick) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorTick)
-	wrapped := m.WithTick(handler)
-
-	wrapped(context.Background(), common.Tick{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "tick") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithTickNoMonitor(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, tick common.Tick) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorNone)
-	wrapped := m.WithTick(handler)
-
-	wrapped(context.Background(), common.Tick{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if strings.Contains(buf.String(), "tick") {
-		t.Error("Unexpected log entry")
-	}
-}
-
-func TestMiddlewareMonitor_WithTickMonitorAll(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	handler := func(ctx context.Context, tick common.Tick) {}
-
-	m := NewMonitor(MonitorAll)
-	wrapped := m.WithTick(handler)
-
-	wrapped(context.Background(), common.Tick{})
-
-	if !strings.Contains(buf.String(), "tick") {
-		t.Error("Log entry not found with MonitorAll")
-	}
-}
-
-func TestMiddlewareMonitor_WithBar(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, bar common.Bar) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorBar)
-	wrapped := m.WithBar(handler)
-
-	wrapped(context.Background(), common.Bar{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "bar") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithEquity(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, equity common.Equity) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorEquity)
-	wrapped := m.WithEquity(handler)
-
-	wrapped(context.Background(), common.Equity{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "equity") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithBalance(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, balance common.Balance) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorBalance)
-	wrapped := m.WithBalance(handler)
-
-	wrapped(context.Background(), common.Balance{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "balance") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithPositionOpened(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, position common.Position) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorPositionOpen)
-	wrapped := m.WithPositionOpen(handler)
-
-	wrapped(context.Background(), common.Position{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "position_open") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithPositionClosed(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, position common.Position) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorPositionClose)
-	wrapped := m.WithPositionClose(handler)
-
-	wrapped(context.Background(), common.Position{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "position_closed") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithPositionPnLUpdated(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, position common.Position) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorPositionUpdate)
-	wrapped := m.WithPositionUpdate(handler)
-
-	wrapped(context.Background(), common.Position{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "position_update") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithOrder(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, order common.Order) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorOrder)
-	wrapped := m.WithOrder(handler)
-
-	wrapped(context.Background(), common.Order{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "order") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithOrderRejected(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, rejected common.OrderRejected) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorOrderRejection)
-	wrapped := m.WithOrderRejection(handler)
-
-	wrapped(context.Background(), common.OrderRejected{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "order_rejected") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithOrderAccepted(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, accepted common.OrderAccepted) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorOrderAcceptance)
-	wrapped := m.WithOrderAcceptance(handler)
-
-	wrapped(context.Background(), common.OrderAccepted{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "order_accepted") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithOrderFilled(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, filled common.OrderFilled) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorOrderFilled)
-	wrapped := m.WithOrderFilled(handler)
-
-	wrapped(context.Background(), common.OrderFilled{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "order_filled") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithOrderCancelled(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, cancelled common.OrderCancelled) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorOrderCancelled)
-	wrapped := m.WithOrderCancelled(handler)
-
-	wrapped(context.Background(), common.OrderCancelled{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "order_cancelled") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithSignal(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, signal common.Signal) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorSignal)
-	wrapped := m.WithSignal(handler)
-
-	wrapped(context.Background(), common.Signal{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "signal") {
-		t.Error("Log entry not found")
-	}
-}
-
-func TestMiddlewareMonitor_WithSignalAcceptance(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, signal common.SignalAccepted) {
-		handlerCalled = true
-	}
-
-	m := NewMonitor(MonitorSignalAcceptance)
-	wrapped := m.WithSignalAcceptance(handler)
-
-	wrapped(context.Background(), common.SignalAccepted{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if !strings.Contains(buf.String(), "signal") {
-		t.Error("Log entry not found")
-	}
+// callWrapped wraps a handler with the middleware method with, calls it with an empty event and reports whether
+// the handler ran. The handler sleeps for delay, so that the middleware measures it.
+func callWrapped[T any, H ~func(context.Context, T)](with func(H) H, delay time.Duration) bool {
+	var called bool
+	var event T
+	with(func(context.Context, T) {
+		called = true
+		time.Sleep(delay)
+	})(context.Background(), event)
+	return called
 }
 
-func TestMiddlewareMonitor_WithSignalRejection(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, signal common.SignalRejected) {
-		handlerCalled = true
+func TestMiddlewareMonitor_Handlers(t *testing.T) {
+	tests := []struct {
+		log  string
+		flag MonitorFlags
+		call func(m *Monitor) bool
+	}{
+		{log: "tick", flag: MonitorTick, call: func(m *Monitor) bool { return callWrapped(m.WithTick, 0) }},
+		{log: "bar", flag: MonitorBar, call: func(m *Monitor) bool { return callWrapped(m.WithBar, 0) }},
+		{log: "equity", flag: MonitorEquity, call: func(m *Monitor) bool { return callWrapped(m.WithEquity, 0) }},
+		{log: "balance", flag: MonitorBalance, call: func(m *Monitor) bool { return callWrapped(m.WithBalance, 0) }},
+		{log: "position_open", flag: MonitorPositionOpen, call: func(m *Monitor) bool { return callWrapped(m.WithPositionOpen, 0) }},
+		{log: "position_closed", flag: MonitorPositionClose, call: func(m *Monitor) bool { return callWrapped(m.WithPositionClose, 0) }},
+		{log: "position_update", flag: MonitorPositionUpdate, call: func(m *Monitor) bool { return callWrapped(m.WithPositionUpdate, 0) }},
+		{log: "order", flag: MonitorOrder, call: func(m *Monitor) bool { return callWrapped(m.WithOrder, 0) }},
+		{log: "order_rejected", flag: MonitorOrderRejection, call: func(m *Monitor) bool { return callWrapped(m.WithOrderRejection, 0) }},
+		{log: "order_accepted", flag: MonitorOrderAcceptance, call: func(m *Monitor) bool { return callWrapped(m.WithOrderAcceptance, 0) }},
+		{log: "order_filled", flag: MonitorOrderFilled, call: func(m *Monitor) bool { return callWrapped(m.WithOrderFilled, 0) }},
+		{log: "order_cancelled", flag: MonitorOrderCancelled, call: func(m *Monitor) bool { return callWrapped(m.WithOrderCancelled, 0) }},
+		{log: "signal", flag: MonitorSignal, call: func(m *Monitor) bool { return callWrapped(m.WithSignal, 0) }},
+		{log: "signal_accepted", flag: MonitorSignalAcceptance, call: func(m *Monitor) bool { return callWrapped(m.WithSignalAcceptance, 0) }},
+		{log: "signal_rejected", flag: MonitorSignalRejection, call: func(m *Monitor) bool { return callWrapped(m.WithSignalRejection, 0) }},
+		{log: "timer", flag: MonitorTimer, call: func(m *Monitor) bool { return callWrapped(m.WithTimer, 0) }},
+		{log: "order_amended", flag: MonitorOrderAmended, call: func(m *Monitor) bool { return callWrapped(m.WithOrderAmended, 0) }},
+		{log: "depth", flag: MonitorDepth, call: func(m *Monitor) bool { return callWrapped(m.WithDepth, 0) }},
+		{log: "account", flag: MonitorAccount, call: func(m *Monitor) bool { return callWrapped(m.WithAccount, 0) }},
+		{log: "margin_call", flag: MonitorMarginCall, call: func(m *Monitor) bool { return callWrapped(m.WithMarginCall, 0) }},
+		{log: "stop_out", flag: MonitorStopOut, call: func(m *Monitor) bool { return callWrapped(m.WithStopOut, 0) }},
 	}
 
-	m := NewMonitor(MonitorSignalRejection)
-	wrapped := m.WithSignalRejection(handler)
-
-	wrapped(context.Background(), common.SignalRejected{})
+	for _, test := range tests {
+		t.Run(test.log, func(t *testing.T) {
+			for _, flags := range []MonitorFlags{test.flag, MonitorAll, MonitorNone} {
+				buf := setupTestLogger(t)
 
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
+				if !test.call(NewMonitor(flags)) {
+					t.Errorf("Handler not called with flags %d", flags)
+				}
 
-	if !strings.Contains(buf.String(), "signal") {
-		t.Error("Log entry not found")
+				logged := strings.Contains(buf.String(), test.log)
+				if flags == MonitorNone && logged {
+					t.Error("Unexpected log entry")
+				}
+				if flags != MonitorNone && !logged {
+					t.Errorf("Log entry not found with flags %d", flags)
+				}
+			}
+		})
 	}
 }
 
@@ -428,131 +124,6 @@ func TestMiddlewareMonitor_MultipleFlags(t *testing.T) {
 	}
 }
 
-func TestMiddlewareMonitor_MonitorAllOverride(t *testing.T) {
-	buf := setupTestLogger(t)
-
-	m := NewMonitor(MonitorAll)
-
-	handlers := []struct {
-		name    string
-		execute func()
-	}{
-		{
-			"tick",
-			func() {
-				h := m.WithTick(func(ctx context.Context, tick common.Tick) {})
-				h(context.Background(), common.Tick{})
-			},
-		},
-		{
-			"bar",
-			func() {
-				h := m.WithBar(func(ctx context.Context, bar common.Bar) {})
-				h(context.Background(), common.Bar{})
-			},
-		},
-		{
-			"equity",
-			func() {
-				h := m.WithEquity(func(ctx context.Context, equity common.Equity) {})
-				h(context.Background(), common.Equity{})
-			},
-		},
-		{
-			"balance",
-			func() {
-				h := m.WithBalance(func(ctx context.Context, balance common.Balance) {})
-				h(context.Background(), common.Balance{})
-			},
-		},
-		{
-			"position_open",
-			func() {
-				h := m.WithPositionOpen(func(ctx context.Context, position common.Position) {})
-				h(context.Background(), common.Position{})
-			},
-		},
-		{
-			"position_closed",
-			func() {
-				h := m.WithPositionClose(func(ctx context.Context, position common.Position) {})
-				h(context.Background(), common.Position{})
-			},
-		},
-		{
-			"position_update",
-			func() {
-				h := m.WithPositionUpdate(func(ctx context.Context, position common.Position) {})
-				h(context.Background(), common.Position{})
-			},
-		},
-		{
-			"order",
-			func() {
-				h := m.WithOrder(func(ctx context.Context, order common.Order) {})
-				h(context.Background(), common.Order{})
-			},
-		},
-		{
-			"order_rejected",
-			func() {
-				h := m.WithOrderRejection(func(ctx context.Context, rejected common.OrderRejected) {})
-				h(context.Background(), common.OrderRejected{})
-			},
-		},
-		{
-			"order_accepted",
-			func() {
-				h := m.WithOrderAcceptance(func(ctx context.Context, accepted common.OrderAccepted) {})
-				h(context.Background(), common.OrderAccepted{})
-			},
-		},
-		{
-			"order_filled",
-			func() {
-				h := m.WithOrderFilled(func(ctx context.Context, filled common.OrderFilled) {})
-				h(context.Background(), common.OrderFilled{})
-			},
-		},
-		{
-			"order_cancelled",
-			func() {
-				h := m.WithOrderCancelled(func(ctx context.Context, cancelled common.OrderCancelled) {})
-				h(context.Background(), common.OrderCancelled{})
-			},
-		},
-		{
-			"signal",
-			func() {
-				h := m.WithSignal(func(ctx context.Context, signal common.Signal) {})
-				h(context.Background(), common.Signal{})
-			},
-		},
-		{
-			"signal_accepted",
-			func() {
-				h := m.WithSignalAcceptance(func(ctx context.Context, signal common.SignalAccepted) {})
-				h(context.Background(), common.SignalAccepted{})
-			},
-		},
-		{
-			"signal_rejected",
-			func() {
-				h := m.WithSignalRejection(func(ctx context.Context, signal common.SignalRejected) {})
-				h(context.Background(), common.SignalRejected{})
-			},
-		},
-	}
-
-	for _, h := range handlers {
-		buf.Reset()
-		h.execute()
-		if !strings.Contains(buf.String(), h.name) {
-			t.Errorf("Expected log for %s with MonitorAll", h.name)
-		}
-	}
-}
-
 func TestMiddlewareMonitor_ContextPropagation(t *testing.T) {
 	m := NewMonitor(MonitorNone)
 
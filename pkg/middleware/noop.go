@@ -22,4 +22,10 @@ var (
 	NoopSignalHandler           = func(context.Context, common.Signal) {}
 	NoopSignalRejectionHandler  = func(context.Context, common.SignalRejected) {}
 	NoopSignalAcceptanceHandler = func(context.Context, common.SignalAccepted) {}
+	NoopTimerHandler            = func(context.Context, common.Timer) {}
+	NoopOrderAmendedHandler     = func(context.Context, common.OrderAmended) {}
+	NoopDepthHandler            = func(context.Context, common.OrderBook) {}
+	NoopAccountHandler          = func(context.Context, common.Account) {}
+	NoopMarginCallHandler       = func(context.Context, common.MarginCall) {}
+	NoopStopOutHandler          = func(context.Context, common.StopOut) {}
 )
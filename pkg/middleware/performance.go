@@ -26,22 +26,34 @@ type Performance struct {
 	signalEventCounter             int64
 	signalRejectedEventCounter     int64
 	signalAcceptedEventCounter     int64
-
-	totalTickHandlerDur    time.Duration
-	totalBarHandlerDur     time.Duration
-	totalBalanceHandlerDur time.Duration
-	totalEquityHandlerDur  time.Duration
-	totalPosOpenHandlerDur time.Duration
-	totalPosUpdtHandlerDur time.Duration
-	totalPosClosHandlerDur time.Duration
-	totalOrderHandlerDur   time.Duration
-	totalOrderRejectedDur  time.Duration
-	totalOrderAcceptedDur  time.Duration
-	totalOrderFilledDur    time.Duration
-	totalOrderCancelDur    time.Duration
-	totalSignalHandlerDur  time.Duration
-	totalSignalRejectedDur time.Duration
-	totalSignalAcceptedDur time.Duration
+	timerEventCounter              int64
+	orderAmendedEventCounter       int64
+	depthEventCounter              int64
+	accountEventCounter            int64
+	marginCallEventCounter         int64
+	stopOutEventCounter            int64
+
+	totalTickHandlerDur       time.Duration
+	totalBarHandlerDur        time.Duration
+	totalBalanceHandlerDur    time.Duration
+	totalEquityHandlerDur     time.Duration
+	totalPosOpenHandlerDur    time.Duration
+	totalPosUpdtHandlerDur    time.Duration
+	totalPosClosHandlerDur    time.Duration
+	totalOrderHandlerDur      time.Duration
+	totalOrderRejectedDur     time.Duration
+	totalOrderAcceptedDur     time.Duration
+	totalOrderFilledDur       time.Duration
+	totalOrderCancelDur       time.Duration
+	totalSignalHandlerDur     time.Duration
+	totalSignalRejectedDur    time.Duration
+	totalSignalAcceptedDur    time.Duration
+	totalTimerHandlerDur      time.Duration
+	totalOrderAmendedDur      time.Duration
+	totalDepthHandlerDur      time.Duration
+	totalAccountHandlerDur    time.Duration
+	totalMarginCallHandlerDur time.Duration
+	totalStopOutHandlerDur    time.Duration
 }
 
 func NewPerformance() *Performance {
@@ -183,6 +195,60 @@ func (p *Performance) WithSignalAcceptance(handler bus.SignalAcceptanceEventHand
 	}
 }
 
+func (p *Performance) WithTimer(handler bus.TimerEventHandler) bus.TimerEventHandler {
+	return func(ctx context.Context, timer common.Timer) {
+		startTime := time.Now()
+		handler(ctx, timer)
+		p.totalTimerHandlerDur += time.Since(startTime)
+		p.timerEventCounter++
+	}
+}
+
+func (p *Performance) WithOrderAmended(handler bus.OrderAmendedHandler) bus.OrderAmendedHandler {
+	return func(ctx context.Context, amended common.OrderAmended) {
+		startTime := time.Now()
+		handler(ctx, amended)
+		p.totalOrderAmendedDur += time.Since(startTime)
+		p.orderAmendedEventCounter++
+	}
+}
+
+func (p *Performance) WithDepth(handler bus.DepthEventHandler) bus.DepthEventHandler {
+	return func(ctx context.Context, book common.OrderBook) {
+		startTime := time.Now()
+		handler(ctx, book)
+		p.totalDepthHandlerDur += time.Since(startTime)
+		p.depthEventCounter++
+	}
+}
+
+func (p *Performance) WithAccount(handler bus.AccountEventHandler) bus.AccountEventHandler {
+	return func(ctx context.Context, account common.Account) {
+		startTime := time.Now()
+		handler(ctx, account)
+		p.totalAccountHandlerDur += time.Since(startTime)
+		p.accountEventCounter++
+	}
+}
+
+func (p *Performance) WithMarginCall(handler bus.MarginCallEventHandler) bus.MarginCallEventHandler {
+	return func(ctx context.Context, marginCall common.MarginCall) {
+		startTime := time.Now()
+		handler(ctx, marginCall)
+		p.totalMarginCallHandlerDur += time.Since(startTime)
+		p.marginCallEventCounter++
+	}
+}
+
+func (p *Performance) WithStopOut(handler bus.StopOutEventHandler) bus.StopOutEventHandler {
+	return func(ctx context.Context, stopOut common.StopOut) {
+		startTime := time.Now()
+		handler(ctx, stopOut)
+		p.totalStopOutHandlerDur += time.Since(startTime)
+		p.stopOutEventCounter++
+	}
+}
+
 func (p *Performance) PrintStatistics() {
 	var args []any
 
@@ -334,6 +400,66 @@ func (p *Performance) PrintStatistics() {
 		}
 	}
 
+	if p.timerEventCounter > 0 {
+		avgTimer := p.totalTimerHandlerDur / time.Duration(p.timerEventCounter)
+		if avgTimer > 0 {
+			args = append(args,
+				"timer_event_count", p.timerEventCounter,
+				"timer_avg_duration", fmt.Sprintf("%dns", avgTimer.Nanoseconds()),
+			)
+		}
+	}
+
+	if p.orderAmendedEventCounter > 0 {
+		avgOrderAmended := p.totalOrderAmendedDur / time.Duration(p.orderAmendedEventCounter)
+		if avgOrderAmended > 0 {
+			args = append(args,
+				"order_amended_event_count", p.orderAmendedEventCounter,
+				"order_amended_avg_duration", fmt.Sprintf("%dns", avgOrderAmended.Nanoseconds()),
+			)
+		}
+	}
+
+	if p.depthEventCounter > 0 {
+		avgDepth := p.totalDepthHandlerDur / time.Duration(p.depthEventCounter)
+		if avgDepth > 0 {
+			args = append(args,
+				"depth_event_count", p.depthEventCounter,
+				"depth_avg_duration", fmt.Sprintf("%dns", avgDepth.Nanoseconds()),
+			)
+		}
+	}
+
+	if p.accountEventCounter > 0 {
+		avgAccount := p.totalAccountHandlerDur / time.Duration(p.accountEventCounter)
+		if avgAccount > 0 {
+			args = append(args,
+				"account_event_count", p.accountEventCounter,
+				"account_avg_duration", fmt.Sprintf("%dns", avgAccount.Nanoseconds()),
+			)
+		}
+	}
+
+	if p.marginCallEventCounter > 0 {
+		avgMarginCall := p.totalMarginCallHandlerDur / time.Duration(p.marginCallEventCounter)
+		if avgMarginCall > 0 {
+			args = append(args,
+				"margin_call_event_count", p.marginCallEventCounter,
+				"margin_call_avg_duration", fmt.Sprintf("%dns", avgMarginCall.Nanoseconds()),
+			)
+		}
+	}
+
+	if p.stopOutEventCounter > 0 {
+		avgStopOut := p.totalStopOutHandlerDur / time.Duration(p.stopOutEventCounter)
+		if avgStopOut > 0 {
+			args = append(args,
+				"stop_out_event_count", p.stopOutEventCounter,
+				"stop_out_avg_duration", fmt.Sprintf("%dns", avgStopOut.Nanoseconds()),
+			)
+		}
+	}
+
 	if len(args) > 0 {
 		slog.Info("performance statistics", args...)
 	}
@@ -23,328 +23,62 @@ func TestMiddlewarePerformance_NewPerformance(t *testing.T) {
 	}
 }
 
-func TestMiddlewarePerformance_WithTick(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, tick common.Tick) {
-		handlerCalled = true
-		time.Sleep(10 * time.Millisecond)
-	}
-
-	wrapped := p.WithTick(handler)
-	wrapped(context.Background(), common.Tick{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.tickEventCounter != 1 {
-		t.Errorf("Expected tickEventCounter=1, got %d", p.tickEventCounter)
-	}
-
-	if p.totalTickHandlerDur < 10*time.Millisecond {
-		t.Errorf("Expected duration >= 10ms, got %v", p.totalTickHandlerDur)
-	}
-}
-
-func TestMiddlewarePerformance_WithBar(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, bar common.Bar) {
-		handlerCalled = true
-		time.Sleep(5 * time.Millisecond)
-	}
-
-	wrapped := p.WithBar(handler)
-	wrapped(context.Background(), common.Bar{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.barEventCounter != 1 {
-		t.Errorf("Expected barEventCounter=1, got %d", p.barEventCounter)
-	}
-
-	if p.totalBarHandlerDur < 5*time.Millisecond {
-		t.Errorf("Expected duration >= 5ms, got %v", p.totalBarHandlerDur)
-	}
-}
-
-func TestMiddlewarePerformance_WithBalance(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, balance common.Balance) {
-		handlerCalled = true
-	}
-
-	wrapped := p.WithBalance(handler)
-	wrapped(context.Background(), common.Balance{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.balanceEventCounter != 1 {
-		t.Errorf("Expected balanceEventCounter=1, got %d", p.balanceEventCounter)
-	}
-}
-
-func TestMiddlewarePerformance_WithEquity(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, equity common.Equity) {
-		handlerCalled = true
-	}
-
-	wrapped := p.WithEquity(handler)
-	wrapped(context.Background(), common.Equity{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.equityEventCounter != 1 {
-		t.Errorf("Expected equityEventCounter=1, got %d", p.equityEventCounter)
-	}
-}
-
-func TestMiddlewarePerformance_WithPositionOpened(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, position common.Position) {
-		handlerCalled = true
-	}
-
-	wrapped := p.WithPositionOpen(handler)
-	wrapped(context.Background(), common.Position{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.positionOpenedEventCounter != 1 {
-		t.Errorf("Expected positionOpenedEventCounter=1, got %d", p.positionOpenedEventCounter)
-	}
-}
-
-func TestMiddlewarePerformance_WithPositionClosed(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, position common.Position) {
-		handlerCalled = true
-	}
-
-	wrapped := p.WithPositionClose(handler)
-	wrapped(context.Background(), common.Position{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.positionClosedEventCounter != 1 {
-		t.Errorf("Expected positionClosedEventCounter=1, got %d", p.positionClosedEventCounter)
-	}
-}
-
-func TestMiddlewarePerformance_WithPositionPnLUpdated(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, position common.Position) {
-		handlerCalled = true
-	}
-
-	wrapped := p.WithPositionUpdate(handler)
-	wrapped(context.Background(), common.Position{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.positionPnLUpdatedEventCounter != 1 {
-		t.Errorf("Expected positionPnLUpdatedEventCounter=1, got %d", p.positionPnLUpdatedEventCounter)
-	}
-}
-
-func TestMiddlewarePerformance_WithOrder(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, order common.Order) {
-		handlerCalled = true
-	}
-
-	wrapped := p.WithOrder(handler)
-	wrapped(context.Background(), common.Order{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.orderEventCounter != 1 {
-		t.Errorf("Expected orderEventCounter=1, got %d", p.orderEventCounter)
-	}
-}
-
-func TestMiddlewarePerformance_WithOrderRejected(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, rejected common.OrderRejected) {
-		handlerCalled = true
-	}
-
-	wrapped := p.WithOrderRejection(handler)
-	wrapped(context.Background(), common.OrderRejected{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.orderRejectedEventCounter != 1 {
-		t.Errorf("Expected orderRejectedEventCounter=1, got %d", p.orderRejectedEventCounter)
-	}
-}
-
-func TestMiddlewarePerformance_WithOrderAccepted(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, accepted common.OrderAccepted) {
-		handlerCalled = true
-	}
-
-	wrapped := p.WithOrderAcceptance(handler)
-	wrapped(context.Background(), common.OrderAccepted{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.orderAcceptedEventCounter != 1 {
-		t.Errorf("Expected orderAcceptedEventCounter=1, got %d", p.orderAcceptedEventCounter)
-	}
-}
-
-func TestMiddlewarePerformance_WithOrderFilled(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, accepted common.OrderFilled) {
-		handlerCalled = true
-	}
-
-	wrapped := p.WithOrderFilled(handler)
-	wrapped(context.Background(), common.OrderFilled{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.orderFilledEventCounter != 1 {
-		t.Errorf("Expected orderFilledEventCounter=1, got %d", p.orderFilledEventCounter)
-	}
-}
-
-func TestMiddlewarePerformance_WithOrderCancelled(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, accepted common.OrderCancelled) {
-		handlerCalled = true
-	}
-
-	wrapped := p.WithOrderCancelled(handler)
-	wrapped(context.Background(), common.OrderCancelled{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.orderCancelledEventCounter != 1 {
-		t.Errorf("Expected orderCancelledEventCounter=1, got %d", p.orderCancelledEventCounter)
-	}
-}
-
-func TestMiddlewarePerformance_WithSignal(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, signal common.Signal) {
-		handlerCalled = true
-		time.Sleep(15 * time.Millisecond)
-	}
-
-	wrapped := p.WithSignal(handler)
-	wrapped(context.Background(), common.Signal{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.signalEventCounter != 1 {
-		t.Errorf("Expected signalEventCounter=1, got %d", p.signalEventCounter)
-	}
-
-	if p.totalSignalHandlerDur < 15*time.Millisecond {
-		t.Errorf("Expected duration >= 15ms, got %v", p.totalSignalHandlerDur)
-	}
-}
-
-func TestMiddlewarePerformance_WithSignalAcceptance(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, signal common.SignalAccepted) {
-		handlerCalled = true
-		time.Sleep(15 * time.Millisecond)
-	}
-
-	wrapped := p.WithSignalAcceptance(handler)
-	wrapped(context.Background(), common.SignalAccepted{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.signalAcceptedEventCounter != 1 {
-		t.Errorf("Expected signalAcceptedEventCounter=1, got %d", p.signalAcceptedEventCounter)
-	}
-
-	if p.totalSignalAcceptedDur < 15*time.Millisecond {
-		t.Errorf("Expected duration >= 15ms, got %v", p.totalSignalAcceptedDur)
-	}
-}
-
-func TestMiddlewarePerformance_WithSignalRejection(t *testing.T) {
-	p := NewPerformance()
-
-	var handlerCalled bool
-	handler := func(ctx context.Context, signal common.SignalRejected) {
-		handlerCalled = true
-		time.Sleep(15 * time.Millisecond)
-	}
-
-	wrapped := p.WithSignalRejection(handler)
-	wrapped(context.Background(), common.SignalRejected{})
-
-	if !handlerCalled {
-		t.Error("Handler not called")
-	}
-
-	if p.signalRejectedEventCounter != 1 {
-		t.Errorf("Expected signalRejectedEventCounter=1, got %d", p.signalRejectedEventCounter)
-	}
-
-	if p.totalSignalRejectedDur < 15*time.Millisecond {
-		t.Errorf("Expected duration >= 15ms, got %v", p.totalSignalRejectedDur)
+func TestMiddlewarePerformance_Handlers(t *testing.T) {
+	const delay = 2 * time.Millisecond
+
+	tests := []struct {
+		name    string
+		call    func(p *Performance) bool
+		counter func(p *Performance) int64
+		dur     func(p *Performance) time.Duration
+	}{
+		{name: "tick", call: func(p *Performance) bool { return callWrapped(p.WithTick, delay) }, counter: func(p *Performance) int64 { return p.tickEventCounter }, dur: func(p *Performance) time.Duration { return p.totalTickHandlerDur }},
+		{name: "bar", call: func(p *Performance) bool { return callWrapped(p.WithBar, delay) }, counter: func(p *Performance) int64 { return p.barEventCounter }, dur: func(p *Performance) time.Duration { return p.totalBarHandlerDur }},
+		{name: "balance", call: func(p *Performance) bool { return callWrapped(p.WithBalance, delay) }, counter: func(p *Performance) int64 { return p.balanceEventCounter }, dur: func(p *Performance) time.Duration { return p.totalBalanceHandlerDur }},
+		{name: "equity", call: func(p *Performance) bool { return callWrapped(p.WithEquity, delay) }, counter: func(p *Performance) int64 { return p.equityEventCounter }, dur: func(p *Performance) time.Duration { return p.totalEquityHandlerDur }},
+		{name: "position_open", call: func(p *Performance) bool { return callWrapped(p.WithPositionOpen, delay) }, counter: func(p *Performance) int64 { return p.positionOpenedEventCounter }, dur: func(p *Performance) time.Duration { return p.totalPosOpenHandlerDur }},
+		{name: "position_close", call: func(p *Performance) bool { return callWrapped(p.WithPositionClose, delay) }, counter: func(p *Performance) int64 { return p.positionClosedEventCounter }, dur: func(p *Performance) time.Duration { return p.totalPosClosHandlerDur }},
+		{name: "position_update", call: func(p *Performance) bool { return callWrapped(p.WithPositionUpdate, delay) }, counter: func(p *Performance) int64 { return p.positionPnLUpdatedEventCounter }, dur: func(p *Performance) time.Duration { return p.totalPosUpdtHandlerDur }},
+		{name: "order", call: func(p *Performance) bool { return callWrapped(p.WithOrder, delay) }, counter: func(p *Performance) int64 { return p.orderEventCounter }, dur: func(p *Performance) time.Duration { return p.totalOrderHandlerDur }},
+		{name: "order_rejection", call: func(p *Performance) bool { return callWrapped(p.WithOrderRejection, delay) }, counter: func(p *Performance) int64 { return p.orderRejectedEventCounter }, dur: func(p *Performance) time.Duration { return p.totalOrderRejectedDur }},
+		{name: "order_acceptance", call: func(p *Performance) bool { return callWrapped(p.WithOrderAcceptance, delay) }, counter: func(p *Performance) int64 { return p.orderAcceptedEventCounter }, dur: func(p *Performance) time.Duration { return p.totalOrderAcceptedDur }},
+		{name: "order_filled", call: func(p *Performance) bool { return callWrapped(p.WithOrderFilled, delay) }, counter: func(p *Performance) int64 { return p.orderFilledEventCounter }, dur: func(p *Performance) time.Duration { return p.totalOrderFilledDur }},
+		{name: "order_cancelled", call: func(p *Performance) bool { return callWrapped(p.WithOrderCancelled, delay) }, counter: func(p *Performance) int64 { return p.orderCancelledEventCounter }, dur: func(p *Performance) time.Duration { return p.totalOrderCancelDur }},
+		{name: "signal", call: func(p *Performance) bool { return callWrapped(p.WithSignal, delay) }, counter: func(p *Performance) int64 { return p.signalEventCounter }, dur: func(p *Performance) time.Duration { return p.totalSignalHandlerDur }},
+		{name: "signal_rejection", call: func(p *Performance) bool { return callWrapped(p.WithSignalRejection, delay) }, counter: func(p *Performance) int64 { return p.signalRejectedEventCounter }, dur: func(p *Performance) time.Duration { return p.totalSignalRejectedDur }},
+		{name: "signal_acceptance", call: func(p *Performance) bool { return callWrapped(p.WithSignalAcceptance, delay) }, counter: func(p *Performance) int64 { return p.signalAcceptedEventCounter }, dur: func(p *Performance) time.Duration { return p.totalSignalAcceptedDur }},
+		{name: "timer", call: func(p *Performance) bool { return callWrapped(p.WithTimer, delay) }, counter: func(p *Performance) int64 { return p.timerEventCounter }, dur: func(p *Performance) time.Duration { return p.totalTimerHandlerDur }},
+		{name: "order_amended", call: func(p *Performance) bool { return callWrapped(p.WithOrderAmended, delay) }, counter: func(p *Performance) int64 { return p.orderAmendedEventCounter }, dur: func(p *Performance) time.Duration { return p.totalOrderAmendedDur }},
+		{name: "depth", call: func(p *Performance) bool { return callWrapped(p.WithDepth, delay) }, counter: func(p *Performance) int64 { return p.depthEventCounter }, dur: func(p *Performance) time.Duration { return p.totalDepthHandlerDur }},
+		{name: "account", call: func(p *Performance) bool { return callWrapped(p.WithAccount, delay) }, counter: func(p *Performance) int64 { return p.accountEventCounter }, dur: func(p *Performance) time.Duration { return p.totalAccountHandlerDur }},
+		{name: "margin_call", call: func(p *Performance) bool { return callWrapped(p.WithMarginCall, delay) }, counter: func(p *Performance) int64 { return p.marginCallEventCounter }, dur: func(p *Performance) time.Duration { return p.totalMarginCallHandlerDur }},
+		{name: "stop_out", call: func(p *Performance) bool { return callWrapped(p.WithStopOut, delay) }, counter: func(p *Performance) int64 { return p.stopOutEventCounter }, dur: func(p *Performance) time.Duration { return p.totalStopOutHandlerDur }},
+	}
+
+	all := NewPerformance()
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := NewPerformance()
+
+			if !test.call(p) {
+				t.Error("Handler not called")
+			}
+			if test.counter(p) != 1 {
+				t.Errorf("Expected counter=1, got %d", test.counter(p))
+			}
+			if test.dur(p) < delay {
+				t.Errorf("Expected duration >= %v, got %v", delay, test.dur(p))
+			}
+
+			test.call(all)
+		})
+	}
+
+	// every handler counts on its own counter
+	for _, test := range tests {
+		if test.counter(all) != 1 {
+			t.Errorf("Expected %s counter=1 after calling every handler once, got %d", test.name, test.counter(all))
+		}
 	}
 }
 
@@ -376,103 +110,6 @@ func TestMiddlewarePerformance_MultipleCallsSameHandler(t *testing.T) {
 	}
 }
 
-func TestMiddlewarePerformance_AllHandlers(t *testing.T) {
-	p := NewPerformance()
-
-	handlers := map[string]bool{
-		"tick":           false,
-		"bar":            false,
-		"balance":        false,
-		"equity":         false,
-		"positionOpened": false,
-		"positionClosed": false,
-		"positionPnL":    false,
-		"order":          false,
-		"orderRejected":  false,
-		"orderAccepted":  false,
-		"orderFilled":    false,
-		"orderCancelled": false,
-		"signal":         false,
-		"signalAccepted": false,
-		"signalRejected": false,
-	}
-
-	p.WithTick(func(ctx context.Context, tick common.Tick) {
-		handlers["tick"] = true
-	})(context.Background(), common.Tick{})
-
-	p.WithBar(func(ctx context.Context, bar common.Bar) {
-		handlers["bar"] = true
-	})(context.Background(), common.Bar{})
-
-	p.WithBalance(func(ctx context.Context, balance common.Balance) {
-		handlers["balance"] = true
-	})(context.Background(), common.Balance{})
-
-	p.WithEquity(func(ctx context.Context, equity common.Equity) {
-		handlers["equity"] = true
-	})(context.Background(), common.Equity{})
-
-	p.WithPositionOpen(func(ctx context.Context, position common.Position) {
-		handlers["positionOpened"] = true
-	})(context.Background(), common.Position{})
-
-	p.WithPositionClose(func(ctx context.Context, position common.Position) {
-		handlers["positionClosed"] = true
-	})(context.Background(), common.Position{})
-
-	p.WithPositionUpdate(func(ctx context.Context, position common.Position) {
-		handlers["positionPnL"] = true
-	})(context.Background(), common.Position{})
-
-	p.WithOrder(func(ctx context.Context, order common.Order) {
-		handlers["order"] = true
-	})(context.Background(), common.Order{})
-
-	p.WithOrderRejection(func(ctx context.Context, rejected common.OrderRejected) {
-		handlers["orderRejected"] = true
-	})(context.Background(), common.OrderRejected{})
-
-	p.WithOrderAcceptance(func(ctx context.Context, accepted common.OrderAccepted) {
-		handlers["orderAccepted"] = true
-	})(context.Background(), common.OrderAccepted{})
-
-	p.WithOrderFilled(func(ctx context.Context, filled common.OrderFilled) {
-		handlers["orderFilled"] = true
-	})(context.Background(), common.OrderFilled{})
-
-	p.WithOrderCancelled(func(ctx context.Context, cancelled common.OrderCancelled) {
-		handlers["orderCancelled"] = true
-	})(context.Background(), common.OrderCancelled{})
-
-	p.WithSignal(func(ctx context.Context, signal common.Signal) {
-		handlers["signal"] = true
-	})(context.Background(), common.Signal{})
-
-	p.WithSignalAcceptance(func(ctx context.Context, signal common.SignalAccepted) {
-		handlers["signalAccepted"] = true
-	})(context.Background(), common.SignalAccepted{})
-
-	p.WithSignalRejection(func(ctx context.Context, signal common.SignalRejected) {
-		handlers["signalRejected"] = true
-	})(context.Background(), common.SignalRejected{})
-
-	for name, called := range handlers {
-		if !called {
-			t.Errorf("Handler %s not called", name)
-		}
-	}
-
-	totalEvents := p.tickEventCounter + p.barEventCounter + p.balanceEventCounter +
-		p.equityEventCounter + p.positionOpenedEventCounter + p.positionClosedEventCounter +
-		p.positionPnLUpdatedEventCounter + p.orderEventCounter + p.orderRejectedEventCounter + p.orderFilledEventCounter + p.orderCancelledEventCounter +
-		p.orderAcceptedEventCounter + p.signalEventCounter + p.signalAcceptedEventCounter + p.signalRejectedEventCounter
-
-	if totalEvents != 15 {
-		t.Errorf("Expected total events=11, got %d", totalEvents)
-	}
-}
-
 func TestMiddlewarePerformance_PrintStatistics(t *testing.T) {
 	buf := &bytes.Buffer{}
 	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
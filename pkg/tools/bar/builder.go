@@ -4,7 +4,6 @@ import (
 	"context"
 	"github.com/peter-kozarec/equinox/pkg/bus"
 	"github.com/peter-kozarec/equinox/pkg/common"
-	"github.com/peter-kozarec/equinox/pkg/utility"
 	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
 	"log/slog"
 	"time"
@@ -111,8 +110,8 @@ func (b *Builder) construct(symbol string, period common.BarPeriod, mode PriceMo
 		bar := common.Bar{
 			Source:      "bar-builder",
 			Symbol:      symbol,
-			ExecutionId: utility.GetExecutionID(),
-			TraceID:     utility.CreateTraceID(),
+			ExecutionId: b.router.IDs().ExecutionID(),
+			TraceID:     b.router.IDs().TraceID(),
 			Period:      period,
 			TimeStamp:   tick.TimeStamp,
 			OpenTime:    getAlignedPeriodStart(period, tick.TimeStamp),
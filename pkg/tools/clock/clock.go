@@ -0,0 +1,52 @@
+package clock
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/common"
+)
+
+// Clock provides the current time of a run, wall time when live and market time in backtests
+type Clock interface {
+	Now() time.Time
+}
+
+type WallClock struct{}
+
+func (WallClock) Now() time.Time {
+	return time.Now()
+}
+
+// TickClock follows the timestamps of the ticks it is fed and never goes backwards
+type TickClock struct {
+	now atomic.Int64
+}
+
+func NewTickClock() *TickClock {
+	return &TickClock{}
+}
+
+func (c *TickClock) OnTick(_ context.Context, tick common.Tick) {
+	c.Advance(tick.TimeStamp)
+}
+
+func (c *TickClock) Advance(t time.Time) {
+	n := t.UnixNano()
+	for {
+		current := c.now.Load()
+		if n <= current || c.now.CompareAndSwap(current, n) {
+			return
+		}
+	}
+}
+
+// Now returns the timestamp of the latest tick, or the zero time when no tick was seen yet
+func (c *TickClock) Now() time.Time {
+	n := c.now.Load()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
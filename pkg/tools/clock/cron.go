@@ -0,0 +1,195 @@
+package clock
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCron = errors.New("invalid cron expression")
+
+// Schedule computes the recurring times of a timer
+type Schedule interface {
+	// Next returns the first time strictly after the given time
+	Next(after time.Time) time.Time
+}
+
+// Interval is a schedule firing every d
+type Interval time.Duration
+
+func (i Interval) Next(after time.Time) time.Time {
+	return after.Add(time.Duration(i))
+}
+
+// Cron is a schedule defined by a standard five field cron expression
+// "minute hour day-of-month month day-of-week". Fields accept *, single values, ranges a-b,
+// steps */n or a-b/n and comma separated lists of those. Day of week is 0-7, both 0 and 7 are Sunday.
+// As in cron, when both day fields are restricted a time matches if either of them does.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	domAny, dowAny                bool
+	loc                           *time.Location
+}
+
+type cronField struct {
+	min, max int
+}
+
+var (
+	cronMinute = cronField{0, 59}
+	cronHour   = cronField{0, 23}
+	cronDom    = cronField{1, 31}
+	cronMonth  = cronField{1, 12}
+	cronDow    = cronField{0, 7}
+)
+
+// ParseCron parses the expression, the schedule is evaluated in loc, or in UTC when loc is nil
+func ParseCron(expr string, loc *time.Location) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w %q: expected 5 fields, got %d", ErrInvalidCron, expr, len(fields))
+	}
+
+	if loc == nil {
+		loc = time.UTC
+	}
+	c := &Cron{
+		loc:    loc,
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+
+	var err error
+	if c.minute, err = cronMinute.parse(fields[0]); err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidCron, expr, err)
+	}
+	if c.hour, err = cronHour.parse(fields[1]); err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidCron, expr, err)
+	}
+	if c.dom, err = cronDom.parse(fields[2]); err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidCron, expr, err)
+	}
+	if c.month, err = cronMonth.parse(fields[3]); err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidCron, expr, err)
+	}
+	if c.dow, err = cronDow.parse(fields[4]); err != nil {
+		return nil, fmt.Errorf("%w %q: %w", ErrInvalidCron, expr, err)
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+
+	return c, nil
+}
+
+// MustParseCron is like ParseCron but panics on an invalid expression
+func MustParseCron(expr string, loc *time.Location) *Cron {
+	c, err := ParseCron(expr, loc)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c *Cron) Next(after time.Time) time.Time {
+	t := after.In(c.loc).Truncate(time.Minute).Add(time.Minute)
+
+	// Every valid expression matches at least once within a leap year cycle
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+		if !c.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (c *Cron) matchDay(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+func (f cronField) parse(field string) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", part)
+			}
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			loPart, hiPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = f.value(loPart); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(hiPart); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			var err error
+			if lo, err = f.value(rangePart); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if hasStep {
+				hi = f.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, f.min, f.max)
+	}
+	return v, nil
+}
@@ -0,0 +1,59 @@
+package clock
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClockCron_Next(t *testing.T) {
+	// 2024-01-05 is a Friday
+	from := time.Date(2024, 1, 5, 16, 54, 30, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"* * * * *", from, time.Date(2024, 1, 5, 16, 55, 0, 0, time.UTC)},
+		{"55 16 * * 1-5", from, time.Date(2024, 1, 5, 16, 55, 0, 0, time.UTC)},
+		{"55 16 * * 1-5", time.Date(2024, 1, 5, 16, 55, 0, 0, time.UTC), time.Date(2024, 1, 8, 16, 55, 0, 0, time.UTC)},
+		{"*/15 * * * *", from, time.Date(2024, 1, 5, 17, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", from, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 9 29 2 *", from, time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC)},
+		{"30 8 * * 0", from, time.Date(2024, 1, 7, 8, 30, 0, 0, time.UTC)},
+		{"30 8 * * 7", from, time.Date(2024, 1, 7, 8, 30, 0, 0, time.UTC)},
+		{"0 12 10 * 1", from, time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)},
+		{"0,30 9-10 * * *", from, time.Date(2024, 1, 6, 9, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := ParseCron(tt.expr, nil)
+			if err != nil {
+				t.Fatalf("ParseCron(%q) error = %v", tt.expr, err)
+			}
+			if got := c.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v; want %v", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClockCron_Location(t *testing.T) {
+	loc := time.FixedZone("EST", -5*3600)
+	c := MustParseCron("0 17 * * *", loc)
+
+	got := c.Next(time.Date(2024, 1, 5, 12, 0, 0, 0, time.UTC))
+	if want := time.Date(2024, 1, 5, 22, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next() = %v; want %v", got, want)
+	}
+}
+
+func TestClockCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := ParseCron(expr, nil); !errors.Is(err, ErrInvalidCron) {
+			t.Errorf("ParseCron(%q) expected ErrInvalidCron, got %v", expr, err)
+		}
+	}
+}
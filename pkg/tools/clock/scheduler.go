@@ -0,0 +1,218 @@
+package clock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+)
+
+const (
+	componentNameScheduler = "tools.clock.scheduler"
+)
+
+var (
+	ErrRouterIsNil   = errors.New("router is nil")
+	ErrClockIsNil    = errors.New("clock is nil")
+	ErrCallbackIsNil = errors.New("callback is nil")
+)
+
+// schedulerCount numbers the schedulers of the process, so that their timers can be told apart on a shared router
+var schedulerCount atomic.Uint64
+
+type TimerCallback func(context.Context, common.Timer)
+
+type timer struct {
+	id       common.TimerId
+	name     string
+	at       time.Time
+	schedule Schedule
+	callback TimerCallback
+	fired    bool
+}
+
+// Scheduler posts a bus.TimerEvent when a timer is due and runs the timer callback when the event is
+// dispatched, so the callbacks run on the router goroutine like any other handler. Time is advanced
+// by OnTick in backtests, timers are due on the first tick at or after their time, and by Run when live.
+// Timer ids are unique per scheduler, the timers are stamped with the source of their scheduler so that
+// several schedulers can share a router.
+type Scheduler struct {
+	router *bus.Router
+	clock  Clock
+	source string
+
+	mu     sync.Mutex
+	lastId common.TimerId
+	timers map[common.TimerId]*timer
+}
+
+func NewScheduler(router *bus.Router, clock Clock) (*Scheduler, error) {
+	if router == nil {
+		return nil, ErrRouterIsNil
+	}
+	if clock == nil {
+		return nil, ErrClockIsNil
+	}
+
+	return &Scheduler{
+		router: router,
+		clock:  clock,
+		source: fmt.Sprintf("%s.%d", componentNameScheduler, schedulerCount.Add(1)),
+		timers: make(map[common.TimerId]*timer),
+	}, nil
+}
+
+// At schedules a one-shot timer
+func (s *Scheduler) At(name string, at time.Time, callback TimerCallback) (common.TimerId, error) {
+	return s.add(&timer{name: name, at: at, callback: callback})
+}
+
+// After schedules a one-shot timer d after the current time of the clock
+func (s *Scheduler) After(name string, d time.Duration, callback TimerCallback) (common.TimerId, error) {
+	return s.At(name, s.clock.Now().Add(d), callback)
+}
+
+// Every schedules a recurring timer, e.g. Every("flatten", MustParseCron("55 16 * * 1-5", loc), cb).
+// When the clock jumps over several occurrences, as over a weekend in a backtest, the timer fires once.
+// A timer scheduled before the clock has a time, e.g. before the first tick, starts on the first Advance.
+func (s *Scheduler) Every(name string, schedule Schedule, callback TimerCallback) (common.TimerId, error) {
+	var at time.Time
+	if now := s.clock.Now(); !now.IsZero() {
+		at = schedule.Next(now)
+	}
+	return s.add(&timer{name: name, at: at, schedule: schedule, callback: callback})
+}
+
+func (s *Scheduler) Cancel(id common.TimerId) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.timers[id]
+	delete(s.timers, id)
+	return ok
+}
+
+func (s *Scheduler) OnTick(_ context.Context, tick common.Tick) {
+	s.Advance(tick.TimeStamp)
+}
+
+// Run advances the scheduler by the clock every interval until the context is done
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			s.Advance(s.clock.Now())
+		}
+	}
+}
+
+// Advance posts a timer event for every timer due at now, in the order of their scheduled times
+func (s *Scheduler) Advance(now time.Time) {
+	s.mu.Lock()
+
+	var due []common.Timer
+	for _, t := range s.timers {
+		if t.at.IsZero() && t.schedule != nil {
+			t.at = t.schedule.Next(now)
+			continue
+		}
+		if t.fired || t.at.After(now) {
+			continue
+		}
+
+		due = append(due, common.Timer{
+			Id:            t.id,
+			Name:          t.name,
+			ScheduledTime: t.at,
+			Recurring:     t.schedule != nil,
+			Source:        s.source,
+			ExecutionId:   s.router.IDs().ExecutionID(),
+			TraceID:       s.router.IDs().TraceID(),
+			TimeStamp:     now,
+		})
+
+		if t.schedule != nil {
+			t.at = t.schedule.Next(now)
+		} else {
+			t.fired = true
+		}
+	}
+
+	s.mu.Unlock()
+
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].ScheduledTime.Equal(due[j].ScheduledTime) {
+			return due[i].Id < due[j].Id
+		}
+		return due[i].ScheduledTime.Before(due[j].ScheduledTime)
+	})
+
+	for _, t := range due {
+		if err := s.router.Post(bus.TimerEvent, t); err != nil {
+			slog.Warn("unable to post timer event", "error", err, "timer", t.Name)
+			s.rearm(t)
+		}
+	}
+}
+
+// OnTimer runs the callback of the dispatched timer. Timers of other schedulers and timers cancelled after
+// they were posted are skipped.
+func (s *Scheduler) OnTimer(ctx context.Context, timer common.Timer) {
+	if timer.Source != s.source {
+		return
+	}
+
+	s.mu.Lock()
+	t, ok := s.timers[timer.Id]
+	if ok && t.schedule == nil {
+		delete(s.timers, timer.Id)
+	}
+	s.mu.Unlock()
+
+	if ok {
+		t.callback(ctx, timer)
+	}
+}
+
+// rearm makes a timer that could not be posted due again on the next Advance, a recurring timer keeps the
+// occurrence it missed instead of skipping to the next one
+func (s *Scheduler) rearm(timer common.Timer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, ok := s.timers[timer.Id]
+	if !ok {
+		return
+	}
+	if t.schedule != nil {
+		t.at = timer.ScheduledTime
+	} else {
+		t.fired = false
+	}
+}
+
+func (s *Scheduler) add(t *timer) (common.TimerId, error) {
+	if t.callback == nil {
+		return 0, ErrCallbackIsNil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastId++
+	t.id = s.lastId
+	s.timers[t.id] = t
+	return t.id, nil
+}
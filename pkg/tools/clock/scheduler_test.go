@@ -0,0 +1,249 @@
+package clock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+)
+
+func newTestScheduler(t *testing.T) (*bus.Router, *TickClock, *Scheduler) {
+	t.Helper()
+
+	router := bus.NewRouter(100)
+	tickClock := NewTickClock()
+	scheduler, err := NewScheduler(router, tickClock)
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+
+	router.OnTick = bus.MergeHandlers(tickClock.OnTick, scheduler.OnTick)
+	router.OnTimer = scheduler.OnTimer
+	return router, tickClock, scheduler
+}
+
+func feed(t *testing.T, router *bus.Router, times ...time.Time) {
+	t.Helper()
+	for _, ts := range times {
+		if err := router.Post(bus.TickEvent, common.Tick{TimeStamp: ts}); err != nil {
+			t.Fatalf("Post failed: %v", err)
+		}
+		if err := router.DrainEvents(context.Background()); err != nil {
+			t.Fatalf("DrainEvents failed: %v", err)
+		}
+	}
+}
+
+func TestClockTickClock(t *testing.T) {
+	c := NewTickClock()
+	if !c.Now().IsZero() {
+		t.Errorf("Expected zero time, got %v", c.Now())
+	}
+
+	now := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	c.Advance(now)
+	c.Advance(now.Add(-time.Second))
+
+	if !c.Now().Equal(now) {
+		t.Errorf("Expected %v, got %v", now, c.Now())
+	}
+}
+
+func TestClockScheduler_OneShot(t *testing.T) {
+	router, _, scheduler := newTestScheduler(t)
+	start := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+
+	var fired []common.Timer
+	if _, err := scheduler.At("once", start.Add(time.Minute), func(_ context.Context, timer common.Timer) {
+		fired = append(fired, timer)
+	}); err != nil {
+		t.Fatalf("At failed: %v", err)
+	}
+
+	feed(t, router, start, start.Add(30*time.Second), start.Add(90*time.Second), start.Add(time.Hour))
+
+	if len(fired) != 1 {
+		t.Fatalf("Expected 1 fired timer, got %d", len(fired))
+	}
+	if fired[0].Name != "once" || !fired[0].ScheduledTime.Equal(start.Add(time.Minute)) || !fired[0].TimeStamp.Equal(start.Add(90*time.Second)) {
+		t.Errorf("Unexpected timer: %+v", fired[0])
+	}
+}
+
+func TestClockScheduler_After(t *testing.T) {
+	router, _, scheduler := newTestScheduler(t)
+	start := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+
+	feed(t, router, start)
+
+	var count int
+	if _, err := scheduler.After("after", time.Minute, func(_ context.Context, _ common.Timer) { count++ }); err != nil {
+		t.Fatalf("After failed: %v", err)
+	}
+
+	feed(t, router, start.Add(59*time.Second))
+	if count != 0 {
+		t.Errorf("Expected timer not to fire yet, count=%d", count)
+	}
+	feed(t, router, start.Add(time.Minute))
+	if count != 1 {
+		t.Errorf("Expected count=1, got %d", count)
+	}
+}
+
+func TestClockScheduler_Recurring(t *testing.T) {
+	router, _, scheduler := newTestScheduler(t)
+
+	var fired []time.Time
+	if _, err := scheduler.Every("flatten", MustParseCron("55 16 * * 1-5", nil), func(_ context.Context, timer common.Timer) {
+		if !timer.Recurring {
+			t.Error("Expected recurring timer")
+		}
+		fired = append(fired, timer.ScheduledTime)
+	}); err != nil {
+		t.Fatalf("Every failed: %v", err)
+	}
+
+	// Thursday to Tuesday, the clock jumps over the weekend
+	feed(t, router,
+		time.Date(2024, 1, 4, 16, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 4, 16, 56, 0, 0, time.UTC),
+		time.Date(2024, 1, 4, 17, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 5, 16, 55, 0, 0, time.UTC),
+		time.Date(2024, 1, 9, 9, 0, 0, 0, time.UTC),
+	)
+
+	expected := []time.Time{
+		time.Date(2024, 1, 4, 16, 55, 0, 0, time.UTC),
+		time.Date(2024, 1, 5, 16, 55, 0, 0, time.UTC),
+		time.Date(2024, 1, 8, 16, 55, 0, 0, time.UTC),
+	}
+	if len(fired) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, fired)
+	}
+	for i := range expected {
+		if !fired[i].Equal(expected[i]) {
+			t.Errorf("At index %d: expected %v, got %v", i, expected[i], fired[i])
+		}
+	}
+}
+
+func TestClockScheduler_Cancel(t *testing.T) {
+	router, _, scheduler := newTestScheduler(t)
+	start := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+
+	var count int
+	id, _ := scheduler.At("cancelled", start, func(_ context.Context, _ common.Timer) { count++ })
+	if !scheduler.Cancel(id) {
+		t.Error("Expected Cancel to find the timer")
+	}
+	if scheduler.Cancel(id) {
+		t.Error("Expected second Cancel to fail")
+	}
+
+	// Cancelled after it was posted, before it was dispatched
+	id, _ = scheduler.At("posted", start, func(_ context.Context, _ common.Timer) { count++ })
+	scheduler.Advance(start)
+	scheduler.Cancel(id)
+	_ = router.DrainEvents(context.Background())
+
+	if count != 0 {
+		t.Errorf("Expected no callbacks, got %d", count)
+	}
+}
+
+func TestClockScheduler_Order(t *testing.T) {
+	router, _, scheduler := newTestScheduler(t)
+	start := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+
+	var names []string
+	record := func(_ context.Context, timer common.Timer) { names = append(names, timer.Name) }
+	_, _ = scheduler.At("third", start.Add(3*time.Second), record)
+	_, _ = scheduler.At("first", start.Add(time.Second), record)
+	_, _ = scheduler.At("second", start.Add(2*time.Second), record)
+
+	feed(t, router, start.Add(time.Minute))
+
+	if len(names) != 3 || names[0] != "first" || names[1] != "second" || names[2] != "third" {
+		t.Errorf("Expected [first second third], got %v", names)
+	}
+}
+
+func TestClockScheduler_SharedRouter(t *testing.T) {
+	router, tickClock, first := newTestScheduler(t)
+	second, err := NewScheduler(router, tickClock)
+	if err != nil {
+		t.Fatalf("NewScheduler failed: %v", err)
+	}
+	router.OnTick = bus.MergeHandlers(tickClock.OnTick, first.OnTick, second.OnTick)
+	router.OnTimer = bus.MergeHandlers(first.OnTimer, second.OnTimer)
+
+	start := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	var names []string
+	record := func(_ context.Context, timer common.Timer) { names = append(names, timer.Name) }
+	firstId, _ := first.At("first", start, record)
+	secondId, _ := second.At("second", start, record)
+	if firstId != secondId {
+		t.Fatalf("Expected both schedulers to start at the same id, got %d and %d", firstId, secondId)
+	}
+
+	feed(t, router, start)
+
+	if len(names) != 2 || names[0] == names[1] {
+		t.Errorf("Expected each callback once, got %v", names)
+	}
+}
+
+func TestClockScheduler_PostFailure(t *testing.T) {
+	router := bus.NewRouter(1)
+	scheduler, _ := NewScheduler(router, NewTickClock())
+	router.OnTimer = scheduler.OnTimer
+
+	start := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	var oneShot int
+	var recurring []time.Time
+	_, _ = scheduler.At("one-shot", start, func(_ context.Context, _ common.Timer) { oneShot++ })
+	_, _ = scheduler.Every("recurring", Interval(time.Hour), func(_ context.Context, timer common.Timer) {
+		recurring = append(recurring, timer.ScheduledTime)
+	})
+	scheduler.Advance(start.Add(-time.Minute))
+
+	// the queue is full, both timers fail to post and must be due again
+	if err := router.Post(bus.TickEvent, common.Tick{}); err != nil {
+		t.Fatalf("Post failed: %v", err)
+	}
+	scheduler.Advance(start.Add(time.Hour))
+	_ = router.DrainEvents(context.Background())
+	if oneShot != 0 || len(recurring) != 0 {
+		t.Fatalf("Expected no callbacks while the queue was full, got %d and %v", oneShot, recurring)
+	}
+
+	for range 2 {
+		scheduler.Advance(start.Add(time.Hour))
+		_ = router.DrainEvents(context.Background())
+	}
+
+	if oneShot != 1 {
+		t.Errorf("Expected the one-shot timer to fire once, got %d", oneShot)
+	}
+	if len(recurring) != 1 || !recurring[0].Equal(start.Add(59*time.Minute)) {
+		t.Errorf("Expected the missed occurrence at %v, got %v", start.Add(59*time.Minute), recurring)
+	}
+}
+
+func TestClockScheduler_Errors(t *testing.T) {
+	if _, err := NewScheduler(nil, WallClock{}); !errors.Is(err, ErrRouterIsNil) {
+		t.Errorf("Expected ErrRouterIsNil, got %v", err)
+	}
+	if _, err := NewScheduler(bus.NewRouter(1), nil); !errors.Is(err, ErrClockIsNil) {
+		t.Errorf("Expected ErrClockIsNil, got %v", err)
+	}
+
+	scheduler, _ := NewScheduler(bus.NewRouter(1), WallClock{})
+	if _, err := scheduler.At("nil", time.Now(), nil); !errors.Is(err, ErrCallbackIsNil) {
+		t.Errorf("Expected ErrCallbackIsNil, got %v", err)
+	}
+}
@@ -13,8 +13,10 @@ const (
 )
 
 type Audit struct {
-	equities  []common.Equity
-	positions []common.Position
+	equities    []common.Equity
+	positions   []common.Position
+	marginCalls int
+	stopOuts    int
 }
 
 func NewAudit() *Audit {
@@ -31,8 +33,19 @@ func (a *Audit) OnPositionClosed(_ context.Context, position common.Position) {
 	a.positions = append(a.positions, position)
 }
 
+func (a *Audit) OnMarginCall(_ context.Context, _ common.MarginCall) {
+	a.marginCalls++
+}
+
+func (a *Audit) OnStopOut(_ context.Context, _ common.StopOut) {
+	a.stopOuts++
+}
+
 func (a *Audit) GenerateReport() Report {
-	report := Report{}
+	report := Report{
+		MarginCalls: a.marginCalls,
+		StopOuts:    a.stopOuts,
+	}
 
 	auditedDays := a.dayCount()
 	year := fixed.FromInt64(36500, 2)
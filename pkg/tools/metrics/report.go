@@ -30,6 +30,8 @@ type Report struct {
 	SharpeRatio          fixed.Point
 	SortinoRatio         fixed.Point
 	AnnualizedVolatility fixed.Point
+	MarginCalls          int
+	StopOuts             int
 }
 
 func (r Report) Print() {
@@ -56,5 +58,7 @@ func (r Report) Print() {
 	slog.Info("risk metrics",
 		"sharpe_ratio", r.SharpeRatio,
 		"sortino_ratio", r.SortinoRatio,
-		"annualized_volatility", fmt.Sprintf("%s%%", r.AnnualizedVolatility))
+		"annualized_volatility", fmt.Sprintf("%s%%", r.AnnualizedVolatility),
+		"margin_calls", r.MarginCalls,
+		"stop_outs", r.StopOuts)
 }
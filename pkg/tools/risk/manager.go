@@ -12,7 +12,6 @@ import (
 	"github.com/peter-kozarec/equinox/pkg/common"
 	"github.com/peter-kozarec/equinox/pkg/exchange"
 	"github.com/peter-kozarec/equinox/pkg/tools/store"
-	"github.com/peter-kozarec/equinox/pkg/utility"
 	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
 )
 
@@ -21,10 +20,11 @@ const (
 )
 
 var (
-	ErrRouterIsNil  = errors.New("router is nil")
-	ErrSlHandlerNil = errors.New("sl handler is nil")
-	ErrTpHandlerNil = errors.New("tp handler is nil")
-	ErrCfgInvalid   = errors.New("invalid configuration")
+	ErrRouterIsNil   = errors.New("router is nil")
+	ErrSlHandlerNil  = errors.New("sl handler is nil")
+	ErrTpHandlerNil  = errors.New("tp handler is nil")
+	ErrCfgInvalid    = errors.New("invalid configuration")
+	ErrPipSizeNotSet = errors.New("symbol has neither pip size nor tick size")
 
 	errSignalValidation = errors.New("signal validation error")
 )
@@ -47,6 +47,7 @@ type Manager struct {
 	ts      time.Time
 	equity  fixed.Point
 	balance fixed.Point
+	account *common.Account
 
 	tickCache     map[string]common.Tick
 	openOrders    []common.Order
@@ -91,6 +92,13 @@ func (m *Manager) OnEquity(_ context.Context, equity common.Equity) {
 	m.equity = equity.Value
 }
 
+// OnAccount keeps the last account snapshot, signals are rejected while it shows no free margin
+func (m *Manager) OnAccount(_ context.Context, account common.Account) {
+	m.balance = account.Balance
+	m.equity = account.Equity
+	m.account = &account
+}
+
 func (m *Manager) OnTick(_ context.Context, tick common.Tick) {
 	m.ts = tick.TimeStamp
 	m.tickCache[tick.Symbol] = tick
@@ -187,7 +195,11 @@ func (m *Manager) OnSignal(_ context.Context, signal common.Signal) {
 		return
 	}
 
-	pipDiff, pipVal := m.calcPipDiffAndVal(signal.Entry, sl, signal.Symbol)
+	pipDiff, pipVal, err := m.calcPipDiffAndVal(signal.Entry, sl, signal.Symbol)
+	if err != nil {
+		m.postSignalRejected(signal, err.Error(), "original signal dropped")
+		return
+	}
 	baseSize := m.calcSizeForBaseRiskRate(pipDiff, pipVal)
 	minSize := m.calcSizeForMinRiskRate(pipDiff, pipVal)
 	maxSize := m.calcSizeForMaxRiskRate(pipDiff, pipVal)
@@ -211,6 +223,9 @@ func (m *Manager) OnSignal(_ context.Context, signal common.Signal) {
 
 	m.postSignalAccepted(signal, multiplierComment)
 	order := m.createOpenOrder(signal.Entry, sl, tp, finalSize, signal.Symbol)
+	if order.ParentTraceID == 0 {
+		order.ParentTraceID = signal.TraceID
+	}
 	m.postOrder(order)
 }
 
@@ -225,6 +240,9 @@ func (m *Manager) checkForPositionAdjustment(tick common.Tick) {
 			if !shouldAdjust {
 				continue
 			}
+			if order.ParentTraceID == 0 {
+				order.ParentTraceID = openPosition.TraceID
+			}
 			m.postOrder(order)
 		}
 	}
@@ -257,9 +275,17 @@ func (m *Manager) applyMultipliers(baseSize fixed.Point, signal common.Signal) (
 	return baseSize, "No size multiplier strategy applied."
 }
 
-func (m *Manager) calcPipDiffAndVal(entry, closePrice fixed.Point, symbol string) (fixed.Point, fixed.Point) {
+func (m *Manager) calcPipDiffAndVal(entry, closePrice fixed.Point, symbol string) (fixed.Point, fixed.Point, error) {
 	symbolInfo := m.symbolStore.MustGet(symbol)
-	return closePrice.Sub(entry).Abs().Div(symbolInfo.PipSize), symbolInfo.ContractSize.Mul(symbolInfo.PipSize)
+	pipSize := symbolInfo.PipSize
+	if pipSize.IsZero() {
+		// Non-forex symbols are often quoted in ticks only
+		pipSize = symbolInfo.TickSize
+	}
+	if pipSize.IsZero() {
+		return fixed.Zero, fixed.Zero, fmt.Errorf("%w: %s", ErrPipSizeNotSet, symbol)
+	}
+	return closePrice.Sub(entry).Abs().Div(pipSize), symbolInfo.Profit(pipSize, fixed.One), nil
 }
 
 func (m *Manager) calcSizeForBaseRiskRate(pipDiff, pipValue fixed.Point) fixed.Point {
@@ -286,7 +312,10 @@ func (m *Manager) calcOpenRiskRate() (fixed.Point, error) {
 			return fixed.Point{}, fmt.Errorf("unable to get close price: %w", err)
 		}
 		openPrice := position.OpenPrice
-		pipDiff, pipVal := m.calcPipDiffAndVal(openPrice, closePrice, position.Symbol)
+		pipDiff, pipVal, err := m.calcPipDiffAndVal(openPrice, closePrice, position.Symbol)
+		if err != nil {
+			return fixed.Point{}, err
+		}
 		riskRate := m.calcRiskRateForSize(pipDiff, pipVal, position.Size)
 		openRiskRate = openRiskRate.Add(riskRate)
 	}
@@ -339,6 +368,9 @@ func (m *Manager) rescaleSize(size fixed.Point) fixed.Point {
 }
 
 func (m *Manager) checkMarginRequirementsForSize(pipDiff, pipValue, size fixed.Point) error {
+	if m.account != nil && m.account.OpenPositions > 0 && m.account.FreeMargin.Lte(fixed.Zero) {
+		return fmt.Errorf("no free margin left, margin level is %s%%", m.account.MarginLevel.String())
+	}
 	riskRate := m.calcRiskRateForSize(pipDiff, pipValue, size)
 	openRiskRate, err := m.calcOpenRiskRate()
 	if err != nil {
@@ -372,8 +404,8 @@ func (m *Manager) defaultOpenOrderHandler(entry, sl, tp, size fixed.Point, symbo
 		TakeProfit:  tp,
 		Source:      componentNameRiskManager,
 		Symbol:      symbol,
-		ExecutionId: utility.GetExecutionID(),
-		TraceID:     utility.CreateTraceID(),
+		ExecutionId: m.router.IDs().ExecutionID(),
+		TraceID:     m.router.IDs().TraceID(),
 		TimeStamp:   m.ts,
 	}
 }
@@ -392,8 +424,9 @@ func (m *Manager) postSignalAccepted(signal common.Signal, comment string) {
 		Comment:        comment,
 		OriginalSignal: signal,
 		Source:         componentNameRiskManager,
-		ExecutionID:    utility.GetExecutionID(),
-		TraceID:        utility.CreateTraceID(),
+		ExecutionID:    m.router.IDs().ExecutionID(),
+		TraceID:        m.router.IDs().TraceID(),
+		ParentTraceID:  signal.TraceID,
 		TimeStamp:      m.ts,
 	}
 	if err := m.router.Post(bus.SignalAcceptanceEvent, signalAccepted); err != nil {
@@ -408,8 +441,9 @@ func (m *Manager) postSignalRejected(signal common.Signal, reason, comment strin
 		Comment:        comment,
 		OriginalSignal: signal,
 		Source:         componentNameRiskManager,
-		ExecutionID:    utility.GetExecutionID(),
-		TraceID:        utility.CreateTraceID(),
+		ExecutionID:    m.router.IDs().ExecutionID(),
+		TraceID:        m.router.IDs().TraceID(),
+		ParentTraceID:  signal.TraceID,
 		TimeStamp:      m.ts,
 	}
 	if err := m.router.Post(bus.SignalRejectionEvent, signalRejected); err != nil {
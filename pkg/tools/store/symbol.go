@@ -47,6 +47,17 @@ func (s SymbolStore) MustGet(symbolName string) exchange.SymbolInfo {
 	return symbol
 }
 
+// Chain returns the futures contracts with the given root ordered by expiry
+func (s SymbolStore) Chain(root string) exchange.ContractChain {
+	var contracts []exchange.SymbolInfo
+	for _, symbol := range s.symbols {
+		if symbol.Root != "" && strings.EqualFold(symbol.Root, root) {
+			contracts = append(contracts, symbol)
+		}
+	}
+	return exchange.NewContractChain(contracts...)
+}
+
 func CreateSymbolTestStore() SymbolStore {
 	return CreateSymbolStore([]exchange.SymbolInfo{
 		{
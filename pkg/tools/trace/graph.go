@@ -0,0 +1,288 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+	"github.com/peter-kozarec/equinox/pkg/utility"
+)
+
+var (
+	ErrPositionNotFound = errors.New("position not found")
+)
+
+// Event is a single event recorded under a trace id
+type Event struct {
+	Id      bus.EventId
+	Payload interface{}
+}
+
+// Node is an event, or several events sharing a trace id as positions do across open, update and
+// close, together with the nodes it caused
+type Node struct {
+	TraceID       utility.TraceID
+	ParentTraceID utility.TraceID
+	Events        []Event
+	Children      []*Node
+}
+
+// Graph collects events and links them by their ParentTraceID into causal trees
+type Graph struct {
+	mu        sync.Mutex
+	nodes     map[utility.TraceID]*Node
+	children  map[utility.TraceID][]utility.TraceID
+	positions map[common.PositionId]utility.TraceID
+}
+
+func NewGraph() *Graph {
+	return &Graph{
+		nodes:     make(map[utility.TraceID]*Node),
+		children:  make(map[utility.TraceID][]utility.TraceID),
+		positions: make(map[common.PositionId]utility.TraceID),
+	}
+}
+
+// Record adds the event to the graph, events without a trace id are ignored
+func (g *Graph) Record(id bus.EventId, data interface{}) {
+	traceID, parentTraceID := traceIDsOf(data)
+	if traceID == 0 {
+		return
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	node, ok := g.nodes[traceID]
+	if !ok {
+		node = &Node{TraceID: traceID}
+		g.nodes[traceID] = node
+	}
+	if node.ParentTraceID == 0 && parentTraceID != 0 {
+		node.ParentTraceID = parentTraceID
+		g.children[parentTraceID] = append(g.children[parentTraceID], traceID)
+	}
+	node.Events = append(node.Events, Event{Id: id, Payload: data})
+
+	if position, ok := data.(common.Position); ok {
+		g.positions[position.Id] = traceID
+	}
+}
+
+// Subscribe records every signal, order and position event posted to the router
+func (g *Graph) Subscribe(router *bus.Router) (func(), error) {
+	ids := []bus.EventId{
+		bus.SignalEvent, bus.SignalAcceptanceEvent, bus.SignalRejectionEvent,
+		bus.OrderEvent, bus.OrderAcceptanceEvent, bus.OrderRejectionEvent, bus.OrderFilledEvent, bus.OrderCancelledEvent,
+		bus.OrderAmendedEvent, bus.PositionOpenEvent, bus.PositionUpdateEvent, bus.PositionCloseEvent,
+	}
+
+	var unsubscribes []func()
+	unsubscribe := func() {
+		for _, u := range unsubscribes {
+			u()
+		}
+	}
+
+	for _, id := range ids {
+		u, err := subscribe(router, g, id)
+		if err != nil {
+			unsubscribe()
+			return nil, err
+		}
+		unsubscribes = append(unsubscribes, u)
+	}
+
+	return unsubscribe, nil
+}
+
+// LoadJournal builds the graph from a journal written by bus.Journal, the router is only used to decode
+// the payloads, so user defined events must be registered on it
+func LoadJournal(router *bus.Router, r io.Reader) (*Graph, error) {
+	g := NewGraph()
+	reader := bus.NewJournalReader(r)
+
+	for {
+		entry, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return g, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if entry.TraceID == 0 {
+			continue
+		}
+
+		data, err := router.DecodePayload(entry)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode journal entry %d: %w", entry.Sequence, err)
+		}
+		g.Record(entry.Event, data)
+	}
+}
+
+// PositionTree returns the causal tree the position belongs to, rooted at its earliest known ancestor
+func (g *Graph) PositionTree(id common.PositionId) (*Node, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	traceID, ok := g.positions[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrPositionNotFound, id)
+	}
+
+	// Walk up to the root, the visited set guards against malformed cyclic links
+	visited := map[utility.TraceID]bool{traceID: true}
+	root := traceID
+	for {
+		parent := g.nodes[root].ParentTraceID
+		if _, ok := g.nodes[parent]; !ok || visited[parent] {
+			break
+		}
+		visited[parent] = true
+		root = parent
+	}
+
+	return g.build(root, make(map[utility.TraceID]bool)), nil
+}
+
+func (g *Graph) build(traceID utility.TraceID, visited map[utility.TraceID]bool) *Node {
+	visited[traceID] = true
+	src := g.nodes[traceID]
+
+	node := &Node{
+		TraceID:       src.TraceID,
+		ParentTraceID: src.ParentTraceID,
+		Events:        append([]Event(nil), src.Events...),
+	}
+
+	children := append([]utility.TraceID(nil), g.children[traceID]...)
+	sort.Slice(children, func(i, j int) bool { return children[i] < children[j] })
+
+	for _, child := range children {
+		if _, ok := g.nodes[child]; !ok || visited[child] {
+			continue
+		}
+		node.Children = append(node.Children, g.build(child, visited))
+	}
+
+	return node
+}
+
+// Print writes the tree as indented text, one line per event
+func (n *Node) Print(w io.Writer) error {
+	return n.print(w, 0)
+}
+
+func (n *Node) print(w io.Writer, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	for _, ev := range n.Events {
+		if _, err := fmt.Fprintf(w, "%s%d %s %+v\n", indent, n.TraceID, eventName(ev.Id), ev.Payload); err != nil {
+			return err
+		}
+	}
+	for _, child := range n.Children {
+		if err := child.print(w, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Walk calls fn for every node of the tree in depth first order
+func (n *Node) Walk(fn func(node *Node, depth int)) {
+	n.walk(fn, 0)
+}
+
+func (n *Node) walk(fn func(node *Node, depth int), depth int) {
+	fn(n, depth)
+	for _, child := range n.Children {
+		child.walk(fn, depth+1)
+	}
+}
+
+func subscribe(router *bus.Router, g *Graph, id bus.EventId) (func(), error) {
+	record := func(data interface{}) { g.Record(id, data) }
+
+	switch id {
+	case bus.SignalEvent:
+		return bus.Subscribe(router, func(_ context.Context, v common.Signal) { record(v) })
+	case bus.SignalAcceptanceEvent:
+		return bus.Subscribe(router, func(_ context.Context, v common.SignalAccepted) { record(v) })
+	case bus.SignalRejectionEvent:
+		return bus.Subscribe(router, func(_ context.Context, v common.SignalRejected) { record(v) })
+	case bus.OrderEvent:
+		return bus.Subscribe(router, func(_ context.Context, v common.Order) { record(v) })
+	case bus.OrderAcceptanceEvent:
+		return bus.Subscribe(router, func(_ context.Context, v common.OrderAccepted) { record(v) })
+	case bus.OrderRejectionEvent:
+		return bus.Subscribe(router, func(_ context.Context, v common.OrderRejected) { record(v) })
+	case bus.OrderFilledEvent:
+		return bus.Subscribe(router, func(_ context.Context, v common.OrderFilled) { record(v) })
+	case bus.OrderCancelledEvent:
+		return bus.Subscribe(router, func(_ context.Context, v common.OrderCancelled) { record(v) })
+	case bus.OrderAmendedEvent:
+		return bus.Subscribe(router, func(_ context.Context, v common.OrderAmended) { record(v) })
+	default:
+		return bus.Subscribe(router, func(_ context.Context, v common.Position) { record(v) }, bus.ForEvent(id))
+	}
+}
+
+func traceIDsOf(data interface{}) (utility.TraceID, utility.TraceID) {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Struct {
+		return 0, 0
+	}
+	return uintField(v, "TraceID"), uintField(v, "ParentTraceID")
+}
+
+func uintField(v reflect.Value, name string) uint64 {
+	f := v.FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.Uint64 {
+		return 0
+	}
+	return f.Uint()
+}
+
+func eventName(id bus.EventId) string {
+	switch id {
+	case bus.SignalEvent:
+		return "signal"
+	case bus.SignalAcceptanceEvent:
+		return "signal_accepted"
+	case bus.SignalRejectionEvent:
+		return "signal_rejected"
+	case bus.OrderEvent:
+		return "order"
+	case bus.OrderAcceptanceEvent:
+		return "order_accepted"
+	case bus.OrderRejectionEvent:
+		return "order_rejected"
+	case bus.OrderFilledEvent:
+		return "order_filled"
+	case bus.OrderCancelledEvent:
+		return "order_cancelled"
+	case bus.OrderAmendedEvent:
+		return "order_amended"
+	case bus.PositionOpenEvent:
+		return "position_open"
+	case bus.PositionUpdateEvent:
+		return "position_update"
+	case bus.PositionCloseEvent:
+		return "position_close"
+	case bus.BarEvent:
+		return "bar"
+	case bus.TickEvent:
+		return "tick"
+	default:
+		return fmt.Sprintf("event_%d", id)
+	}
+}
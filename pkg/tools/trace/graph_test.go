@@ -0,0 +1,135 @@
+package trace
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/peter-kozarec/equinox/pkg/bus"
+	"github.com/peter-kozarec/equinox/pkg/common"
+)
+
+func postLifecycle(t *testing.T, r *bus.Router) {
+	t.Helper()
+
+	events := []struct {
+		id   bus.EventId
+		data interface{}
+	}{
+		{bus.SignalEvent, common.Signal{TraceID: 1}},
+		{bus.SignalAcceptanceEvent, common.SignalAccepted{TraceID: 2, ParentTraceID: 1}},
+		{bus.OrderEvent, common.Order{TraceID: 3, ParentTraceID: 1}},
+		{bus.OrderAcceptanceEvent, common.OrderAccepted{TraceID: 4, ParentTraceID: 3}},
+		{bus.PositionOpenEvent, common.Position{Id: 9, TraceID: 5, ParentTraceID: 3}},
+		{bus.OrderFilledEvent, common.OrderFilled{PositionId: 9, TraceID: 6, ParentTraceID: 3}},
+		{bus.PositionUpdateEvent, common.Position{Id: 9, TraceID: 5, ParentTraceID: 3}},
+		{bus.OrderEvent, common.Order{Command: common.OrderCommandPositionClose, PositionId: 9, TraceID: 7, ParentTraceID: 5}},
+		{bus.PositionCloseEvent, common.Position{Id: 9, TraceID: 5, ParentTraceID: 3}},
+		{bus.OrderEvent, common.Order{TraceID: 8}},
+	}
+
+	for _, ev := range events {
+		if err := r.Post(ev.id, ev.data); err != nil {
+			t.Fatalf("Post failed: %v", err)
+		}
+	}
+	if err := r.DrainEvents(context.Background()); err != nil {
+		t.Fatalf("DrainEvents failed: %v", err)
+	}
+}
+
+func checkLifecycleTree(t *testing.T, g *Graph) {
+	t.Helper()
+
+	root, err := g.PositionTree(9)
+	if err != nil {
+		t.Fatalf("PositionTree failed: %v", err)
+	}
+	if root.TraceID != 1 {
+		t.Fatalf("Expected root trace id 1, got %d", root.TraceID)
+	}
+
+	var got []string
+	root.Walk(func(node *Node, depth int) {
+		for _, ev := range node.Events {
+			got = append(got, strings.Repeat(".", depth)+eventName(ev.Id))
+		}
+	})
+
+	expected := []string{
+		"signal",
+		".signal_accepted",
+		".order",
+		"..order_accepted",
+		"..position_open",
+		"..position_update",
+		"..position_close",
+		"...order",
+		"..order_filled",
+	}
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected tree %v, got %v", expected, got)
+	}
+
+	var buf bytes.Buffer
+	if err := root.Print(&buf); err != nil {
+		t.Fatalf("Print failed: %v", err)
+	}
+	if lines := strings.Count(buf.String(), "\n"); lines != len(expected) {
+		t.Errorf("Expected %d printed lines, got %d", len(expected), lines)
+	}
+
+	if _, err := g.PositionTree(10); !errors.Is(err, ErrPositionNotFound) {
+		t.Errorf("Expected ErrPositionNotFound, got %v", err)
+	}
+}
+
+func TestTraceGraph_Subscribe(t *testing.T) {
+	r := bus.NewRouter(32)
+	g := NewGraph()
+
+	unsubscribe, err := g.Subscribe(r)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer unsubscribe()
+
+	postLifecycle(t, r)
+	checkLifecycleTree(t, g)
+}
+
+func TestTraceGraph_LoadJournal(t *testing.T) {
+	var buf bytes.Buffer
+	journal := bus.NewJournal(&buf)
+	r := bus.NewRouter(32, bus.WithJournal(journal))
+
+	postLifecycle(t, r)
+	if err := journal.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+
+	g, err := LoadJournal(bus.NewRouter(1), &buf)
+	if err != nil {
+		t.Fatalf("LoadJournal failed: %v", err)
+	}
+	checkLifecycleTree(t, g)
+}
+
+func TestTraceGraph_Cycle(t *testing.T) {
+	g := NewGraph()
+	g.Record(bus.OrderEvent, common.Order{TraceID: 1, ParentTraceID: 2})
+	g.Record(bus.PositionOpenEvent, common.Position{Id: 1, TraceID: 2, ParentTraceID: 1})
+
+	root, err := g.PositionTree(1)
+	if err != nil {
+		t.Fatalf("PositionTree failed: %v", err)
+	}
+
+	count := 0
+	root.Walk(func(*Node, int) { count++ })
+	if count != 2 {
+		t.Errorf("Expected 2 nodes, got %d", count)
+	}
+}
@@ -1,10 +1,20 @@
 package fixed
 
 import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+
 	"github.com/govalues/decimal"
 	"github.com/peter-kozarec/equinox/pkg/utility"
 )
 
+const (
+	binaryNegFlag  = 0x80
+	binaryTextFlag = 0x40
+)
+
 // Point is an unsafe wrapper around decimal implementation. Caller must make sure the calculations
 // are correct and will not result in an error state, otherwise it will panic
 type Point struct {
@@ -61,6 +71,87 @@ func (p Point) Log() Point { return Point{must(p.v.Log())} }
 
 func (p Point) MarshalText() ([]byte, error) { return []byte(p.String()), nil }
 
+func (p *Point) UnmarshalText(text []byte) error {
+	v, err := decimal.Parse(string(text))
+	if err != nil {
+		return err
+	}
+	p.v = v
+	return nil
+}
+
+// AppendBinary appends the compact binary form of the point: a header byte holding the sign and the scale
+// followed by the coefficient as uvarint. Coefficients beyond int64 fall back to the text form.
+func (p Point) AppendBinary(b []byte) ([]byte, error) {
+	coef := p.v.Coef()
+	if coef > math.MaxInt64 {
+		text := p.v.String()
+		b = append(b, binaryTextFlag)
+		b = binary.AppendUvarint(b, uint64(len(text)))
+		return append(b, text...), nil
+	}
+
+	header := byte(p.v.Scale())
+	if p.v.IsNeg() {
+		header |= binaryNegFlag
+	}
+	b = append(b, header)
+	return binary.AppendUvarint(b, coef), nil
+}
+
+func (p Point) MarshalBinary() ([]byte, error) {
+	return p.AppendBinary(nil)
+}
+
+func (p *Point) UnmarshalBinary(data []byte) error {
+	v, n, err := ReadBinary(data)
+	if err != nil {
+		return err
+	}
+	if n != len(data) {
+		return fmt.Errorf("%d trailing bytes after point", len(data)-n)
+	}
+	*p = v
+	return nil
+}
+
+// ReadBinary decodes a point written by AppendBinary from the start of data and returns the bytes consumed
+func ReadBinary(data []byte) (Point, int, error) {
+	if len(data) == 0 {
+		return Point{}, 0, io.ErrUnexpectedEOF
+	}
+	header := data[0]
+
+	value, n := binary.Uvarint(data[1:])
+	if n <= 0 {
+		return Point{}, 0, io.ErrUnexpectedEOF
+	}
+	consumed := 1 + n
+
+	if header == binaryTextFlag {
+		if uint64(len(data)-consumed) < value {
+			return Point{}, 0, io.ErrUnexpectedEOF
+		}
+		v, err := decimal.Parse(string(data[consumed : consumed+int(value)]))
+		if err != nil {
+			return Point{}, 0, err
+		}
+		return Point{v}, consumed + int(value), nil
+	}
+
+	if value > math.MaxInt64 {
+		return Point{}, 0, fmt.Errorf("point coefficient %d out of range", value)
+	}
+	v, err := decimal.New(int64(value), int(header&^binaryNegFlag))
+	if err != nil {
+		return Point{}, 0, err
+	}
+	if header&binaryNegFlag != 0 {
+		v = v.Neg()
+	}
+	return Point{v}, consumed, nil
+}
+
 func must(v decimal.Decimal, err error) decimal.Decimal {
 	if err == nil {
 		// Return in the happy path
@@ -3,6 +3,8 @@ package fixed
 import (
 	"math"
 	"testing"
+
+	"github.com/govalues/decimal"
 )
 
 func TestFixedPoint_FromInt64(t *testing.T) {
@@ -529,6 +531,56 @@ func TestFixedPoint_String(t *testing.T) {
 	}
 }
 
+func TestFixedPoint_UnmarshalText(t *testing.T) {
+	for _, want := range []Point{FromInt64(123, 0), FromInt64(-456, 3), FromFloat64(1.08345), {}} {
+		text, err := want.MarshalText()
+		if err != nil {
+			t.Fatalf("MarshalText() error = %v", err)
+		}
+		var got Point
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("UnmarshalText(%s) error = %v", text, err)
+		}
+		if !got.Eq(want) || got.Scale() != want.Scale() {
+			t.Errorf("UnmarshalText(%s) = %s; want %s", text, got, want)
+		}
+	}
+
+	var p Point
+	if err := p.UnmarshalText([]byte("abc")); err == nil {
+		t.Error("UnmarshalText(abc) expected error")
+	}
+}
+
+func TestFixedPoint_UnmarshalBinary(t *testing.T) {
+	large, err := decimal.Parse("9999999999999999999")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []Point{FromInt64(123, 0), FromInt64(-456, 3), FromFloat64(1.08345), FromInt64(math.MinInt64, 0), {large}, {}} {
+		data, err := want.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error = %v", err)
+		}
+		var got Point
+		if err := got.UnmarshalBinary(data); err != nil {
+			t.Fatalf("UnmarshalBinary(%x) error = %v", data, err)
+		}
+		if !got.Eq(want) || got.Scale() != want.Scale() {
+			t.Errorf("UnmarshalBinary(%x) = %s; want %s", data, got, want)
+		}
+	}
+
+	var p Point
+	if err := p.UnmarshalBinary(nil); err == nil {
+		t.Error("UnmarshalBinary(nil) expected error")
+	}
+	if err := p.UnmarshalBinary([]byte{0x02, 0x01, 0x00}); err == nil {
+		t.Error("UnmarshalBinary with trailing bytes expected error")
+	}
+}
+
 func TestFixedPoint_ChainedOperations(t *testing.T) {
 	a := FromInt64(10, 0)
 	b := FromInt64(5, 0)
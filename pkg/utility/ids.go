@@ -0,0 +1,71 @@
+package utility
+
+import (
+	"context"
+	"encoding/binary"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type idGeneratorKey struct{}
+
+// IDGenerator issues the execution id and the trace ids of a single run. A nil generator falls back
+// to the process wide GetExecutionID and CreateTraceID, so components can hold an optional one.
+type IDGenerator struct {
+	eid ExecutionID
+	tid atomic.Uint64
+}
+
+// NewIDGenerator returns a deterministic generator, runs using the same seed get the same execution id
+// and the same sequence of trace ids. Runs using different seeds get different execution ids and their
+// trace ids start at seed * 1e10 (wrapping for large seeds), as the time seeded ones start at unix time * 1e10
+func NewIDGenerator(seed uint64) *IDGenerator {
+	var name [8]byte
+	binary.BigEndian.PutUint64(name[:], seed)
+
+	g := &IDGenerator{
+		eid: uuid.NewSHA1(uuid.NameSpaceOID, name[:]),
+	}
+	g.tid.Store(seed * base)
+	return g
+}
+
+// NewRandomIDGenerator returns a generator with a random execution id and trace ids seeded by the current
+// time, as the process wide ones are
+func NewRandomIDGenerator() *IDGenerator {
+	g := &IDGenerator{
+		eid: uuid.New(),
+	}
+	g.tid.Store(I64ToU64Unsafe(time.Now().Unix()) * base)
+	return g
+}
+
+func (g *IDGenerator) ExecutionID() ExecutionID {
+	if g == nil {
+		return GetExecutionID()
+	}
+	return g.eid
+}
+
+func (g *IDGenerator) TraceID() TraceID {
+	if g == nil {
+		return CreateTraceID()
+	}
+	return g.tid.Add(delta)
+}
+
+// ContextWithIDGenerator returns a copy of ctx carrying the generator
+func ContextWithIDGenerator(ctx context.Context, g *IDGenerator) context.Context {
+	return context.WithValue(ctx, idGeneratorKey{}, g)
+}
+
+// IDGeneratorFromContext returns the generator carried by ctx, or nil when there is none
+func IDGeneratorFromContext(ctx context.Context) *IDGenerator {
+	if ctx == nil {
+		return nil
+	}
+	g, _ := ctx.Value(idGeneratorKey{}).(*IDGenerator)
+	return g
+}
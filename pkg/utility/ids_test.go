@@ -0,0 +1,71 @@
+package utility
+
+import (
+	"context"
+	"testing"
+)
+
+func TestUtility_IDGeneratorDeterministic(t *testing.T) {
+	g1 := NewIDGenerator(42)
+	g2 := NewIDGenerator(42)
+
+	if g1.ExecutionID() != g2.ExecutionID() {
+		t.Errorf("Expected same ExecutionID for same seed, got %s and %s", g1.ExecutionID(), g2.ExecutionID())
+	}
+
+	for i := 0; i < 100; i++ {
+		id1, id2 := g1.TraceID(), g2.TraceID()
+		if id1 != id2 {
+			t.Fatalf("Expected same TraceID sequence, got %d and %d at %d", id1, id2, i)
+		}
+		if want := 42*base + TraceID(i+1)*delta; id1 != want {
+			t.Fatalf("Expected TraceID %d, got %d", want, id1)
+		}
+	}
+
+	g3 := NewIDGenerator(43)
+	if g3.ExecutionID() == g1.ExecutionID() {
+		t.Error("Expected different ExecutionID for different seeds")
+	}
+	if g3.TraceID() == NewIDGenerator(42).TraceID() {
+		t.Error("Expected different TraceID sequences for different seeds")
+	}
+	if g1.ExecutionID() == GetExecutionID() {
+		t.Error("Expected run ExecutionID to differ from the process one")
+	}
+}
+
+func TestUtility_IDGeneratorRandom(t *testing.T) {
+	g1 := NewRandomIDGenerator()
+	g2 := NewRandomIDGenerator()
+
+	if g1.ExecutionID() == g2.ExecutionID() {
+		t.Error("Expected different ExecutionIDs")
+	}
+	if id1, id2 := g1.TraceID(), g1.TraceID(); id2-id1 != delta {
+		t.Errorf("Expected delta=%d, got %d", delta, id2-id1)
+	}
+}
+
+func TestUtility_IDGeneratorNil(t *testing.T) {
+	var g *IDGenerator
+
+	if g.ExecutionID() != GetExecutionID() {
+		t.Error("Expected nil generator to return the process ExecutionID")
+	}
+	if id1, id2 := g.TraceID(), CreateTraceID(); id2 <= id1 {
+		t.Errorf("Expected nil generator to use the process TraceID sequence, got %d then %d", id1, id2)
+	}
+}
+
+func TestUtility_IDGeneratorContext(t *testing.T) {
+	if IDGeneratorFromContext(context.Background()) != nil {
+		t.Error("Expected no generator in background context")
+	}
+
+	g := NewIDGenerator(1)
+	ctx := ContextWithIDGenerator(context.Background(), g)
+	if IDGeneratorFromContext(ctx) != g {
+		t.Error("Expected generator from context")
+	}
+}
@@ -106,6 +106,9 @@ func main() {
 	router.OnPositionUpdate = middleware.Chain(monitor.WithPositionUpdate, perf.WithPositionUpdate)(riskManager.OnPositionUpdate)
 	router.OnEquity = middleware.Chain(monitor.WithEquity, perf.WithEquity)(bus.MergeHandlers(riskManager.OnEquity, audit.OnEquity))
 	router.OnBalance = middleware.Chain(monitor.WithBalance, perf.WithBalance)(riskManager.OnBalance)
+	router.OnAccount = middleware.Chain(monitor.WithAccount, perf.WithAccount)(riskManager.OnAccount)
+	router.OnMarginCall = middleware.Chain(monitor.WithMarginCall, perf.WithMarginCall)(audit.OnMarginCall)
+	router.OnStopOut = middleware.Chain(monitor.WithStopOut, perf.WithStopOut)(audit.OnStopOut)
 	router.OnSignal = middleware.Chain(monitor.WithSignal, perf.WithSignal)(riskManager.OnSignal)
 	router.OnSignalAcceptance = middleware.Chain(monitor.WithSignalAcceptance, perf.WithSignalAcceptance)(middleware.NoopSignalAcceptanceHandler)
 	router.OnSignalRejection = middleware.Chain(monitor.WithSignalRejection, perf.WithSignalRejection)(middleware.NoopSignalRejectionHandler)
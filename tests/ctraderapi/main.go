@@ -35,7 +35,7 @@ var accessToken = os.Getenv("CtAccessToken")
 func main() {
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, nil)))
 
-	router := bus.NewRouter(routerCapacity)
+	router := bus.NewRouter(routerCapacity, bus.WithPriorityLanes(bus.DefaultStarvationLimit), bus.WithLatencyHistograms())
 	c, err := ctrader.DialDemo()
 	if err != nil {
 		slog.Error("unable to connect to demo device", "error", err)
@@ -64,6 +64,7 @@ func main() {
 	router.OnBar = middleware.Chain(monitor.WithBar)(advisor.OnBar)
 	router.OnBalance = middleware.Chain(monitor.WithBalance)(middleware.NoopBalanceHandler)
 	router.OnEquity = middleware.Chain(monitor.WithEquity)(middleware.NoopEquityHandler)
+	router.OnAccount = middleware.Chain(monitor.WithAccount)(middleware.NoopAccountHandler)
 	router.OnPositionOpen = middleware.Chain(monitor.WithPositionOpen)(middleware.NoopPositionUpdateHandler)
 	router.OnPositionClose = middleware.Chain(monitor.WithPositionClose)(middleware.NoopPositionUpdateHandler)
 	router.OnPositionUpdate = middleware.Chain(monitor.WithPositionUpdate)(middleware.NoopPositionUpdateHandler)
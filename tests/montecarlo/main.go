@@ -20,6 +20,7 @@ import (
 	"github.com/peter-kozarec/equinox/pkg/tools/metrics"
 	"github.com/peter-kozarec/equinox/pkg/tools/risk"
 	"github.com/peter-kozarec/equinox/pkg/tools/store"
+	"github.com/peter-kozarec/equinox/pkg/utility"
 	"github.com/peter-kozarec/equinox/pkg/utility/fixed"
 )
 
@@ -34,7 +35,8 @@ var (
 
 	meanReversionWindow = 60
 
-	genRng      = rand.New(rand.NewSource(time.Now().UnixNano()))
+	genSeed     = time.Now().UnixNano()
+	genRng      = rand.New(rand.NewSource(genSeed))
 	genDuration = 30 * 24 * time.Hour
 	genMu       = 0.1607143264
 	genSigma    = 0.0698081590
@@ -56,7 +58,8 @@ var (
 func main() {
 	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stdout, nil)))
 
-	router := bus.NewRouter(routerCapacity)
+	ids := utility.NewIDGenerator(uint64(genSeed))
+	router := bus.NewRouter(routerCapacity, bus.WithIDGenerator(ids))
 	simulator, err := sandbox.NewSimulator(router, accountCurrency, startBalance, symbolStore,
 		sandbox.WithSlippageHandler(func(_ common.Position) fixed.Point { return slippage }),
 		sandbox.WithMaintenanceMargin(fixed.FromFloat64(20)))
@@ -67,6 +70,7 @@ func main() {
 
 	builder := bar.NewBuilder(router, bar.With(symbolName, barPeriod, bar.PriceModeBid))
 	generator := synthetic.NewEURUSDTickGenerator(symbolName, genRng, genDuration, genMu, genSigma)
+	generator.SetIDGenerator(ids)
 
 	monitor := middleware.NewMonitor(middleware.MonitorAll)
 	perf := middleware.NewPerformance()
@@ -95,6 +99,9 @@ func main() {
 	router.OnPositionUpdate = middleware.Chain(monitor.WithPositionUpdate, perf.WithPositionUpdate)(riskManager.OnPositionUpdate)
 	router.OnEquity = middleware.Chain(monitor.WithEquity, perf.WithEquity)(bus.MergeHandlers(riskManager.OnEquity, audit.OnEquity))
 	router.OnBalance = middleware.Chain(monitor.WithBalance, perf.WithBalance)(riskManager.OnBalance)
+	router.OnAccount = middleware.Chain(monitor.WithAccount, perf.WithAccount)(riskManager.OnAccount)
+	router.OnMarginCall = middleware.Chain(monitor.WithMarginCall, perf.WithMarginCall)(audit.OnMarginCall)
+	router.OnStopOut = middleware.Chain(monitor.WithStopOut, perf.WithStopOut)(audit.OnStopOut)
 	router.OnSignal = middleware.Chain(monitor.WithSignal, perf.WithSignal)(riskManager.OnSignal)
 	router.OnSignalAcceptance = middleware.Chain(monitor.WithSignalAcceptance, perf.WithSignalAcceptance)(middleware.NoopSignalAcceptanceHandler)
 	router.OnSignalRejection = middleware.Chain(monitor.WithSignalRejection, perf.WithSignalRejection)(middleware.NoopSignalRejectionHandler)